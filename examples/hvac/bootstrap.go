@@ -59,7 +59,7 @@ func (b *HVACBootstrap) Process(f frames.Frame) ([]frames.Frame, error) {
 	}
 	switch sf.Name() {
 	case "call_end":
-		streamID := sf.Meta()[frames.MetaStreamID]
+		streamID := sf.Metadata().StreamID()
 		if streamID != "" {
 			b.mu.Lock()
 			delete(b.injected, streamID)
@@ -25,7 +25,11 @@ import (
 	"github.com/harunnryd/ranya/pkg/resilience"
 	"github.com/harunnryd/ranya/pkg/transports"
 	mocktransport "github.com/harunnryd/ranya/pkg/transports/mock"
+	siptransport "github.com/harunnryd/ranya/pkg/transports/sip"
+	telnyxtransport "github.com/harunnryd/ranya/pkg/transports/telnyx"
 	twiliotransport "github.com/harunnryd/ranya/pkg/transports/twilio"
+	vonagetransport "github.com/harunnryd/ranya/pkg/transports/vonage"
+	wstransport "github.com/harunnryd/ranya/pkg/transports/websocket"
 )
 
 // LogConfig defines the configuration for structured logging
@@ -302,7 +306,7 @@ func main() {
 	}
 
 	// 5. Initialize Ranya Engine
-	transport, err := buildTransport(cfg)
+	transport, namedTransports, err := buildTransports(cfg)
 	if err != nil {
 		panic(err)
 	}
@@ -310,6 +314,7 @@ func main() {
 		Config:           cfg,
 		Providers:        providers,
 		Transport:        transport,
+		Transports:       namedTransports,
 		Tools:            tools,
 		Agents:           agents,
 		Router:           router,
@@ -586,23 +591,47 @@ func validateSettings(path string, input map[string]any, schema configutil.Schem
 	return nil
 }
 
-func buildTransport(cfg ranya.Config) (transports.Transport, error) {
-	switch strings.ToLower(strings.TrimSpace(cfg.Transports.Provider)) {
+// buildTransports builds the default transport plus any transports.named
+// entries.
+func buildTransports(cfg ranya.Config) (transports.Transport, map[string]transports.Transport, error) {
+	var def transports.Transport
+	if strings.TrimSpace(cfg.Transports.Provider) != "" {
+		t, err := buildTransport("transports", cfg.Transports.Provider, cfg.Transports.Settings)
+		if err != nil {
+			return nil, nil, err
+		}
+		def = t
+	}
+	named := make(map[string]transports.Transport, len(cfg.Transports.Named))
+	for name, tc := range cfg.Transports.Named {
+		t, err := buildTransport("transports.named."+name, tc.Provider, tc.Settings)
+		if err != nil {
+			return nil, nil, err
+		}
+		named[name] = t
+	}
+	return def, named, nil
+}
+
+// buildTransport builds one transport; path is its config key, e.g.
+// "transports" or "transports.named.web".
+func buildTransport(path, provider string, rawSettings map[string]any) (transports.Transport, error) {
+	switch strings.ToLower(strings.TrimSpace(provider)) {
 	case "twilio":
-		if err := validateSettings("transports.settings", cfg.Transports.Settings, configutil.Schema{
+		if err := validateSettings(path+".settings", rawSettings, configutil.Schema{
 			Required: []string{"account_sid", "auth_token"},
-			Optional: []string{"public_url", "server_addr", "voice_path", "ws_path", "tts_webhook_path", "status_callback_path", "voice_greeting", "allow_any_origin", "allowed_origins"},
+			Optional: []string{"public_url", "server_addr", "voice_path", "ws_path", "tts_webhook_path", "status_callback_path", "voice_greeting", "dtmf_mode", "allow_any_origin", "allowed_origins"},
 		}); err != nil {
 			return nil, err
 		}
 		var settings twilioSettings
-		if err := configutil.DecodeSettings(cfg.Transports.Settings, &settings); err != nil {
+		if err := configutil.DecodeSettings(rawSettings, &settings); err != nil {
 			return nil, err
 		}
-		if err := configutil.RequireString(settings.AccountSID, "transports.settings.account_sid"); err != nil {
+		if err := configutil.RequireString(settings.AccountSID, path+".settings.account_sid"); err != nil {
 			return nil, err
 		}
-		if err := configutil.RequireString(settings.AuthToken, "transports.settings.auth_token"); err != nil {
+		if err := configutil.RequireString(settings.AuthToken, path+".settings.auth_token"); err != nil {
 			return nil, err
 		}
 		return twiliotransport.New(twiliotransport.Config{
@@ -618,9 +647,57 @@ func buildTransport(cfg ranya.Config) (transports.Transport, error) {
 			AllowAnyOrigin:     settings.AllowAnyOrigin,
 			AllowedOrigins:     settings.AllowedOrigins,
 		}), nil
+	case "websocket":
+		if err := validateSettings(path+".settings", rawSettings, configutil.Schema{
+			Optional: []string{"server_addr", "public_url", "path", "auth_tokens", "allow_any_origin", "allowed_origins", "input_audio", "output_audio", "emit_text", "max_message_size", "max_metadata"},
+		}); err != nil {
+			return nil, err
+		}
+		var settings wstransport.Config
+		if err := configutil.DecodeSettings(rawSettings, &settings); err != nil {
+			return nil, err
+		}
+		return wstransport.New(settings), nil
+	case "sip":
+		if err := validateSettings(path+".settings", rawSettings, configutil.Schema{
+			Optional: []string{"listen_addr", "network", "public_host", "rtp_port_min", "rtp_port_max", "codecs", "user_agent", "allowed_peers"},
+		}); err != nil {
+			return nil, err
+		}
+		var settings siptransport.Config
+		if err := configutil.DecodeSettings(rawSettings, &settings); err != nil {
+			return nil, err
+		}
+		return siptransport.New(settings), nil
+	case "telnyx":
+		if err := validateSettings(path+".settings", rawSettings, configutil.Schema{
+			Required: []string{"api_key"},
+			Optional: []string{"public_key", "connection_id", "public_url", "server_addr", "voice_path", "webhook_path", "ws_path", "api_base_url", "allow_any_origin", "allowed_origins"},
+		}); err != nil {
+			return nil, err
+		}
+		var settings telnyxtransport.Config
+		if err := configutil.DecodeSettings(rawSettings, &settings); err != nil {
+			return nil, err
+		}
+		if err := configutil.RequireString(settings.APIKey, path+".settings.api_key"); err != nil {
+			return nil, err
+		}
+		return telnyxtransport.New(settings), nil
+	case "vonage":
+		if err := validateSettings(path+".settings", rawSettings, configutil.Schema{
+			Optional: []string{"server_addr", "public_url", "signature_secret", "application_id", "private_key", "private_key_path", "answer_path", "event_path", "ws_path", "api_base_url", "sample_rate", "allow_any_origin", "allowed_origins"},
+		}); err != nil {
+			return nil, err
+		}
+		var settings vonagetransport.Config
+		if err := configutil.DecodeSettings(rawSettings, &settings); err != nil {
+			return nil, err
+		}
+		return vonagetransport.New(settings), nil
 	case "mock":
 		return mocktransport.New(), nil
 	default:
-		return nil, fmt.Errorf("unsupported transport provider: %s", cfg.Transports.Provider)
+		return nil, fmt.Errorf("unsupported transport provider: %s", provider)
 	}
 }
@@ -34,7 +34,7 @@ func (b *BadNetworkSimulator) Process(f frames.Frame) ([]frames.Frame, error) {
 	case frames.KindSystem:
 		sf := f.(frames.SystemFrame)
 		if sf.Name() == "call_end" {
-			streamID := sf.Meta()[frames.MetaStreamID]
+			streamID := sf.Metadata().StreamID()
 			if streamID != "" {
 				b.mu.Lock()
 				delete(b.counter, streamID)
@@ -44,7 +44,7 @@ func (b *BadNetworkSimulator) Process(f frames.Frame) ([]frames.Frame, error) {
 		return []frames.Frame{f}, nil
 	case frames.KindAudio:
 		af := f.(frames.AudioFrame)
-		streamID := af.Meta()[frames.MetaStreamID]
+		streamID := af.Metadata().StreamID()
 		if streamID == "" {
 			return []frames.Frame{f}, nil
 		}
@@ -53,7 +53,7 @@ func (b *BadNetworkSimulator) Process(f frames.Frame) ([]frames.Frame, error) {
 		n := b.counter[streamID]
 		b.mu.Unlock()
 		if n%b.dropEvery == 0 {
-			frames.ReleaseAudioFrame(f)
+			// Returning no frames hands f back to the orchestrator for release.
 			return nil, nil
 		}
 	}
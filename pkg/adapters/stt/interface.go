@@ -14,7 +14,9 @@ type StreamingSTT interface {
 	Start(ctx context.Context) error
 	// Close shuts down the STT connection.
 	Close() error
-	// SendAudio sends audio frames to the STT service.
+	// SendAudio sends audio frames to the STT service. The caller keeps
+	// ownership of pooled frames; implementations must not hold the payload
+	// after returning unless they retain the frame.
 	SendAudio(frame frames.AudioFrame) error
 	// Results returns a channel of transcription/control frames.
 	Results() <-chan frames.Frame
@@ -18,7 +18,8 @@ type StreamingTTS interface {
 	SendText(text string) error
 	// Flush stops current synthesis and clears buffers.
 	Flush()
-	// Results returns a channel of audio/control frames.
+	// Results returns a channel of audio/control frames. Audio frames may be
+	// pooled; the receiver owns and releases them.
 	Results() <-chan frames.Frame
 }
 
@@ -19,6 +19,8 @@ type TextAggregator struct {
 	meta        map[string]string
 	lastTokenAt time.Time
 	history     []string
+	// parts holds typed transcripts of the buffered fragments, if any.
+	parts []frames.Transcript
 }
 
 func NewTextAggregator(cfg AggregatorConfig) *TextAggregator {
@@ -79,13 +81,14 @@ func (a *TextAggregator) FlushFrame() *frames.TextFrame {
 		return nil
 	}
 
-	tf := frames.NewTextFrame(a.streamID, a.firstPTS, out, a.meta)
+	tf := a.frameLocked(out)
 
 	a.sb.Reset()
 	a.tokenCount = 0
 	a.firstPTS = 0
 	a.streamID = ""
 	a.meta = nil
+	a.parts = nil
 	a.appendHistory(out)
 
 	return &tf
@@ -100,23 +103,28 @@ func (a *TextAggregator) Process(f frames.Frame) ([]frames.Frame, error) {
 		a.mu.Lock()
 		if a.firstPTS == 0 {
 			a.firstPTS = tf.PTS()
-			a.streamID = tf.Meta()[frames.MetaStreamID]
+			a.streamID = tf.Metadata().StreamID()
 			a.meta = tf.Meta()
 		}
 		a.sb.WriteString(tf.Text())
 		a.tokenCount++
 		a.lastTokenAt = time.Now()
 		text := a.sb.String()
-		isFinal := tf.Meta()[frames.MetaIsFinal] == "true"
+		isFinal := tf.Metadata().Get(frames.MetaIsFinal) == "true"
+		if tr, ok := tf.Transcript(); ok {
+			a.parts = append(a.parts, tr)
+			isFinal = tr.Final
+		}
 		shouldFlush := eosDetected(text) || a.tokenCount >= a.cfg.MaxTokens || isFinal
 		final := strings.TrimSpace(text)
 		if shouldFlush && len(final) >= a.cfg.MinLen {
-			out := frames.NewTextFrame(a.streamID, a.firstPTS, final, a.meta)
+			out := a.frameLocked(final)
 			a.sb.Reset()
 			a.tokenCount = 0
 			a.firstPTS = 0
 			a.streamID = ""
 			a.meta = nil
+			a.parts = nil
 			a.appendHistory(final)
 			a.mu.Unlock()
 			return []frames.Frame{out}, nil
@@ -128,12 +136,13 @@ func (a *TextAggregator) Process(f frames.Frame) ([]frames.Frame, error) {
 		text := strings.TrimSpace(a.sb.String())
 		timeout := time.Since(a.lastTokenAt) > a.cfg.FlushTimeout && a.tokenCount > 0
 		if timeout && len(text) >= a.cfg.MinLen {
-			out := frames.NewTextFrame(a.streamID, a.firstPTS, text, a.meta)
+			out := a.frameLocked(text)
 			a.sb.Reset()
 			a.tokenCount = 0
 			a.firstPTS = 0
 			a.streamID = ""
 			a.meta = nil
+			a.parts = nil
 			a.appendHistory(text)
 			a.mu.Unlock()
 			return []frames.Frame{out, f}, nil
@@ -143,6 +152,15 @@ func (a *TextAggregator) Process(f frames.Frame) ([]frames.Frame, error) {
 	}
 }
 
+// frameLocked builds the aggregated frame, merging typed transcripts so word
+// timings survive aggregation. Callers hold a.mu.
+func (a *TextAggregator) frameLocked(text string) frames.TextFrame {
+	if len(a.parts) == 0 {
+		return frames.NewTextFrame(a.streamID, a.firstPTS, text, a.meta)
+	}
+	return frames.NewTranscriptFrame(a.streamID, a.firstPTS, frames.MergeTranscripts(text, a.parts), a.meta)
+}
+
 func eosDetected(s string) bool {
 	t := strings.TrimSpace(s)
 	if len(t) == 0 {
@@ -0,0 +1,108 @@
+// Package audio holds the G.711 codecs and PCM helpers shared by
+// transports, processors and observers.
+package audio
+
+var (
+	alawToUlaw [256]byte
+	ulawToAlaw [256]byte
+)
+
+func init() {
+	for i := 0; i < 256; i++ {
+		alawToUlaw[i] = LinearToULaw(ALawToLinear(byte(i)))
+		ulawToAlaw[i] = LinearToALaw(ULawToLinear(byte(i)))
+	}
+}
+
+// ULawToLinear decodes a G.711 μ-law sample.
+func ULawToLinear(u byte) int16 {
+	u = ^u
+	t := (int(u&0x0F) << 3) + 0x84
+	t <<= (u & 0x70) >> 4
+	if u&0x80 != 0 {
+		return int16(0x84 - t)
+	}
+	return int16(t - 0x84)
+}
+
+// LinearToULaw encodes a PCM16 sample as G.711 μ-law.
+func LinearToULaw(pcm int16) byte {
+	const bias = 0x84
+	const clip = 32635
+	sample := int(pcm)
+	sign := 0
+	if sample < 0 {
+		sample = -sample
+		sign = 0x80
+	}
+	if sample > clip {
+		sample = clip
+	}
+	sample += bias
+	exponent := 7
+	for mask := 0x4000; sample&mask == 0 && exponent > 0; mask >>= 1 {
+		exponent--
+	}
+	mantissa := (sample >> (exponent + 3)) & 0x0F
+	return ^byte(sign | exponent<<4 | mantissa)
+}
+
+// ALawToLinear decodes a G.711 A-law sample.
+func ALawToLinear(a byte) int16 {
+	a ^= 0x55
+	t := int(a&0x0F) << 4
+	seg := int(a&0x70) >> 4
+	switch seg {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t += 0x108
+		t <<= seg - 1
+	}
+	if a&0x80 != 0 {
+		return int16(t)
+	}
+	return int16(-t)
+}
+
+var alawSegEnd = [8]int{0x1F, 0x3F, 0x7F, 0xFF, 0x1FF, 0x3FF, 0x7FF, 0xFFF}
+
+// LinearToALaw encodes a PCM16 sample as G.711 A-law.
+func LinearToALaw(pcm int16) byte {
+	sample := int(pcm) >> 3
+	mask := 0xD5
+	if sample < 0 {
+		mask = 0x55
+		sample = -sample - 1
+	}
+	seg := 0
+	for seg < 8 && sample > alawSegEnd[seg] {
+		seg++
+	}
+	if seg >= 8 {
+		return byte(0x7F ^ mask)
+	}
+	aval := seg << 4
+	if seg < 2 {
+		aval |= (sample >> 1) & 0x0F
+	} else {
+		aval |= (sample >> seg) & 0x0F
+	}
+	return byte(aval ^ mask)
+}
+
+// ULawToALaw transcodes μ-law samples to A-law in place.
+func ULawToALaw(b []byte) {
+	for i, v := range b {
+		b[i] = ulawToAlaw[v]
+	}
+}
+
+// ALawToULaw transcodes A-law samples to μ-law in place.
+func ALawToULaw(b []byte) {
+	for i, v := range b {
+		b[i] = alawToUlaw[v]
+	}
+}
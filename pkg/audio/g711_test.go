@@ -0,0 +1,35 @@
+package audio
+
+import "testing"
+
+func TestG711RoundTrip(t *testing.T) {
+	for _, v := range []int16{0, 1000, -1000, 8000, -32000} {
+		if got := ULawToLinear(LinearToULaw(v)); absDiff(got, v) > int(absInt16(v))/16+16 {
+			t.Fatalf("ulaw round trip %d -> %d", v, got)
+		}
+		if got := ALawToLinear(LinearToALaw(v)); absDiff(got, v) > int(absInt16(v))/16+16 {
+			t.Fatalf("alaw round trip %d -> %d", v, got)
+		}
+	}
+	b := []byte{LinearToULaw(1000)}
+	ULawToALaw(b)
+	ALawToULaw(b)
+	if got := ULawToLinear(b[0]); absDiff(got, 1000) > 1000/16+16 {
+		t.Fatalf("transcode round trip 1000 -> %d", got)
+	}
+}
+
+func absInt16(v int16) int16 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func absDiff(a, b int16) int {
+	d := int(a) - int(b)
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,129 @@
+package audio
+
+import (
+	"encoding/binary"
+	"strings"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+)
+
+// Encoding is a sample format the shared helpers can decode.
+type Encoding int
+
+const (
+	EncodingUnknown Encoding = iota
+	EncodingMuLaw
+	EncodingALaw
+	EncodingPCM16
+)
+
+// ParseEncoding maps a frames.MetaEncoding value to an Encoding. Telephony
+// audio without an encoding is μ-law, as transports deliver it.
+func ParseEncoding(name string) Encoding {
+	switch strings.ToLower(name) {
+	case "", "mulaw", "ulaw", "pcmu":
+		return EncodingMuLaw
+	case "alaw", "pcma":
+		return EncodingALaw
+	case "pcm16", "linear16", "pcm", "s16le":
+		return EncodingPCM16
+	}
+	return EncodingUnknown
+}
+
+// EncodingOf returns the encoding of af.
+func EncodingOf(af frames.AudioFrame) Encoding {
+	return ParseEncoding(af.Metadata().Get(frames.MetaEncoding))
+}
+
+// BytesPerSample is the size of one sample of one channel. Telephony codecs
+// use one byte per sample; PCM uses two.
+func (e Encoding) BytesPerSample() int {
+	if e == EncodingPCM16 {
+		return 2
+	}
+	return 1
+}
+
+// Decode converts data to PCM16, keeping the first of channels interleaved
+// channels. It returns nil for EncodingUnknown.
+func Decode(data []byte, enc Encoding, channels int) []int16 {
+	if channels <= 0 {
+		channels = 1
+	}
+	var pcm []int16
+	switch enc {
+	case EncodingMuLaw:
+		pcm = make([]int16, len(data)/channels)
+		for i := range pcm {
+			pcm[i] = ULawToLinear(data[i*channels])
+		}
+	case EncodingALaw:
+		pcm = make([]int16, len(data)/channels)
+		for i := range pcm {
+			pcm[i] = ALawToLinear(data[i*channels])
+		}
+	case EncodingPCM16:
+		pcm = make([]int16, len(data)/(2*channels))
+		for i := range pcm {
+			pcm[i] = int16(binary.LittleEndian.Uint16(data[i*2*channels:]))
+		}
+	}
+	return pcm
+}
+
+// DecodeFrame converts af to mono PCM16 at its own rate. It reports false
+// for encodings it cannot decode.
+func DecodeFrame(af frames.AudioFrame) ([]int16, bool) {
+	enc := EncodingOf(af)
+	if enc == EncodingUnknown {
+		return nil, false
+	}
+	return Decode(af.RawPayload(), enc, af.Channels()), true
+}
+
+// EncodePCM16 returns pcm as little-endian bytes.
+func EncodePCM16(pcm []int16) []byte {
+	out := make([]byte, 0, len(pcm)*2)
+	for _, s := range pcm {
+		out = binary.LittleEndian.AppendUint16(out, uint16(s))
+	}
+	return out
+}
+
+// Duration is how long af takes to play. Frames without a rate are taken as
+// 8kHz telephony audio.
+func Duration(af frames.AudioFrame) time.Duration {
+	rate := af.Rate()
+	if rate <= 0 {
+		rate = 8000
+	}
+	ch := af.Channels()
+	if ch <= 0 {
+		ch = 1
+	}
+	samples := len(af.RawPayload()) / (EncodingOf(af).BytesPerSample() * ch)
+	return time.Duration(samples) * time.Second / time.Duration(rate)
+}
+
+// Resample converts between rates with linear interpolation, which is
+// plenty for speech.
+func Resample(pcm []int16, from, to int) []int16 {
+	if from == to || len(pcm) == 0 {
+		return pcm
+	}
+	n := len(pcm) * to / from
+	out := make([]int16, n)
+	for i := range out {
+		pos := float64(i) * float64(from) / float64(to)
+		j := int(pos)
+		if j+1 >= len(pcm) {
+			out[i] = pcm[len(pcm)-1]
+			continue
+		}
+		frac := pos - float64(j)
+		out[i] = int16(float64(pcm[j])*(1-frac) + float64(pcm[j+1])*frac)
+	}
+	return out
+}
@@ -0,0 +1,431 @@
+// Package campaign dials a contact list through an outbound-capable
+// transport, honouring concurrency limits, calling windows and per-outcome
+// retry policies, and writes one result line per attempt.
+//
+// Outcomes come from call_end reports: call Campaign.HandleCallEnd from
+// ranya.EngineHooks.OnCallEnd, or call Report directly.
+package campaign
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/transports"
+)
+
+// Outcome is the result of one call attempt.
+type Outcome string
+
+const (
+	OutcomeCompleted Outcome = "completed"
+	OutcomeBusy      Outcome = "busy"
+	OutcomeNoAnswer  Outcome = "no_answer"
+	// OutcomeFailed covers carrier failures, dial errors and attempts whose
+	// outcome never arrived within Config.ResultTimeout.
+	OutcomeFailed Outcome = "failed"
+)
+
+// OutcomeFromReason maps a call_end_reason to an outcome. Reasons for calls
+// that connected (completed, agent_hangup, transferred, ...) are completed.
+func OutcomeFromReason(reason string) Outcome {
+	switch strings.ToLower(strings.TrimSpace(reason)) {
+	case "busy":
+		return OutcomeBusy
+	case "no_answer", "no-answer", "noanswer":
+		return OutcomeNoAnswer
+	case "failed", "canceled", "cancelled", "unknown", "":
+		return OutcomeFailed
+	}
+	return OutcomeCompleted
+}
+
+// RetryRule schedules further attempts after an outcome.
+type RetryRule struct {
+	// MaxAttempts caps the total attempts for a contact, counting the first,
+	// while its latest outcome is this one.
+	MaxAttempts int
+	// Delay is the wait after the attempt ends; the calling window still
+	// applies.
+	Delay time.Duration
+}
+
+// Config controls a campaign.
+type Config struct {
+	// From is the caller ID presented to contacts.
+	From string
+	// URL is the voice webhook passed to the dialer; empty uses the
+	// transport's default.
+	URL string
+	// SendDigits is passed through DialOptions when the dialer supports it.
+	SendDigits string
+	// MachineDetection is passed through DialOptions to request carrier
+	// answering machine detection.
+	MachineDetection string
+	// PassVars sends each contact's ID and Vars to the call as stream
+	// parameters, so the session sees them in its metadata.
+	PassVars bool
+	// MaxConcurrent caps calls in flight. Default 1.
+	MaxConcurrent int
+	// MaxPerNumber caps calls in flight to the same phone number, for lists
+	// with duplicate numbers. Default 1.
+	MaxPerNumber int
+	Window       Window
+	// Retry maps outcomes to retry rules; outcomes without a rule are final.
+	Retry map[Outcome]RetryRule
+	// ResultTimeout bounds the wait for an attempt's outcome after dialing.
+	// Default 30m.
+	ResultTimeout time.Duration
+}
+
+const defaultResultTimeout = 30 * time.Minute
+
+func (c Config) withDefaults() Config {
+	if c.MaxConcurrent <= 0 {
+		c.MaxConcurrent = 1
+	}
+	if c.MaxPerNumber <= 0 {
+		c.MaxPerNumber = 1
+	}
+	if c.ResultTimeout <= 0 {
+		c.ResultTimeout = defaultResultTimeout
+	}
+	return c
+}
+
+// Result is written as one JSON line per attempt.
+type Result struct {
+	ContactID string            `json:"contact_id"`
+	Phone     string            `json:"phone"`
+	Attempt   int               `json:"attempt"`
+	CallSID   string            `json:"call_sid,omitempty"`
+	Outcome   Outcome           `json:"outcome"`
+	Reason    string            `json:"reason,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	StartedAt time.Time         `json:"started_at"`
+	EndedAt   time.Time         `json:"ended_at"`
+	Final     bool              `json:"final"`
+	NextAt    *time.Time        `json:"next_attempt_at,omitempty"`
+	Vars      map[string]string `json:"vars,omitempty"`
+}
+
+// Campaign dials contacts and tracks their outcomes. A Campaign runs one
+// contact list at a time.
+type Campaign struct {
+	cfg    Config
+	window window
+	dialer transports.OutboundDialer
+	now    func() time.Time
+
+	outMu sync.Mutex
+	out   io.Writer
+
+	mu      sync.Mutex
+	calls   map[string]*call
+	dialing int
+	// early holds outcomes reported while a dial was still returning its
+	// call SID; it is dropped once no dial is in flight.
+	early map[string]report
+}
+
+type call struct {
+	contact Contact
+	done    chan report
+}
+
+type report struct {
+	outcome Outcome
+	reason  string
+}
+
+// New creates a campaign that dials through dialer and writes results to
+// out (which may be nil).
+func New(cfg Config, dialer transports.OutboundDialer, out io.Writer) (*Campaign, error) {
+	if dialer == nil {
+		return nil, errors.New("campaign: dialer required")
+	}
+	if sr, ok := dialer.(transports.CallStatusReporter); ok && !sr.ReportsCallStatus() {
+		// Busy and no-answer calls would never report an outcome and hold
+		// their slot forever.
+		return nil, errors.New("campaign: dialer does not report call status; configure a status callback (public_url)")
+	}
+	cfg = cfg.withDefaults()
+	if strings.TrimSpace(cfg.From) == "" {
+		return nil, errors.New("campaign: from number required")
+	}
+	w, err := cfg.Window.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Campaign{
+		cfg:    cfg,
+		window: w,
+		dialer: dialer,
+		now:    time.Now,
+		out:    out,
+		calls:  make(map[string]*call),
+		early:  make(map[string]report),
+	}, nil
+}
+
+// HandleCallEnd reports a call that ended with the given call_end_reason,
+// typically from ranya.EngineHooks.OnCallEnd. Calls the campaign did not
+// place are ignored.
+func (c *Campaign) HandleCallEnd(callSID, reason string) {
+	c.report(callSID, report{outcome: OutcomeFromReason(reason), reason: reason})
+}
+
+// Report records the outcome of a call the campaign placed. It returns
+// false if callSID is not an active campaign call.
+func (c *Campaign) Report(callSID string, outcome Outcome) bool {
+	return c.report(callSID, report{outcome: outcome})
+}
+
+func (c *Campaign) report(callSID string, r report) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cl, ok := c.calls[callSID]
+	if !ok {
+		if c.dialing > 0 {
+			c.early[callSID] = r
+		}
+		return false
+	}
+	delete(c.calls, callSID)
+	cl.done <- r
+	return true
+}
+
+// Contact returns the contact an active call was placed to, so processors
+// can pull per-contact variables into the conversation.
+func (c *Campaign) Contact(callSID string) (Contact, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cl, ok := c.calls[callSID]
+	if !ok {
+		return Contact{}, false
+	}
+	return cl.contact, true
+}
+
+// job is a contact waiting for its next attempt.
+type job struct {
+	contact  Contact
+	number   string
+	loc      *time.Location
+	attempts int
+	nextAt   time.Time
+}
+
+// Run dials every contact until each has a final result or ctx ends. It
+// returns ctx.Err() if cancelled; results already written stand.
+func (c *Campaign) Run(ctx context.Context, contacts []Contact) error {
+	jobs := make([]*job, 0, len(contacts))
+	for _, ct := range contacts {
+		loc := c.window.loc
+		if ct.Timezone != "" {
+			l, err := time.LoadLocation(ct.Timezone)
+			if err != nil {
+				// Never guess local hours; a contact with a bad timezone is
+				// not called at all.
+				now := c.now()
+				c.write(Result{
+					ContactID: ct.ID, Phone: ct.Phone, Outcome: OutcomeFailed,
+					Error: fmt.Sprintf("invalid timezone %q", ct.Timezone), StartedAt: now, EndedAt: now,
+					Final: true, Vars: ct.Vars,
+				})
+				continue
+			}
+			loc = l
+		}
+		jobs = append(jobs, &job{contact: ct, number: normalizeNumber(ct.Phone), loc: loc})
+	}
+
+	done := make(chan attemptResult, c.cfg.MaxConcurrent)
+	active := 0
+	perNumber := make(map[string]int)
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+	for len(jobs) > 0 || active > 0 {
+		now := c.now()
+		var wake time.Time
+		waiting := jobs[:0]
+		for _, j := range jobs {
+			if active >= c.cfg.MaxConcurrent || perNumber[j.number] >= c.cfg.MaxPerNumber {
+				waiting = append(waiting, j)
+				continue
+			}
+			at := j.nextAt
+			if at.Before(now) {
+				at = now
+			}
+			at = c.window.next(at, j.loc)
+			if at.After(now) {
+				if wake.IsZero() || at.Before(wake) {
+					wake = at
+				}
+				waiting = append(waiting, j)
+				continue
+			}
+			active++
+			perNumber[j.number]++
+			j.attempts++
+			go func(j *job) { done <- c.attempt(ctx, j) }(j)
+		}
+		jobs = waiting
+
+		var wakeCh <-chan time.Time
+		if !wake.IsZero() {
+			timer.Reset(wake.Sub(now))
+			wakeCh = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case res := <-done:
+			active--
+			perNumber[res.job.number]--
+			if next, ok := c.retryAt(res); ok {
+				res.job.nextAt = next
+				res.NextAt = &next
+				jobs = append(jobs, res.job)
+			} else {
+				res.Final = true
+			}
+			c.write(res.Result)
+		case <-wakeCh:
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+	}
+	return nil
+}
+
+type attemptResult struct {
+	Result
+	job *job
+}
+
+func (c *Campaign) retryAt(res attemptResult) (time.Time, bool) {
+	rule, ok := c.cfg.Retry[res.Outcome]
+	if !ok || res.job.attempts >= rule.MaxAttempts {
+		return time.Time{}, false
+	}
+	return res.EndedAt.Add(rule.Delay), true
+}
+
+// attempt dials one contact and waits for its outcome.
+func (c *Campaign) attempt(ctx context.Context, j *job) attemptResult {
+	res := attemptResult{job: j, Result: Result{
+		ContactID: j.contact.ID,
+		Phone:     j.contact.Phone,
+		Attempt:   j.attempts,
+		StartedAt: c.now(),
+		Vars:      j.contact.Vars,
+	}}
+	finish := func(r report, err error) attemptResult {
+		res.Outcome = r.outcome
+		res.Reason = r.reason
+		if err != nil {
+			res.Error = err.Error()
+		}
+		res.EndedAt = c.now()
+		return res
+	}
+
+	c.mu.Lock()
+	c.dialing++
+	c.mu.Unlock()
+	callSID, err := c.dial(ctx, j.contact)
+	cl := &call{contact: j.contact, done: make(chan report, 1)}
+	c.mu.Lock()
+	c.dialing--
+	if err == nil {
+		if r, ok := c.early[callSID]; ok {
+			cl.done <- r
+		} else {
+			c.calls[callSID] = cl
+		}
+	}
+	if c.dialing == 0 {
+		c.early = make(map[string]report)
+	}
+	c.mu.Unlock()
+	if err != nil {
+		return finish(report{outcome: OutcomeFailed}, fmt.Errorf("dial: %w", err))
+	}
+	res.CallSID = callSID
+
+	timer := time.NewTimer(c.cfg.ResultTimeout)
+	defer timer.Stop()
+	select {
+	case r := <-cl.done:
+		return finish(r, nil)
+	case <-timer.C:
+		c.forget(callSID)
+		return finish(report{outcome: OutcomeFailed}, fmt.Errorf("no outcome within %s", c.cfg.ResultTimeout))
+	case <-ctx.Done():
+		c.forget(callSID)
+		return finish(report{outcome: OutcomeFailed}, ctx.Err())
+	}
+}
+
+func (c *Campaign) dial(ctx context.Context, ct Contact) (string, error) {
+	opts := transports.DialOptions{SendDigits: c.cfg.SendDigits, MachineDetection: c.cfg.MachineDetection}
+	if c.cfg.PassVars {
+		opts.Parameters = maps.Clone(ct.Vars)
+		if opts.Parameters == nil {
+			opts.Parameters = make(map[string]string)
+		}
+		opts.Parameters["contact_id"] = ct.ID
+	}
+	if d, ok := c.dialer.(transports.OutboundDialerWithOptions); ok && (opts.SendDigits != "" || opts.MachineDetection != "" || len(opts.Parameters) > 0) {
+		return d.DialWithOptions(ctx, ct.Phone, c.cfg.From, c.cfg.URL, opts)
+	}
+	return c.dialer.Dial(ctx, ct.Phone, c.cfg.From, c.cfg.URL)
+}
+
+func (c *Campaign) forget(callSID string) {
+	c.mu.Lock()
+	delete(c.calls, callSID)
+	c.mu.Unlock()
+}
+
+func (c *Campaign) write(r Result) {
+	if c.out == nil {
+		return
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	c.outMu.Lock()
+	defer c.outMu.Unlock()
+	_, _ = c.out.Write(append(b, '\n'))
+}
+
+// normalizeNumber strips formatting so "+1 (555) 010-0000" and
+// "+15550100000" share a per-number limit.
+func normalizeNumber(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if (r >= '0' && r <= '9') || r == '+' {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return phone
+	}
+	return b.String()
+}
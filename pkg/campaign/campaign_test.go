@@ -0,0 +1,197 @@
+package campaign
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/transports/mock"
+)
+
+func TestReadContacts(t *testing.T) {
+	csvContacts, err := ReadCSV(strings.NewReader("ID,Phone,timezone,name\nc1,+15550001,America/New_York,Ann\n,+15550002,,\n"))
+	if err != nil || len(csvContacts) != 2 {
+		t.Fatalf("read csv: %v %v", csvContacts, err)
+	}
+	if c := csvContacts[0]; c.ID != "c1" || c.Timezone != "America/New_York" || c.Vars["name"] != "Ann" {
+		t.Fatalf("unexpected csv contact %+v", c)
+	}
+	if c := csvContacts[1]; c.ID != "2" || c.Vars != nil {
+		t.Fatalf("unexpected numbered contact %+v", c)
+	}
+	jsonContacts, err := ReadJSONL(strings.NewReader(`{"id":"j1","phone":"+15550003","vars":{"plan":"gold"},"balance":12.5}` + "\n\n"))
+	if err != nil || len(jsonContacts) != 1 {
+		t.Fatalf("read jsonl: %v %v", jsonContacts, err)
+	}
+	if c := jsonContacts[0]; c.Vars["plan"] != "gold" || c.Vars["balance"] != "12.5" {
+		t.Fatalf("unexpected jsonl contact %+v", c)
+	}
+	if _, err := ReadJSONL(strings.NewReader(`{"id":"x"}`)); err == nil {
+		t.Fatalf("expected missing phone error")
+	}
+}
+
+func TestWindowNext(t *testing.T) {
+	w, err := Window{Start: "09:00", End: "17:00", Days: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}}.parse()
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	loc := time.FixedZone("UTC-5", -5*3600)
+	at := func(day, hour int) time.Time { return time.Date(2026, 10, day, hour, 0, 0, 0, loc) }
+	// 2026-10-17 is a Saturday.
+	if got := w.next(at(17, 10), loc); !got.Equal(at(19, 9)) {
+		t.Fatalf("weekend: got %v", got)
+	}
+	if got := w.next(at(20, 8), loc); !got.Equal(at(20, 9)) {
+		t.Fatalf("before open: got %v", got)
+	}
+	if got := w.next(at(20, 12), loc); !got.Equal(at(20, 12)) {
+		t.Fatalf("inside: got %v", got)
+	}
+	// Evaluated in the contact's zone, 12:00 UTC is 07:00 local.
+	if got := w.next(time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC), loc); !got.Equal(at(20, 9)) {
+		t.Fatalf("zone: got %v", got)
+	}
+	night, _ := Window{Start: "22:00", End: "02:00"}.parse()
+	if got := night.next(at(20, 1), loc); !got.Equal(at(20, 1)) {
+		t.Fatalf("overnight: got %v", got)
+	}
+	if _, err := (Window{Start: "9am", End: "17:00"}).parse(); err == nil {
+		t.Fatalf("expected invalid start error")
+	}
+}
+
+func TestCampaignRetriesAndLimits(t *testing.T) {
+	tr := mock.New()
+	var out bytes.Buffer
+	c, err := New(Config{
+		From:          "+15559999",
+		MaxConcurrent: 2,
+		PassVars:      true,
+		Retry:         map[Outcome]RetryRule{OutcomeBusy: {MaxAttempts: 2, Delay: 10 * time.Millisecond}},
+	}, tr, &out)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	contacts, err := ReadCSV(strings.NewReader("id,phone,timezone,name\na,+15550001,,Ann\nb,+1 555 0001,,Ben\nc,+15550002,,Cy\nd,+15550003,Mars/Base,Di\n"))
+	if err != nil {
+		t.Fatalf("contacts: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	runErr := make(chan error, 1)
+	go func() { runErr <- c.Run(ctx, contacts) }()
+
+	// Play the carrier: the first call to +15550001 is busy, +15550002
+	// never answers, everything else completes.
+	var mu sync.Mutex
+	var violations []string
+	seen := 0
+	answered := map[string]bool{}
+	for {
+		select {
+		case err := <-runErr:
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(violations) > 0 {
+				t.Fatalf("limit violations: %v", violations)
+			}
+			checkResults(t, out.String(), len(tr.Dials()))
+			return
+		case <-time.After(time.Millisecond):
+		}
+		dials := tr.Dials()
+		for ; seen < len(dials); seen++ {
+			d := dials[seen]
+			contact, ok := c.Contact(d.CallSID)
+			if !ok || contact.Vars["name"] == "" {
+				t.Fatalf("no contact for %s", d.CallSID)
+			}
+			if p := d.Options.Parameters; p["contact_id"] != contact.ID || p["name"] != contact.Vars["name"] {
+				t.Fatalf("unexpected dial parameters %v", p)
+			}
+			if d.From != "+15559999" {
+				t.Fatalf("unexpected caller id %q", d.From)
+			}
+			number := normalizeNumber(d.To)
+			reason := "completed"
+			switch {
+			case number == "+15550002":
+				reason = "no_answer"
+			case !answered[number]:
+				answered[number] = true
+				reason = "busy"
+			}
+			go func(sid, number, reason string) {
+				// Overlapping calls to one number would show up as two
+				// contacts active at once.
+				for _, other := range tr.Dials() {
+					if other.CallSID != sid && normalizeNumber(other.To) == number {
+						if _, active := c.Contact(other.CallSID); active {
+							mu.Lock()
+							violations = append(violations, sid+" overlaps "+other.CallSID)
+							mu.Unlock()
+						}
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				c.HandleCallEnd(sid, reason)
+			}(d.CallSID, number, reason)
+		}
+	}
+}
+
+func checkResults(t *testing.T, out string, dials int) {
+	t.Helper()
+	final := map[string]Result{}
+	attempts := map[string]int{}
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		var r Result
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			t.Fatalf("decode result %q: %v", sc.Text(), err)
+		}
+		attempts[r.ContactID]++
+		if r.Final {
+			final[r.ContactID] = r
+		} else if r.NextAt == nil {
+			t.Fatalf("retry without next attempt time: %+v", r)
+		}
+	}
+	// a is busy then retried; b shares its number and waits for it.
+	if dials != 4 || attempts["a"] != 2 || final["a"].Outcome != OutcomeCompleted || final["a"].Attempt != 2 {
+		t.Fatalf("unexpected results for a (dials=%d):\n%s", dials, out)
+	}
+	if final["b"].Outcome != OutcomeCompleted || final["c"].Outcome != OutcomeNoAnswer || final["c"].Reason != "no_answer" {
+		t.Fatalf("unexpected results:\n%s", out)
+	}
+	if d := final["d"]; d.Outcome != OutcomeFailed || d.CallSID != "" || !strings.Contains(d.Error, "timezone") {
+		t.Fatalf("expected d to fail without dialing: %+v", d)
+	}
+	if final["c"].Vars["name"] != "Cy" {
+		t.Fatalf("expected contact vars in results: %+v", final["c"])
+	}
+}
+
+type statuslessDialer struct{}
+
+func (statuslessDialer) Dial(ctx context.Context, to, from, url string) (string, error) {
+	return "call-1", nil
+}
+
+func (statuslessDialer) ReportsCallStatus() bool { return false }
+
+func TestNewRequiresCallStatus(t *testing.T) {
+	if _, err := New(Config{From: "+15559999"}, statuslessDialer{}, nil); err == nil {
+		t.Fatalf("expected a dialer without call status reports to be refused")
+	}
+}
@@ -0,0 +1,198 @@
+package campaign
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Contact is one entry of a contact list.
+type Contact struct {
+	ID    string `json:"id"`
+	Phone string `json:"phone"`
+	// Timezone is an IANA name (e.g. "America/New_York") used for the
+	// calling window; empty uses Window.Timezone.
+	Timezone string `json:"timezone,omitempty"`
+	// Vars are free-form per-contact values, for example a name or account
+	// number the agent prompt refers to.
+	Vars map[string]string `json:"vars,omitempty"`
+}
+
+// LoadContacts reads a contact list from path, choosing the format by
+// extension: .csv, or .jsonl/.ndjson.
+func LoadContacts(path string) ([]Contact, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return ReadCSV(f)
+	case ".jsonl", ".ndjson":
+		return ReadJSONL(f)
+	}
+	return nil, fmt.Errorf("campaign: unsupported contact list %q (want .csv or .jsonl)", path)
+}
+
+// ReadCSV reads contacts from CSV with a header row. The id, phone and
+// timezone columns are recognized (case-insensitive); every other column
+// becomes a variable. A phone column is required.
+func ReadCSV(r io.Reader) ([]Contact, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("campaign: read csv header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+	hasPhone := false
+	for _, h := range header {
+		if strings.EqualFold(h, "phone") {
+			hasPhone = true
+		}
+	}
+	if !hasPhone {
+		return nil, errors.New("campaign: csv has no phone column")
+	}
+	var out []Contact
+	for row := 1; ; row++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("campaign: read csv row %d: %w", row, err)
+		}
+		var c Contact
+		for i, h := range header {
+			if i >= len(rec) {
+				break
+			}
+			v := strings.TrimSpace(rec[i])
+			switch strings.ToLower(h) {
+			case "id":
+				c.ID = v
+			case "phone":
+				c.Phone = v
+			case "timezone":
+				c.Timezone = v
+			default:
+				if v == "" {
+					continue
+				}
+				if c.Vars == nil {
+					c.Vars = make(map[string]string)
+				}
+				c.Vars[h] = v
+			}
+		}
+		if err := c.finish(row); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+}
+
+// ReadJSONL reads one JSON object per line. Besides id, phone, timezone and
+// a vars object, any other top-level scalar field becomes a variable. Blank
+// lines are skipped.
+func ReadJSONL(r io.Reader) ([]Contact, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	var out []Contact
+	row := 0
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		row++
+		var raw map[string]any
+		if err := json.Unmarshal([]byte(line), &raw); err != nil {
+			return nil, fmt.Errorf("campaign: decode contact %d: %w", row, err)
+		}
+		var c Contact
+		for k, v := range raw {
+			switch k {
+			case "id":
+				c.ID = scalar(v)
+			case "phone":
+				c.Phone = scalar(v)
+			case "timezone":
+				c.Timezone = scalar(v)
+			case "vars":
+				vars, ok := v.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("campaign: contact %d: vars must be an object", row)
+				}
+				for vk, vv := range vars {
+					c.setVar(vk, scalar(vv))
+				}
+			default:
+				if _, nested := v.(map[string]any); nested {
+					continue
+				}
+				if _, list := v.([]any); list {
+					continue
+				}
+				c.setVar(k, scalar(v))
+			}
+		}
+		if err := c.finish(row); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("campaign: read contacts: %w", err)
+	}
+	return out, nil
+}
+
+func (c *Contact) setVar(k, v string) {
+	if v == "" {
+		return
+	}
+	if c.Vars == nil {
+		c.Vars = make(map[string]string)
+	}
+	c.Vars[k] = v
+}
+
+// finish validates a parsed contact; rows without an id are numbered.
+func (c *Contact) finish(row int) error {
+	c.Phone = strings.TrimSpace(c.Phone)
+	if c.Phone == "" {
+		return fmt.Errorf("campaign: contact %d has no phone", row)
+	}
+	if c.ID == "" {
+		c.ID = strconv.Itoa(row)
+	}
+	return nil
+}
+
+func scalar(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(t)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	default:
+		return fmt.Sprint(t)
+	}
+}
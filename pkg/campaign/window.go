@@ -0,0 +1,110 @@
+package campaign
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window restricts dialing to local hours on given weekdays, evaluated in
+// each contact's timezone. The zero value allows calls at any time.
+type Window struct {
+	// Start and End are local "HH:MM" times. End at or before Start spans
+	// midnight. Both empty means all day.
+	Start string
+	End   string
+	// Days lists allowed weekdays; empty allows every day. For windows that
+	// span midnight the day is the one the window opens on.
+	Days []time.Weekday
+	// Timezone is the IANA name used for contacts without one; empty is the
+	// process's local time.
+	Timezone string
+}
+
+// window is a parsed Window.
+type window struct {
+	start, end time.Duration
+	allDay     bool
+	days       [7]bool
+	loc        *time.Location
+}
+
+func (w Window) parse() (window, error) {
+	out := window{allDay: w.Start == "" && w.End == "", loc: time.Local}
+	var err error
+	if !out.allDay {
+		if out.start, err = parseClock(w.Start); err != nil {
+			return out, fmt.Errorf("campaign: window start: %w", err)
+		}
+		if out.end, err = parseClock(w.End); err != nil {
+			return out, fmt.Errorf("campaign: window end: %w", err)
+		}
+	}
+	if len(w.Days) == 0 {
+		for i := range out.days {
+			out.days[i] = true
+		}
+	}
+	for _, d := range w.Days {
+		if d < time.Sunday || d > time.Saturday {
+			return out, fmt.Errorf("campaign: invalid weekday %d", d)
+		}
+		out.days[d] = true
+	}
+	if w.Timezone != "" {
+		if out.loc, err = time.LoadLocation(w.Timezone); err != nil {
+			return out, fmt.Errorf("campaign: window timezone: %w", err)
+		}
+	}
+	return out, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (want HH:MM)", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// next returns the earliest time at or after t that falls inside the window
+// in loc.
+func (w window) next(t time.Time, loc *time.Location) time.Time {
+	lt := t.In(loc)
+	// Start a day early so a window that opened yesterday and spans
+	// midnight is still considered.
+	for i := -1; i <= 7; i++ {
+		day := time.Date(lt.Year(), lt.Month(), lt.Day()+i, 0, 0, 0, 0, loc)
+		if !w.days[day.Weekday()] {
+			continue
+		}
+		if w.allDay {
+			switch {
+			case i < 0:
+				continue
+			case i == 0:
+				return t
+			}
+			return day
+		}
+		opens := atClock(day, w.start)
+		closes := atClock(day, w.end)
+		if w.end <= w.start {
+			closes = atClock(day.AddDate(0, 0, 1), w.end)
+		}
+		if !t.Before(closes) {
+			continue
+		}
+		if t.Before(opens) {
+			return opens
+		}
+		return t
+	}
+	return t
+}
+
+// atClock returns d on day's date; it goes through time.Date so DST
+// transitions land on the intended wall-clock time.
+func atClock(day time.Time, d time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), int(d/time.Hour), int(d%time.Hour/time.Minute), 0, 0, day.Location())
+}
@@ -24,4 +24,6 @@ const (
 
 	ReasonTransportInvalidSignature ReasonCode = "webhook_invalid_signature"
 	ReasonTransportSend             ReasonCode = "transport_send"
+
+	ReasonProcessor ReasonCode = "processor_error"
 )
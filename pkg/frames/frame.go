@@ -26,6 +26,10 @@ const (
 	ControlToolCall          ControlCode = "tool_call"
 	ControlAudioReady        ControlCode = "audio_ready"
 	ControlDTMF              ControlCode = "dtmf"
+	ControlTransfer          ControlCode = "transfer"
+	ControlHangup            ControlCode = "hangup"
+	ControlRecordingPause    ControlCode = "recording_pause"
+	ControlRecordingResume   ControlCode = "recording_resume"
 )
 
 type Frame interface {
@@ -35,12 +39,12 @@ type Frame interface {
 }
 
 type AudioFrame struct {
-	pts    int64
-	data   []byte
-	rate   int
-	ch     int
-	meta   map[string]string
-	pooled bool
+	pts  int64
+	data []byte
+	rate int
+	ch   int
+	meta Metadata
+	ref  *audioRef
 }
 
 func NewAudioFrame(streamID string, pts int64, data []byte, rate, ch int, meta map[string]string) AudioFrame {
@@ -49,102 +53,116 @@ func NewAudioFrame(streamID string, pts int64, data []byte, rate, ch int, meta m
 		data: data,
 		rate: rate,
 		ch:   ch,
-		meta: mergeMeta(streamID, meta),
+		meta: newMetadata(streamID, meta),
 	}
 }
 
+// NewAudioFrameFromPool copies data into a pooled buffer. The returned frame
+// holds one reference; see RetainAudioFrame for the ownership rules.
 func NewAudioFrameFromPool(streamID string, pts int64, data []byte, rate, ch int, meta map[string]string) AudioFrame {
 	buf := AcquireAudioBuf(len(data))
 	copy(buf, data)
+	return NewAudioFrameFromBuf(streamID, pts, buf, rate, ch, meta)
+}
+
+// NewAudioFrameFromBuf wraps a buffer obtained from AcquireAudioBuf without
+// copying. The frame takes ownership of buf; the caller must not reuse it.
+func NewAudioFrameFromBuf(streamID string, pts int64, buf []byte, rate, ch int, meta map[string]string) AudioFrame {
 	return AudioFrame{
-		pts:    pts,
-		data:   buf,
-		rate:   rate,
-		ch:     ch,
-		meta:   mergeMeta(streamID, meta),
-		pooled: true,
+		pts:  pts,
+		data: buf,
+		rate: rate,
+		ch:   ch,
+		meta: newMetadata(streamID, meta),
+		ref:  newAudioRef(buf),
 	}
 }
 
 func (a AudioFrame) Kind() Kind              { return KindAudio }
 func (a AudioFrame) PTS() int64              { return a.pts }
-func (a AudioFrame) Meta() map[string]string { return cloneMeta(a.meta) }
+func (a AudioFrame) Meta() map[string]string { return a.meta.Map() }
+func (a AudioFrame) Metadata() Metadata      { return a.meta }
 func (a AudioFrame) Data() []byte            { return append([]byte(nil), a.data...) }
 func (a AudioFrame) RawPayload() []byte      { return a.data }
 func (a AudioFrame) Rate() int               { return a.rate }
 func (a AudioFrame) Channels() int           { return a.ch }
+func (a AudioFrame) Pooled() bool            { return a.ref != nil }
 
-func ReleaseAudioFrame(f Frame) bool {
-	af, ok := f.(AudioFrame)
-	if !ok {
-		if ap, ok := f.(*AudioFrame); ok {
-			af = *ap
-		} else {
-			return false
-		}
-	}
-	if af.pooled {
-		ReleaseAudioBuf(af.data)
-		return true
+// Refs returns the number of live references to a pooled frame, or 0 for
+// non-pooled frames.
+func (a AudioFrame) Refs() int {
+	if a.ref == nil {
+		return 0
 	}
-	return false
+	return int(a.ref.refs.Load())
 }
 
 type TextFrame struct {
 	pts  int64
 	text string
-	meta map[string]string
+	meta Metadata
+	tr   *Transcript
 }
 
 func NewTextFrame(streamID string, pts int64, text string, meta map[string]string) TextFrame {
 	return TextFrame{
 		pts:  pts,
 		text: text,
-		meta: mergeMeta(streamID, meta),
+		meta: newMetadata(streamID, meta),
 	}
 }
 
 func (t TextFrame) Kind() Kind              { return KindText }
 func (t TextFrame) PTS() int64              { return t.pts }
-func (t TextFrame) Meta() map[string]string { return cloneMeta(t.meta) }
+func (t TextFrame) Meta() map[string]string { return t.meta.Map() }
+func (t TextFrame) Metadata() Metadata      { return t.meta }
 func (t TextFrame) Text() string            { return t.text }
 
+// WithMeta returns a copy of t with new metadata, keeping the text and any
+// transcript.
+func (t TextFrame) WithMeta(streamID string, meta map[string]string) TextFrame {
+	t.meta = newMetadata(streamID, meta)
+	return t
+}
+
 type ControlFrame struct {
 	pts  int64
 	code ControlCode
-	meta map[string]string
+	meta Metadata
 }
 
 func NewControlFrame(streamID string, pts int64, code ControlCode, meta map[string]string) ControlFrame {
 	return ControlFrame{
 		pts:  pts,
 		code: code,
-		meta: mergeMeta(streamID, meta),
+		meta: newMetadata(streamID, meta),
 	}
 }
 
 func (c ControlFrame) Kind() Kind              { return KindControl }
 func (c ControlFrame) PTS() int64              { return c.pts }
-func (c ControlFrame) Meta() map[string]string { return cloneMeta(c.meta) }
+func (c ControlFrame) Meta() map[string]string { return c.meta.Map() }
+func (c ControlFrame) Metadata() Metadata      { return c.meta }
 func (c ControlFrame) Code() ControlCode       { return c.code }
 
 type SystemFrame struct {
 	pts  int64
 	name string
-	meta map[string]string
+	meta Metadata
 }
 
 func NewSystemFrame(streamID string, pts int64, name string, meta map[string]string) SystemFrame {
 	return SystemFrame{
 		pts:  pts,
 		name: name,
-		meta: mergeMeta(streamID, meta),
+		meta: newMetadata(streamID, meta),
 	}
 }
 
 func (s SystemFrame) Kind() Kind              { return KindSystem }
 func (s SystemFrame) PTS() int64              { return s.pts }
-func (s SystemFrame) Meta() map[string]string { return cloneMeta(s.meta) }
+func (s SystemFrame) Meta() map[string]string { return s.meta.Map() }
+func (s SystemFrame) Metadata() Metadata      { return s.meta }
 func (s SystemFrame) Name() string            { return s.name }
 
 type ImageFrame struct {
@@ -152,7 +170,7 @@ type ImageFrame struct {
 	data   []byte
 	mime   string
 	url    string
-	meta   map[string]string
+	meta   Metadata
 	pooled bool
 }
 
@@ -162,7 +180,7 @@ func NewImageFrame(streamID string, pts int64, data []byte, mime, url string, me
 		data: data,
 		mime: mime,
 		url:  url,
-		meta: mergeMeta(streamID, meta),
+		meta: newMetadata(streamID, meta),
 	}
 }
 
@@ -174,14 +192,15 @@ func NewImageFrameFromPool(streamID string, pts int64, data []byte, mime, url st
 		data:   buf,
 		mime:   mime,
 		url:    url,
-		meta:   mergeMeta(streamID, meta),
+		meta:   newMetadata(streamID, meta),
 		pooled: true,
 	}
 }
 
 func (i ImageFrame) Kind() Kind              { return KindImage }
 func (i ImageFrame) PTS() int64              { return i.pts }
-func (i ImageFrame) Meta() map[string]string { return cloneMeta(i.meta) }
+func (i ImageFrame) Meta() map[string]string { return i.meta.Map() }
+func (i ImageFrame) Metadata() Metadata      { return i.meta }
 func (i ImageFrame) Data() []byte            { return append([]byte(nil), i.data...) }
 func (i ImageFrame) RawPayload() []byte      { return i.data }
 func (i ImageFrame) MIME() string            { return i.mime }
@@ -255,22 +274,3 @@ func AcquireImageBuf(size int) []byte {
 func ReleaseImageBuf(b []byte) {
 	imageBufPool.Put(b[:0])
 }
-
-func mergeMeta(streamID string, meta map[string]string) map[string]string {
-	out := make(map[string]string, 2+len(meta))
-	if streamID != "" {
-		out[MetaStreamID] = streamID
-	}
-	for k, v := range meta {
-		out[k] = v
-	}
-	return out
-}
-
-func cloneMeta(meta map[string]string) map[string]string {
-	out := make(map[string]string, len(meta))
-	for k, v := range meta {
-		out[k] = v
-	}
-	return out
-}
@@ -5,8 +5,11 @@ const (
 	MetaStreamID           = "stream_id"
 	MetaSource             = "source"
 	MetaCallSID            = "call_sid"
+	MetaClientCallID       = "client_call_id"
 	MetaTraceID            = "trace_id"
 	MetaFromNumber         = "from_number"
+	MetaToNumber           = "to_number"
+	MetaCallDirection      = "call_direction"
 	MetaAgent              = "agent"
 	MetaHandoffAgent       = "handoff_agent"
 	MetaSystemMessage      = "system_message"
@@ -16,6 +19,7 @@ const (
 	MetaGlobalLanguage     = "global_language"
 	MetaGlobalAgent        = "global_current_agent"
 	MetaGlobalPrefix       = "global_"
+	MetaParamPrefix        = "param_"
 
 	MetaToolCallID          = "tool_call_id"
 	MetaToolName            = "tool_name"
@@ -36,6 +40,9 @@ const (
 	MetaTTSFlush          = "tts_flush"
 	MetaDTMFDigit         = "dtmf_digit"
 	MetaDTMFPriority      = "dtmf_priority"
+	MetaDTMFDigits        = "dtmf_digits"
+	MetaGatherOptions     = "gather_options"
+	MetaGatherStatus      = "gather_status"
 	MetaReason            = "reason"
 	MetaRepromptAttempt   = "reprompt_attempt"
 	MetaShortTurnEnforced = "short_turn_enforced"
@@ -43,9 +50,21 @@ const (
 	MetaRecoveryReason    = "recovery_reason"
 	MetaCallEndReason     = "call_end_reason"
 	MetaCallSummary       = "call_summary"
+	MetaTransferTarget    = "transfer_target"
+	MetaTransferMode      = "transfer_mode"
+	MetaAnsweredBy        = "answered_by"
+	MetaAMDReason         = "amd_reason"
+	MetaSupervisor        = "supervisor"
+
+	MetaErrorReason    = "error_reason"
+	MetaErrorProcessor = "error_processor"
+	MetaErrorMessage   = "error_message"
+	MetaErrorAction    = "error_action"
 
 	MetaEncoding    = "encoding"
 	MetaCodec       = "codec"
 	MetaFormat      = "format"
 	MetaOldStreamID = "old_stream_id"
+	MetaInputMode   = "input_mode"
+	MetaMarkName    = "mark_name"
 )
@@ -0,0 +1,129 @@
+package frames
+
+// Metadata is the immutable key/value metadata attached to a frame.
+//
+// A Metadata value is never mutated after construction, so frames and their
+// copies share the same backing map and reads never allocate. Meta() on a
+// frame still returns a private clone for callers that want to edit and
+// re-attach metadata; hot paths should prefer Get or the typed accessors.
+type Metadata struct {
+	m        map[string]string
+	streamID string
+	traceID  string
+	callSID  string
+}
+
+// NewMetadata copies m into an immutable Metadata.
+func NewMetadata(m map[string]string) Metadata {
+	return newMetadata("", m)
+}
+
+func newMetadata(streamID string, m map[string]string) Metadata {
+	out := make(map[string]string, 1+len(m))
+	if streamID != "" {
+		out[MetaStreamID] = streamID
+	}
+	for k, v := range m {
+		out[k] = v
+	}
+	return wrapMetadata(out)
+}
+
+// wrapMetadata takes ownership of m; callers must not modify it afterwards.
+func wrapMetadata(m map[string]string) Metadata {
+	return Metadata{
+		m:        m,
+		streamID: m[MetaStreamID],
+		traceID:  m[MetaTraceID],
+		callSID:  m[MetaCallSID],
+	}
+}
+
+// Get returns the value for key, or "" if absent.
+func (md Metadata) Get(key string) string {
+	switch key {
+	case MetaStreamID:
+		return md.streamID
+	case MetaTraceID:
+		return md.traceID
+	case MetaCallSID:
+		return md.callSID
+	}
+	return md.m[key]
+}
+
+// Lookup returns the value for key and whether it was present.
+func (md Metadata) Lookup(key string) (string, bool) {
+	v, ok := md.m[key]
+	return v, ok
+}
+
+func (md Metadata) StreamID() string { return md.streamID }
+func (md Metadata) TraceID() string  { return md.traceID }
+func (md Metadata) CallSID() string  { return md.callSID }
+func (md Metadata) Len() int         { return len(md.m) }
+
+// Range calls fn for each entry until fn returns false.
+func (md Metadata) Range(fn func(key, value string) bool) {
+	for k, v := range md.m {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
+
+// Map returns a mutable copy of the metadata.
+func (md Metadata) Map() map[string]string {
+	out := make(map[string]string, len(md.m))
+	for k, v := range md.m {
+		out[k] = v
+	}
+	return out
+}
+
+// With returns a copy of md with key set to value; md is unchanged.
+func (md Metadata) With(key, value string) Metadata {
+	out := make(map[string]string, len(md.m)+1)
+	for k, v := range md.m {
+		out[k] = v
+	}
+	out[key] = value
+	return wrapMetadata(out)
+}
+
+// MetadataCarrier is implemented by frames exposing their metadata without
+// copying. All frame types in this package implement it.
+type MetadataCarrier interface {
+	Metadata() Metadata
+}
+
+// MetadataOf returns f's metadata, avoiding a copy when f is a MetadataCarrier.
+func MetadataOf(f Frame) Metadata {
+	if f == nil {
+		return Metadata{}
+	}
+	if mc, ok := f.(MetadataCarrier); ok {
+		return mc.Metadata()
+	}
+	return NewMetadata(f.Meta())
+}
+
+// MetaValue returns a single metadata value from f without cloning the map.
+func MetaValue(f Frame, key string) string {
+	if f == nil {
+		return ""
+	}
+	if mc, ok := f.(MetadataCarrier); ok {
+		return mc.Metadata().Get(key)
+	}
+	return f.Meta()[key]
+}
+
+// StreamIDOf returns the stream ID of f.
+func StreamIDOf(f Frame) string { return MetaValue(f, MetaStreamID) }
+
+// TraceIDOf returns the trace ID of f.
+func TraceIDOf(f Frame) string { return MetaValue(f, MetaTraceID) }
+
+// CallSIDOf returns the call SID of f.
+func CallSIDOf(f Frame) string { return MetaValue(f, MetaCallSID) }
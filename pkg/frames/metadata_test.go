@@ -0,0 +1,92 @@
+package frames
+
+import "testing"
+
+func testAudioFrame() AudioFrame {
+	return NewAudioFrame("stream-1", 1, make([]byte, 160), 8000, 1, map[string]string{
+		MetaCallSID:    "call-1",
+		MetaTraceID:    "trace-1",
+		MetaFromNumber: "+15550001",
+		MetaEncoding:   "mulaw",
+	})
+}
+
+func TestMetaReturnsPrivateCopy(t *testing.T) {
+	f := testAudioFrame()
+	m := f.Meta()
+	m[MetaStreamID] = "changed"
+	m["extra"] = "x"
+	if got := f.Meta()[MetaStreamID]; got != "stream-1" {
+		t.Fatalf("frame metadata mutated through Meta(): %q", got)
+	}
+	if got := StreamIDOf(f); got != "stream-1" {
+		t.Fatalf("expected stream-1, got %q", got)
+	}
+}
+
+func TestMetadataAccessors(t *testing.T) {
+	f := testAudioFrame()
+	md := f.Metadata()
+	if md.StreamID() != "stream-1" || md.TraceID() != "trace-1" || md.CallSID() != "call-1" {
+		t.Fatalf("unexpected typed accessors: %+v", md)
+	}
+	if md.Get(MetaFromNumber) != "+15550001" || md.Get("missing") != "" {
+		t.Fatalf("unexpected Get results")
+	}
+	next := md.With(MetaTraceID, "trace-2")
+	if next.TraceID() != "trace-2" || md.TraceID() != "trace-1" {
+		t.Fatalf("With must copy on write")
+	}
+	// Explicit stream_id in meta wins over the constructor argument.
+	tf := NewTextFrame("a", 0, "hi", map[string]string{MetaStreamID: "b"})
+	if StreamIDOf(tf) != "b" {
+		t.Fatalf("expected meta stream_id to win, got %q", StreamIDOf(tf))
+	}
+}
+
+func TestMetaValueDoesNotAllocate(t *testing.T) {
+	var f Frame = testAudioFrame()
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = StreamIDOf(f)
+		_ = TraceIDOf(f)
+		_ = CallSIDOf(f)
+		_ = MetaValue(f, MetaEncoding)
+	})
+	if allocs != 0 {
+		t.Fatalf("expected zero allocations, got %v", allocs)
+	}
+}
+
+func BenchmarkAudioFrameMetaClone(b *testing.B) {
+	var f Frame = testAudioFrame()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m := f.Meta()
+		_ = m[MetaStreamID]
+	}
+}
+
+func BenchmarkAudioFrameMetaValue(b *testing.B) {
+	var f Frame = testAudioFrame()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = StreamIDOf(f)
+	}
+}
+
+// BenchmarkAudioHotPath mimics what a 20 ms inbound audio frame goes through:
+// pooled construction, a handful of per-stage metadata reads, and release.
+func BenchmarkAudioHotPath(b *testing.B) {
+	payload := make([]byte, 160)
+	meta := map[string]string{MetaCallSID: "call-1", MetaTraceID: "trace-1"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var f Frame = NewAudioFrameFromPool("stream-1", int64(i), payload, 8000, 1, meta)
+		for stage := 0; stage < 8; stage++ {
+			_ = StreamIDOf(f)
+			_ = TraceIDOf(f)
+			_ = MetaValue(f, MetaAgent)
+		}
+		ReleaseAudioFrame(f)
+	}
+}
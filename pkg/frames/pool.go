@@ -0,0 +1,210 @@
+package frames
+
+import (
+	"encoding/base64"
+	"log/slog"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Pooled audio frames share one reference-counted buffer between all copies
+// of the frame value. Ownership rules:
+//
+//   - A pooled frame is created with one reference, owned by whoever created it.
+//   - Passing the frame on (returning it from Process, sending it on a channel)
+//     transfers that reference; the sender must not touch the payload afterwards.
+//   - Anyone who keeps the frame beyond the hand-off (async observers, replay
+//     buffers, fan-out to several consumers) must RetainAudioFrame first and
+//     ReleaseAudioFrame when done.
+//   - Every reference is released exactly once. The buffer returns to the pool
+//     when the last reference is released.
+//
+// Non-pooled frames ignore retain and release.
+type audioRef struct {
+	buf  []byte
+	refs atomic.Int32
+	id   uint64
+}
+
+// poisonByte fills released buffers in debug mode so use-after-release shows
+// up as loud noise instead of someone else's audio.
+const poisonByte = 0xA5
+
+var (
+	poolDebug atomic.Bool
+	poolSeq   atomic.Uint64
+
+	poolAcquired      atomic.Int64
+	poolReleased      atomic.Int64
+	poolDoubleRelease atomic.Int64
+
+	liveAudioRefs sync.Map // id -> liveAudioRef, debug mode only
+)
+
+type liveAudioRef struct {
+	since time.Time
+	stack string
+}
+
+// SetPoolDebug enables leak and double-release tracking for pooled audio
+// frames. In debug mode acquisition stacks are recorded and released buffers
+// are poisoned and never reused. It is meant for tests and local debugging.
+func SetPoolDebug(on bool) {
+	poolDebug.Store(on)
+	if !on {
+		liveAudioRefs.Range(func(k, _ any) bool {
+			liveAudioRefs.Delete(k)
+			return true
+		})
+	}
+}
+
+// PoolDebug reports whether pool debug mode is enabled.
+func PoolDebug() bool { return poolDebug.Load() }
+
+// PoolStats is a snapshot of pooled audio frame counters.
+type PoolStats struct {
+	Acquired       int64
+	Released       int64
+	Live           int64
+	DoubleReleases int64
+}
+
+// AudioPoolStats returns process-wide pooled audio frame counters.
+func AudioPoolStats() PoolStats {
+	acq := poolAcquired.Load()
+	rel := poolReleased.Load()
+	return PoolStats{
+		Acquired:       acq,
+		Released:       rel,
+		Live:           acq - rel,
+		DoubleReleases: poolDoubleRelease.Load(),
+	}
+}
+
+// LeakedFrame describes a pooled audio frame still holding references.
+type LeakedFrame struct {
+	ID    uint64
+	Age   time.Duration
+	Stack string
+}
+
+// LeakedAudioFrames lists pooled frames acquired at least minAge ago that were
+// never fully released, oldest first. It only reports frames acquired while
+// debug mode was enabled.
+func LeakedAudioFrames(minAge time.Duration) []LeakedFrame {
+	now := time.Now()
+	var out []LeakedFrame
+	liveAudioRefs.Range(func(k, v any) bool {
+		live := v.(liveAudioRef)
+		if age := now.Sub(live.since); age >= minAge {
+			out = append(out, LeakedFrame{ID: k.(uint64), Age: age, Stack: live.stack})
+		}
+		return true
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Age > out[j].Age })
+	return out
+}
+
+func newAudioRef(buf []byte) *audioRef {
+	r := &audioRef{buf: buf, id: poolSeq.Add(1)}
+	r.refs.Store(1)
+	poolAcquired.Add(1)
+	if poolDebug.Load() {
+		liveAudioRefs.Store(r.id, liveAudioRef{since: time.Now(), stack: string(debug.Stack())})
+	}
+	return r
+}
+
+func (r *audioRef) retain() bool {
+	for {
+		n := r.refs.Load()
+		if n <= 0 {
+			reportPoolMisuse("audio_frame_retain_after_release", r)
+			return false
+		}
+		if r.refs.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+func (r *audioRef) release() bool {
+	n := r.refs.Add(-1)
+	if n > 0 {
+		return true
+	}
+	if n < 0 {
+		poolDoubleRelease.Add(1)
+		reportPoolMisuse("audio_frame_double_release", r)
+		return false
+	}
+	poolReleased.Add(1)
+	if poolDebug.Load() {
+		liveAudioRefs.Delete(r.id)
+		for i := range r.buf {
+			r.buf[i] = poisonByte
+		}
+		return true
+	}
+	ReleaseAudioBuf(r.buf)
+	return true
+}
+
+func reportPoolMisuse(event string, r *audioRef) {
+	if !poolDebug.Load() {
+		return
+	}
+	slog.Error(event, "frame_id", r.id, "refs", r.refs.Load(), "stack", string(debug.Stack()))
+}
+
+// RetainAudioFrame adds a reference to a pooled audio frame so its payload
+// stays valid until a matching ReleaseAudioFrame. It returns false for
+// non-pooled frames and for frames already fully released.
+func RetainAudioFrame(f Frame) bool {
+	af, ok := audioFrameOf(f)
+	if !ok || af.ref == nil {
+		return false
+	}
+	return af.ref.retain()
+}
+
+// ReleaseAudioFrame drops one reference to a pooled audio frame, returning the
+// buffer to the pool when it was the last. It returns false for non-pooled
+// frames and on double release.
+func ReleaseAudioFrame(f Frame) bool {
+	af, ok := audioFrameOf(f)
+	if !ok || af.ref == nil {
+		return false
+	}
+	return af.ref.release()
+}
+
+func audioFrameOf(f Frame) (AudioFrame, bool) {
+	switch x := f.(type) {
+	case AudioFrame:
+		return x, true
+	case *AudioFrame:
+		if x == nil {
+			return AudioFrame{}, false
+		}
+		return *x, true
+	}
+	return AudioFrame{}, false
+}
+
+// DecodeAudioBase64 decodes a standard base64 payload into a buffer from
+// AcquireAudioBuf, ready for NewAudioFrameFromBuf. On error the buffer is
+// returned to the pool.
+func DecodeAudioBase64(s string) ([]byte, error) {
+	buf := AcquireAudioBuf(base64.StdEncoding.DecodedLen(len(s)))
+	n, err := base64.StdEncoding.Decode(buf, []byte(s))
+	if err != nil {
+		ReleaseAudioBuf(buf)
+		return nil, err
+	}
+	return buf[:n], nil
+}
@@ -0,0 +1,73 @@
+package frames
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPooledAudioFrameRefCounting(t *testing.T) {
+	SetPoolDebug(true)
+	defer SetPoolDebug(false)
+
+	f := NewAudioFrameFromPool("s1", 1, []byte{1, 2, 3}, 8000, 1, nil)
+	if !f.Pooled() || f.Refs() != 1 {
+		t.Fatalf("expected pooled frame with one ref, got pooled=%v refs=%d", f.Pooled(), f.Refs())
+	}
+	if !RetainAudioFrame(f) || f.Refs() != 2 {
+		t.Fatalf("expected retain to add a ref, got %d", f.Refs())
+	}
+	ReleaseAudioFrame(f)
+	if got := f.RawPayload(); got[0] != 1 {
+		t.Fatalf("payload released while still referenced: %v", got)
+	}
+	ReleaseAudioFrame(f)
+	if got := f.RawPayload(); got[0] != poisonByte {
+		t.Fatalf("expected poisoned payload after last release, got %v", got)
+	}
+	if RetainAudioFrame(f) {
+		t.Fatalf("expected retain after release to fail")
+	}
+
+	plain := NewAudioFrame("s1", 1, []byte{1}, 8000, 1, nil)
+	if RetainAudioFrame(plain) || ReleaseAudioFrame(plain) {
+		t.Fatalf("expected non-pooled frame to ignore retain/release")
+	}
+}
+
+func TestPooledAudioFrameDoubleReleaseAndLeaks(t *testing.T) {
+	SetPoolDebug(true)
+	defer SetPoolDebug(false)
+
+	before := AudioPoolStats()
+	f := NewAudioFrameFromPool("s1", 1, []byte{1}, 8000, 1, nil)
+	ReleaseAudioFrame(f)
+	if ReleaseAudioFrame(f) {
+		t.Fatalf("expected double release to report false")
+	}
+	if got := AudioPoolStats().DoubleReleases - before.DoubleReleases; got != 1 {
+		t.Fatalf("expected 1 double release, got %d", got)
+	}
+
+	leaked := NewAudioFrameFromPool("s1", 1, []byte{1}, 8000, 1, nil)
+	leaks := LeakedAudioFrames(0)
+	if len(leaks) != 1 || leaks[0].Stack == "" {
+		t.Fatalf("expected one leak with stack, got %v", leaks)
+	}
+	ReleaseAudioFrame(leaked)
+	if leaks := LeakedAudioFrames(0); len(leaks) != 0 {
+		t.Fatalf("expected no leaks after release, got %d", len(leaks))
+	}
+	if len(LeakedAudioFrames(time.Hour)) != 0 {
+		t.Fatalf("expected age filter to apply")
+	}
+}
+
+func TestDecodeAudioBase64(t *testing.T) {
+	buf, err := DecodeAudioBase64("AQID")
+	if err != nil || len(buf) != 3 || buf[2] != 3 {
+		t.Fatalf("unexpected decode result %v, %v", buf, err)
+	}
+	if _, err := DecodeAudioBase64("!!"); err == nil {
+		t.Fatalf("expected decode error")
+	}
+}
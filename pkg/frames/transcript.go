@@ -0,0 +1,108 @@
+package frames
+
+import (
+	"strconv"
+	"time"
+)
+
+// Word is a single recognized word. Start and End are offsets from the start
+// of the STT stream.
+type Word struct {
+	Text       string        `json:"text"`
+	Punctuated string        `json:"punctuated,omitempty"`
+	Start      time.Duration `json:"start"`
+	End        time.Duration `json:"end"`
+	Confidence float64       `json:"confidence"`
+	Speaker    string        `json:"speaker,omitempty"`
+	Language   string        `json:"language,omitempty"`
+}
+
+// Transcript is the typed STT result carried by transcription text frames.
+//
+// Stability is how likely an interim result is to survive into the final one,
+// from 0 to 1, or 0 when the vendor does not report it; finals are always 1.
+// Speaker is empty unless the vendor diarizes. Like Metadata, a Transcript is
+// shared between frame copies and must not be modified; Words in particular
+// is not copied.
+type Transcript struct {
+	Text               string
+	Final              bool
+	Confidence         float64
+	Language           string
+	LanguageConfidence float64
+	Stability          float64
+	Speaker            string
+	Start              time.Duration
+	Duration           time.Duration
+	Words              []Word
+}
+
+// NewTranscriptFrame returns a text frame carrying tr. The frame text is
+// tr.Text, and is_final, language and language_confidence are mirrored into
+// metadata for processors that only read string metadata.
+func NewTranscriptFrame(streamID string, pts int64, tr Transcript, meta map[string]string) TextFrame {
+	m := make(map[string]string, len(meta)+3)
+	for k, v := range meta {
+		m[k] = v
+	}
+	m[MetaIsFinal] = strconv.FormatBool(tr.Final)
+	if tr.Language != "" {
+		m[MetaLanguage] = tr.Language
+		if tr.LanguageConfidence > 0 {
+			m[MetaLanguageConfidence] = strconv.FormatFloat(tr.LanguageConfidence, 'f', 2, 64)
+		}
+	}
+	if tr.Final {
+		tr.Stability = 1
+	}
+	tf := NewTextFrame(streamID, pts, tr.Text, m)
+	tf.tr = &tr
+	return tf
+}
+
+// Transcript returns the typed STT result, if the frame carries one.
+func (t TextFrame) Transcript() (Transcript, bool) {
+	if t.tr == nil {
+		return Transcript{}, false
+	}
+	return *t.tr, true
+}
+
+// TranscriptOf returns the typed STT result of f, if any.
+func TranscriptOf(f Frame) (Transcript, bool) {
+	tf, ok := f.(TextFrame)
+	if !ok {
+		return Transcript{}, false
+	}
+	return tf.Transcript()
+}
+
+// MergeTranscripts joins consecutive results into one, as when fragments of an
+// utterance are aggregated. Word timings are kept in order; confidence is the
+// lowest of the parts, and Final is taken from the last part.
+func MergeTranscripts(text string, parts []Transcript) Transcript {
+	out := Transcript{Text: text}
+	if len(parts) == 0 {
+		return out
+	}
+	first, last := parts[0], parts[len(parts)-1]
+	out.Final = last.Final
+	out.Stability = last.Stability
+	out.Language = last.Language
+	out.LanguageConfidence = last.LanguageConfidence
+	out.Start = first.Start
+	out.Duration = last.Start + last.Duration - first.Start
+	out.Confidence = first.Confidence
+	speaker := first.Speaker
+	for _, p := range parts {
+		if p.Confidence < out.Confidence {
+			out.Confidence = p.Confidence
+		}
+		if p.Speaker != speaker {
+			speaker = ""
+		}
+		out.Words = append(out.Words, p.Words...)
+	}
+	out.Speaker = speaker
+	return out
+}
@@ -0,0 +1,42 @@
+package frames
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTranscriptFrameMirrorsMetaAndSurvivesWithMeta(t *testing.T) {
+	tr := Transcript{
+		Text:     "hello there",
+		Final:    true,
+		Language: "en",
+		Words: []Word{
+			{Text: "hello", Start: 0, End: 300 * time.Millisecond, Confidence: 0.9},
+			{Text: "there", Start: 300 * time.Millisecond, End: 600 * time.Millisecond, Confidence: 0.8},
+		},
+	}
+	tf := NewTranscriptFrame("s1", 1, tr, map[string]string{MetaSource: "stt"})
+	md := tf.Metadata()
+	if tf.Text() != "hello there" || md.Get(MetaIsFinal) != "true" || md.Get(MetaLanguage) != "en" {
+		t.Fatalf("unexpected frame %q meta %v", tf.Text(), md.Map())
+	}
+	got, ok := TranscriptOf(tf.WithMeta("s1", map[string]string{MetaAgent: "triage"}))
+	if !ok || len(got.Words) != 2 || got.Stability != 1 {
+		t.Fatalf("expected transcript to survive WithMeta, got %+v ok=%v", got, ok)
+	}
+	if _, ok := TranscriptOf(NewTextFrame("s1", 1, "plain", nil)); ok {
+		t.Fatalf("expected plain text frame without transcript")
+	}
+}
+
+func TestMergeTranscripts(t *testing.T) {
+	a := Transcript{Confidence: 0.9, Speaker: "0", Start: time.Second, Duration: time.Second, Words: []Word{{Text: "a"}}}
+	b := Transcript{Final: true, Confidence: 0.7, Speaker: "1", Start: 2 * time.Second, Duration: time.Second, Words: []Word{{Text: "b"}}}
+	m := MergeTranscripts("a b", []Transcript{a, b})
+	if !m.Final || m.Confidence != 0.7 || m.Speaker != "" || len(m.Words) != 2 {
+		t.Fatalf("unexpected merge %+v", m)
+	}
+	if m.Start != time.Second || m.Duration != 2*time.Second {
+		t.Fatalf("unexpected span start=%v duration=%v", m.Start, m.Duration)
+	}
+}
@@ -5,4 +5,15 @@ const (
 	EventBreakerClose  = "breaker_close"
 	EventBreakerDenied = "breaker_denied"
 	EventRateLimit     = "rate_limit"
+	EventDeadLetter    = "dead_letter"
+	EventSessionReaped = "session_reaped"
+	EventStreamResumed = "stream_resumed"
+	EventCallQueued    = "call_queued"
+	EventCallDequeued  = "call_dequeued"
+	EventCallRejected  = "call_rejected"
+
+	EventSupervisorWhisper  = "supervisor_whisper"
+	EventSupervisorTakeover = "supervisor_takeover"
+	EventSupervisorSay      = "supervisor_say"
+	EventSupervisorRelease  = "supervisor_release"
 )
@@ -8,6 +8,10 @@ type MetricsEvent struct {
 	Value  float64
 	Tags   map[string]string
 	Fields map[string]any
+	// Frame is the frame a frame_in or frame_out event is about, left nil
+	// for audio. It is for in-process observers that look at content, such
+	// as the live tap; it is never serialized.
+	Frame any
 }
 
 type Observer interface {
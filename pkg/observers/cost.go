@@ -22,20 +22,45 @@ type CostSummary struct {
 	RecordedAtUTC string  `json:"recorded_at_utc"`
 }
 
+// CostObserver totals billable usage per trace (or stream). With a dir it
+// writes the totals on Close; without one, Take hands them out per call.
 type CostObserver struct {
 	dir   string
 	mu    sync.Mutex
 	stats map[string]*CostSummary
+	// taken remembers recently taken ids so late events do not start a new
+	// total that nobody takes.
+	taken map[string]time.Time
 }
 
+// costTakenTTL bounds how long late events for a taken id are ignored.
+const costTakenTTL = time.Minute
+
 func NewCostObserver(dir string) *CostObserver {
-	return &CostObserver{dir: dir, stats: make(map[string]*CostSummary)}
+	return &CostObserver{dir: dir, stats: make(map[string]*CostSummary), taken: make(map[string]time.Time)}
 }
 
-func (o *CostObserver) RecordEvent(ev metrics.MetricsEvent) {
-	if strings.TrimSpace(o.dir) == "" {
-		return
+// Take returns and forgets the totals for id, the trace ID or else the
+// stream ID of a call.
+func (o *CostObserver) Take(id string) (CostSummary, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := time.Now()
+	for k, at := range o.taken {
+		if now.Sub(at) > costTakenTTL {
+			delete(o.taken, k)
+		}
+	}
+	o.taken[id] = now
+	stat, ok := o.stats[id]
+	if !ok {
+		return CostSummary{}, false
 	}
+	delete(o.stats, id)
+	return *stat, true
+}
+
+func (o *CostObserver) RecordEvent(ev metrics.MetricsEvent) {
 	id := ""
 	streamID := ""
 	traceID := ""
@@ -58,6 +83,10 @@ func (o *CostObserver) RecordEvent(ev metrics.MetricsEvent) {
 			return
 		}
 		o.mu.Lock()
+		if _, done := o.taken[id]; done {
+			o.mu.Unlock()
+			return
+		}
 		stat := o.stats[id]
 		if stat == nil {
 			stat = &CostSummary{TraceID: traceID, StreamID: streamID}
@@ -75,6 +104,10 @@ func (o *CostObserver) RecordEvent(ev metrics.MetricsEvent) {
 	if ev.Name == "llm_done" && ev.Fields != nil {
 		if v, ok := ev.Fields["tokens"].(int); ok {
 			o.mu.Lock()
+			if _, done := o.taken[id]; done {
+				o.mu.Unlock()
+				return
+			}
 			stat := o.stats[id]
 			if stat == nil {
 				stat = &CostSummary{TraceID: traceID, StreamID: streamID}
@@ -0,0 +1,39 @@
+package observers
+
+import (
+	"sync/atomic"
+
+	"github.com/harunnryd/ranya/pkg/metrics"
+)
+
+// DeadLetterObserver writes frames that failed processing to a per-call
+// <id>.deadletter.jsonl file next to the timeline artifacts.
+type DeadLetterObserver struct {
+	*TimelineObserver
+	count atomic.Int64
+}
+
+// NewDeadLetterObserver creates a dead-letter observer writing to dir.
+func NewDeadLetterObserver(dir string) *DeadLetterObserver {
+	return &DeadLetterObserver{TimelineObserver: NewFilteredTimelineObserver(dir, "deadletter", isDeadLetter)}
+}
+
+// RecordEvent implements metrics.Observer.
+func (o *DeadLetterObserver) RecordEvent(ev metrics.MetricsEvent) {
+	if !isDeadLetter(ev) {
+		return
+	}
+	o.count.Add(1)
+	o.TimelineObserver.RecordEvent(ev)
+}
+
+// Count returns the number of dead-lettered frames seen.
+func (o *DeadLetterObserver) Count() int64 {
+	return o.count.Load()
+}
+
+func isDeadLetter(ev metrics.MetricsEvent) bool {
+	return ev.Name == metrics.EventDeadLetter
+}
+
+var _ metrics.Observer = (*DeadLetterObserver)(nil)
@@ -0,0 +1,38 @@
+package observers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/metrics"
+)
+
+func TestDeadLetterObserverWritesOnlyDeadLetters(t *testing.T) {
+	dir := t.TempDir()
+	obs := NewDeadLetterObserver(dir)
+
+	tags := map[string]string{"stream_id": "stream-1", "trace_id": "trace-1", "processor": "llm"}
+	obs.RecordEvent(metrics.MetricsEvent{Name: "frame_out", Time: time.Now(), Tags: tags})
+	obs.RecordEvent(metrics.MetricsEvent{
+		Name:   metrics.EventDeadLetter,
+		Time:   time.Now(),
+		Tags:   tags,
+		Fields: map[string]any{"text": "hello", "error": "boom"},
+	})
+	_ = obs.Close()
+
+	if obs.Count() != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", obs.Count())
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "trace-1.deadletter.jsonl"))
+	if err != nil {
+		t.Fatalf("read file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], "boom") {
+		t.Fatalf("unexpected dead letter file: %s", b)
+	}
+}
@@ -0,0 +1,463 @@
+package observers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/audio"
+	"github.com/harunnryd/ranya/pkg/frames"
+)
+
+// Recording formats.
+const (
+	RecordingWAV  = "wav"
+	RecordingOpus = "opus"
+)
+
+// recorderLag is how far behind the call clock samples stay buffered, so
+// late caller audio and agent barge-in truncation still land in place.
+const recorderLag = time.Second
+
+// OpusEncoder encodes one 20ms frame of interleaved stereo PCM into an Opus
+// packet. Ranya ships no encoder; wrap a library such as
+// github.com/hraban/opus, whose Encoder has this signature.
+type OpusEncoder interface {
+	Encode(pcm []int16, data []byte) (int, error)
+}
+
+// RecorderConfig configures CallRecorder.
+type RecorderConfig struct {
+	Dir string
+	// Format is RecordingWAV (default) or RecordingOpus.
+	Format string
+	// SampleRate of the output; inputs are resampled. Defaults to 8000.
+	SampleRate int
+	// NewOpusEncoder builds an encoder per call for RecordingOpus. The
+	// encoder must accept SampleRate and two channels.
+	NewOpusEncoder func(sampleRate, channels int) (OpusEncoder, error)
+}
+
+// CallRecorder writes one stereo file per call, caller on the left channel
+// and agent on the right. Both tracks are placed on the call's wall clock:
+// caller audio where it arrived, agent audio where it starts playing (after
+// any agent audio already queued), so the file sounds like the call did.
+type CallRecorder struct {
+	cfg   RecorderConfig
+	mu    sync.Mutex
+	calls map[string]*recording
+	now   func() time.Time
+}
+
+// NewCallRecorder creates a recorder writing into cfg.Dir.
+func NewCallRecorder(cfg RecorderConfig) *CallRecorder {
+	if cfg.SampleRate <= 0 {
+		cfg.SampleRate = 8000
+	}
+	if cfg.Format == "" {
+		cfg.Format = RecordingWAV
+	}
+	return &CallRecorder{cfg: cfg, calls: make(map[string]*recording), now: time.Now}
+}
+
+// Caller records inbound audio for callID.
+func (r *CallRecorder) Caller(callID string, af frames.AudioFrame) {
+	r.write(callID, af, true)
+}
+
+// Agent records outbound audio for callID.
+func (r *CallRecorder) Agent(callID string, af frames.AudioFrame) {
+	r.write(callID, af, false)
+}
+
+// ClearAgent drops agent audio that has not played yet, mirroring a
+// transport clearing its playback buffer on barge-in.
+func (r *CallRecorder) ClearAgent(callID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if rec := r.calls[callID]; rec != nil {
+		rec.clearAgent(rec.clock(r.now()))
+	}
+}
+
+// Pause stops capturing audio for callID until Resume; the paused span is
+// written as silence so the tracks stay aligned.
+func (r *CallRecorder) Pause(callID string) {
+	r.setPaused(callID, true)
+}
+
+// Resume continues capturing audio for callID.
+func (r *CallRecorder) Resume(callID string) {
+	r.setPaused(callID, false)
+}
+
+func (r *CallRecorder) setPaused(callID string, paused bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec := r.calls[callID]
+	if rec == nil {
+		return
+	}
+	rec.paused = paused
+	if paused {
+		rec.clearAgent(rec.clock(r.now()))
+	}
+}
+
+// Finish writes out callID's recording and returns the file path.
+func (r *CallRecorder) Finish(callID string) (string, error) {
+	r.mu.Lock()
+	rec := r.calls[callID]
+	delete(r.calls, callID)
+	r.mu.Unlock()
+	if rec == nil {
+		return "", nil
+	}
+	return rec.path, rec.finish()
+}
+
+// Close finishes every open recording.
+func (r *CallRecorder) Close() error {
+	r.mu.Lock()
+	calls := r.calls
+	r.calls = make(map[string]*recording)
+	r.mu.Unlock()
+	var err error
+	for _, rec := range calls {
+		err = errors.Join(err, rec.finish())
+	}
+	return err
+}
+
+func (r *CallRecorder) write(callID string, af frames.AudioFrame, caller bool) {
+	if callID == "" {
+		return
+	}
+	pcm, ok := decodePCM(af, r.cfg.SampleRate)
+	if !ok || len(pcm) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	rec := r.calls[callID]
+	if rec == nil {
+		start := now
+		if caller {
+			// The first caller chunk started before it arrived.
+			start = now.Add(-time.Duration(len(pcm)) * time.Second / time.Duration(r.cfg.SampleRate))
+		}
+		var err error
+		if rec, err = r.open(callID, start); err != nil {
+			return
+		}
+		r.calls[callID] = rec
+	}
+	if rec.paused || rec.err != nil {
+		return
+	}
+	at := rec.clock(now)
+	if caller {
+		// Inbound audio arrives once it has been spoken.
+		rec.place(&rec.caller, &rec.callerEnd, at-len(pcm), pcm)
+	} else {
+		rec.place(&rec.agent, &rec.agentEnd, at, pcm)
+	}
+	if upto := at - r.cfg.SampleRate*int(recorderLag/time.Second); upto-rec.base >= r.cfg.SampleRate {
+		rec.flush(upto)
+	}
+}
+
+func (r *CallRecorder) open(callID string, start time.Time) (*recording, error) {
+	safe := sanitizeID(callID)
+	if safe == "" || strings.TrimSpace(r.cfg.Dir) == "" {
+		return nil, errors.New("recorder: missing call id or dir")
+	}
+	if err := os.MkdirAll(r.cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	ext := ".wav"
+	if r.cfg.Format == RecordingOpus {
+		ext = ".opus"
+	}
+	path := filepath.Join(r.cfg.Dir, safe+ext)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	var w trackWriter
+	if r.cfg.Format == RecordingOpus {
+		if r.cfg.NewOpusEncoder == nil {
+			_ = f.Close()
+			return nil, errors.New("recorder: opus requires an encoder")
+		}
+		enc, err := r.cfg.NewOpusEncoder(r.cfg.SampleRate, 2)
+		if err == nil {
+			w, err = newOggOpusWriter(f, enc, r.cfg.SampleRate)
+		}
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	} else if w, err = newWAVWriter(f, r.cfg.SampleRate); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &recording{path: path, start: start, rate: r.cfg.SampleRate, w: w}, nil
+}
+
+type trackWriter interface {
+	// write takes interleaved stereo samples.
+	write(pcm []int16) error
+	close() error
+}
+
+// recording holds both tracks from sample index base until they are flushed.
+type recording struct {
+	path   string
+	start  time.Time
+	rate   int
+	w      trackWriter
+	paused bool
+	err    error
+
+	base      int
+	caller    []int16
+	agent     []int16
+	callerEnd int
+	agentEnd  int
+}
+
+func (rec *recording) clock(now time.Time) int {
+	return int(now.Sub(rec.start) * time.Duration(rec.rate) / time.Second)
+}
+
+// place writes pcm at sample index at, or right after the track's previous
+// audio if that ends later. Gaps are left as silence.
+func (rec *recording) place(track *[]int16, end *int, at int, pcm []int16) {
+	if at < *end {
+		at = *end
+	}
+	if at < rec.base {
+		pcm = pcm[min(rec.base-at, len(pcm)):]
+		at = rec.base
+	}
+	need := at + len(pcm) - rec.base
+	if need > len(*track) {
+		*track = append(*track, make([]int16, need-len(*track))...)
+	}
+	copy((*track)[at-rec.base:], pcm)
+	*end = at + len(pcm)
+}
+
+func (rec *recording) clearAgent(at int) {
+	if rec.agentEnd <= at {
+		return
+	}
+	if at < rec.base {
+		at = rec.base
+	}
+	rec.agent = rec.agent[:max(at-rec.base, 0)]
+	rec.agentEnd = at
+}
+
+// flush writes both tracks up to sample index upto.
+func (rec *recording) flush(upto int) {
+	n := upto - rec.base
+	if n <= 0 || rec.err != nil {
+		return
+	}
+	out := make([]int16, 2*n)
+	for i := 0; i < n; i++ {
+		if i < len(rec.caller) {
+			out[2*i] = rec.caller[i]
+		}
+		if i < len(rec.agent) {
+			out[2*i+1] = rec.agent[i]
+		}
+	}
+	rec.err = rec.w.write(out)
+	rec.caller = rec.caller[min(n, len(rec.caller)):]
+	rec.agent = rec.agent[min(n, len(rec.agent)):]
+	rec.base = upto
+}
+
+func (rec *recording) finish() error {
+	rec.flush(max(rec.callerEnd, rec.agentEnd))
+	return errors.Join(rec.err, rec.w.close())
+}
+
+// decodePCM converts af to mono PCM16 at rate. Telephony audio without an
+// encoding is μ-law, as transports deliver it.
+func decodePCM(af frames.AudioFrame, rate int) ([]int16, bool) {
+	pcm, ok := audio.DecodeFrame(af)
+	if !ok {
+		return nil, false
+	}
+	in := af.Rate()
+	if in <= 0 {
+		in = 8000
+	}
+	return audio.Resample(pcm, in, rate), true
+}
+
+// wavWriter streams 16-bit stereo PCM and patches the header sizes on close.
+type wavWriter struct {
+	f    *os.File
+	size uint32
+}
+
+func newWAVWriter(f *os.File, rate int) (*wavWriter, error) {
+	const channels, bits = 2, 16
+	hdr := make([]byte, 44)
+	copy(hdr[0:], "RIFF")
+	copy(hdr[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(hdr[16:], 16)
+	binary.LittleEndian.PutUint16(hdr[20:], 1) // PCM
+	binary.LittleEndian.PutUint16(hdr[22:], channels)
+	binary.LittleEndian.PutUint32(hdr[24:], uint32(rate))
+	binary.LittleEndian.PutUint32(hdr[28:], uint32(rate*channels*bits/8))
+	binary.LittleEndian.PutUint16(hdr[32:], channels*bits/8)
+	binary.LittleEndian.PutUint16(hdr[34:], bits)
+	copy(hdr[36:], "data")
+	if _, err := f.Write(hdr); err != nil {
+		return nil, err
+	}
+	return &wavWriter{f: f}, nil
+}
+
+func (w *wavWriter) write(pcm []int16) error {
+	buf := make([]byte, 2*len(pcm))
+	for i, s := range pcm {
+		binary.LittleEndian.PutUint16(buf[2*i:], uint16(s))
+	}
+	n, err := w.f.Write(buf)
+	w.size += uint32(n)
+	return err
+}
+
+func (w *wavWriter) close() error {
+	var sizes [4]byte
+	binary.LittleEndian.PutUint32(sizes[:], 36+w.size)
+	_, err := w.f.WriteAt(sizes[:], 4)
+	binary.LittleEndian.PutUint32(sizes[:], w.size)
+	if _, werr := w.f.WriteAt(sizes[:], 40); err == nil {
+		err = werr
+	}
+	return errors.Join(err, w.f.Close())
+}
+
+// oggOpusWriter packs 20ms Opus packets into an Ogg stream (RFC 7845).
+type oggOpusWriter struct {
+	f       *os.File
+	enc     OpusEncoder
+	frame   int // samples per channel in 20ms
+	pending []int16
+	serial  uint32
+	seq     uint32
+	granule uint64
+	packet  []byte
+}
+
+// opusPreSkip is libopus's encoder lookahead at 48kHz.
+const opusPreSkip = 312
+
+func newOggOpusWriter(f *os.File, enc OpusEncoder, rate int) (*oggOpusWriter, error) {
+	w := &oggOpusWriter{f: f, enc: enc, frame: rate / 50, serial: uint32(time.Now().UnixNano()), packet: make([]byte, 4000)}
+	head := make([]byte, 19)
+	copy(head, "OpusHead")
+	head[8] = 1 // version
+	head[9] = 2 // channels
+	binary.LittleEndian.PutUint16(head[10:], opusPreSkip)
+	binary.LittleEndian.PutUint32(head[12:], uint32(rate))
+	if err := w.page(head, 0x02, 0); err != nil {
+		return nil, err
+	}
+	vendor := "ranya"
+	tags := make([]byte, 8+4+len(vendor)+4)
+	copy(tags, "OpusTags")
+	binary.LittleEndian.PutUint32(tags[8:], uint32(len(vendor)))
+	copy(tags[12:], vendor)
+	if err := w.page(tags, 0, 0); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *oggOpusWriter) write(pcm []int16) error {
+	w.pending = append(w.pending, pcm...)
+	for len(w.pending) >= 2*w.frame {
+		if err := w.encode(w.pending[:2*w.frame], 0); err != nil {
+			return err
+		}
+		w.pending = w.pending[2*w.frame:]
+	}
+	return nil
+}
+
+func (w *oggOpusWriter) encode(frame []int16, flags byte) error {
+	n, err := w.enc.Encode(frame, w.packet)
+	if err != nil {
+		return fmt.Errorf("recorder: opus encode: %w", err)
+	}
+	w.granule += 960 // 20ms at 48kHz
+	return w.page(w.packet[:n], flags, w.granule)
+}
+
+func (w *oggOpusWriter) close() error {
+	last := make([]int16, 2*w.frame)
+	copy(last, w.pending)
+	err := w.encode(last, 0x04) // end of stream
+	return errors.Join(err, w.f.Close())
+}
+
+// page writes one packet as a single Ogg page.
+func (w *oggOpusWriter) page(packet []byte, flags byte, granule uint64) error {
+	segments := len(packet)/255 + 1
+	hdr := make([]byte, 27+segments)
+	copy(hdr, "OggS")
+	hdr[5] = flags
+	binary.LittleEndian.PutUint64(hdr[6:], granule)
+	binary.LittleEndian.PutUint32(hdr[14:], w.serial)
+	binary.LittleEndian.PutUint32(hdr[18:], w.seq)
+	hdr[26] = byte(segments)
+	for i := 0; i < segments-1; i++ {
+		hdr[27+i] = 255
+	}
+	hdr[27+segments-1] = byte(len(packet) % 255)
+	w.seq++
+	crc := oggCRC(oggCRC(0, hdr), packet)
+	binary.LittleEndian.PutUint32(hdr[22:], crc)
+	if _, err := w.f.Write(hdr); err != nil {
+		return err
+	}
+	_, err := w.f.Write(packet)
+	return err
+}
+
+var oggCRCTable = func() (t [256]uint32) {
+	for i := range t {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		t[i] = r
+	}
+	return t
+}()
+
+func oggCRC(crc uint32, data []byte) uint32 {
+	for _, b := range data {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
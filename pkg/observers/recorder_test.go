@@ -0,0 +1,105 @@
+package observers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+)
+
+func TestCallRecorderWritesAlignedStereoWAV(t *testing.T) {
+	dir := t.TempDir()
+	r := NewCallRecorder(RecorderConfig{Dir: dir})
+	start := time.Unix(1000, 0)
+	now := start
+	r.now = func() time.Time { return now }
+
+	// 100ms of loud μ-law from the caller, arriving at 100ms.
+	now = start.Add(100 * time.Millisecond)
+	r.Caller("CA1", frames.NewAudioFrame("s1", 0, bytes.Repeat([]byte{0x00}, 800), 8000, 1, nil))
+	// 100ms of 16kHz PCM from the agent starts playing at 100ms.
+	agent := make([]byte, 3200)
+	for i := 0; i < len(agent); i += 2 {
+		binary.LittleEndian.PutUint16(agent[i:], 1000)
+	}
+	pcmMeta := map[string]string{frames.MetaEncoding: "pcm16"}
+	r.Agent("CA1", frames.NewAudioFrame("s1", 0, agent, 16000, 1, pcmMeta))
+	// A second agent chunk queues behind the first, then barge-in drops it.
+	r.Agent("CA1", frames.NewAudioFrame("s1", 0, agent, 16000, 1, pcmMeta))
+	now = start.Add(250 * time.Millisecond)
+	r.ClearAgent("CA1")
+	// Paused audio is not captured.
+	r.Pause("CA1")
+	r.Caller("CA1", frames.NewAudioFrame("s1", 0, bytes.Repeat([]byte{0x00}, 800), 8000, 1, nil))
+	r.Resume("CA1")
+
+	path, err := r.Finish("CA1")
+	if err != nil || path != filepath.Join(dir, "CA1.wav") {
+		t.Fatalf("finish: path=%q err=%v", path, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(data[0:4]) != "RIFF" || binary.LittleEndian.Uint16(data[22:]) != 2 || binary.LittleEndian.Uint32(data[24:]) != 8000 {
+		t.Fatalf("unexpected wav header % x", data[:44])
+	}
+	pcm := data[44:]
+	// Caller spans 0-100ms, agent 100-250ms (cut by the barge-in).
+	if got := binary.LittleEndian.Uint32(data[40:]); got != uint32(len(pcm)) || len(pcm) != 2000*4 {
+		t.Fatalf("unexpected data size %d (%d bytes)", got, len(pcm))
+	}
+	sample := func(i, ch int) int16 { return int16(binary.LittleEndian.Uint16(pcm[4*i+2*ch:])) }
+	if sample(0, 0) > -30000 || sample(799, 0) > -30000 || sample(800, 0) != 0 {
+		t.Fatalf("caller track misplaced: %d %d %d", sample(0, 0), sample(799, 0), sample(800, 0))
+	}
+	if sample(799, 1) != 0 || sample(800, 1) != 1000 || sample(1999, 1) != 1000 {
+		t.Fatalf("agent track misplaced: %d %d %d", sample(799, 1), sample(800, 1), sample(1999, 1))
+	}
+}
+
+type fakeOpus struct{ frames int }
+
+func (f *fakeOpus) Encode(pcm []int16, data []byte) (int, error) {
+	f.frames++
+	return copy(data, []byte{0xfc, 0xff, 0xfe}), nil
+}
+
+func TestCallRecorderWritesOggOpus(t *testing.T) {
+	dir := t.TempDir()
+	enc := &fakeOpus{}
+	r := NewCallRecorder(RecorderConfig{Dir: dir, Format: RecordingOpus, NewOpusEncoder: func(rate, ch int) (OpusEncoder, error) {
+		if rate != 8000 || ch != 2 {
+			t.Fatalf("unexpected encoder params %d/%d", rate, ch)
+		}
+		return enc, nil
+	}})
+	r.Caller("CA2", frames.NewAudioFrame("s2", 0, bytes.Repeat([]byte{0xff}, 400), 8000, 1, nil))
+	path, err := r.Finish("CA2")
+	if err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if filepath.Ext(path) != ".opus" || !bytes.HasPrefix(data, []byte("OggS")) || !bytes.Contains(data, []byte("OpusHead")) || !bytes.Contains(data, []byte("OpusTags")) {
+		t.Fatalf("unexpected ogg stream % x", data)
+	}
+	// 50ms of audio is three 20ms frames, the last padded.
+	if enc.frames != 3 {
+		t.Fatalf("expected 3 encoded frames, got %d", enc.frames)
+	}
+	// The first page checksums over itself with the CRC field zeroed.
+	page := append([]byte(nil), data[:27+1+19]...)
+	want := binary.LittleEndian.Uint32(page[22:])
+	binary.LittleEndian.PutUint32(page[22:], 0)
+	if got := oggCRC(0, page); got != want {
+		t.Fatalf("bad page crc %08x want %08x", got, want)
+	}
+	last := bytes.LastIndex(data, []byte("OggS"))
+	if data[last+5]&0x04 == 0 {
+		t.Fatalf("expected end-of-stream flag on last page")
+	}
+}
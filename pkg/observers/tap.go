@@ -0,0 +1,151 @@
+package observers
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/metrics"
+	"github.com/harunnryd/ranya/pkg/redact"
+)
+
+// TapEvent is a metrics event as delivered to tap subscribers.
+type TapEvent struct {
+	Time     time.Time         `json:"time"`
+	Event    string            `json:"event"`
+	Value    float64           `json:"value,omitempty"`
+	StreamID string            `json:"stream_id,omitempty"`
+	TraceID  string            `json:"trace_id,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty"`
+	Fields   map[string]any    `json:"fields,omitempty"`
+}
+
+// Tap fans metrics events out to live subscribers, such as a debugger
+// following one call. It never blocks: a subscriber that falls behind loses
+// events and sees the count in Dropped. Register it behind an
+// AsyncObserver so tapping a call adds nothing to the pipeline.
+type Tap struct {
+	mu   sync.RWMutex
+	subs map[*TapSubscription]struct{}
+}
+
+// NewTap creates a tap with no subscribers.
+func NewTap() *Tap {
+	return &Tap{subs: make(map[*TapSubscription]struct{})}
+}
+
+// TapSubscription receives the events its filter accepts on C until Close.
+type TapSubscription struct {
+	C <-chan TapEvent
+
+	ch      chan TapEvent
+	filter  func(metrics.MetricsEvent) bool
+	tap     *Tap
+	dropped atomic.Int64
+	once    sync.Once
+}
+
+// Subscribe starts delivering events accepted by filter, which must be
+// cheap since it runs for every event. buffer bounds the events held for a
+// slow reader.
+func (t *Tap) Subscribe(filter func(metrics.MetricsEvent) bool, buffer int) *TapSubscription {
+	if buffer <= 0 {
+		buffer = 256
+	}
+	ch := make(chan TapEvent, buffer)
+	sub := &TapSubscription{C: ch, ch: ch, filter: filter, tap: t}
+	t.mu.Lock()
+	t.subs[sub] = struct{}{}
+	t.mu.Unlock()
+	return sub
+}
+
+// Subscribers returns the number of open subscriptions.
+func (t *Tap) Subscribers() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.subs)
+}
+
+// RecordEvent implements metrics.Observer.
+func (t *Tap) RecordEvent(ev metrics.MetricsEvent) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if len(t.subs) == 0 {
+		return
+	}
+	var out *TapEvent
+	for sub := range t.subs {
+		if sub.filter != nil && !sub.filter(ev) {
+			continue
+		}
+		if out == nil {
+			out = &TapEvent{
+				Time:     ev.Time.UTC(),
+				Event:    mapEventName(ev),
+				Value:    ev.Value,
+				StreamID: ev.Tags["stream_id"],
+				TraceID:  ev.Tags["trace_id"],
+				Tags:     copyTags(ev.Tags),
+				Fields:   sanitizeFields(ev.Fields),
+			}
+			addFrameDetails(out, ev.Frame)
+		}
+		select {
+		case sub.ch <- *out:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// addFrameDetails adds what a frame says: its text, tool name, arguments
+// and result. Only the tap reads them, and only for events a subscriber
+// takes, so other observers and untapped calls pay nothing.
+func addFrameDetails(out *TapEvent, f any) {
+	frame, ok := f.(frames.Frame)
+	if !ok {
+		return
+	}
+	set := func(k, v string) {
+		if v == "" {
+			return
+		}
+		if out.Fields == nil {
+			out.Fields = make(map[string]any, 2)
+		}
+		out.Fields[k] = redact.Text(v)
+	}
+	switch v := frame.(type) {
+	case frames.TextFrame:
+		set("text", v.Text())
+	case frames.ControlFrame, frames.SystemFrame:
+		meta := frames.MetadataOf(frame)
+		if tool := meta.Get(frames.MetaToolName); tool != "" {
+			if out.Tags == nil {
+				out.Tags = make(map[string]string, 1)
+			}
+			out.Tags[frames.MetaToolName] = tool
+		}
+		set(frames.MetaToolArgs, meta.Get(frames.MetaToolArgs))
+		set(frames.MetaToolResult, meta.Get(frames.MetaToolResult))
+	}
+}
+
+// Dropped returns how many events were lost because the reader fell behind.
+func (s *TapSubscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close stops delivery and closes C.
+func (s *TapSubscription) Close() {
+	s.once.Do(func() {
+		s.tap.mu.Lock()
+		delete(s.tap.subs, s)
+		close(s.ch)
+		s.tap.mu.Unlock()
+	})
+}
+
+var _ metrics.Observer = (*Tap)(nil)
@@ -0,0 +1,46 @@
+package observers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/metrics"
+	"github.com/harunnryd/ranya/pkg/redact"
+)
+
+func TestTapFiltersRedactsAndDrops(t *testing.T) {
+	redact.SetEnabled(true)
+	defer redact.SetEnabled(false)
+
+	tap := NewTap()
+	sub := tap.Subscribe(func(ev metrics.MetricsEvent) bool { return ev.Tags["stream_id"] == "stream-1" }, 1)
+	tap.RecordEvent(metrics.MetricsEvent{Name: "frame_in", Time: time.Now(), Tags: map[string]string{"stream_id": "stream-2"}})
+	tap.RecordEvent(metrics.MetricsEvent{
+		Name:  "frame_in",
+		Time:  time.Now(),
+		Tags:  map[string]string{"stream_id": "stream-1", "kind": "text"},
+		Frame: frames.NewTextFrame("stream-1", 0, "mail me at jane@example.com", map[string]string{"stream_id": "stream-1"}),
+	})
+	tap.RecordEvent(metrics.MetricsEvent{Name: "frame_out", Time: time.Now(), Tags: map[string]string{"stream_id": "stream-1"}})
+
+	ev := <-sub.C
+	if ev.Event != "frame_in" || ev.StreamID != "stream-1" {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	if text, _ := ev.Fields["text"].(string); text == "" || text == "mail me at jane@example.com" {
+		t.Fatalf("expected redacted text, got %q", text)
+	}
+	if sub.Dropped() != 1 {
+		t.Fatalf("expected the overflow event to be dropped, got %d", sub.Dropped())
+	}
+
+	sub.Close()
+	if _, ok := <-sub.C; ok {
+		t.Fatalf("expected C to close")
+	}
+	if tap.Subscribers() != 0 {
+		t.Fatalf("expected no subscribers after Close")
+	}
+	tap.RecordEvent(metrics.MetricsEvent{Name: "frame_in", Tags: map[string]string{"stream_id": "stream-1"}})
+}
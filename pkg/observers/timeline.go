@@ -15,18 +15,33 @@ import (
 
 // TimelineObserver writes a per-call timeline JSONL trace.
 type TimelineObserver struct {
-	dir   string
+	dir string
+	// ext is the file extension, ".jsonl" for the full timeline.
+	ext   string
+	keep  func(metrics.MetricsEvent) bool
 	mu    sync.Mutex
 	files map[string]*os.File
 }
 
 // NewTimelineObserver creates a new timeline observer writing to dir.
 func NewTimelineObserver(dir string) *TimelineObserver {
-	return &TimelineObserver{dir: dir, files: make(map[string]*os.File)}
+	return &TimelineObserver{dir: dir, ext: ".jsonl", files: make(map[string]*os.File)}
+}
+
+// NewFilteredTimelineObserver writes only the events keep accepts, to
+// per-call <id>.<name>.jsonl files in dir.
+func NewFilteredTimelineObserver(dir, name string, keep func(metrics.MetricsEvent) bool) *TimelineObserver {
+	o := NewTimelineObserver(dir)
+	o.ext = "." + name + ".jsonl"
+	o.keep = keep
+	return o
 }
 
 // RecordEvent implements metrics.Observer.
 func (o *TimelineObserver) RecordEvent(ev metrics.MetricsEvent) {
+	if o.keep != nil && !o.keep(ev) {
+		return
+	}
 	id := ""
 	streamID := ""
 	traceID := ""
@@ -105,7 +120,7 @@ func (o *TimelineObserver) fileFor(id string) *os.File {
 	if err := os.MkdirAll(o.dir, 0o755); err != nil {
 		return nil
 	}
-	path := filepath.Join(o.dir, safe+".jsonl")
+	path := filepath.Join(o.dir, safe+o.ext)
 	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
 	if err != nil {
 		return nil
@@ -27,6 +27,9 @@ type Config struct {
 	LowCapacity   int
 	FairnessRatio int
 	Backpressure  BackpressureMode
+	// ErrorPolicies overrides DefaultErrorPolicy by processor name.
+	ErrorPolicies      map[string]ErrorPolicy
+	DefaultErrorPolicy ErrorPolicy
 }
 
 type PipelineConfig struct {
@@ -37,12 +40,16 @@ type PipelineConfig struct {
 type EngineConfig struct {
 	SampleRate      int `mapstructure:"samplerate"`
 	STTReplayChunks int `mapstructure:"stt_replay_chunks"`
+	// SessionIdleTimeoutMS ends sessions that receive no transport frames for
+	// this long (0 disables the reaper).
+	SessionIdleTimeoutMS int `mapstructure:"session_idle_timeout_ms"`
 }
 
 func LogConfiguration(cfg EngineConfig) {
 	slog.Info("engine_config",
 		"sample_rate", cfg.SampleRate,
 		"stt_replay_chunks", cfg.STTReplayChunks,
+		"session_idle_timeout_ms", cfg.SessionIdleTimeoutMS,
 	)
 }
 
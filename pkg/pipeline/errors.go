@@ -0,0 +1,211 @@
+package pipeline
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/errorsx"
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/metrics"
+	"github.com/harunnryd/ranya/pkg/redact"
+)
+
+// ErrorAction selects what the orchestrator does when a processor fails.
+type ErrorAction int
+
+const (
+	// ErrorActionDrop releases the failing frame (default).
+	ErrorActionDrop ErrorAction = iota
+	// ErrorActionPassThrough forwards the original frame to the next stage.
+	ErrorActionPassThrough
+	// ErrorActionRetry re-runs Process up to Retries times before dropping.
+	// Audio frames are not retried: a processor that failed may already have
+	// released their pooled buffer.
+	ErrorActionRetry
+	// ErrorActionFallback replaces the failing frame with a fallback control frame.
+	ErrorActionFallback
+	// ErrorActionEndCall asks the session owner to end the call.
+	ErrorActionEndCall
+)
+
+func (a ErrorAction) String() string {
+	switch a {
+	case ErrorActionPassThrough:
+		return "pass_through"
+	case ErrorActionRetry:
+		return "retry"
+	case ErrorActionFallback:
+		return "fallback"
+	case ErrorActionEndCall:
+		return "end_call"
+	default:
+		return "drop"
+	}
+}
+
+// ErrorPolicy configures error handling for a processor.
+type ErrorPolicy struct {
+	Action  ErrorAction
+	Retries int
+	Backoff time.Duration
+}
+
+// ErrorFrameName is the system frame name emitted when a processor fails.
+const ErrorFrameName = "error"
+
+// NewErrorFrame builds the standard error system frame for a failing frame.
+func NewErrorFrame(src frames.Frame, processor string, reason errorsx.ReasonCode, err error, action ErrorAction) frames.SystemFrame {
+	meta := map[string]string{
+		frames.MetaErrorReason:    string(reason),
+		frames.MetaErrorProcessor: processor,
+		frames.MetaErrorAction:    action.String(),
+	}
+	if err != nil {
+		meta[frames.MetaErrorMessage] = err.Error()
+	}
+	var pts int64
+	streamID := ""
+	if src != nil {
+		pts = src.PTS()
+		srcMeta := src.Meta()
+		streamID = srcMeta[frames.MetaStreamID]
+		for _, key := range []string{frames.MetaCallSID, frames.MetaTraceID, frames.MetaAgent} {
+			if v := srcMeta[key]; v != "" {
+				meta[key] = v
+			}
+		}
+	}
+	return frames.NewSystemFrame(streamID, pts, ErrorFrameName, meta)
+}
+
+func (o *orchestrator) policyFor(name string) ErrorPolicy {
+	if p, ok := o.cfg.ErrorPolicies[name]; ok {
+		return p
+	}
+	return o.cfg.DefaultErrorPolicy
+}
+
+// runStage runs a single processor on f and applies the error policy on failure.
+func (o *orchestrator) runStage(p FrameProcessor, f frames.Frame) []frames.Frame {
+	if m, ok := p.(StreamMigrator); ok {
+		migrateStream(m, f)
+	}
+	start := time.Now()
+	r, err := p.Process(f)
+	if err != nil {
+		policy := o.policyFor(p.Name())
+		if policy.Action == ErrorActionRetry && f.Kind() != frames.KindAudio {
+			for i := 0; i < policy.Retries && err != nil; i++ {
+				if policy.Backoff > 0 {
+					select {
+					case <-o.ctx.Done():
+						frames.ReleaseAudioFrame(f)
+						return nil
+					case <-time.After(policy.Backoff):
+					}
+				}
+				start = time.Now()
+				r, err = p.Process(f)
+			}
+		}
+		if err != nil {
+			return o.handleError(p.Name(), f, err, policy)
+		}
+	}
+	if r == nil {
+		frames.ReleaseAudioFrame(f)
+		return nil
+	}
+	o.recordStage(p.Name(), f, start)
+	return r
+}
+
+func (o *orchestrator) handleError(name string, f frames.Frame, err error, policy ErrorPolicy) []frames.Frame {
+	reason := errorsx.Reason(err)
+	if reason == errorsx.ReasonUnknown {
+		reason = errorsx.ReasonProcessor
+	}
+	action := policy.Action
+	if action == ErrorActionRetry {
+		action = ErrorActionDrop
+	}
+	slog.Warn("processor_error",
+		"processor", name,
+		"action", action.String(),
+		"reason", string(reason),
+		"stream_id", streamIDFromFrame(f),
+		"error", err,
+	)
+	o.recordDeadLetter(name, f, err, reason, action)
+
+	errFrame := NewErrorFrame(f, name, reason, err, action)
+	switch action {
+	case ErrorActionPassThrough:
+		return []frames.Frame{f, errFrame}
+	case ErrorActionFallback:
+		srcMeta := f.Meta()
+		meta := map[string]string{frames.MetaReason: string(reason)}
+		for _, key := range []string{frames.MetaCallSID, frames.MetaTraceID} {
+			if v := srcMeta[key]; v != "" {
+				meta[key] = v
+			}
+		}
+		fallback := frames.NewControlFrame(streamIDFromFrame(f), f.PTS(), frames.ControlFallback, meta)
+		frames.ReleaseAudioFrame(f)
+		return []frames.Frame{errFrame, fallback}
+	default:
+		frames.ReleaseAudioFrame(f)
+		return []frames.Frame{errFrame}
+	}
+}
+
+func (o *orchestrator) recordDeadLetter(name string, f frames.Frame, err error, reason errorsx.ReasonCode, action ErrorAction) {
+	if o.obs == nil {
+		return
+	}
+	tags := map[string]string{
+		"processor":         name,
+		frames.MetaStreamID: streamIDFromFrame(f),
+		frames.MetaTraceID:  traceIDFromFrame(f),
+		frames.MetaAgent:    agentFromFrame(f),
+		"kind":              kindFromFrame(f),
+		"reason":            string(reason),
+		"action":            action.String(),
+	}
+	addFrameDetailTags(tags, f)
+	fields := frameSummary(f)
+	fields["error"] = err.Error()
+	o.obs.RecordEvent(metrics.MetricsEvent{
+		Name:   metrics.EventDeadLetter,
+		Time:   time.Now(),
+		Value:  1,
+		Tags:   tags,
+		Fields: fields,
+	})
+}
+
+// frameSummary returns a compact, redacted description of a frame.
+func frameSummary(f frames.Frame) map[string]any {
+	out := map[string]any{}
+	if f == nil {
+		return out
+	}
+	out["pts"] = f.PTS()
+	switch v := f.(type) {
+	case frames.AudioFrame:
+		out["audio_bytes"] = len(v.RawPayload())
+		out["sample_rate"] = v.Rate()
+		out["channels"] = v.Channels()
+	case frames.TextFrame:
+		text := v.Text()
+		if len(text) > 200 {
+			text = text[:200]
+		}
+		out["text"] = redact.Text(text)
+	case frames.ControlFrame:
+		out["control_code"] = string(v.Code())
+	case frames.SystemFrame:
+		out["system_name"] = v.Name()
+	}
+	return out
+}
@@ -0,0 +1,144 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+)
+
+// ProcessorStarter is implemented by processors that need setup before the
+// first frame. Start is called once from Orchestrator.Start with the session
+// context.
+type ProcessorStarter interface {
+	Start(ctx context.Context) error
+}
+
+// ProcessorCloser is implemented by processors holding resources (vendor
+// sessions, timers). Close is called once from Orchestrator.Stop, in reverse
+// pipeline order, after all stage goroutines have exited.
+type ProcessorCloser interface {
+	Close() error
+}
+
+// SessionEndHandler is implemented by processors keeping per-stream or
+// per-call state. OnSessionEnd is called once from Orchestrator.Stop before
+// Close, whether or not a call_end frame ever reached the processor.
+type SessionEndHandler interface {
+	OnSessionEnd(meta map[string]string)
+}
+
+// StreamMigrator is implemented by processors keeping per-stream state.
+// When a call resumes on a new media stream, MigrateStream is called from the
+// processor's stage goroutine just before the call_reconnect frame reaches
+// Process, so state moves to the new stream ID without racing Process.
+type StreamMigrator interface {
+	MigrateStream(oldStreamID, newStreamID string)
+}
+
+// SessionEnder is implemented by orchestrators that forward session end
+// metadata (call SID, stream ID, end reason) to SessionEndHandler processors.
+type SessionEnder interface {
+	EndSession(meta map[string]string)
+}
+
+// stopWait bounds how long Stop waits for stage goroutines before running
+// lifecycle hooks.
+const stopWait = 2 * time.Second
+
+func (o *orchestrator) startProcessors() error {
+	for _, p := range o.procs {
+		s, ok := p.(ProcessorStarter)
+		if !ok {
+			continue
+		}
+		if err := s.Start(o.ctx); err != nil {
+			return fmt.Errorf("start processor %s: %w", p.Name(), err)
+		}
+	}
+	return nil
+}
+
+func (o *orchestrator) EndSession(meta map[string]string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.endMeta == nil {
+		o.endMeta = make(map[string]string, len(meta))
+	}
+	for k, v := range meta {
+		o.endMeta[k] = v
+	}
+}
+
+func (o *orchestrator) sessionEndMeta() map[string]string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	out := make(map[string]string, len(o.endMeta))
+	for k, v := range o.endMeta {
+		out[k] = v
+	}
+	return out
+}
+
+func (o *orchestrator) finishProcessors() {
+	meta := o.sessionEndMeta()
+	for _, p := range o.procs {
+		if h, ok := p.(SessionEndHandler); ok {
+			h.OnSessionEnd(copyMeta(meta))
+		}
+	}
+	for i := len(o.procs) - 1; i >= 0; i-- {
+		c, ok := o.procs[i].(ProcessorCloser)
+		if !ok {
+			continue
+		}
+		if err := c.Close(); err != nil {
+			slog.Warn("processor_close_failed", "processor", o.procs[i].Name(), "error", err)
+		}
+	}
+}
+
+// FrameCallReconnect is the system frame announcing that a call moved to a
+// new media stream; MetaOldStreamID names the stream it left.
+const FrameCallReconnect = "call_reconnect"
+
+func migrateStream(m StreamMigrator, f frames.Frame) {
+	if f.Kind() != frames.KindSystem {
+		return
+	}
+	sf := f.(frames.SystemFrame)
+	if sf.Name() != FrameCallReconnect {
+		return
+	}
+	oldStreamID := sf.Meta()[frames.MetaOldStreamID]
+	newStreamID := frames.StreamIDOf(f)
+	if oldStreamID == "" || newStreamID == "" || oldStreamID == newStreamID {
+		return
+	}
+	m.MigrateStream(oldStreamID, newStreamID)
+}
+
+func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func copyMeta(in map[string]string) map[string]string {
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
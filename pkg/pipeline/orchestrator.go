@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/harunnryd/ranya/pkg/frames"
@@ -22,6 +23,11 @@ type orchestrator struct {
 	stageCh []chan frames.Frame
 	sink    func(frames.Frame)
 	obs     metrics.Observer
+
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+	mu       sync.Mutex
+	endMeta  map[string]string
 }
 
 func New(cfg Config) Orchestrator {
@@ -62,6 +68,9 @@ func (o *orchestrator) AddProcessor(p FrameProcessor) error {
 }
 
 func (o *orchestrator) Start() error {
+	if err := o.startProcessors(); err != nil {
+		return err
+	}
 	if o.cfg.Async {
 		return o.startAsync()
 	}
@@ -69,15 +78,27 @@ func (o *orchestrator) Start() error {
 }
 
 func (o *orchestrator) Stop() error {
-	o.cancel()
-	// allow goroutines to exit and drain
-	time.Sleep(5 * time.Millisecond)
-	close(o.out)
+	o.stopOnce.Do(func() {
+		o.cancel()
+		if !waitTimeout(&o.wg, stopWait) {
+			slog.Warn("pipeline_stop_timeout", "timeout", stopWait)
+		}
+		o.finishProcessors()
+		close(o.out)
+	})
 	return nil
 }
 
-func (o *orchestrator) startSync() error {
+func (o *orchestrator) goLoop(fn func()) {
+	o.wg.Add(1)
 	go func() {
+		defer o.wg.Done()
+		fn()
+	}()
+}
+
+func (o *orchestrator) startSync() error {
+	o.goLoop(func() {
 		for {
 			select {
 			case <-o.ctx.Done():
@@ -97,14 +118,17 @@ func (o *orchestrator) startSync() error {
 				o.recordIn(f)
 			}
 		}
-	}()
-	go func() {
+	})
+	o.goLoop(func() {
 		for {
 			select {
 			case <-o.ctx.Done():
 				return
 			default:
-				fAny, _ := o.pq.Pop()
+				fAny, ok := o.pq.PopContext(o.ctx)
+				if !ok {
+					return
+				}
 				f := fAny.(frames.Frame)
 				if shouldDropForLag(f, 500*time.Millisecond) {
 					frames.ReleaseAudioFrame(f)
@@ -115,14 +139,7 @@ func (o *orchestrator) startSync() error {
 				for _, p := range o.procs {
 					var next []frames.Frame
 					for _, cur := range out {
-						start := time.Now()
-						r, err := p.Process(cur)
-						if err != nil || r == nil {
-							frames.ReleaseAudioFrame(cur)
-							continue
-						}
-						o.recordStage(p.Name(), cur, start)
-						next = append(next, r...)
+						next = append(next, o.runStage(p, cur)...)
 					}
 					out = next
 					if out == nil {
@@ -135,7 +152,7 @@ func (o *orchestrator) startSync() error {
 				}
 			}
 		}
-	}()
+	})
 	return nil
 }
 
@@ -145,29 +162,22 @@ func (o *orchestrator) startAsync() error {
 		o.stageCh[i] = make(chan frames.Frame, o.cfg.StageBuffer)
 	}
 	for i, p := range o.procs {
-		inCh, outCh := o.stageCh[i], o.stageCh[i+1]
-		go func(proc FrameProcessor, in, out chan frames.Frame) {
+		proc, in, out := p, o.stageCh[i], o.stageCh[i+1]
+		o.goLoop(func() {
 			for {
 				select {
 				case <-o.ctx.Done():
 					return
 				case f := <-in:
-					start := time.Now()
-					r, err := proc.Process(f)
-					if err != nil || r == nil {
-						frames.ReleaseAudioFrame(f)
-						continue
-					}
-					o.recordStage(proc.Name(), f, start)
-					for _, e := range r {
+					for _, e := range o.runStage(proc, f) {
 						o.push(out, e)
 					}
 				}
 			}
-		}(p, inCh, outCh)
+		})
 	}
 	// feeder from in -> high/low pq
-	go func() {
+	o.goLoop(func() {
 		for {
 			select {
 			case <-o.ctx.Done():
@@ -187,15 +197,18 @@ func (o *orchestrator) startAsync() error {
 				o.recordIn(f)
 			}
 		}
-	}()
+	})
 	// pop from pq to stage0 honoring fairness
-	go func() {
+	o.goLoop(func() {
 		for {
 			select {
 			case <-o.ctx.Done():
 				return
 			default:
-				fAny, _ := o.pq.Pop()
+				fAny, ok := o.pq.PopContext(o.ctx)
+				if !ok {
+					return
+				}
 				f := fAny.(frames.Frame)
 				if shouldDropForLag(f, 500*time.Millisecond) {
 					frames.ReleaseAudioFrame(f)
@@ -205,9 +218,9 @@ func (o *orchestrator) startAsync() error {
 				o.push(o.stageCh[0], f)
 			}
 		}
-	}()
+	})
 	// final stage to out
-	go func() {
+	o.goLoop(func() {
 		final := o.stageCh[len(o.stageCh)-1]
 		for {
 			select {
@@ -218,7 +231,7 @@ func (o *orchestrator) startAsync() error {
 				o.emit(e)
 			}
 		}
-	}()
+	})
 	return nil
 }
 
@@ -268,6 +281,7 @@ func (o *orchestrator) recordStage(name string, f frames.Frame, start time.Time)
 			frames.MetaStreamID: streamIDFromFrame(f),
 			frames.MetaTraceID:  traceIDFromFrame(f),
 			frames.MetaAgent:    agentFromFrame(f),
+			"kind":              kindFromFrame(f),
 		},
 	})
 }
@@ -284,9 +298,10 @@ func (o *orchestrator) recordIn(f frames.Frame) {
 	}
 	addFrameDetailTags(tags, f)
 	o.obs.RecordEvent(metrics.MetricsEvent{
-		Name: "frame_in",
-		Time: time.Now(),
-		Tags: tags,
+		Name:  "frame_in",
+		Time:  time.Now(),
+		Tags:  tags,
+		Frame: contentFrame(f),
 	})
 }
 
@@ -302,9 +317,10 @@ func (o *orchestrator) recordOut(f frames.Frame) {
 	}
 	addFrameDetailTags(tags, f)
 	o.obs.RecordEvent(metrics.MetricsEvent{
-		Name: "frame_out",
-		Time: time.Now(),
-		Tags: tags,
+		Name:  "frame_out",
+		Time:  time.Now(),
+		Tags:  tags,
+		Frame: contentFrame(f),
 	})
 }
 
@@ -325,25 +341,11 @@ func (o *orchestrator) recordDrop(f frames.Frame) {
 }
 
 func streamIDFromFrame(f frames.Frame) string {
-	if f == nil {
-		return ""
-	}
-	m := f.Meta()
-	if m == nil {
-		return ""
-	}
-	return m[frames.MetaStreamID]
+	return frames.StreamIDOf(f)
 }
 
 func traceIDFromFrame(f frames.Frame) string {
-	if f == nil {
-		return ""
-	}
-	m := f.Meta()
-	if m == nil {
-		return ""
-	}
-	return m[frames.MetaTraceID]
+	return frames.TraceIDOf(f)
 }
 
 func logPipeline(procs []FrameProcessor) {
@@ -358,14 +360,7 @@ func logPipeline(procs []FrameProcessor) {
 }
 
 func agentFromFrame(f frames.Frame) string {
-	if f == nil {
-		return ""
-	}
-	m := f.Meta()
-	if m == nil {
-		return ""
-	}
-	return m[frames.MetaAgent]
+	return frames.MetaValue(f, frames.MetaAgent)
 }
 
 func kindFromFrame(f frames.Frame) string {
@@ -379,20 +374,16 @@ func addFrameDetailTags(tags map[string]string, f frames.Frame) {
 	if tags == nil || f == nil {
 		return
 	}
-	meta := f.Meta()
-	if meta != nil {
-		if source := meta[frames.MetaSource]; source != "" {
-			tags["source"] = source
-		}
+	meta := frames.MetadataOf(f)
+	if source := meta.Get(frames.MetaSource); source != "" {
+		tags["source"] = source
 	}
 	switch f.Kind() {
 	case frames.KindControl:
 		cf := f.(frames.ControlFrame)
 		tags["control_code"] = string(cf.Code())
-		if meta != nil {
-			if reason := meta[frames.MetaReason]; reason != "" {
-				tags["control_reason"] = reason
-			}
+		if reason := meta.Get(frames.MetaReason); reason != "" {
+			tags["control_reason"] = reason
 		}
 	case frames.KindSystem:
 		sf := f.(frames.SystemFrame)
@@ -402,6 +393,15 @@ func addFrameDetailTags(tags map[string]string, f frames.Frame) {
 	}
 }
 
+// contentFrame returns f for events that carry their frame. Audio is left
+// out since pooled payloads are recycled once the frame is released.
+func contentFrame(f frames.Frame) any {
+	if f == nil || f.Kind() == frames.KindAudio {
+		return nil
+	}
+	return f
+}
+
 func shouldDropForLag(f frames.Frame, maxLag time.Duration) bool {
 	if f == nil || f.Kind() != frames.KindAudio {
 		return false
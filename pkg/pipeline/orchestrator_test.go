@@ -0,0 +1,249 @@
+package pipeline
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/errorsx"
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/metrics"
+)
+
+type failingProcessor struct {
+	mu       sync.Mutex
+	calls    int
+	failFor  int
+	reason   errorsx.ReasonCode
+	procName string
+}
+
+func (p *failingProcessor) Name() string { return p.procName }
+
+func (p *failingProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if p.failFor < 0 || p.calls <= p.failFor {
+		return nil, errorsx.Wrap(errors.New("boom"), p.reason)
+	}
+	return []frames.Frame{f}, nil
+}
+
+func (p *failingProcessor) Calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func runOnce(t *testing.T, cfg Config, proc FrameProcessor, obs metrics.Observer) []frames.Frame {
+	t.Helper()
+	cfg.HighCapacity = 8
+	cfg.LowCapacity = 8
+	cfg.FairnessRatio = 1
+	cfg.StageBuffer = 8
+	orch := New(cfg)
+	_ = orch.AddProcessor(proc)
+	if obs != nil {
+		orch.SetObserver(obs)
+	}
+	var mu sync.Mutex
+	var out []frames.Frame
+	orch.SetSink(func(f frames.Frame) {
+		mu.Lock()
+		out = append(out, f)
+		mu.Unlock()
+	})
+	if err := orch.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer orch.Stop()
+	orch.In() <- frames.NewTextFrame("stream-1", 1, "hello", map[string]string{
+		frames.MetaCallSID:  "call-1",
+		frames.MetaTraceID:  "trace-1",
+		frames.MetaAgent:    "triage",
+		frames.MetaStreamID: "stream-1",
+	})
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]frames.Frame(nil), out...)
+}
+
+func systemNames(list []frames.Frame) []string {
+	var names []string
+	for _, f := range list {
+		if sf, ok := f.(frames.SystemFrame); ok {
+			names = append(names, sf.Name())
+		}
+	}
+	return names
+}
+
+func TestErrorPolicyDropEmitsErrorFrameAndDeadLetter(t *testing.T) {
+	obs := metrics.NewMemoryObserver()
+	proc := &failingProcessor{failFor: -1, reason: errorsx.ReasonLLMGenerate, procName: "llm"}
+	out := runOnce(t, Config{}, proc, obs)
+	if len(out) != 1 {
+		t.Fatalf("expected only the error frame, got %d frames", len(out))
+	}
+	sf, ok := out[0].(frames.SystemFrame)
+	if !ok || sf.Name() != ErrorFrameName {
+		t.Fatalf("expected error system frame, got %#v", out[0])
+	}
+	meta := sf.Meta()
+	if meta[frames.MetaErrorReason] != string(errorsx.ReasonLLMGenerate) {
+		t.Fatalf("expected reason %s, got %q", errorsx.ReasonLLMGenerate, meta[frames.MetaErrorReason])
+	}
+	if meta[frames.MetaErrorProcessor] != "llm" || meta[frames.MetaCallSID] != "call-1" {
+		t.Fatalf("unexpected error meta: %v", meta)
+	}
+	var dead int
+	for _, ev := range obs.Events {
+		if ev.Name == metrics.EventDeadLetter {
+			dead++
+			if ev.Fields["text"] != "hello" {
+				t.Fatalf("expected frame summary text, got %v", ev.Fields["text"])
+			}
+		}
+	}
+	if dead != 1 {
+		t.Fatalf("expected 1 dead letter event, got %d", dead)
+	}
+}
+
+func TestErrorPolicyRetryRecovers(t *testing.T) {
+	proc := &failingProcessor{failFor: 2, procName: "flaky"}
+	cfg := Config{ErrorPolicies: map[string]ErrorPolicy{
+		"flaky": {Action: ErrorActionRetry, Retries: 2, Backoff: time.Millisecond},
+	}}
+	out := runOnce(t, cfg, proc, nil)
+	if proc.Calls() != 3 {
+		t.Fatalf("expected 3 attempts, got %d", proc.Calls())
+	}
+	if len(out) != 1 || out[0].Kind() != frames.KindText {
+		t.Fatalf("expected recovered text frame, got %v", out)
+	}
+}
+
+func TestErrorPolicyRetrySkipsAudio(t *testing.T) {
+	proc := &failingProcessor{failFor: 1, procName: "flaky"}
+	o := New(Config{ErrorPolicies: map[string]ErrorPolicy{
+		"flaky": {Action: ErrorActionRetry, Retries: 2},
+	}}).(*orchestrator)
+	out := o.runStage(proc, frames.NewAudioFrame("stream-1", 1, make([]byte, 160), 8000, 1, nil))
+	if proc.Calls() != 1 {
+		t.Fatalf("expected audio to be tried once, got %d attempts", proc.Calls())
+	}
+	if names := systemNames(out); len(out) != 1 || len(names) != 1 || names[0] != ErrorFrameName {
+		t.Fatalf("expected only the error frame, got %v", out)
+	}
+}
+
+func TestErrorPolicyPassThroughAndFallback(t *testing.T) {
+	proc := &failingProcessor{failFor: -1, procName: "p"}
+	out := runOnce(t, Config{DefaultErrorPolicy: ErrorPolicy{Action: ErrorActionPassThrough}}, proc, nil)
+	if len(out) != 2 || out[0].Kind() != frames.KindText {
+		t.Fatalf("expected original frame plus error frame, got %v", out)
+	}
+
+	proc = &failingProcessor{failFor: -1, procName: "p"}
+	out = runOnce(t, Config{Async: true, DefaultErrorPolicy: ErrorPolicy{Action: ErrorActionFallback}}, proc, nil)
+	var fallback bool
+	for _, f := range out {
+		if cf, ok := f.(frames.ControlFrame); ok && cf.Code() == frames.ControlFallback {
+			fallback = true
+		}
+	}
+	if !fallback {
+		t.Fatalf("expected fallback control frame, got %v", out)
+	}
+	if names := systemNames(out); len(names) != 1 || names[0] != ErrorFrameName {
+		t.Fatalf("expected error frame, got %v", names)
+	}
+}
+
+type passThroughProcessor struct{ name string }
+
+func (p passThroughProcessor) Name() string { return p.name }
+
+func (p passThroughProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
+	_ = frames.StreamIDOf(f)
+	_ = frames.CallSIDOf(f)
+	return []frames.Frame{f}, nil
+}
+
+type dropAudioProcessor struct{}
+
+func (dropAudioProcessor) Name() string { return "drop_audio" }
+
+func (dropAudioProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
+	if f.Kind() == frames.KindAudio {
+		return nil, nil
+	}
+	return []frames.Frame{f}, nil
+}
+
+func TestPooledAudioReleasedExactlyOnce(t *testing.T) {
+	frames.SetPoolDebug(true)
+	defer frames.SetPoolDebug(false)
+	before := frames.AudioPoolStats()
+
+	for _, proc := range []FrameProcessor{passThroughProcessor{name: "pass"}, dropAudioProcessor{}} {
+		orch := New(Config{HighCapacity: 8, LowCapacity: 8, FairnessRatio: 1, StageBuffer: 8, Async: true})
+		_ = orch.AddProcessor(proc)
+		orch.SetSink(func(frames.Frame) {})
+		if err := orch.Start(); err != nil {
+			t.Fatalf("start: %v", err)
+		}
+		for i := 0; i < 4; i++ {
+			orch.In() <- frames.NewAudioFrameFromPool("stream-1", 0, []byte{1, 2}, 8000, 1, nil)
+		}
+		time.Sleep(30 * time.Millisecond)
+		_ = orch.Stop()
+	}
+
+	after := frames.AudioPoolStats()
+	if after.DoubleReleases != before.DoubleReleases {
+		t.Fatalf("expected no double releases, got %d", after.DoubleReleases-before.DoubleReleases)
+	}
+	if live := after.Live - before.Live; live != 0 {
+		t.Fatalf("expected all pooled frames released, %d still live", live)
+	}
+}
+
+// BenchmarkOrchestratorAudioPath measures inbound audio frames through a
+// pipeline of pass-through stages with metrics enabled.
+func BenchmarkOrchestratorAudioPath(b *testing.B) {
+	orch := New(Config{
+		HighCapacity:  64,
+		LowCapacity:   1024,
+		FairnessRatio: 3,
+		StageBuffer:   128,
+		Async:         true,
+		Backpressure:  BackpressureWait,
+	})
+	for i := 0; i < 8; i++ {
+		_ = orch.AddProcessor(passThroughProcessor{name: "stage"})
+	}
+	orch.SetObserver(metrics.NoopObserver{})
+	// Bound frames in flight so the priority queue never drops under load.
+	inflight := make(chan struct{}, 256)
+	orch.SetSink(func(frames.Frame) { <-inflight })
+	if err := orch.Start(); err != nil {
+		b.Fatalf("start: %v", err)
+	}
+	defer orch.Stop()
+	payload := make([]byte, 160)
+	meta := map[string]string{frames.MetaCallSID: "call-1", frames.MetaTraceID: "trace-1"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		inflight <- struct{}{}
+		orch.In() <- frames.NewAudioFrameFromPool("stream-1", 0, payload, 8000, 1, meta)
+	}
+	for i := 0; i < cap(inflight); i++ {
+		inflight <- struct{}{}
+	}
+}
@@ -2,19 +2,52 @@ package pipeline
 
 import (
 	"context"
+	"log/slog"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/metrics"
 )
 
 type Session struct {
-	CallSID  string
+	CallSID string
+	// StreamID is the media stream the session was created on; Stream
+	// returns the current one after reconnects.
 	StreamID string
 	TraceID  string
 	Orch     Orchestrator
 	Ctx      context.Context
 	Cancel   context.CancelFunc
 	Created  time.Time
+
+	lastSeen   atomic.Int64
+	stream     atomic.Pointer[string]
+	reconnects atomic.Int64
+}
+
+// Stream returns the media stream the session is bound to now.
+func (s *Session) Stream() string {
+	if v := s.stream.Load(); v != nil {
+		return *v
+	}
+	return s.StreamID
+}
+
+// Reconnects returns how many times the call moved to a new stream.
+func (s *Session) Reconnects() int {
+	return int(s.reconnects.Load())
+}
+
+// Touch marks the session as active now.
+func (s *Session) Touch() {
+	s.lastSeen.Store(time.Now().UnixNano())
+}
+
+// LastActivity returns the last time the session was touched.
+func (s *Session) LastActivity() time.Time {
+	return time.Unix(0, s.lastSeen.Load())
 }
 
 type SessionFactory func(ctx context.Context, callSID, streamID, traceID string) (Orchestrator, error)
@@ -24,6 +57,9 @@ type SessionRegistry struct {
 	count    atomic.Int64
 	factory  SessionFactory
 	draining atomic.Bool
+	obs      metrics.Observer
+	onEnd    func(*Session)
+	onReap   func(*Session)
 }
 
 func NewSessionRegistry(factory SessionFactory) *SessionRegistry {
@@ -35,7 +71,9 @@ func (r *SessionRegistry) GetOrCreate(callSID, streamID, traceID string) (*Sessi
 		return nil, false, nil
 	}
 	if v, ok := r.sessions.Load(callSID); ok {
-		return v.(*Session), false, nil
+		sess := v.(*Session)
+		sess.Touch()
+		return sess, false, nil
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	orch, err := r.factory(ctx, callSID, streamID, traceID)
@@ -56,6 +94,7 @@ func (r *SessionRegistry) GetOrCreate(callSID, streamID, traceID string) (*Sessi
 		Cancel:   cancel,
 		Created:  time.Now(),
 	}
+	sess.Touch()
 	actual, loaded := r.sessions.LoadOrStore(callSID, sess)
 	if loaded {
 		_ = orch.Stop()
@@ -73,16 +112,156 @@ func (r *SessionRegistry) Get(callSID string) (*Session, bool) {
 	return nil, false
 }
 
+// SetObserver sets the observer used for registry metrics (e.g. reaped sessions).
+func (r *SessionRegistry) SetObserver(obs metrics.Observer) {
+	r.obs = obs
+}
+
+// SetOnEnd registers fn to run after a session has been ended, whatever
+// the cause (call_end, Remove, reaping or CloseAll).
+func (r *SessionRegistry) SetOnEnd(fn func(*Session)) {
+	r.onEnd = fn
+}
+
+// SetOnReap registers fn to run when ReapIdle is about to end a session,
+// while it is still registered, so the call itself can be hung up.
+func (r *SessionRegistry) SetOnReap(fn func(*Session)) {
+	r.onReap = fn
+}
+
 func (r *SessionRegistry) Remove(callSID string) {
-	if v, ok := r.sessions.LoadAndDelete(callSID); ok {
-		sess := v.(*Session)
-		if sess.Cancel != nil {
-			sess.Cancel()
+	r.End(callSID, nil)
+}
+
+// End removes the session for callSID and stops its orchestrator. meta (for
+// example the call_end frame metadata) is forwarded to processors
+// implementing SessionEndHandler. Returns false if no session existed.
+func (r *SessionRegistry) End(callSID string, meta map[string]string) bool {
+	v, ok := r.sessions.LoadAndDelete(callSID)
+	if !ok {
+		return false
+	}
+	sess := v.(*Session)
+	if se, ok := sess.Orch.(SessionEnder); ok {
+		endMeta := map[string]string{
+			frames.MetaCallSID:  sess.CallSID,
+			frames.MetaStreamID: sess.Stream(),
+			frames.MetaTraceID:  sess.TraceID,
 		}
-		if sess.Orch != nil {
-			_ = sess.Orch.Stop()
+		for k, v := range meta {
+			if v != "" {
+				endMeta[k] = v
+			}
+		}
+		se.EndSession(endMeta)
+	}
+	if sess.Cancel != nil {
+		sess.Cancel()
+	}
+	if sess.Orch != nil {
+		_ = sess.Orch.Stop()
+	}
+	r.count.Add(-1)
+	if r.onEnd != nil {
+		r.onEnd(sess)
+	}
+	return true
+}
+
+// ReapIdle ends sessions that have not been touched for longer than idle and
+// returns how many were removed.
+func (r *SessionRegistry) ReapIdle(idle time.Duration) int {
+	if idle <= 0 {
+		return 0
+	}
+	now := time.Now()
+	var reaped int
+	r.sessions.Range(func(key, value any) bool {
+		sess := value.(*Session)
+		idleFor := now.Sub(sess.LastActivity())
+		if idleFor <= idle {
+			return true
+		}
+		if r.onReap != nil {
+			r.onReap(sess)
+		}
+		if !r.End(sess.CallSID, map[string]string{frames.MetaCallEndReason: "idle_timeout"}) {
+			return true
+		}
+		reaped++
+		slog.Warn("session_reaped", "call_sid", sess.CallSID, "stream_id", sess.Stream(), "idle", idleFor)
+		if r.obs != nil {
+			r.obs.RecordEvent(metrics.MetricsEvent{
+				Name:  metrics.EventSessionReaped,
+				Time:  now,
+				Value: idleFor.Seconds(),
+				Tags: map[string]string{
+					frames.MetaCallSID:  sess.CallSID,
+					frames.MetaStreamID: sess.Stream(),
+					frames.MetaTraceID:  sess.TraceID,
+				},
+			})
+		}
+		return true
+	})
+	return reaped
+}
+
+// Rebind moves the session for callSID to streamID after the transport
+// reconnected the call on a new media stream, and returns the stream it
+// left. ok is false when there is no session or it is already on streamID.
+// Processors migrate their own state when the call_reconnect frame reaches
+// them (see StreamMigrator).
+func (r *SessionRegistry) Rebind(callSID, streamID string) (oldStreamID string, ok bool) {
+	v, found := r.sessions.Load(callSID)
+	if !found || streamID == "" {
+		return "", false
+	}
+	sess := v.(*Session)
+	oldStreamID = sess.Stream()
+	if oldStreamID == streamID {
+		return oldStreamID, false
+	}
+	sess.stream.Store(&streamID)
+	n := sess.reconnects.Add(1)
+	sess.Touch()
+	slog.Info("stream_resumed", "call_sid", callSID, "old_stream_id", oldStreamID, "stream_id", streamID, "reconnects", n)
+	if r.obs != nil {
+		r.obs.RecordEvent(metrics.MetricsEvent{
+			Name:  metrics.EventStreamResumed,
+			Time:  time.Now(),
+			Value: float64(n),
+			Tags: map[string]string{
+				frames.MetaCallSID:     callSID,
+				frames.MetaStreamID:    streamID,
+				frames.MetaOldStreamID: oldStreamID,
+				frames.MetaTraceID:     sess.TraceID,
+			},
+		})
+	}
+	return oldStreamID, true
+}
+
+// RunReaper calls ReapIdle every interval until ctx is done.
+func (r *SessionRegistry) RunReaper(ctx context.Context, idle, interval time.Duration) {
+	if idle <= 0 {
+		return
+	}
+	if interval <= 0 {
+		interval = idle / 4
+		if interval < time.Second {
+			interval = time.Second
+		}
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.ReapIdle(idle)
 		}
-		r.count.Add(-1)
 	}
 }
 
@@ -96,6 +275,18 @@ func (r *SessionRegistry) CloseAll() {
 	})
 }
 
+// Sessions returns a snapshot of the live sessions.
+func (r *SessionRegistry) Sessions() []*Session {
+	var out []*Session
+	r.sessions.Range(func(_, value any) bool {
+		if sess, ok := value.(*Session); ok {
+			out = append(out, sess)
+		}
+		return true
+	})
+	return out
+}
+
 func (r *SessionRegistry) Count() int64 {
 	return r.count.Load()
 }
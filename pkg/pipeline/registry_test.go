@@ -0,0 +1,167 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/metrics"
+)
+
+type lifecycleProcessor struct {
+	mu      sync.Mutex
+	events  []string
+	endMeta map[string]string
+}
+
+func (p *lifecycleProcessor) Name() string { return "lifecycle" }
+
+func (p *lifecycleProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
+	return []frames.Frame{f}, nil
+}
+
+func (p *lifecycleProcessor) Start(context.Context) error {
+	p.record("start")
+	return nil
+}
+
+func (p *lifecycleProcessor) OnSessionEnd(meta map[string]string) {
+	p.mu.Lock()
+	p.endMeta = meta
+	p.mu.Unlock()
+	p.record("session_end")
+}
+
+func (p *lifecycleProcessor) MigrateStream(oldStreamID, newStreamID string) {
+	p.record("migrate " + oldStreamID + " " + newStreamID)
+}
+
+func (p *lifecycleProcessor) Close() error {
+	p.record("close")
+	return nil
+}
+
+func (p *lifecycleProcessor) record(ev string) {
+	p.mu.Lock()
+	p.events = append(p.events, ev)
+	p.mu.Unlock()
+}
+
+func newTestRegistry(proc *lifecycleProcessor) *SessionRegistry {
+	return NewSessionRegistry(func(ctx context.Context, callSID, streamID, traceID string) (Orchestrator, error) {
+		orch := New(Config{HighCapacity: 8, LowCapacity: 8, StageBuffer: 8, Async: true})
+		orch.SetContext(ctx)
+		_ = orch.AddProcessor(proc)
+		return orch, nil
+	})
+}
+
+func TestSessionEndRunsLifecycleHooks(t *testing.T) {
+	proc := &lifecycleProcessor{}
+	reg := newTestRegistry(proc)
+	var ended *Session
+	reg.SetOnEnd(func(sess *Session) { ended = sess })
+	if _, created, err := reg.GetOrCreate("call-1", "stream-1", "trace-1"); err != nil || !created {
+		t.Fatalf("expected session created, err=%v", err)
+	}
+	if !reg.End("call-1", map[string]string{frames.MetaCallEndReason: "hangup"}) {
+		t.Fatalf("expected session to be ended")
+	}
+	proc.mu.Lock()
+	defer proc.mu.Unlock()
+	want := []string{"start", "session_end", "close"}
+	if len(proc.events) != len(want) {
+		t.Fatalf("expected %v, got %v", want, proc.events)
+	}
+	for i := range want {
+		if proc.events[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, proc.events)
+		}
+	}
+	if proc.endMeta[frames.MetaStreamID] != "stream-1" || proc.endMeta[frames.MetaCallEndReason] != "hangup" {
+		t.Fatalf("unexpected session end meta: %v", proc.endMeta)
+	}
+	if reg.Count() != 0 {
+		t.Fatalf("expected empty registry, got %d", reg.Count())
+	}
+	if ended == nil || ended.StreamID != "stream-1" {
+		t.Fatalf("expected OnEnd hook with the ended session, got %+v", ended)
+	}
+}
+
+func TestReapIdleRemovesStaleSessions(t *testing.T) {
+	proc := &lifecycleProcessor{}
+	reg := newTestRegistry(proc)
+	obs := metrics.NewMemoryObserver()
+	reg.SetObserver(obs)
+	var reaped []string
+	reg.SetOnReap(func(sess *Session) {
+		if _, ok := reg.Get(sess.CallSID); ok {
+			reaped = append(reaped, sess.CallSID)
+		}
+	})
+	if _, _, err := reg.GetOrCreate("call-1", "stream-1", "trace-1"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if n := reg.ReapIdle(time.Minute); n != 0 {
+		t.Fatalf("expected fresh session to survive, reaped %d", n)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := reg.ReapIdle(10 * time.Millisecond); n != 1 {
+		t.Fatalf("expected 1 reaped session, got %d", n)
+	}
+	if reg.Count() != 0 {
+		t.Fatalf("expected empty registry, got %d", reg.Count())
+	}
+	if len(reaped) != 1 || reaped[0] != "call-1" {
+		t.Fatalf("expected OnReap before the session ended, got %v", reaped)
+	}
+	if len(obs.Events) != 1 || obs.Events[0].Name != metrics.EventSessionReaped {
+		t.Fatalf("expected session_reaped event, got %v", obs.Events)
+	}
+	if proc.endMeta[frames.MetaCallEndReason] != "idle_timeout" {
+		t.Fatalf("expected idle_timeout reason, got %v", proc.endMeta)
+	}
+}
+
+func TestRebindMigratesStreamState(t *testing.T) {
+	proc := &lifecycleProcessor{}
+	reg := newTestRegistry(proc)
+	obs := metrics.NewMemoryObserver()
+	reg.SetObserver(obs)
+	sess, _, err := reg.GetOrCreate("call-1", "stream-1", "trace-1")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	old, ok := reg.Rebind("call-1", "stream-2")
+	if !ok || old != "stream-1" || sess.Stream() != "stream-2" || sess.StreamID != "stream-1" || sess.Reconnects() != 1 {
+		t.Fatalf("unexpected rebind: old=%q ok=%v stream=%q reconnects=%d", old, ok, sess.Stream(), sess.Reconnects())
+	}
+	if _, ok := reg.Rebind("call-1", "stream-2"); ok {
+		t.Fatalf("expected rebind to the current stream to be a no-op")
+	}
+	if len(obs.Events) != 1 || obs.Events[0].Name != metrics.EventStreamResumed || obs.Events[0].Tags[frames.MetaOldStreamID] != "stream-1" {
+		t.Fatalf("expected stream_resumed event, got %v", obs.Events)
+	}
+
+	sess.Orch.In() <- frames.NewSystemFrame("stream-2", time.Now().UnixNano(), FrameCallReconnect, map[string]string{
+		frames.MetaStreamID:    "stream-2",
+		frames.MetaOldStreamID: "stream-1",
+	})
+	select {
+	case <-sess.Orch.Out():
+	case <-time.After(time.Second):
+		t.Fatalf("call_reconnect frame did not pass through")
+	}
+	reg.End("call-1", nil)
+	proc.mu.Lock()
+	defer proc.mu.Unlock()
+	if len(proc.events) < 2 || proc.events[1] != "migrate stream-1 stream-2" {
+		t.Fatalf("expected migration before session end, got %v", proc.events)
+	}
+	if proc.endMeta[frames.MetaStreamID] != "stream-2" {
+		t.Fatalf("expected session end on the new stream, got %v", proc.endMeta)
+	}
+}
@@ -1,6 +1,7 @@
 package priority
 
 import (
+	"context"
 	"sync/atomic"
 	"time"
 )
@@ -80,6 +81,31 @@ func (q *PriorityQueue) Pop() (any, bool) {
 	}
 }
 
+// PopContext is like Pop but returns false once ctx is done.
+func (q *PriorityQueue) PopContext(ctx context.Context) (any, bool) {
+	for {
+		select {
+		case f := <-q.high:
+			atomic.AddInt64(&q.highPop, 1)
+			return f, true
+		default:
+		}
+		if q.fairness > 0 {
+			select {
+			case f := <-q.low:
+				atomic.AddInt64(&q.lowPop, 1)
+				return f, true
+			default:
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
 func (q *PriorityQueue) Stats() Stats {
 	return Stats{
 		HighPush: atomic.LoadInt64(&q.highPush),
@@ -0,0 +1,594 @@
+package processors
+
+import (
+	"maps"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/audio"
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/metrics"
+	"github.com/harunnryd/ranya/pkg/pipeline"
+)
+
+// AMD verdicts and the system frames carrying them.
+const (
+	AMDHuman   = "human"
+	AMDMachine = "machine"
+
+	FrameHumanDetected   = "human_detected"
+	FrameMachineDetected = "machine_detected"
+	// FrameAMDResult carries a carrier's own detection result (for example
+	// Twilio async AMD) in MetaAnsweredBy.
+	FrameAMDResult = "amd_result"
+	// FrameVoicemailDrop speaks the voicemail message through the LLM
+	// greeting path.
+	FrameVoicemailDrop = "voicemail_drop"
+)
+
+// AMDConfig tunes answering machine detection. The timings follow the
+// classic energy-based heuristics (as in Asterisk's AMD()): a human answers
+// with a short greeting and waits, a machine plays a long one or stays
+// silent.
+type AMDConfig struct {
+	// InitialSilence without speech after answer means a machine.
+	InitialSilence time.Duration
+	// Greeting is the longest greeting a human gives before pausing.
+	Greeting time.Duration
+	// AfterGreetingSilence after a short greeting means a human.
+	AfterGreetingSilence time.Duration
+	// TotalAnalysis caps detection; undecided calls count as human so the
+	// agent is never silent on a live caller.
+	TotalAnalysis time.Duration
+	// MaxWords in the transcript before a verdict means a machine.
+	MaxWords int
+	// Phrases in the transcript that mean a machine (case-insensitive).
+	Phrases []string
+	// SilenceThreshold is the RMS level, on the PCM16 scale, below which a
+	// chunk counts as silence.
+	SilenceThreshold float64
+
+	// VoicemailMessage, when set, is spoken after the beep on machine
+	// answers before hanging up. VoicemailByLanguage overrides it per
+	// language.
+	VoicemailMessage    string
+	VoicemailByLanguage map[string]string
+	// BeepTimeout bounds the wait for the beep after a machine is detected.
+	BeepTimeout time.Duration
+	// MessageEndSilence after the greeting counts as its end for machines
+	// that never beep.
+	MessageEndSilence time.Duration
+}
+
+func (c AMDConfig) withDefaults() AMDConfig {
+	if c.InitialSilence <= 0 {
+		c.InitialSilence = 2500 * time.Millisecond
+	}
+	if c.Greeting <= 0 {
+		c.Greeting = 1500 * time.Millisecond
+	}
+	if c.AfterGreetingSilence <= 0 {
+		c.AfterGreetingSilence = 800 * time.Millisecond
+	}
+	if c.TotalAnalysis <= 0 {
+		c.TotalAnalysis = 5 * time.Second
+	}
+	if c.MaxWords <= 0 {
+		c.MaxWords = 6
+	}
+	if c.Phrases == nil {
+		c.Phrases = []string{"leave a message", "leave your message", "not available", "after the tone", "after the beep", "voicemail", "voice mail", "mailbox"}
+	}
+	if c.SilenceThreshold <= 0 {
+		c.SilenceThreshold = 400
+	}
+	if c.BeepTimeout <= 0 {
+		c.BeepTimeout = 30 * time.Second
+	}
+	if c.MessageEndSilence <= 0 {
+		c.MessageEndSilence = 2500 * time.Millisecond
+	}
+	return c
+}
+
+// Beep detection: a single tone in the usual voicemail range carrying most
+// of a chunk's energy for at least beepMin.
+const (
+	beepMinFreq  = 400
+	beepMaxFreq  = 2000
+	beepStep     = 25
+	beepPurity   = 0.6
+	beepMin      = 120 * time.Millisecond
+	beepMaxDrift = 50
+)
+
+// AMDProcessor classifies answered outbound calls as human or machine from
+// inbound audio, transcripts (via Gate) and carrier results, then emits a
+// human_detected or machine_detected system frame. Only calls whose
+// call_start carries call_direction "outbound" are screened; a silent
+// inbound caller must not be taken for a machine. Greetings are held until
+// a human is detected. With a voicemail message configured it waits for the
+// beep, speaks the message and requests a hangup.
+//
+// It belongs in the acoustic stage, ahead of STT; Gate goes after STT.
+type AMDProcessor struct {
+	cfg AMDConfig
+	obs metrics.Observer
+
+	mu      sync.Mutex
+	streams map[string]*amdStream
+}
+
+type amdStream struct {
+	meta     map[string]string
+	language string
+	// screen is set by an outbound call_start; other calls pass untouched.
+	screen bool
+
+	elapsed     time.Duration
+	heardSpeech bool
+	speechStart time.Duration
+	silence     time.Duration
+
+	toneRun  time.Duration
+	toneFreq float64
+	beepDone bool
+
+	verdict   string
+	machineAt time.Duration
+	dropped   bool
+	// held are greeting frames waiting for a verdict.
+	held []frames.Frame
+	// words counts transcript words seen before the verdict.
+	words int
+	// announced is set once the verdict frame has been emitted; Gate may
+	// decide first and the acoustic stage emits nothing further.
+	announced bool
+}
+
+func NewAMDProcessor(cfg AMDConfig) *AMDProcessor {
+	return &AMDProcessor{
+		cfg:     cfg.withDefaults(),
+		streams: make(map[string]*amdStream),
+	}
+}
+
+func (p *AMDProcessor) Name() string { return "amd" }
+
+func (p *AMDProcessor) SetObserver(obs metrics.Observer) { p.obs = obs }
+
+func (p *AMDProcessor) stream(streamID string, meta map[string]string) *amdStream {
+	st := p.streams[streamID]
+	if st == nil {
+		st = &amdStream{meta: make(map[string]string)}
+		p.streams[streamID] = st
+	}
+	for _, k := range []string{frames.MetaStreamID, frames.MetaCallSID, frames.MetaTraceID} {
+		if v := meta[k]; v != "" {
+			st.meta[k] = v
+		}
+	}
+	if lang := strings.ToLower(strings.TrimSpace(meta[frames.MetaLanguage])); lang != "" {
+		st.language = lang
+	} else if lang := strings.ToLower(strings.TrimSpace(meta[frames.MetaGlobalLanguage])); lang != "" && st.language == "" {
+		st.language = lang
+	}
+	return st
+}
+
+func (p *AMDProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
+	streamID := frames.StreamIDOf(f)
+	if streamID == "" {
+		return []frames.Frame{f}, nil
+	}
+	switch f.Kind() {
+	case frames.KindSystem:
+		sf := f.(frames.SystemFrame)
+		meta := sf.Meta()
+		switch sf.Name() {
+		case "call_end":
+			p.OnSessionEnd(meta)
+			return []frames.Frame{f}, nil
+		case "call_start":
+			p.mu.Lock()
+			p.stream(streamID, meta).screen = meta[frames.MetaCallDirection] == "outbound"
+			p.mu.Unlock()
+			return []frames.Frame{f}, nil
+		case FrameAMDResult:
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			return append(p.carrierResult(p.stream(streamID, meta), meta[frames.MetaAnsweredBy]), f), nil
+		}
+		if meta[frames.MetaGreetingText] == "" || sf.Name() == FrameVoicemailDrop {
+			return []frames.Frame{f}, nil
+		}
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		st := p.stream(streamID, meta)
+		if !st.screen {
+			return []frames.Frame{f}, nil
+		}
+		switch st.verdict {
+		case "":
+			st.held = append(st.held, f)
+			return nil, nil
+		case AMDMachine:
+			// Never greet an answering machine.
+			return nil, nil
+		}
+		return []frames.Frame{f}, nil
+	case frames.KindAudio:
+		af := f.(frames.AudioFrame)
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		st := p.stream(streamID, af.Meta())
+		return append(p.analyze(st, af), f), nil
+	}
+	return []frames.Frame{f}, nil
+}
+
+// analyze advances the stream's detection state by one audio chunk.
+func (p *AMDProcessor) analyze(st *amdStream, af frames.AudioFrame) []frames.Frame {
+	if !st.screen || st.dropped || (st.verdict == AMDHuman && st.announced) {
+		return nil
+	}
+	pcm, ok := audio.DecodeFrame(af)
+	if !ok || len(pcm) == 0 {
+		return nil
+	}
+	rate := af.Rate()
+	if rate <= 0 {
+		rate = 8000
+	}
+	d := time.Duration(len(pcm)) * time.Second / time.Duration(rate)
+	st.elapsed += d
+
+	level := rms(pcm)
+	tone, freq := 0.0, 0.0
+	if level >= p.cfg.SilenceThreshold {
+		tone, freq = dominantTone(pcm, rate)
+	}
+	beepEnded := false
+	if tone >= beepPurity && (st.toneRun == 0 || math.Abs(freq-st.toneFreq) <= beepMaxDrift) {
+		st.toneRun += d
+		st.toneFreq = freq
+	} else {
+		beepEnded = st.toneRun >= beepMin
+		st.toneRun = 0
+	}
+	if beepEnded {
+		st.beepDone = true
+	}
+	inTone := st.toneRun > 0
+	if level >= p.cfg.SilenceThreshold && !inTone {
+		if !st.heardSpeech {
+			st.heardSpeech = true
+			st.speechStart = st.elapsed - d
+		}
+		st.silence = 0
+	} else {
+		st.silence += d
+	}
+
+	var out []frames.Frame
+	if st.verdict == "" {
+		switch {
+		case st.toneRun >= beepMin || st.beepDone:
+			out = p.decide(st, AMDMachine, "beep")
+		case !st.heardSpeech && st.elapsed >= p.cfg.InitialSilence:
+			out = p.decide(st, AMDMachine, "initial_silence")
+		case st.heardSpeech && st.elapsed-st.silence-st.speechStart > p.cfg.Greeting:
+			out = p.decide(st, AMDMachine, "long_greeting")
+		case st.heardSpeech && st.silence >= p.cfg.AfterGreetingSilence:
+			out = p.decide(st, AMDHuman, "short_greeting")
+		case st.elapsed >= p.cfg.TotalAnalysis:
+			out = p.decide(st, AMDHuman, "analysis_timeout")
+		}
+	} else if !st.announced {
+		out = p.announce(st)
+	}
+	if st.verdict != AMDMachine || st.dropped || p.message(st) == "" {
+		return out
+	}
+	switch {
+	case st.beepDone:
+		out = append(out, p.drop(st, "beep")...)
+	case st.heardSpeech && st.silence >= p.cfg.MessageEndSilence && st.elapsed-st.machineAt >= p.cfg.MessageEndSilence:
+		out = append(out, p.drop(st, "silence")...)
+	case st.elapsed-st.machineAt >= p.cfg.BeepTimeout:
+		out = append(out, p.drop(st, "beep_timeout")...)
+	}
+	return out
+}
+
+// carrierResult applies a carrier AMD result such as Twilio's AnsweredBy
+// (human, machine_start, machine_end_beep, machine_end_silence,
+// machine_end_other, fax, unknown).
+func (p *AMDProcessor) carrierResult(st *amdStream, answeredBy string) []frames.Frame {
+	answeredBy = strings.ToLower(strings.TrimSpace(answeredBy))
+	var out []frames.Frame
+	switch {
+	case answeredBy == "human":
+		if st.verdict == "" {
+			out = p.decide(st, AMDHuman, "carrier")
+		}
+	case strings.HasPrefix(answeredBy, "machine") || answeredBy == "fax":
+		if st.verdict == "" {
+			out = p.decide(st, AMDMachine, "carrier")
+		}
+		if strings.HasPrefix(answeredBy, "machine_end") && st.verdict == AMDMachine && !st.dropped && p.message(st) != "" {
+			out = append(out, p.drop(st, answeredBy)...)
+		}
+	}
+	return out
+}
+
+// decide records a verdict and returns the frames announcing it.
+func (p *AMDProcessor) decide(st *amdStream, verdict, reason string) []frames.Frame {
+	st.verdict = verdict
+	st.machineAt = st.elapsed
+	st.meta[frames.MetaAMDReason] = reason
+	return p.announce(st)
+}
+
+func (p *AMDProcessor) announce(st *amdStream) []frames.Frame {
+	st.announced = true
+	meta := maps.Clone(st.meta)
+	meta[frames.MetaAnsweredBy] = st.verdict
+	name := FrameHumanDetected
+	if st.verdict == AMDMachine {
+		name = FrameMachineDetected
+	}
+	if p.obs != nil {
+		p.obs.RecordEvent(metrics.MetricsEvent{
+			Name: "amd_result",
+			Time: time.Now(),
+			Tags: map[string]string{
+				frames.MetaStreamID: meta[frames.MetaStreamID],
+				frames.MetaCallSID:  meta[frames.MetaCallSID],
+				frames.MetaTraceID:  meta[frames.MetaTraceID],
+				"result":            st.verdict,
+				"reason":            meta[frames.MetaAMDReason],
+			},
+			Fields: map[string]any{"elapsed_ms": st.elapsed.Milliseconds()},
+		})
+	}
+	out := []frames.Frame{frames.NewSystemFrame(meta[frames.MetaStreamID], time.Now().UnixNano(), name, meta)}
+	if st.verdict == AMDHuman {
+		out = append(out, st.held...)
+	}
+	st.held = nil
+	return out
+}
+
+// drop speaks the voicemail message and asks the engine to hang up once it
+// has played.
+func (p *AMDProcessor) drop(st *amdStream, reason string) []frames.Frame {
+	st.dropped = true
+	streamID := st.meta[frames.MetaStreamID]
+	meta := maps.Clone(st.meta)
+	meta[frames.MetaGreetingText] = p.message(st)
+	meta[frames.MetaReason] = reason
+	if st.language != "" {
+		meta[frames.MetaLanguage] = st.language
+	}
+	hangup := maps.Clone(st.meta)
+	hangup[frames.MetaReason] = "voicemail"
+	return []frames.Frame{
+		frames.NewSystemFrame(streamID, time.Now().UnixNano(), FrameVoicemailDrop, meta),
+		frames.NewControlFrame(streamID, time.Now().UnixNano(), frames.ControlHangup, hangup),
+	}
+}
+
+func (p *AMDProcessor) message(st *amdStream) string {
+	if msg := p.cfg.VoicemailByLanguage[st.language]; msg != "" {
+		return msg
+	}
+	return p.cfg.VoicemailMessage
+}
+
+// transcript counts words of a final transcript seen before a verdict and
+// reports a machine verdict if it looks like a voicemail greeting.
+func (p *AMDProcessor) transcript(streamID, text string, meta map[string]string) []frames.Frame {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := p.stream(streamID, meta)
+	if st.verdict != "" {
+		return nil
+	}
+	st.words += len(strings.Fields(text))
+	lower := strings.ToLower(text)
+	for _, phrase := range p.cfg.Phrases {
+		if phrase != "" && strings.Contains(lower, strings.ToLower(phrase)) {
+			return p.decide(st, AMDMachine, "phrase")
+		}
+	}
+	if st.words > p.cfg.MaxWords {
+		return p.decide(st, AMDMachine, "word_count")
+	}
+	return nil
+}
+
+// screening reports whether the stream's call is being screened.
+func (p *AMDProcessor) screening(streamID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := p.streams[streamID]
+	return st != nil && st.screen
+}
+
+// OnSessionEnd implements pipeline.SessionEndHandler.
+func (p *AMDProcessor) OnSessionEnd(meta map[string]string) {
+	streamID := meta[frames.MetaStreamID]
+	if streamID == "" {
+		return
+	}
+	p.mu.Lock()
+	delete(p.streams, streamID)
+	p.mu.Unlock()
+}
+
+// MigrateStream implements pipeline.StreamMigrator.
+func (p *AMDProcessor) MigrateStream(oldStreamID, newStreamID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	moveKey(p.streams, oldStreamID, newStreamID)
+	if st := p.streams[newStreamID]; st != nil {
+		st.meta[frames.MetaStreamID] = newStreamID
+	}
+}
+
+var _ pipeline.SessionEndHandler = (*AMDProcessor)(nil)
+var _ pipeline.StreamMigrator = (*AMDProcessor)(nil)
+
+// Gate returns the companion stage placed after STT. It holds caller
+// transcripts until a verdict so the agent does not answer a voicemail
+// greeting, feeds them to the word and phrase heuristics, releases them on
+// human_detected and drops them for machines.
+func (p *AMDProcessor) Gate() pipeline.FrameProcessor {
+	return &amdGate{amd: p, held: make(map[string][]frames.Frame), verdict: make(map[string]string)}
+}
+
+type amdGate struct {
+	amd     *AMDProcessor
+	mu      sync.Mutex
+	held    map[string][]frames.Frame
+	verdict map[string]string
+}
+
+func (g *amdGate) Name() string { return "amd_gate" }
+
+func (g *amdGate) Process(f frames.Frame) ([]frames.Frame, error) {
+	streamID := frames.StreamIDOf(f)
+	if streamID == "" {
+		return []frames.Frame{f}, nil
+	}
+	switch f.Kind() {
+	case frames.KindSystem:
+		sf := f.(frames.SystemFrame)
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		switch sf.Name() {
+		case FrameHumanDetected:
+			held := g.held[streamID]
+			delete(g.held, streamID)
+			g.verdict[streamID] = AMDHuman
+			return append([]frames.Frame{f}, held...), nil
+		case FrameMachineDetected:
+			delete(g.held, streamID)
+			g.verdict[streamID] = AMDMachine
+		case "call_end":
+			delete(g.held, streamID)
+			delete(g.verdict, streamID)
+		}
+		return []frames.Frame{f}, nil
+	case frames.KindControl:
+		// Noise on a machine must not barge in on the voicemail message.
+		cf := f.(frames.ControlFrame)
+		if source := cf.Metadata().Get(frames.MetaSource); source != "stt" && source != "vad" {
+			return []frames.Frame{f}, nil
+		}
+		g.mu.Lock()
+		machine := g.verdict[streamID] == AMDMachine
+		g.mu.Unlock()
+		if machine {
+			return nil, nil
+		}
+		return []frames.Frame{f}, nil
+	case frames.KindText:
+		tf := f.(frames.TextFrame)
+		meta := tf.Meta()
+		if meta[frames.MetaSource] != "stt" {
+			return []frames.Frame{f}, nil
+		}
+		g.mu.Lock()
+		verdict := g.verdict[streamID]
+		g.mu.Unlock()
+		switch verdict {
+		case AMDHuman:
+			return []frames.Frame{f}, nil
+		case AMDMachine:
+			return nil, nil
+		}
+		if !g.amd.screening(streamID) {
+			return []frames.Frame{f}, nil
+		}
+		if !isFinalText(tf) {
+			// Interim text before a verdict is stale by the time it could
+			// be released.
+			return nil, nil
+		}
+		if out := g.amd.transcript(streamID, tf.Text(), meta); len(out) > 0 {
+			g.mu.Lock()
+			delete(g.held, streamID)
+			g.verdict[streamID] = AMDMachine
+			g.mu.Unlock()
+			return out, nil
+		}
+		g.mu.Lock()
+		g.held[streamID] = append(g.held[streamID], f)
+		g.mu.Unlock()
+		return nil, nil
+	}
+	return []frames.Frame{f}, nil
+}
+
+// OnSessionEnd implements pipeline.SessionEndHandler.
+func (g *amdGate) OnSessionEnd(meta map[string]string) {
+	streamID := meta[frames.MetaStreamID]
+	if streamID == "" {
+		return
+	}
+	g.mu.Lock()
+	delete(g.held, streamID)
+	delete(g.verdict, streamID)
+	g.mu.Unlock()
+}
+
+// MigrateStream implements pipeline.StreamMigrator.
+func (g *amdGate) MigrateStream(oldStreamID, newStreamID string) {
+	g.mu.Lock()
+	moveKey(g.held, oldStreamID, newStreamID)
+	moveKey(g.verdict, oldStreamID, newStreamID)
+	g.mu.Unlock()
+}
+
+func rms(pcm []int16) float64 {
+	var sum float64
+	for _, s := range pcm {
+		sum += float64(s) * float64(s)
+	}
+	return math.Sqrt(sum / float64(len(pcm)))
+}
+
+// dominantTone scans the beep range with the Goertzel algorithm and returns
+// the share of the chunk's energy in the strongest frequency (about 1 for a
+// pure tone) and that frequency.
+func dominantTone(pcm []int16, rate int) (purity, freq float64) {
+	var energy float64
+	for _, s := range pcm {
+		energy += float64(s) * float64(s)
+	}
+	if energy == 0 {
+		return 0, 0
+	}
+	n := float64(len(pcm))
+	best := 0.0
+	for f := float64(beepMinFreq); f <= beepMaxFreq; f += beepStep {
+		coeff := 2 * math.Cos(2*math.Pi*f/float64(rate))
+		var s1, s2 float64
+		for _, v := range pcm {
+			s := float64(v) + coeff*s1 - s2
+			s2, s1 = s1, s
+		}
+		power := s1*s1 + s2*s2 - coeff*s1*s2
+		if power > best {
+			best, freq = power, f
+		}
+	}
+	// A full-scale sine of N samples has Goertzel power (A*N/2)^2 and
+	// energy A^2*N/2, so this ratio is 1 for a tone on a scanned frequency.
+	return best / (energy * n / 2), freq
+}
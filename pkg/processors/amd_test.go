@@ -0,0 +1,166 @@
+package processors
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+)
+
+var amdMeta = map[string]string{frames.MetaStreamID: "s1", frames.MetaCallSID: "c1", frames.MetaEncoding: "pcm16"}
+
+// amdChunks renders ms of 8kHz pcm16 as 20ms frames; gen returns sample i.
+func amdChunks(ms int, gen func(i int) float64) []frames.Frame {
+	var out []frames.Frame
+	for c := 0; c < ms/20; c++ {
+		buf := make([]byte, 320)
+		for i := 0; i < 160; i++ {
+			binary.LittleEndian.PutUint16(buf[2*i:], uint16(int16(gen(c*160+i))))
+		}
+		out = append(out, frames.NewAudioFrame("s1", 0, buf, 8000, 1, amdMeta))
+	}
+	return out
+}
+
+func speech(ms int) []frames.Frame {
+	seed := uint32(1)
+	return amdChunks(ms, func(int) float64 {
+		seed = seed*1664525 + 1013904223
+		return float64(int32(seed)>>19) * 0.8
+	})
+}
+
+func silence(ms int) []frames.Frame { return amdChunks(ms, func(int) float64 { return 0 }) }
+
+func beep(ms int) []frames.Frame {
+	return amdChunks(ms, func(i int) float64 { return 8000 * math.Sin(2*math.Pi*1000*float64(i)/8000) })
+}
+
+func runAMD(t *testing.T, p interface {
+	Process(frames.Frame) ([]frames.Frame, error)
+}, in ...[]frames.Frame) []frames.Frame {
+	t.Helper()
+	var out []frames.Frame
+	for _, list := range in {
+		for _, f := range list {
+			res, err := p.Process(f)
+			if err != nil {
+				t.Fatalf("process: %v", err)
+			}
+			for _, r := range res {
+				if r.Kind() != frames.KindAudio {
+					out = append(out, r)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func systemNames(fs []frames.Frame) []string {
+	var names []string
+	for _, f := range fs {
+		switch f.Kind() {
+		case frames.KindSystem:
+			names = append(names, f.(frames.SystemFrame).Name())
+		case frames.KindControl:
+			names = append(names, string(f.(frames.ControlFrame).Code()))
+		case frames.KindText:
+			names = append(names, "text")
+		}
+	}
+	return names
+}
+
+func greetingFrame() frames.Frame {
+	return frames.NewSystemFrame("s1", 0, "greeting", map[string]string{frames.MetaStreamID: "s1", frames.MetaGreetingText: "Hi, this is Ranya"})
+}
+
+// callStart runs a call_start with the given direction through p.
+func callStart(t *testing.T, p *AMDProcessor, direction string) {
+	t.Helper()
+	runAMD(t, p, []frames.Frame{frames.NewSystemFrame("s1", 0, "call_start", map[string]string{frames.MetaStreamID: "s1", frames.MetaCallSID: "c1", frames.MetaCallDirection: direction})})
+}
+
+func TestAMDHumanReleasesGreeting(t *testing.T) {
+	p := NewAMDProcessor(AMDConfig{})
+	callStart(t, p, "outbound")
+	out := runAMD(t, p, []frames.Frame{greetingFrame()}, speech(600), silence(800))
+	got := systemNames(out)
+	if len(got) != 2 || got[0] != FrameHumanDetected || got[1] != "greeting" {
+		t.Fatalf("unexpected frames %v", got)
+	}
+	if r := out[0].(frames.SystemFrame).Meta()[frames.MetaAMDReason]; r != "short_greeting" {
+		t.Fatalf("unexpected reason %q", r)
+	}
+}
+
+func TestAMDMachineDropsVoicemailAfterBeep(t *testing.T) {
+	p := NewAMDProcessor(AMDConfig{VoicemailMessage: "Please call us back.", VoicemailByLanguage: map[string]string{"id": "Mohon hubungi kami."}})
+	callStart(t, p, "outbound")
+	out := runAMD(t, p, []frames.Frame{greetingFrame()}, speech(2000))
+	if got := systemNames(out); len(got) != 1 || got[0] != FrameMachineDetected {
+		t.Fatalf("expected machine verdict only, got %v", got)
+	}
+	// More greeting, then the beep; the message follows once it ends.
+	out = runAMD(t, p, speech(1000), beep(300))
+	if len(out) != 0 {
+		t.Fatalf("dropped before the beep ended: %v", systemNames(out))
+	}
+	out = runAMD(t, p, silence(40))
+	if got := systemNames(out); len(got) != 2 || got[0] != FrameVoicemailDrop || got[1] != string(frames.ControlHangup) {
+		t.Fatalf("unexpected drop frames %v", got)
+	}
+	meta := out[0].(frames.SystemFrame).Meta()
+	if meta[frames.MetaGreetingText] != "Please call us back." || meta[frames.MetaReason] != "beep" || meta[frames.MetaCallSID] != "c1" {
+		t.Fatalf("unexpected drop meta %v", meta)
+	}
+	if out := runAMD(t, p, beep(300), silence(3000)); len(out) != 0 {
+		t.Fatalf("expected a single drop, got %v", systemNames(out))
+	}
+}
+
+func TestAMDCarrierResultAndGate(t *testing.T) {
+	p := NewAMDProcessor(AMDConfig{VoicemailMessage: "Bye."})
+	callStart(t, p, "outbound")
+	gate := p.Gate()
+	// A short caller transcript is held until the verdict.
+	hello := frames.NewTextFrame("s1", 0, "hello?", map[string]string{frames.MetaStreamID: "s1", frames.MetaSource: "stt", frames.MetaIsFinal: "true"})
+	if out := runAMD(t, gate, []frames.Frame{hello}); len(out) != 0 {
+		t.Fatalf("expected transcript to be held, got %v", systemNames(out))
+	}
+	human := runAMD(t, p, []frames.Frame{frames.NewSystemFrame("s1", 0, FrameAMDResult, map[string]string{frames.MetaStreamID: "s1", frames.MetaAnsweredBy: "human"})})
+	if got := systemNames(runAMD(t, gate, human)); len(got) != 3 || got[0] != FrameHumanDetected || got[1] != "text" || got[2] != FrameAMDResult {
+		t.Fatalf("unexpected gate output %v", got)
+	}
+
+	// On another call the transcript itself gives the machine away, and
+	// Twilio's machine_end_beep triggers the drop.
+	p = NewAMDProcessor(AMDConfig{VoicemailMessage: "Bye."})
+	callStart(t, p, "outbound")
+	gate = p.Gate()
+	vm := frames.NewTextFrame("s1", 0, "Sorry I missed you, leave a message", map[string]string{frames.MetaStreamID: "s1", frames.MetaSource: "stt", frames.MetaIsFinal: "true"})
+	if got := systemNames(runAMD(t, gate, []frames.Frame{vm})); len(got) != 1 || got[0] != FrameMachineDetected {
+		t.Fatalf("expected machine verdict from transcript, got %v", got)
+	}
+	end := frames.NewSystemFrame("s1", 0, FrameAMDResult, map[string]string{frames.MetaStreamID: "s1", frames.MetaAnsweredBy: "machine_end_beep"})
+	if got := systemNames(runAMD(t, p, []frames.Frame{greetingFrame(), end})); len(got) != 3 || got[0] != FrameVoicemailDrop || got[1] != string(frames.ControlHangup) {
+		t.Fatalf("unexpected carrier drop %v", got)
+	}
+}
+
+func TestAMDSkipsInboundCalls(t *testing.T) {
+	p := NewAMDProcessor(AMDConfig{VoicemailMessage: "Bye."})
+	gate := p.Gate()
+	callStart(t, p, "inbound")
+	// A silent inbound caller is greeted at once and never screened.
+	out := runAMD(t, p, []frames.Frame{greetingFrame()}, silence(4000))
+	if got := systemNames(out); len(got) != 1 || got[0] != "greeting" {
+		t.Fatalf("expected only the greeting, got %v", got)
+	}
+	vm := frames.NewTextFrame("s1", 0, "please leave a message for my friend", map[string]string{frames.MetaStreamID: "s1", frames.MetaSource: "stt", frames.MetaIsFinal: "true"})
+	if got := systemNames(runAMD(t, gate, []frames.Frame{vm})); len(got) != 1 || got[0] != "text" {
+		t.Fatalf("expected inbound transcript to pass, got %v", got)
+	}
+}
@@ -111,7 +111,7 @@ func (p *ContextProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 					out = append(out, *sys)
 				}
 				// Process through aggregator
-				agg := p.aggFor(tf.Meta()[frames.MetaStreamID])
+				agg := p.aggFor(tf.Metadata().StreamID())
 				r, err := agg.Process(tf)
 				if err != nil {
 					return out, err
@@ -123,12 +123,12 @@ func (p *ContextProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 
 	if f.Kind() == frames.KindSystem {
 		sf := f.(frames.SystemFrame)
-		p.updateGlobal(sf.Meta())
+		meta := sf.Meta()
+		p.updateGlobal(meta)
 		if sf.Name() == "call_end" {
-			p.clearScope(sf.Meta())
-			p.clearAgg(sf.Meta()[frames.MetaStreamID])
+			p.OnSessionEnd(meta)
 		}
-		if sys := p.buildBasePrompt(sf.Meta()); sys != nil {
+		if sys := p.buildBasePrompt(meta); sys != nil {
 			return append(out, *sys, f), nil
 		}
 		if sf.Name() == "call_start" {
@@ -152,7 +152,7 @@ func (p *ContextProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 				if sys := p.buildGlobalMessage(tf.Meta()); sys != nil {
 					out = append(out, *sys)
 				}
-				agg := p.aggFor(tf.Meta()[frames.MetaStreamID])
+				agg := p.aggFor(tf.Metadata().StreamID())
 				r, err := agg.Process(tf)
 				if err != nil {
 					return out, err
@@ -169,7 +169,7 @@ func (p *ContextProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 				p.buffer.Flush()
 			} else {
 				// Direct flush behavior
-				agg := p.aggFor(cf.Meta()[frames.MetaStreamID])
+				agg := p.aggFor(cf.Metadata().StreamID())
 				if tf := agg.FlushFrame(); tf != nil {
 					if sys := p.buildBasePrompt(tf.Meta()); sys != nil {
 						out = append(out, *sys)
@@ -190,10 +190,10 @@ func (p *ContextProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 
 		// If using speculative buffer, update it instead of using aggregator directly
 		if p.buffer != nil {
-			if streamID := tf.Meta()[frames.MetaStreamID]; streamID != "" {
+			if streamID := tf.Metadata().StreamID(); streamID != "" {
 				p.buffer.SetStreamID(streamID)
 			}
-			isFinal := isFinal(tf.Meta())
+			isFinal := isFinalText(tf)
 			p.buffer.AddTranscript(tf.Text(), isFinal)
 
 			// For interim results, don't emit anything
@@ -207,7 +207,7 @@ func (p *ContextProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 		}
 
 		// Direct behavior: only process final transcripts
-		if !isFinal(tf.Meta()) {
+		if !isFinalText(tf) {
 			return out, nil
 		}
 
@@ -217,7 +217,7 @@ func (p *ContextProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 		if sys := p.buildGlobalMessage(tf.Meta()); sys != nil {
 			out = append(out, *sys)
 		}
-		agg := p.aggFor(tf.Meta()[frames.MetaStreamID])
+		agg := p.aggFor(tf.Metadata().StreamID())
 		r, err := agg.Process(tf)
 		if err != nil {
 			return out, err
@@ -253,7 +253,7 @@ func (p *ContextProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 	}
 
 	// For other frame types, pass through aggregator
-	streamID := f.Meta()[frames.MetaStreamID]
+	streamID := frames.StreamIDOf(f)
 	if streamID == "" {
 		return append(out, f), nil
 	}
@@ -271,6 +271,14 @@ func isFinal(meta map[string]string) bool {
 	return v == "true" || v == "1" || v == "yes"
 }
 
+// isFinalText prefers the typed transcript over string metadata.
+func isFinalText(tf frames.TextFrame) bool {
+	if tr, ok := tf.Transcript(); ok {
+		return tr.Final
+	}
+	return isFinal(tf.Meta())
+}
+
 func (p *ContextProcessor) aggFor(streamID string) *aggregators.TextAggregator {
 	if streamID == "" {
 		streamID = "default"
@@ -294,7 +302,26 @@ func (p *ContextProcessor) clearAgg(streamID string) {
 	p.mu.Unlock()
 }
 
+// OnSessionEnd implements pipeline.SessionEndHandler.
+func (p *ContextProcessor) OnSessionEnd(meta map[string]string) {
+	p.clearScope(meta)
+	p.clearAgg(meta[frames.MetaStreamID])
+}
+
+// MigrateStream implements pipeline.StreamMigrator.
+func (p *ContextProcessor) MigrateStream(oldStreamID, newStreamID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	moveKey(p.aggs, oldStreamID, newStreamID)
+	// Scopes are call SIDs when known; stream-scoped calls move too.
+	moveKey(p.injected, oldStreamID, newStreamID)
+	moveKey(p.global, oldStreamID, newStreamID)
+	moveKey(p.globalHash, oldStreamID, newStreamID)
+}
+
 var _ pipeline.FrameProcessor = (*ContextProcessor)(nil)
+var _ pipeline.SessionEndHandler = (*ContextProcessor)(nil)
+var _ pipeline.StreamMigrator = (*ContextProcessor)(nil)
 
 func (p *ContextProcessor) buildBasePrompt(meta map[string]string) *frames.SystemFrame {
 	if p.basePrompt == "" {
@@ -319,6 +346,8 @@ func (p *ContextProcessor) updateGlobal(meta map[string]string) {
 	if scope == "" {
 		return
 	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	g := p.global[scope]
 	if g == nil {
 		g = make(map[string]string)
@@ -334,6 +363,19 @@ func (p *ContextProcessor) updateGlobal(meta map[string]string) {
 	}
 }
 
+// Globals returns a copy of the shared context of callSID, or of streamID
+// when the call SID is unknown.
+func (p *ContextProcessor) Globals(callSID, streamID string) map[string]string {
+	scope := p.scopeKey(map[string]string{frames.MetaCallSID: callSID, frames.MetaStreamID: streamID})
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make(map[string]string, len(p.global[scope]))
+	for k, v := range p.global[scope] {
+		out[k] = v
+	}
+	return out
+}
+
 func (p *ContextProcessor) buildGlobalMessage(meta map[string]string) *frames.SystemFrame {
 	streamID := meta[frames.MetaStreamID]
 	scope := p.scopeKey(meta)
@@ -15,17 +15,91 @@ type DTMFDisambiguatorConfig struct {
 	PreferDTMF  bool
 	MarkOnly    bool
 	MetaKeyFlag string
+	// DigitWords maps a language code (e.g. "en", "id") to its spoken digit
+	// words. Entries replace DefaultDigitWords for that language.
+	DigitWords map[string][]string
 }
 
 // DTMFDisambiguator drops or marks spoken digit-only text when DTMF was received recently.
 type DTMFDisambiguator struct {
 	cfg    DTMFDisambiguatorConfig
+	words  digitWordSets
 	mu     sync.Mutex
 	lastDT map[string]time.Time
 }
 
 var digitOnly = regexp.MustCompile(`^[0-9]+$`)
 
+// DefaultDigitWords are the spoken digit words recognized per language.
+var DefaultDigitWords = map[string][]string{
+	"en": {"zero", "oh", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
+	"id": {"nol", "kosong", "satu", "dua", "tiga", "empat", "lima", "enam", "tujuh", "delapan", "sembilan"},
+}
+
+// digitWordSets indexes word lists by language for spokenDigits.
+type digitWordSets map[string]map[string]bool
+
+func newDigitWordSets(overrides map[string][]string) digitWordSets {
+	sets := make(digitWordSets)
+	for _, table := range []map[string][]string{DefaultDigitWords, overrides} {
+		for lang, words := range table {
+			set := make(map[string]bool, len(words))
+			for _, w := range words {
+				set[strings.ToLower(strings.TrimSpace(w))] = true
+			}
+			sets[strings.ToLower(lang)] = set
+		}
+	}
+	return sets
+}
+
+var defaultDigitWordSets = newDigitWordSets(nil)
+
+// match reports whether word is a digit word in lang. Without a known
+// language every table is consulted.
+func (s digitWordSets) match(lang, word string) bool {
+	lang = strings.ToLower(lang)
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		lang = lang[:i]
+	}
+	if set, ok := s[lang]; ok {
+		return set[word]
+	}
+	for _, set := range s {
+		if set[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// spokenDigits reports whether tf is only digits. Typed transcripts are checked
+// word by word against the digit words of the frame's language, so "one two
+// three" matches as well as "123". "Oh" counts as zero only next to another
+// digit, so a bare "oh" stays an interjection.
+func spokenDigits(tf frames.TextFrame, words digitWordSets) bool {
+	if tr, ok := tf.Transcript(); ok && len(tr.Words) > 0 {
+		meta := tf.Meta()
+		lang := meta[frames.MetaLanguage]
+		if lang == "" {
+			lang = meta[frames.MetaGlobalLanguage]
+		}
+		digits := false
+		for _, w := range tr.Words {
+			word := strings.ToLower(strings.TrimSpace(w.Text))
+			if !digitOnly.MatchString(word) && !words.match(lang, word) {
+				return false
+			}
+			if word != "oh" {
+				digits = true
+			}
+		}
+		return digits
+	}
+	text := strings.TrimSpace(tf.Text())
+	return text != "" && digitOnly.MatchString(text)
+}
+
 func NewDTMFDisambiguator(cfg DTMFDisambiguatorConfig) *DTMFDisambiguator {
 	if cfg.Window <= 0 {
 		cfg.Window = 2 * time.Second
@@ -33,8 +107,13 @@ func NewDTMFDisambiguator(cfg DTMFDisambiguatorConfig) *DTMFDisambiguator {
 	if cfg.MetaKeyFlag == "" {
 		cfg.MetaKeyFlag = frames.MetaDTMFPriority
 	}
+	words := defaultDigitWordSets
+	if len(cfg.DigitWords) > 0 {
+		words = newDigitWordSets(cfg.DigitWords)
+	}
 	return &DTMFDisambiguator{
 		cfg:    cfg,
+		words:  words,
 		lastDT: make(map[string]time.Time),
 	}
 }
@@ -46,17 +125,12 @@ func (d *DTMFDisambiguator) Process(f frames.Frame) ([]frames.Frame, error) {
 	case frames.KindSystem:
 		sf := f.(frames.SystemFrame)
 		if sf.Name() == "call_end" {
-			streamID := sf.Meta()[frames.MetaStreamID]
-			if streamID != "" {
-				d.mu.Lock()
-				delete(d.lastDT, streamID)
-				d.mu.Unlock()
-			}
+			d.OnSessionEnd(sf.Meta())
 		}
 	case frames.KindControl:
 		cf := f.(frames.ControlFrame)
 		if cf.Code() == frames.ControlDTMF {
-			streamID := cf.Meta()[frames.MetaStreamID]
+			streamID := cf.Metadata().StreamID()
 			if streamID != "" {
 				d.mu.Lock()
 				d.lastDT[streamID] = time.Now()
@@ -69,8 +143,7 @@ func (d *DTMFDisambiguator) Process(f frames.Frame) ([]frames.Frame, error) {
 		if meta[frames.MetaSource] != "stt" {
 			return []frames.Frame{f}, nil
 		}
-		text := strings.TrimSpace(tf.Text())
-		if text == "" || !digitOnly.MatchString(text) {
+		if !spokenDigits(tf, d.words) {
 			return []frames.Frame{f}, nil
 		}
 		streamID := meta[frames.MetaStreamID]
@@ -85,7 +158,7 @@ func (d *DTMFDisambiguator) Process(f frames.Frame) ([]frames.Frame, error) {
 		}
 		meta[d.cfg.MetaKeyFlag] = "true"
 		if d.cfg.MarkOnly || !d.cfg.PreferDTMF {
-			return []frames.Frame{frames.NewTextFrame(streamID, tf.PTS(), tf.Text(), meta)}, nil
+			return []frames.Frame{tf.WithMeta(streamID, meta)}, nil
 		}
 		// Prefer DTMF: drop spoken digits to avoid duplication.
 		return nil, nil
@@ -93,4 +166,24 @@ func (d *DTMFDisambiguator) Process(f frames.Frame) ([]frames.Frame, error) {
 	return []frames.Frame{f}, nil
 }
 
+// OnSessionEnd implements pipeline.SessionEndHandler.
+func (d *DTMFDisambiguator) OnSessionEnd(meta map[string]string) {
+	streamID := meta[frames.MetaStreamID]
+	if streamID == "" {
+		return
+	}
+	d.mu.Lock()
+	delete(d.lastDT, streamID)
+	d.mu.Unlock()
+}
+
+// MigrateStream implements pipeline.StreamMigrator.
+func (d *DTMFDisambiguator) MigrateStream(oldStreamID, newStreamID string) {
+	d.mu.Lock()
+	moveKey(d.lastDT, oldStreamID, newStreamID)
+	d.mu.Unlock()
+}
+
 var _ pipeline.FrameProcessor = (*DTMFDisambiguator)(nil)
+var _ pipeline.SessionEndHandler = (*DTMFDisambiguator)(nil)
+var _ pipeline.StreamMigrator = (*DTMFDisambiguator)(nil)
@@ -0,0 +1,80 @@
+package processors
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+)
+
+func TestDTMFDisambiguatorMatchesSpokenDigitWords(t *testing.T) {
+	d := NewDTMFDisambiguator(DTMFDisambiguatorConfig{MarkOnly: true})
+	meta := map[string]string{frames.MetaStreamID: "s1", frames.MetaSource: "stt"}
+	if _, err := d.Process(frames.NewControlFrame("s1", 1, frames.ControlDTMF, map[string]string{frames.MetaDTMFDigit: "1"})); err != nil {
+		t.Fatalf("dtmf: %v", err)
+	}
+	tr := frames.Transcript{
+		Text:  "one two three",
+		Final: true,
+		Words: []frames.Word{{Text: "one"}, {Text: "two"}, {Text: "three"}},
+	}
+	out, err := d.Process(frames.NewTranscriptFrame("s1", 2, tr, meta))
+	if err != nil || len(out) != 1 {
+		t.Fatalf("expected one frame, got %v err=%v", out, err)
+	}
+	tf := out[0].(frames.TextFrame)
+	if tf.Metadata().Get(frames.MetaDTMFPriority) != "true" {
+		t.Fatalf("expected spoken digits to be marked, meta=%v", tf.Meta())
+	}
+	if _, ok := tf.Transcript(); !ok {
+		t.Fatalf("expected transcript to be preserved")
+	}
+
+	tr = frames.Transcript{Text: "one more", Final: true, Words: []frames.Word{{Text: "one"}, {Text: "more"}}}
+	out, _ = d.Process(frames.NewTranscriptFrame("s1", 3, tr, meta))
+	if out[0].(frames.TextFrame).Metadata().Get(frames.MetaDTMFPriority) != "" {
+		t.Fatalf("expected non-digit speech to pass unmarked")
+	}
+
+	tr = frames.Transcript{Text: "oh", Final: true, Words: []frames.Word{{Text: "Oh"}}}
+	out, _ = d.Process(frames.NewTranscriptFrame("s1", 4, tr, meta))
+	if out[0].(frames.TextFrame).Metadata().Get(frames.MetaDTMFPriority) != "" {
+		t.Fatalf("expected a bare oh to pass unmarked")
+	}
+	tr = frames.Transcript{Text: "two oh", Final: true, Words: []frames.Word{{Text: "two"}, {Text: "oh"}}}
+	out, _ = d.Process(frames.NewTranscriptFrame("s1", 5, tr, meta))
+	if out[0].(frames.TextFrame).Metadata().Get(frames.MetaDTMFPriority) != "true" {
+		t.Fatalf("expected oh next to a digit to count as zero")
+	}
+}
+
+func TestDTMFDisambiguatorDigitWordsPerLanguage(t *testing.T) {
+	d := NewDTMFDisambiguator(DTMFDisambiguatorConfig{
+		MarkOnly:   true,
+		DigitWords: map[string][]string{"es": {"uno", "dos", "tres"}},
+	})
+	if _, err := d.Process(frames.NewControlFrame("s1", 1, frames.ControlDTMF, map[string]string{frames.MetaStreamID: "s1", frames.MetaDTMFDigit: "1"})); err != nil {
+		t.Fatalf("dtmf: %v", err)
+	}
+	marked := func(lang string, words ...string) bool {
+		tr := frames.Transcript{Text: strings.Join(words, " "), Final: true, Language: lang}
+		for _, w := range words {
+			tr.Words = append(tr.Words, frames.Word{Text: w})
+		}
+		meta := map[string]string{frames.MetaStreamID: "s1", frames.MetaSource: "stt"}
+		out, _ := d.Process(frames.NewTranscriptFrame("s1", 2, tr, meta))
+		return out[0].(frames.TextFrame).Metadata().Get(frames.MetaDTMFPriority) == "true"
+	}
+	if !marked("id", "satu", "dua", "tiga") {
+		t.Fatalf("expected Indonesian digit words to be marked")
+	}
+	if !marked("es-MX", "uno", "dos") {
+		t.Fatalf("expected configured Spanish digit words to be marked")
+	}
+	if marked("id", "one", "two") {
+		t.Fatalf("expected English words not to count in an Indonesian transcript")
+	}
+	if !marked("", "one", "dua") {
+		t.Fatalf("expected every table to be consulted without a language")
+	}
+}
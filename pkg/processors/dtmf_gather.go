@@ -0,0 +1,377 @@
+package processors
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/pipeline"
+)
+
+// Frames for keypad digit gathering.
+const (
+	// FrameGatherDigits arms a gather; MetaGatherOptions holds GatherOptions
+	// as JSON.
+	FrameGatherDigits = "gather_digits"
+	// FrameDTMFGathered carries the result of a gather armed without a tool
+	// call. Tool-armed gathers answer with a tool_result frame instead, with
+	// the same metadata.
+	FrameDTMFGathered = "dtmf_gathered"
+)
+
+// Gather outcomes, in MetaGatherStatus.
+const (
+	GatherComplete   = "complete"
+	GatherIncomplete = "incomplete"
+)
+
+// GatherOptions controls one digit gather. Zero fields take the gatherer's
+// defaults. The JSON form is what the gather_digits tool accepts.
+type GatherOptions struct {
+	MinDigits int `json:"min_digits,omitempty"`
+	// MaxDigits completes the gather as soon as it is reached; 0 waits for
+	// the terminator or a timeout.
+	MaxDigits int `json:"max_digits,omitempty"`
+	// Terminator ends input and is not collected. Default "#"; "none"
+	// disables it.
+	Terminator string `json:"terminator,omitempty"`
+	// InterDigitTimeoutMS ends input after a pause following a digit.
+	InterDigitTimeoutMS int `json:"inter_digit_timeout_ms,omitempty"`
+	// TimeoutMS bounds the whole gather, including the wait for the first
+	// digit.
+	TimeoutMS int `json:"timeout_ms,omitempty"`
+	// Mask hides all but the last four digits from the LLM and logs; the
+	// raw value is only in MetaDTMFDigits.
+	Mask bool `json:"mask,omitempty"`
+	// AcceptSpoken collects digit-only transcripts ("four two") as well.
+	AcceptSpoken bool `json:"accept_spoken,omitempty"`
+}
+
+func (o GatherOptions) merge(def GatherOptions) GatherOptions {
+	if o.MinDigits <= 0 {
+		o.MinDigits = def.MinDigits
+	}
+	if o.MinDigits <= 0 {
+		o.MinDigits = 1
+	}
+	if o.MaxDigits <= 0 {
+		o.MaxDigits = def.MaxDigits
+	}
+	if o.MaxDigits > 0 && o.MaxDigits < o.MinDigits {
+		o.MaxDigits = o.MinDigits
+	}
+	if o.Terminator == "" {
+		o.Terminator = def.Terminator
+	}
+	if o.Terminator == "" {
+		o.Terminator = "#"
+	}
+	if o.InterDigitTimeoutMS <= 0 {
+		o.InterDigitTimeoutMS = def.InterDigitTimeoutMS
+	}
+	if o.InterDigitTimeoutMS <= 0 {
+		o.InterDigitTimeoutMS = 5000
+	}
+	if o.TimeoutMS <= 0 {
+		o.TimeoutMS = def.TimeoutMS
+	}
+	if o.TimeoutMS <= 0 {
+		o.TimeoutMS = 30000
+	}
+	o.Mask = o.Mask || def.Mask
+	o.AcceptSpoken = o.AcceptSpoken || def.AcceptSpoken
+	return o
+}
+
+// DTMFGatherer collects keypad digits into one result while armed, instead
+// of ContextProcessor turning each key into its own "DTMF input" turn.
+// While a gather is active, DTMF frames and digit-only transcripts for the
+// stream are consumed. Spoken digits already marked by DTMFDisambiguator
+// (MetaDTMFPriority) are dropped as keypad echoes.
+//
+// It belongs after DTMFDisambiguator and before ContextProcessor. Timeouts
+// emit through the input set with SetInput.
+type DTMFGatherer struct {
+	defaults GatherOptions
+
+	mu      sync.Mutex
+	in      chan frames.Frame
+	gathers map[string]*gather
+}
+
+type gather struct {
+	opts   GatherOptions
+	meta   map[string]string
+	digits strings.Builder
+	total  *time.Timer
+	pause  *time.Timer
+}
+
+func NewDTMFGatherer(defaults GatherOptions) *DTMFGatherer {
+	return &DTMFGatherer{defaults: defaults, gathers: make(map[string]*gather)}
+}
+
+func (g *DTMFGatherer) Name() string { return "dtmf_gatherer" }
+
+// SetInput sets the channel timed-out results are sent to, normally the
+// orchestrator input.
+func (g *DTMFGatherer) SetInput(in chan frames.Frame) {
+	g.mu.Lock()
+	g.in = in
+	g.mu.Unlock()
+}
+
+// Arm starts a gather on the stream in meta, replacing any active one. meta
+// supplies call, trace and, for tool-armed gathers, tool call metadata.
+func (g *DTMFGatherer) Arm(meta map[string]string, opts GatherOptions) {
+	streamID := meta[frames.MetaStreamID]
+	if streamID == "" {
+		return
+	}
+	opts = opts.merge(g.defaults)
+	keep := map[string]string{frames.MetaStreamID: streamID}
+	for _, k := range []string{frames.MetaCallSID, frames.MetaTraceID, frames.MetaToolCallID, frames.MetaToolName, frames.MetaLanguage} {
+		if v := meta[k]; v != "" {
+			keep[k] = v
+		}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if old := g.gathers[streamID]; old != nil {
+		old.stop()
+	}
+	gt := &gather{opts: opts, meta: keep}
+	gt.total = time.AfterFunc(time.Duration(opts.TimeoutMS)*time.Millisecond, func() { g.expire(gt, "timeout") })
+	g.gathers[streamID] = gt
+	slog.Info("dtmf_gather_armed", "stream_id", streamID, "min_digits", opts.MinDigits, "max_digits", opts.MaxDigits, "terminator", opts.Terminator)
+}
+
+// Active reports whether a gather is running on streamID.
+func (g *DTMFGatherer) Active(streamID string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.gathers[streamID] != nil
+}
+
+func (g *DTMFGatherer) Process(f frames.Frame) ([]frames.Frame, error) {
+	streamID := frames.StreamIDOf(f)
+	switch f.Kind() {
+	case frames.KindSystem:
+		sf := f.(frames.SystemFrame)
+		meta := sf.Meta()
+		switch sf.Name() {
+		case FrameGatherDigits:
+			var opts GatherOptions
+			if raw := meta[frames.MetaGatherOptions]; raw != "" {
+				if err := json.Unmarshal([]byte(raw), &opts); err != nil {
+					slog.Warn("dtmf_gather_bad_options", "stream_id", streamID, "error", err)
+				}
+			}
+			if meta[frames.MetaStreamID] == "" {
+				meta[frames.MetaStreamID] = streamID
+			}
+			g.Arm(meta, opts)
+			return nil, nil
+		case "call_end":
+			g.OnSessionEnd(meta)
+		}
+	case frames.KindControl:
+		cf := f.(frames.ControlFrame)
+		if cf.Code() != frames.ControlDTMF {
+			break
+		}
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		gt := g.gathers[streamID]
+		if gt == nil {
+			break
+		}
+		return g.addLocked(streamID, gt, cf.Meta()[frames.MetaDTMFDigit]), nil
+	case frames.KindText:
+		tf := f.(frames.TextFrame)
+		meta := tf.Meta()
+		if meta[frames.MetaSource] != "stt" || !spokenDigits(tf, defaultDigitWordSets) {
+			break
+		}
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		gt := g.gathers[streamID]
+		if gt == nil {
+			break
+		}
+		if !isFinalText(tf) || meta[frames.MetaDTMFPriority] == "true" || !gt.opts.AcceptSpoken {
+			// Keep spoken digits from starting an LLM turn mid-gather.
+			return nil, nil
+		}
+		var out []frames.Frame
+		for _, d := range spokenToDigits(tf) {
+			out = append(out, g.addLocked(streamID, gt, d)...)
+			if g.gathers[streamID] != gt {
+				break
+			}
+		}
+		return out, nil
+	}
+	return []frames.Frame{f}, nil
+}
+
+// addLocked applies one key to gt and returns the result frame if the
+// gather finished.
+func (g *DTMFGatherer) addLocked(streamID string, gt *gather, key string) []frames.Frame {
+	if key == "" {
+		return nil
+	}
+	if gt.opts.Terminator != "none" && key == gt.opts.Terminator {
+		return []frames.Frame{g.finishLocked(streamID, gt, "terminator")}
+	}
+	gt.digits.WriteString(key)
+	if gt.opts.MaxDigits > 0 && gt.digits.Len() >= gt.opts.MaxDigits {
+		return []frames.Frame{g.finishLocked(streamID, gt, "max_digits")}
+	}
+	if gt.pause != nil {
+		gt.pause.Stop()
+	}
+	gt.pause = time.AfterFunc(time.Duration(gt.opts.InterDigitTimeoutMS)*time.Millisecond, func() { g.expire(gt, "inter_digit_timeout") })
+	return nil
+}
+
+// expire finishes gt from a timer, unless it already ended, and sends the
+// result to the input. The stream is read from gt since it may have moved.
+func (g *DTMFGatherer) expire(gt *gather, reason string) {
+	g.mu.Lock()
+	streamID := gt.meta[frames.MetaStreamID]
+	if g.gathers[streamID] != gt {
+		g.mu.Unlock()
+		return
+	}
+	f := g.finishLocked(streamID, gt, reason)
+	in := g.in
+	g.mu.Unlock()
+	if in == nil {
+		return
+	}
+	select {
+	case in <- f:
+	default:
+		slog.Warn("dtmf_gather_result_dropped", "stream_id", streamID)
+	}
+}
+
+func (g *DTMFGatherer) finishLocked(streamID string, gt *gather, reason string) frames.Frame {
+	gt.stop()
+	delete(g.gathers, streamID)
+	digits := gt.digits.String()
+	status := GatherComplete
+	if len(digits) < gt.opts.MinDigits {
+		status = GatherIncomplete
+	}
+	shown := digits
+	if gt.opts.Mask {
+		shown = maskDigits(digits)
+	}
+	slog.Info("dtmf_gathered", "stream_id", streamID, "status", status, "reason", reason, "digits", shown, "count", len(digits))
+
+	meta := make(map[string]string, len(gt.meta)+6)
+	for k, v := range gt.meta {
+		meta[k] = v
+	}
+	meta[frames.MetaDTMFDigits] = digits
+	meta[frames.MetaGatherStatus] = status
+	meta[frames.MetaReason] = reason
+	result, _ := json.Marshal(map[string]any{
+		"status": status,
+		"digits": shown,
+		"count":  len(digits),
+		"reason": reason,
+		"masked": gt.opts.Mask,
+	})
+	name := FrameDTMFGathered
+	if meta[frames.MetaToolCallID] != "" {
+		name = "tool_result"
+		meta[frames.MetaToolResult] = string(result)
+		meta[frames.MetaToolStatus] = "ok"
+	} else {
+		meta[frames.MetaSystemMessage] = "Keypad input: " + string(result)
+	}
+	return frames.NewSystemFrame(streamID, time.Now().UnixNano(), name, meta)
+}
+
+func (gt *gather) stop() {
+	if gt.total != nil {
+		gt.total.Stop()
+	}
+	if gt.pause != nil {
+		gt.pause.Stop()
+	}
+}
+
+// OnSessionEnd implements pipeline.SessionEndHandler.
+func (g *DTMFGatherer) OnSessionEnd(meta map[string]string) {
+	streamID := meta[frames.MetaStreamID]
+	if streamID == "" {
+		return
+	}
+	g.mu.Lock()
+	if gt := g.gathers[streamID]; gt != nil {
+		gt.stop()
+		delete(g.gathers, streamID)
+	}
+	g.mu.Unlock()
+}
+
+// MigrateStream implements pipeline.StreamMigrator; a gather in progress
+// keeps collecting on the new stream.
+func (g *DTMFGatherer) MigrateStream(oldStreamID, newStreamID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	moveKey(g.gathers, oldStreamID, newStreamID)
+	if gt := g.gathers[newStreamID]; gt != nil {
+		gt.meta[frames.MetaStreamID] = newStreamID
+	}
+}
+
+var _ pipeline.SessionEndHandler = (*DTMFGatherer)(nil)
+var _ pipeline.StreamMigrator = (*DTMFGatherer)(nil)
+
+// maskDigits keeps the last four digits of longer inputs.
+func maskDigits(digits string) string {
+	keep := 0
+	if len(digits) > 4 {
+		keep = 4
+	}
+	return strings.Repeat("*", len(digits)-keep) + digits[len(digits)-keep:]
+}
+
+var spokenDigitValues = map[string]string{
+	"zero": "0", "oh": "0", "one": "1", "two": "2", "three": "3", "four": "4",
+	"five": "5", "six": "6", "seven": "7", "eight": "8", "nine": "9",
+}
+
+// spokenToDigits splits a digit-only transcript into keys.
+func spokenToDigits(tf frames.TextFrame) []string {
+	var words []string
+	if tr, ok := tf.Transcript(); ok && len(tr.Words) > 0 {
+		for _, w := range tr.Words {
+			words = append(words, w.Text)
+		}
+	} else {
+		words = strings.Fields(tf.Text())
+	}
+	var keys []string
+	for _, w := range words {
+		w = strings.ToLower(strings.TrimSpace(w))
+		if v, ok := spokenDigitValues[w]; ok {
+			keys = append(keys, v)
+			continue
+		}
+		for _, r := range w {
+			if r >= '0' && r <= '9' {
+				keys = append(keys, string(r))
+			}
+		}
+	}
+	return keys
+}
@@ -0,0 +1,144 @@
+package processors
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+)
+
+func dtmfKey(digit string) frames.Frame {
+	return frames.NewControlFrame("s1", 0, frames.ControlDTMF, map[string]string{frames.MetaStreamID: "s1", frames.MetaDTMFDigit: digit})
+}
+
+func pressKeys(t *testing.T, g *DTMFGatherer, keys string) []frames.Frame {
+	t.Helper()
+	var out []frames.Frame
+	for _, k := range keys {
+		res, err := g.Process(dtmfKey(string(k)))
+		if err != nil {
+			t.Fatalf("process: %v", err)
+		}
+		out = append(out, res...)
+	}
+	return out
+}
+
+func TestDTMFGathererToolResult(t *testing.T) {
+	g := NewDTMFGatherer(GatherOptions{})
+	// Unarmed keys pass through for ContextProcessor.
+	if out := pressKeys(t, g, "5"); len(out) != 1 || out[0].Kind() != frames.KindControl {
+		t.Fatalf("expected passthrough, got %v", out)
+	}
+	g.Arm(map[string]string{frames.MetaStreamID: "s1", frames.MetaCallSID: "c1", frames.MetaToolCallID: "call-1", frames.MetaToolName: "gather_digits"},
+		GatherOptions{MinDigits: 16, MaxDigits: 16, Mask: true})
+	if out := pressKeys(t, g, "411111111111111"); len(out) != 0 {
+		t.Fatalf("expected digits to be buffered, got %d frames", len(out))
+	}
+	out := pressKeys(t, g, "1")
+	if len(out) != 1 {
+		t.Fatalf("expected one result frame, got %d", len(out))
+	}
+	sf := out[0].(frames.SystemFrame)
+	meta := sf.Meta()
+	if sf.Name() != "tool_result" || meta[frames.MetaToolCallID] != "call-1" || meta[frames.MetaDTMFDigits] != "4111111111111111" || meta[frames.MetaGatherStatus] != GatherComplete {
+		t.Fatalf("unexpected result %s %v", sf.Name(), meta)
+	}
+	var result map[string]any
+	if err := json.Unmarshal([]byte(meta[frames.MetaToolResult]), &result); err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+	if result["digits"] != "************1111" || result["reason"] != "max_digits" {
+		t.Fatalf("unexpected tool result %v", result)
+	}
+	if g.Active("s1") {
+		t.Fatalf("gather should end after the result")
+	}
+}
+
+func TestDTMFGathererTerminatorAndTimeout(t *testing.T) {
+	g := NewDTMFGatherer(GatherOptions{InterDigitTimeoutMS: 20})
+	in := make(chan frames.Frame, 1)
+	g.SetInput(in)
+
+	arm := frames.NewSystemFrame("s1", 0, FrameGatherDigits, map[string]string{frames.MetaStreamID: "s1", frames.MetaGatherOptions: `{"min_digits":4}`})
+	if out, _ := g.Process(arm); len(out) != 0 {
+		t.Fatalf("arm frame should be consumed")
+	}
+	out := pressKeys(t, g, "12#")
+	if len(out) != 1 {
+		t.Fatalf("expected result on terminator, got %d frames", len(out))
+	}
+	meta := out[0].(frames.SystemFrame).Meta()
+	if out[0].(frames.SystemFrame).Name() != FrameDTMFGathered || meta[frames.MetaGatherStatus] != GatherIncomplete || meta[frames.MetaReason] != "terminator" || meta[frames.MetaDTMFDigits] != "12" {
+		t.Fatalf("unexpected terminator result %v", meta)
+	}
+
+	g.Arm(map[string]string{frames.MetaStreamID: "s1"}, GatherOptions{})
+	pressKeys(t, g, "987")
+	select {
+	case f := <-in:
+		meta := f.(frames.SystemFrame).Meta()
+		if meta[frames.MetaReason] != "inter_digit_timeout" || meta[frames.MetaDTMFDigits] != "987" || meta[frames.MetaGatherStatus] != GatherComplete {
+			t.Fatalf("unexpected timeout result %v", meta)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected inter-digit timeout result")
+	}
+}
+
+func TestDTMFGathererSpokenDigits(t *testing.T) {
+	g := NewDTMFGatherer(GatherOptions{})
+	spoken := func(text string, meta map[string]string) frames.Frame {
+		m := map[string]string{frames.MetaStreamID: "s1", frames.MetaSource: "stt", frames.MetaIsFinal: "true"}
+		for k, v := range meta {
+			m[k] = v
+		}
+		return frames.NewTextFrame("s1", 0, text, m)
+	}
+	g.Arm(map[string]string{frames.MetaStreamID: "s1"}, GatherOptions{MaxDigits: 4})
+	// Without accept_spoken, digits are swallowed so they do not start a turn.
+	if out, _ := g.Process(spoken("42", nil)); len(out) != 0 {
+		t.Fatalf("expected spoken digits to be dropped")
+	}
+	if out, _ := g.Process(spoken("what was that", nil)); len(out) != 1 {
+		t.Fatalf("expected other speech to pass")
+	}
+
+	g.Arm(map[string]string{frames.MetaStreamID: "s1"}, GatherOptions{MaxDigits: 4, AcceptSpoken: true})
+	// Echoes of keypad input flagged by DTMFDisambiguator are not collected.
+	if out, _ := g.Process(spoken("99", map[string]string{frames.MetaDTMFPriority: "true"})); len(out) != 0 {
+		t.Fatalf("expected flagged digits to be dropped")
+	}
+	pressKeys(t, g, "1")
+	out, _ := g.Process(spoken("234", nil))
+	if len(out) != 1 || out[0].(frames.SystemFrame).Meta()[frames.MetaDTMFDigits] != "1234" {
+		t.Fatalf("expected spoken digits to complete the gather, got %v", out)
+	}
+}
+
+func TestDTMFGathererMigrateStream(t *testing.T) {
+	g := NewDTMFGatherer(GatherOptions{InterDigitTimeoutMS: 20})
+	in := make(chan frames.Frame, 1)
+	g.SetInput(in)
+	g.Arm(map[string]string{frames.MetaStreamID: "s1", frames.MetaCallSID: "c1"}, GatherOptions{})
+	pressKeys(t, g, "12")
+
+	g.MigrateStream("s1", "s2")
+	if g.Active("s1") || !g.Active("s2") {
+		t.Fatalf("expected the gather to move to the new stream")
+	}
+	key := frames.NewControlFrame("s2", 0, frames.ControlDTMF, map[string]string{frames.MetaStreamID: "s2", frames.MetaDTMFDigit: "3"})
+	if out, _ := g.Process(key); len(out) != 0 {
+		t.Fatalf("expected digit to be buffered on the new stream")
+	}
+	select {
+	case f := <-in:
+		if f.(frames.SystemFrame).Meta()[frames.MetaDTMFDigits] != "123" || frames.StreamIDOf(f) != "s2" {
+			t.Fatalf("unexpected result %v", f.(frames.SystemFrame).Meta())
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected inter-digit timeout on the new stream")
+	}
+}
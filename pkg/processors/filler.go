@@ -38,7 +38,7 @@ func (p *FillerProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 	switch f.Kind() {
 	case frames.KindSystem:
 		sf := f.(frames.SystemFrame)
-		streamID := sf.Meta()[frames.MetaStreamID]
+		streamID := sf.Metadata().StreamID()
 		if sf.Name() == "call_end" {
 			p.clear(streamID)
 			return []frames.Frame{f}, nil
@@ -53,7 +53,7 @@ func (p *FillerProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 	case frames.KindControl:
 		cf := f.(frames.ControlFrame)
 		if cf.Code() == frames.ControlFlush || cf.Code() == frames.ControlCancel {
-			p.clear(cf.Meta()[frames.MetaStreamID])
+			p.clear(cf.Metadata().StreamID())
 		}
 	}
 	return []frames.Frame{f}, nil
@@ -78,6 +78,18 @@ func (p *FillerProcessor) play(streamID string, meta map[string]string) []frames
 	return out
 }
 
+// OnSessionEnd implements pipeline.SessionEndHandler.
+func (p *FillerProcessor) OnSessionEnd(meta map[string]string) {
+	p.clear(meta[frames.MetaStreamID])
+}
+
+// MigrateStream implements pipeline.StreamMigrator.
+func (p *FillerProcessor) MigrateStream(oldStreamID, newStreamID string) {
+	p.mu.Lock()
+	moveKey(p.active, oldStreamID, newStreamID)
+	p.mu.Unlock()
+}
+
 func (p *FillerProcessor) clear(streamID string) {
 	p.mu.Lock()
 	delete(p.active, streamID)
@@ -103,3 +115,5 @@ func loadFiller(path string) []byte {
 }
 
 var _ pipeline.FrameProcessor = (*FillerProcessor)(nil)
+var _ pipeline.SessionEndHandler = (*FillerProcessor)(nil)
+var _ pipeline.StreamMigrator = (*FillerProcessor)(nil)
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"log/slog"
+	"maps"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -41,6 +43,7 @@ type LLMProcessor struct {
 	lastLanguage       map[string]string
 	lastLanguageByCall map[string]string
 	lastCallSID        map[string]string
+	takenOver          map[string]bool
 	maxHistory         int
 	maxTokens          int
 	confirmMode        string
@@ -72,6 +75,7 @@ func NewLLMProcessor(adapter llm.LLMAdapter, system string, tools []llm.Tool) *L
 		lastLanguage:       make(map[string]string),
 		lastLanguageByCall: make(map[string]string),
 		lastCallSID:        make(map[string]string),
+		takenOver:          make(map[string]bool),
 	}
 }
 
@@ -130,6 +134,9 @@ func (p *LLMProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 		sf := f.(frames.SystemFrame)
 		meta := sf.Meta()
 		scope := p.scopeKey(meta, meta[frames.MetaStreamID])
+		if p.handleSupervisor(sf) {
+			return nil, nil
+		}
 		if msg := meta[frames.MetaSystemMessage]; msg != "" {
 			p.appendSystem(scope, msg)
 		}
@@ -163,6 +170,14 @@ func (p *LLMProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 	p.setCallSIDFromMeta(meta)
 	scope := p.scopeKey(meta, streamID)
 
+	if p.isTakenOver(streamID) {
+		// The supervisor answers; keep the caller's words for when the
+		// agent gets the call back.
+		p.contextWithUserWithAgent(tf.Text(), p.resolveAgent(meta, streamID), scope)
+		slog.Info("llm_input_supervised", "stream_id", streamID, "text", redact.Text(tf.Text()))
+		return nil, nil
+	}
+
 	if out, ok := p.handlePendingConfirmation(streamID, tf); ok {
 		return out, nil
 	}
@@ -404,6 +419,63 @@ func (p *LLMProcessor) applyLanguageMeta(meta map[string]string, streamID string
 	}
 }
 
+// OnSessionEnd implements pipeline.SessionEndHandler.
+func (p *LLMProcessor) OnSessionEnd(meta map[string]string) {
+	p.clearCall(meta)
+}
+
+// MigrateStream implements pipeline.StreamMigrator. History is scoped by
+// call where possible, so only stream-keyed state moves.
+func (p *LLMProcessor) MigrateStream(oldStreamID, newStreamID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	moveKey(p.activeAgent, oldStreamID, newStreamID)
+	moveKey(p.lastLanguage, oldStreamID, newStreamID)
+	moveKey(p.pendingConfirms, oldStreamID, newStreamID)
+	moveKey(p.lastCallSID, oldStreamID, newStreamID)
+	moveKey(p.takenOver, oldStreamID, newStreamID)
+	moveKey(p.messagesByScope, "stream:"+oldStreamID, "stream:"+newStreamID)
+	moveKey(p.lastInjected, "stream:"+oldStreamID, "stream:"+newStreamID)
+}
+
+// LLMSnapshot is a point-in-time view of a call's conversation state.
+type LLMSnapshot struct {
+	Agent        string           `json:"agent,omitempty"`
+	Language     string           `json:"language,omitempty"`
+	PendingTools []string         `json:"pending_tools,omitempty"`
+	History      []map[string]any `json:"history,omitempty"`
+}
+
+// Snapshot returns the conversation state of callSID on streamID. Tools
+// waiting on the caller's confirmation are listed with a "(confirm)" suffix.
+// The history is a copy and safe to hold on to.
+func (p *LLMProcessor) Snapshot(callSID, streamID string) LLMSnapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	snap := LLMSnapshot{
+		Agent:    p.activeAgent[streamID],
+		Language: p.lastLanguage[streamID],
+	}
+	if snap.Agent == "" {
+		snap.Agent = p.defaultAgent
+	}
+	if snap.Language == "" {
+		snap.Language = p.lastLanguageByCall[callSID]
+	}
+	for _, call := range p.pendingTools {
+		snap.PendingTools = append(snap.PendingTools, call.Name)
+	}
+	sort.Strings(snap.PendingTools)
+	if pending, ok := p.pendingConfirms[streamID]; ok {
+		snap.PendingTools = append(snap.PendingTools, pending.call.Name+" (confirm)")
+	}
+	scope := p.scopeKey(map[string]string{frames.MetaCallSID: callSID}, streamID)
+	for _, msg := range p.messagesByScope[scope] {
+		snap.History = append(snap.History, maps.Clone(msg))
+	}
+	return snap
+}
+
 func (p *LLMProcessor) clearCall(meta map[string]string) {
 	if meta == nil {
 		return
@@ -415,6 +487,7 @@ func (p *LLMProcessor) clearCall(meta map[string]string) {
 	delete(p.lastLanguage, streamID)
 	delete(p.pendingConfirms, streamID)
 	delete(p.lastCallSID, streamID)
+	delete(p.takenOver, streamID)
 	if streamID != "" {
 		delete(p.messagesByScope, "stream:"+streamID)
 		delete(p.lastInjected, "stream:"+streamID)
@@ -747,6 +820,9 @@ func (p *LLMProcessor) applyToolResult(sf frames.SystemFrame) ([]frames.Frame, e
 	msgs = p.pruneMessagesLocked(msgs)
 	p.messagesByScope[scopeKeyOrDefault(scope)] = msgs
 	p.mu.Unlock()
+	if p.isTakenOver(streamID) {
+		return nil, nil
+	}
 	adapter := p.adapterFor(p.resolveAgent(meta, streamID))
 	ctx := p.contextSnapshot(scope)
 	ch, err := adapter.Stream(p.ctx, ctx)
@@ -1013,7 +1089,7 @@ func (p *LLMProcessor) streamToFrames(src frames.TextFrame, ch <-chan string) []
 	var full strings.Builder
 	var chunk strings.Builder
 	first := true
-	streamID := src.Meta()[frames.MetaStreamID]
+	streamID := frames.StreamIDOf(src)
 	scope := p.scopeKey(src.Meta(), streamID)
 	const minChunkLen = 120
 	emitChunk := func(text string, flush bool) {
@@ -1030,7 +1106,7 @@ func (p *LLMProcessor) streamToFrames(src frames.TextFrame, ch <-chan string) []
 		chunk.WriteString(tok)
 		if first {
 			first = false
-			p.record("llm_first_token", streamID, src.Meta()[frames.MetaTraceID])
+			p.record("llm_first_token", streamID, frames.TraceIDOf(src))
 		}
 		if chunk.Len() >= minChunkLen {
 			emitChunk(chunk.String(), false)
@@ -1043,8 +1119,8 @@ func (p *LLMProcessor) streamToFrames(src frames.TextFrame, ch <-chan string) []
 		emitChunk("", true)
 	}
 	p.appendAssistant(scope, full.String())
-	p.recordWithFields("llm_output_text", streamID, src.Meta()[frames.MetaTraceID], map[string]any{"text": redact.Text(full.String())})
-	p.record("llm_done", streamID, src.Meta()[frames.MetaTraceID])
+	p.recordWithFields("llm_output_text", streamID, frames.TraceIDOf(src), map[string]any{"text": redact.Text(full.String())})
+	p.record("llm_done", streamID, frames.TraceIDOf(src))
 	return out
 }
 
@@ -1096,6 +1172,7 @@ func cloneMessages(in []map[string]any) []map[string]any {
 }
 
 var _ pipeline.FrameProcessor = (*LLMProcessor)(nil)
+var _ pipeline.SessionEndHandler = (*LLMProcessor)(nil)
 
 func (p *LLMProcessor) record(name, streamID, traceID string) {
 	if p.obs == nil {
@@ -77,12 +77,12 @@ func (p *TurnProcessor) Name() string { return "turn_processor" }
 func (p *TurnProcessor) Manager() turn.Manager { return p.mgr }
 
 func (p *TurnProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
-	if traceID := f.Meta()[frames.MetaTraceID]; traceID != "" {
+	if traceID := frames.TraceIDOf(f); traceID != "" {
 		p.mu.Lock()
 		p.lastTraceID = traceID
 		p.mu.Unlock()
 	}
-	if streamID := f.Meta()[frames.MetaStreamID]; streamID != "" {
+	if streamID := frames.StreamIDOf(f); streamID != "" {
 		p.lastID = streamID
 	}
 	var out []frames.Frame
@@ -91,14 +91,14 @@ func (p *TurnProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 	case frames.KindControl:
 		cf := f.(frames.ControlFrame)
 		if cf.Code() == frames.ControlFlush {
-			source := cf.Meta()[frames.MetaSource]
+			source := cf.Metadata().Get(frames.MetaSource)
 			if source == "stt" || source == "vad" || source == "audio_gate" {
-				reason := cf.Meta()[frames.MetaReason]
+				reason := cf.Metadata().Get(frames.MetaReason)
 				if isEndOfTurnReason(reason) {
 					p.stopEndOfTurnTimer()
 					p.mgr.OnUserSpeechEnd()
 				} else {
-					p.onUserSpeechStart(cf.Meta()[frames.MetaStreamID])
+					p.onUserSpeechStart(cf.Metadata().StreamID())
 				}
 			}
 			p.resetSilenceTimer()
@@ -109,21 +109,21 @@ func (p *TurnProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 		}
 	case frames.KindText:
 		tf := f.(frames.TextFrame)
-		if lang := tf.Meta()[frames.MetaLanguage]; lang != "" {
+		if lang := tf.Metadata().Get(frames.MetaLanguage); lang != "" {
 			p.mu.Lock()
 			p.lastLanguage = lang
 			p.mu.Unlock()
 		}
-		if tf.Meta()[frames.MetaSource] == "stt" {
+		if tf.Metadata().Get(frames.MetaSource) == "stt" {
 			p.resetSilenceTimer()
-			if isFinal(tf.Meta()) {
+			if isFinalText(tf) {
 				p.stopEndOfTurnTimer()
 				p.mgr.OnUserSpeechEnd()
 			} else {
-				p.onUserSpeechStart(tf.Meta()[frames.MetaStreamID])
+				p.onUserSpeechStart(tf.Metadata().StreamID())
 			}
 		}
-		if tf.Meta()[frames.MetaSource] == "llm" {
+		if tf.Metadata().Get(frames.MetaSource) == "llm" {
 			p.mgr.OnAgentSpeechStart()
 			p.resetSilenceTimer()
 		}
@@ -135,11 +135,7 @@ func (p *TurnProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 		case "thinking_end":
 			p.mgr.OnAgentThinkEnd()
 		case "call_end":
-			p.resetSilenceTimer()
-			p.stopEndOfTurnTimer()
-			p.mu.Lock()
-			p.lastTraceID = ""
-			p.mu.Unlock()
+			p.OnSessionEnd(sf.Meta())
 		}
 	}
 	out = append(out, f)
@@ -180,13 +176,38 @@ func (p *TurnProcessor) ensureStreamID(f frames.Frame) frames.Frame {
 		return frames.NewSystemFrame(p.lastID, sf.PTS(), sf.Name(), meta)
 	case frames.KindText:
 		tf := f.(frames.TextFrame)
-		return frames.NewTextFrame(p.lastID, tf.PTS(), tf.Text(), meta)
+		return tf.WithMeta(p.lastID, meta)
 	default:
 		return f
 	}
 }
 
+// OnSessionEnd implements pipeline.SessionEndHandler.
+func (p *TurnProcessor) OnSessionEnd(map[string]string) {
+	p.resetSilenceTimer()
+	p.stopEndOfTurnTimer()
+	p.mu.Lock()
+	p.lastTraceID = ""
+	p.mu.Unlock()
+}
+
+// MigrateStream implements pipeline.StreamMigrator. A pending end-of-turn
+// timeout restarts on the new stream.
+func (p *TurnProcessor) MigrateStream(oldStreamID, newStreamID string) {
+	if p.lastID == oldStreamID {
+		p.lastID = newStreamID
+	}
+	p.mu.Lock()
+	pending := p.endOfTurnTimer != nil && p.endOfTurnStream == oldStreamID
+	p.mu.Unlock()
+	if pending {
+		p.startEndOfTurnTimer(newStreamID)
+	}
+}
+
 var _ pipeline.FrameProcessor = (*TurnProcessor)(nil)
+var _ pipeline.SessionEndHandler = (*TurnProcessor)(nil)
+var _ pipeline.StreamMigrator = (*TurnProcessor)(nil)
 
 func (p *TurnProcessor) startSilenceTimer() {
 	p.mu.Lock()
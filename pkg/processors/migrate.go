@@ -0,0 +1,13 @@
+package processors
+
+// moveKey re-keys per-stream state from oldKey to newKey, replacing anything
+// already stored under newKey. It is the building block of the
+// pipeline.StreamMigrator implementations in this package.
+func moveKey[V any](m map[string]V, oldKey, newKey string) {
+	v, ok := m[oldKey]
+	if !ok {
+		return
+	}
+	m[newKey] = v
+	delete(m, oldKey)
+}
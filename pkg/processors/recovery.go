@@ -47,7 +47,7 @@ func NewRecoveryProcessor(cfg RecoveryConfig) *RecoveryProcessor {
 func (r *RecoveryProcessor) Name() string { return "recovery_processor" }
 
 func (r *RecoveryProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
-	streamID := f.Meta()[frames.MetaStreamID]
+	streamID := frames.StreamIDOf(f)
 	if streamID == "" {
 		return []frames.Frame{f}, nil
 	}
@@ -115,6 +115,18 @@ func (r *RecoveryProcessor) bump(streamID string) bool {
 	return r.counts[streamID] <= r.cfg.MaxAttempts
 }
 
+// OnSessionEnd implements pipeline.SessionEndHandler.
+func (r *RecoveryProcessor) OnSessionEnd(meta map[string]string) {
+	r.reset(meta[frames.MetaStreamID])
+}
+
+// MigrateStream implements pipeline.StreamMigrator.
+func (r *RecoveryProcessor) MigrateStream(oldStreamID, newStreamID string) {
+	r.mu.Lock()
+	moveKey(r.counts, oldStreamID, newStreamID)
+	r.mu.Unlock()
+}
+
 func (r *RecoveryProcessor) reset(streamID string) {
 	r.mu.Lock()
 	delete(r.counts, streamID)
@@ -122,3 +134,5 @@ func (r *RecoveryProcessor) reset(streamID string) {
 }
 
 var _ pipeline.FrameProcessor = (*RecoveryProcessor)(nil)
+var _ pipeline.SessionEndHandler = (*RecoveryProcessor)(nil)
+var _ pipeline.StreamMigrator = (*RecoveryProcessor)(nil)
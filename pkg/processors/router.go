@@ -85,20 +85,20 @@ func (p *RouterProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 	case frames.KindControl:
 		cf := f.(frames.ControlFrame)
 		if cf.Code() == frames.ControlHandoff {
-			agent := cf.Meta()["handoff_agent"]
+			agent := cf.Metadata().Get("handoff_agent")
 			if agent != "" {
-				p.setAgent(cf.Meta()[frames.MetaStreamID], agent)
+				p.setAgent(cf.Metadata().StreamID(), agent)
 				sys := map[string]string{frames.MetaGlobalAgent: agent, frames.MetaSystemMessage: "Handoff ke agent " + agent}
-				if traceID := cf.Meta()[frames.MetaTraceID]; traceID != "" {
+				if traceID := cf.Metadata().TraceID(); traceID != "" {
 					sys[frames.MetaTraceID] = traceID
 				}
-				return []frames.Frame{frames.NewSystemFrame(cf.Meta()[frames.MetaStreamID], time.Now().UnixNano(), "global_update", sys), f}, nil
+				return []frames.Frame{frames.NewSystemFrame(cf.Metadata().StreamID(), time.Now().UnixNano(), "global_update", sys), f}, nil
 			}
 		}
 	case frames.KindSystem:
 		sf := f.(frames.SystemFrame)
 		if sf.Name() == "call_end" {
-			p.resetStream(sf.Meta()[frames.MetaStreamID])
+			p.resetStream(sf.Metadata().StreamID())
 		}
 	case frames.KindText:
 		tf := f.(frames.TextFrame)
@@ -106,7 +106,7 @@ func (p *RouterProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 		streamID := meta[frames.MetaStreamID]
 		if meta[frames.MetaSource] == "stt" && p.strategy != nil {
 			var out []frames.Frame
-			final := isFinal(meta)
+			final := isFinalText(tf)
 			if final && (p.codeSwitching || !p.hasLanguage(streamID, meta)) {
 				lang, conf := p.detectLanguage(tf.Text(), meta)
 				if lang != "" && conf >= p.langMinConfidence {
@@ -147,12 +147,12 @@ func (p *RouterProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 			if agent := p.getAgent(streamID); agent != "" {
 				meta[frames.MetaAgent] = agent
 			}
-			out = append(out, frames.NewTextFrame(streamID, tf.PTS(), tf.Text(), meta))
+			out = append(out, tf.WithMeta(streamID, meta))
 			return out, nil
 		}
 		if agent := p.getAgent(streamID); agent != "" {
 			meta[frames.MetaAgent] = agent
-			return []frames.Frame{frames.NewTextFrame(streamID, tf.PTS(), tf.Text(), meta)}, nil
+			return []frames.Frame{tf.WithMeta(streamID, meta)}, nil
 		}
 	}
 	return []frames.Frame{f}, nil
@@ -248,6 +248,20 @@ func (p *RouterProcessor) languagePrompt(lang string) string {
 	return p.langPrompts[lang]
 }
 
+// OnSessionEnd implements pipeline.SessionEndHandler.
+func (p *RouterProcessor) OnSessionEnd(meta map[string]string) {
+	p.resetStream(meta[frames.MetaStreamID])
+}
+
+// MigrateStream implements pipeline.StreamMigrator.
+func (p *RouterProcessor) MigrateStream(oldStreamID, newStreamID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	moveKey(p.active, oldStreamID, newStreamID)
+	moveKey(p.langActive, oldStreamID, newStreamID)
+	moveKey(p.turnCount, oldStreamID, newStreamID)
+}
+
 func (p *RouterProcessor) resetStream(streamID string) {
 	if streamID == "" {
 		return
@@ -284,3 +298,5 @@ func formatConfidence(v float64) string {
 }
 
 var _ pipeline.FrameProcessor = (*RouterProcessor)(nil)
+var _ pipeline.SessionEndHandler = (*RouterProcessor)(nil)
+var _ pipeline.StreamMigrator = (*RouterProcessor)(nil)
@@ -160,12 +160,7 @@ func (p *STTProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 		meta := sf.Meta()
 		streamID := meta[frames.MetaStreamID]
 		if sf.Name() == "call_end" {
-			if streamID == "" {
-				streamID = p.streamForCall(meta[frames.MetaCallSID])
-			}
-			if streamID != "" {
-				p.CloseStream(streamID)
-			}
+			p.OnSessionEnd(meta)
 			return []frames.Frame{f}, nil
 		}
 		if lang := meta[frames.MetaGlobalLanguage]; streamID != "" && lang != "" {
@@ -183,15 +178,15 @@ func (p *STTProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 		return []frames.Frame{f}, nil
 	}
 	af := f.(frames.AudioFrame)
-	meta := af.Meta()
-	streamID := meta[frames.MetaStreamID]
-	callSID := meta[frames.MetaCallSID]
+	md := af.Metadata()
+	streamID := md.StreamID()
+	callSID := md.CallSID()
 	p.trackCallStream(callSID, streamID)
 	p.addReplay(streamID, af)
-	if v := meta[frames.MetaFromNumber]; v != "" {
+	if v := md.Get(frames.MetaFromNumber); v != "" {
 		p.setFrom(streamID, v)
 	}
-	if v := meta[frames.MetaTraceID]; v != "" {
+	if v := md.TraceID(); v != "" {
 		p.setTrace(streamID, v)
 	}
 
@@ -200,7 +195,7 @@ func (p *STTProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 		p.setBreakerOpen(true, streamID, p.getTrace(streamID))
 		slog.Info("stt_circuit_open", "stream_id", streamID, "reason_code", string(errorsx.ReasonSTTCircuitOpen))
 		frames.ReleaseAudioFrame(f)
-		return []frames.Frame{frames.NewControlFrame(streamID, time.Now().UnixNano(), frames.ControlFallback, meta)}, nil
+		return []frames.Frame{frames.NewControlFrame(streamID, time.Now().UnixNano(), frames.ControlFallback, md.Map())}, nil
 	}
 	p.setBreakerOpen(false, streamID, p.getTrace(streamID))
 
@@ -211,7 +206,7 @@ func (p *STTProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 		p.recordRateLimit(err, streamID, p.getTrace(streamID))
 		p.breaker.OnError(err)
 		frames.ReleaseAudioFrame(f)
-		return []frames.Frame{frames.NewControlFrame(streamID, time.Now().UnixNano(), frames.ControlFallback, meta)}, nil
+		return []frames.Frame{frames.NewControlFrame(streamID, time.Now().UnixNano(), frames.ControlFallback, md.Map())}, nil
 	}
 	p.setProviderFromSession(sttSession)
 	p.record("stt_audio_in", streamID, p.getTrace(streamID))
@@ -237,7 +232,7 @@ func (p *STTProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 			p.recordRateLimit(retryErr, streamID, p.getTrace(streamID))
 			p.breaker.OnError(retryErr)
 			frames.ReleaseAudioFrame(f)
-			return []frames.Frame{frames.NewControlFrame(streamID, time.Now().UnixNano(), frames.ControlFallback, meta)}, nil
+			return []frames.Frame{frames.NewControlFrame(streamID, time.Now().UnixNano(), frames.ControlFallback, md.Map())}, nil
 		}
 	}
 	p.breaker.OnSuccess()
@@ -297,6 +292,28 @@ func (p *STTProcessor) CloseStream(streamID string) {
 	delete(p.replay, streamID)
 }
 
+// MigrateStream implements pipeline.StreamMigrator. The vendor session is
+// bound to the old stream, so it is closed and a new one opens on the next
+// audio frame; language, caller and trace carry over.
+func (p *STTProcessor) MigrateStream(oldStreamID, newStreamID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if sttSession, ok := p.sessions[oldStreamID]; ok {
+		_ = sttSession.Close()
+		delete(p.sessions, oldStreamID)
+	}
+	if callSID := p.streamCall[oldStreamID]; callSID != "" {
+		p.callStream[callSID] = newStreamID
+		p.streamCall[newStreamID] = callSID
+		delete(p.streamCall, oldStreamID)
+	}
+	moveKey(p.streamLang, oldStreamID, newStreamID)
+	moveKey(p.from, oldStreamID, newStreamID)
+	moveKey(p.trace, oldStreamID, newStreamID)
+	delete(p.replay, oldStreamID)
+	delete(p.interimLogged, oldStreamID)
+}
+
 func (p *STTProcessor) streamForCall(callSID string) string {
 	if callSID == "" {
 		return ""
@@ -306,6 +323,23 @@ func (p *STTProcessor) streamForCall(callSID string) string {
 	return p.callStream[callSID]
 }
 
+// OnSessionEnd implements pipeline.SessionEndHandler.
+func (p *STTProcessor) OnSessionEnd(meta map[string]string) {
+	streamID := meta[frames.MetaStreamID]
+	if streamID == "" {
+		streamID = p.streamForCall(meta[frames.MetaCallSID])
+	}
+	if streamID != "" {
+		p.CloseStream(streamID)
+	}
+}
+
+// Close implements pipeline.ProcessorCloser.
+func (p *STTProcessor) Close() error {
+	p.CloseAll()
+	return nil
+}
+
 func (p *STTProcessor) CloseAll() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -342,14 +376,14 @@ func (p *STTProcessor) drainFramesWithSignals(ch <-chan frames.Frame, streamID s
 					}
 					out = append(out, frames.NewControlFrame(streamID, time.Now().UnixNano(), frames.ControlFlush, meta))
 				}
-				if tf.Meta()[frames.MetaIsFinal] != "true" {
+				if !isFinalText(tf) {
 					p.logInterim(streamID, tf.Text())
 					if forwardInterim {
 						out = append(out, tf)
 					}
 					continue
 				}
-				p.logFinal(streamID, tf.Text())
+				p.logFinal(streamID, tf)
 				out = append(out, tf)
 				continue
 			}
@@ -361,6 +395,9 @@ func (p *STTProcessor) drainFramesWithSignals(ch <-chan frames.Frame, streamID s
 }
 
 var _ pipeline.FrameProcessor = (*STTProcessor)(nil)
+var _ pipeline.SessionEndHandler = (*STTProcessor)(nil)
+var _ pipeline.StreamMigrator = (*STTProcessor)(nil)
+var _ pipeline.ProcessorCloser = (*STTProcessor)(nil)
 
 func (p *STTProcessor) record(name, streamID, traceID string) {
 	if p.obs == nil {
@@ -583,7 +620,7 @@ func (p *STTProcessor) attachFrom(framesIn []frames.Frame, streamID string) []fr
 		if meta[frames.MetaTraceID] == "" && traceID != "" {
 			meta[frames.MetaTraceID] = traceID
 		}
-		out = append(out, frames.NewTextFrame(streamID, tf.PTS(), tf.Text(), meta))
+		out = append(out, tf.WithMeta(streamID, meta))
 	}
 	return out
 }
@@ -601,11 +638,43 @@ func (p *STTProcessor) logInterim(streamID, text string) {
 	slog.Info("stt_interim", "stream_id", streamID, "trace_id", traceID, "text", clipText(safe))
 }
 
-func (p *STTProcessor) logFinal(streamID, text string) {
+func (p *STTProcessor) logFinal(streamID string, tf frames.TextFrame) {
 	traceID := p.getTrace(streamID)
-	safe := redact.Text(text)
+	safe := redact.Text(tf.Text())
 	slog.Info("stt_final", "stream_id", streamID, "trace_id", traceID, "text", clipText(safe))
-	p.recordWithFields("stt_final_text", streamID, traceID, map[string]any{"text": safe})
+	fields := map[string]any{"text": safe}
+	if tr, ok := tf.Transcript(); ok {
+		addTranscriptFields(fields, tr)
+	}
+	p.recordWithFields("stt_final_text", streamID, traceID, fields)
+}
+
+// addTranscriptFields adds confidence, language and word timings for the
+// timeline. Word text is left out; the redacted utterance is in "text".
+func addTranscriptFields(fields map[string]any, tr frames.Transcript) {
+	fields["confidence"] = tr.Confidence
+	if tr.Language != "" {
+		fields["language"] = tr.Language
+	}
+	if tr.Speaker != "" {
+		fields["speaker"] = tr.Speaker
+	}
+	if len(tr.Words) == 0 {
+		return
+	}
+	words := make([]map[string]any, 0, len(tr.Words))
+	for _, w := range tr.Words {
+		word := map[string]any{
+			"start_ms":   w.Start.Milliseconds(),
+			"end_ms":     w.End.Milliseconds(),
+			"confidence": w.Confidence,
+		}
+		if w.Speaker != "" {
+			word["speaker"] = w.Speaker
+		}
+		words = append(words, word)
+	}
+	fields["words"] = words
 }
 
 func clipText(text string) string {
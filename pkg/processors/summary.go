@@ -23,6 +23,7 @@ type SummaryProcessor struct {
 	lastTraceID map[string]string
 	lastCallSID map[string]string
 	obs         metrics.Observer
+	onSummary   func(callSID, summary string)
 }
 
 type summaryEntry struct {
@@ -50,22 +51,25 @@ func (p *SummaryProcessor) Name() string { return "summary_processor" }
 
 func (p *SummaryProcessor) SetObserver(obs metrics.Observer) { p.obs = obs }
 
+// SetOnSummary registers fn to receive each call's summary when it ends.
+func (p *SummaryProcessor) SetOnSummary(fn func(callSID, summary string)) { p.onSummary = fn }
+
 func (p *SummaryProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
-	streamID := f.Meta()[frames.MetaStreamID]
+	streamID := frames.StreamIDOf(f)
 	if streamID == "" {
 		return []frames.Frame{f}, nil
 	}
-	if traceID := f.Meta()[frames.MetaTraceID]; traceID != "" {
+	if traceID := frames.TraceIDOf(f); traceID != "" {
 		p.mu.Lock()
 		p.lastTraceID[streamID] = traceID
 		p.mu.Unlock()
 	}
-	if callSID := f.Meta()[frames.MetaCallSID]; callSID != "" {
+	if callSID := frames.CallSIDOf(f); callSID != "" {
 		p.mu.Lock()
 		p.lastCallSID[streamID] = callSID
 		p.mu.Unlock()
 	}
-	if lang := strings.ToLower(strings.TrimSpace(f.Meta()[frames.MetaLanguage])); lang != "" {
+	if lang := strings.ToLower(strings.TrimSpace(frames.MetaValue(f, frames.MetaLanguage))); lang != "" {
 		p.mu.Lock()
 		p.lastLang[streamID] = lang
 		p.mu.Unlock()
@@ -83,6 +87,14 @@ func (p *SummaryProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 			role = "agent"
 		}
 		p.append(streamID, role, tf.Text())
+	case frames.KindControl:
+		cf := f.(frames.ControlFrame)
+		meta := cf.Meta()
+		// Warm transfers brief the human with the running summary.
+		if cf.Code() == frames.ControlTransfer && meta[frames.MetaTransferMode] == "warm" && meta[frames.MetaCallSummary] == "" {
+			meta[frames.MetaCallSummary] = p.buildSummary(streamID)
+			return []frames.Frame{frames.NewControlFrame(streamID, cf.PTS(), cf.Code(), meta)}, nil
+		}
 	case frames.KindSystem:
 		sf := f.(frames.SystemFrame)
 		if sf.Name() == "call_end" {
@@ -176,6 +188,9 @@ func clipSummary(text string) string {
 }
 
 func (p *SummaryProcessor) recordSummary(streamID, summary string) {
+	if p.onSummary != nil {
+		p.onSummary(p.getCallSID(streamID), summary)
+	}
 	if p.obs == nil {
 		return
 	}
@@ -194,6 +209,33 @@ func (p *SummaryProcessor) recordSummary(streamID, summary string) {
 	})
 }
 
+// OnSessionEnd implements pipeline.SessionEndHandler. It records the summary
+// of a session whose call_end frame never reached this processor.
+func (p *SummaryProcessor) OnSessionEnd(meta map[string]string) {
+	streamID := meta[frames.MetaStreamID]
+	if streamID == "" {
+		return
+	}
+	p.mu.Lock()
+	pending := len(p.entries[streamID]) > 0
+	p.mu.Unlock()
+	if pending {
+		p.recordSummary(streamID, p.buildSummary(streamID))
+	}
+	p.clear(streamID)
+}
+
+// MigrateStream implements pipeline.StreamMigrator, so one summary
+// covers the whole call.
+func (p *SummaryProcessor) MigrateStream(oldStreamID, newStreamID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	moveKey(p.entries, oldStreamID, newStreamID)
+	moveKey(p.lastLang, oldStreamID, newStreamID)
+	moveKey(p.lastTraceID, oldStreamID, newStreamID)
+	moveKey(p.lastCallSID, oldStreamID, newStreamID)
+}
+
 func (p *SummaryProcessor) clear(streamID string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -222,3 +264,5 @@ func (p *SummaryProcessor) getLang(streamID string) string {
 }
 
 var _ pipeline.FrameProcessor = (*SummaryProcessor)(nil)
+var _ pipeline.SessionEndHandler = (*SummaryProcessor)(nil)
+var _ pipeline.StreamMigrator = (*SummaryProcessor)(nil)
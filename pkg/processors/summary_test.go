@@ -38,3 +38,25 @@ func TestSummaryProcessorEmitsSummaryOnCallEnd(t *testing.T) {
 		t.Fatalf("call_summary not emitted")
 	}
 }
+
+func TestSummaryProcessorBriefsWarmTransfer(t *testing.T) {
+	proc := NewSummaryProcessor(SummaryConfig{})
+	streamID := "stream-1"
+	meta := map[string]string{frames.MetaStreamID: streamID, frames.MetaSource: "stt", frames.MetaIsFinal: "true", frames.MetaLanguage: "en"}
+	_, _ = proc.Process(frames.NewTextFrame(streamID, time.Now().UnixNano(), "I want to talk to a person", meta))
+
+	transfer := map[string]string{frames.MetaStreamID: streamID, frames.MetaTransferMode: "warm"}
+	out, _ := proc.Process(frames.NewControlFrame(streamID, time.Now().UnixNano(), frames.ControlTransfer, transfer))
+	if len(out) != 1 {
+		t.Fatalf("expected one frame, got %d", len(out))
+	}
+	if got := out[0].(frames.ControlFrame).Meta()[frames.MetaCallSummary]; got == "" || got == defaultSummary("en") {
+		t.Fatalf("expected running summary on warm transfer, got %q", got)
+	}
+
+	transfer[frames.MetaTransferMode] = "cold"
+	out, _ = proc.Process(frames.NewControlFrame(streamID, time.Now().UnixNano(), frames.ControlTransfer, transfer))
+	if out[0].(frames.ControlFrame).Meta()[frames.MetaCallSummary] != "" {
+		t.Fatalf("cold transfers should not carry a summary")
+	}
+}
@@ -0,0 +1,59 @@
+package processors
+
+import (
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/metrics"
+	"github.com/harunnryd/ranya/pkg/redact"
+)
+
+// System frames a human supervisor uses to steer a call. A whisper carries
+// guidance in MetaSystemMessage that only the LLM sees. Between a takeover
+// and a release the LLM stays quiet: caller turns go into the history only,
+// and supervisor_say frames speak the supervisor's MetaGreetingText instead.
+const (
+	FrameSupervisorWhisper  = "supervisor_whisper"
+	FrameSupervisorTakeover = "supervisor_takeover"
+	FrameSupervisorSay      = "supervisor_say"
+	FrameSupervisorRelease  = "supervisor_release"
+)
+
+// handleSupervisor applies and records supervisor frames. It reports true
+// for frames to drop, which are silence reprompts during a takeover.
+func (p *LLMProcessor) handleSupervisor(sf frames.SystemFrame) bool {
+	meta := sf.Meta()
+	streamID := meta[frames.MetaStreamID]
+	switch sf.Name() {
+	case FrameSupervisorWhisper:
+		p.recordSupervisor(metrics.EventSupervisorWhisper, meta, meta[frames.MetaSystemMessage])
+	case FrameSupervisorSay:
+		p.recordSupervisor(metrics.EventSupervisorSay, meta, meta[frames.MetaGreetingText])
+	case FrameSupervisorTakeover:
+		p.mu.Lock()
+		p.takenOver[streamID] = true
+		p.mu.Unlock()
+		p.recordSupervisor(metrics.EventSupervisorTakeover, meta, "")
+	case FrameSupervisorRelease:
+		p.mu.Lock()
+		delete(p.takenOver, streamID)
+		p.mu.Unlock()
+		p.recordSupervisor(metrics.EventSupervisorRelease, meta, "")
+	case "reprompt":
+		return p.isTakenOver(streamID)
+	}
+	return false
+}
+
+func (p *LLMProcessor) isTakenOver(streamID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.takenOver[streamID]
+}
+
+func (p *LLMProcessor) recordSupervisor(name string, meta map[string]string, text string) {
+	p.setCallSIDFromMeta(meta)
+	fields := map[string]any{"supervisor": meta[frames.MetaSupervisor]}
+	if text != "" {
+		fields["text"] = redact.Text(text)
+	}
+	p.recordWithFields(name, meta[frames.MetaStreamID], meta[frames.MetaTraceID], fields)
+}
@@ -0,0 +1,68 @@
+package processors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	mockllm "github.com/harunnryd/ranya/pkg/providers/mock"
+)
+
+func TestLLMSupervisorTakeover(t *testing.T) {
+	proc := NewLLMProcessor(mockllm.NewLLMAdapter(mockllm.LLMConfig{ResponseText: "agent reply"}), "", nil)
+	meta := func(extra map[string]string) map[string]string {
+		m := map[string]string{frames.MetaStreamID: "stream-1", frames.MetaCallSID: "call-1"}
+		for k, v := range extra {
+			m[k] = v
+		}
+		return m
+	}
+	system := func(name string, extra map[string]string) []frames.Frame {
+		out, err := proc.Process(frames.NewSystemFrame("stream-1", time.Now().UnixNano(), name, meta(extra)))
+		if err != nil {
+			t.Fatalf("process %s: %v", name, err)
+		}
+		return out
+	}
+	caller := func(text string) []frames.Frame {
+		out, err := proc.Process(frames.NewTextFrame("stream-1", time.Now().UnixNano(), text, meta(map[string]string{frames.MetaSource: "stt"})))
+		if err != nil {
+			t.Fatalf("process caller text: %v", err)
+		}
+		return out
+	}
+
+	system(FrameSupervisorWhisper, map[string]string{frames.MetaSystemMessage: "Offer a refund."})
+	system(FrameSupervisorTakeover, nil)
+	if out := caller("I want a refund"); len(out) != 0 {
+		t.Fatalf("expected the agent to stay quiet during a takeover, got %d frames", len(out))
+	}
+	if out := system("reprompt", map[string]string{frames.MetaGreetingText: "Are you there?"}); len(out) != 0 {
+		t.Fatalf("expected silence reprompts to be dropped during a takeover")
+	}
+	out := system(FrameSupervisorSay, map[string]string{frames.MetaGreetingText: "I'll process it now."})
+	if len(out) != 1 || out[0].Kind() != frames.KindText || out[0].(frames.TextFrame).Text() != "I'll process it now." {
+		t.Fatalf("expected the supervisor's text to be spoken, got %#v", out)
+	}
+
+	snap := proc.Snapshot("call-1", "stream-1")
+	var sawWhisper, sawCaller, sawSupervisor bool
+	for _, msg := range snap.History {
+		switch msg["content"] {
+		case "Offer a refund.":
+			sawWhisper = msg["role"] == "system"
+		case "I want a refund":
+			sawCaller = msg["role"] == "user"
+		case "I'll process it now.":
+			sawSupervisor = msg["role"] == "assistant"
+		}
+	}
+	if !sawWhisper || !sawCaller || !sawSupervisor {
+		t.Fatalf("expected whisper, caller and supervisor turns in history, got %v", snap.History)
+	}
+
+	system(FrameSupervisorRelease, nil)
+	if out := caller("Thanks"); len(out) == 0 {
+		t.Fatalf("expected the agent to answer after release")
+	}
+}
@@ -29,6 +29,7 @@ type TTSProcessor struct {
 	trace         map[string]string
 	callStream    map[string]string
 	streamCall    map[string]string
+	speech        map[string]*ttsSpeech
 
 	// Native parameters (optional)
 	outputFormat string
@@ -43,6 +44,20 @@ type TTSProcessor struct {
 	logger *slog.Logger
 }
 
+// Vendors send no end-of-utterance signal, so a stream counts as speaking
+// from the moment text is sent until its audio stops for ttsTailGap, or
+// until ttsFirstAudioWait passes without any audio.
+const (
+	ttsTailGap        = 400 * time.Millisecond
+	ttsFirstAudioWait = 5 * time.Second
+)
+
+// ttsSpeech tracks synthesis on one stream for Speaking.
+type ttsSpeech struct {
+	textAt  time.Time
+	audioAt time.Time
+}
+
 type flushSender interface {
 	SendTextWithOptions(text string, flush bool) error
 }
@@ -56,6 +71,7 @@ func NewTTSProcessor(factory func(callSID, streamID string) tts.StreamingTTS) *T
 		trace:         make(map[string]string),
 		callStream:    make(map[string]string),
 		streamCall:    make(map[string]string),
+		speech:        make(map[string]*ttsSpeech),
 		outputFormat:  "ulaw_8000",
 		breaker:       resilience.NewCircuitBreaker(3, 30*time.Second),
 		retry:         resilience.NewRetryPolicy(2, 200*time.Millisecond),
@@ -115,11 +131,11 @@ func (p *TTSProcessor) trackCallStream(callSID, streamID string) {
 }
 
 func (p *TTSProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
-	streamID := f.Meta()[frames.MetaStreamID]
-	if callSID := f.Meta()[frames.MetaCallSID]; callSID != "" {
+	streamID := frames.StreamIDOf(f)
+	if callSID := frames.CallSIDOf(f); callSID != "" {
 		p.trackCallStream(callSID, streamID)
 	}
-	lang := f.Meta()[frames.MetaLanguage]
+	lang := frames.MetaValue(f, frames.MetaLanguage)
 	if lang == "" {
 		lang = p.defaultLang
 	}
@@ -128,12 +144,7 @@ func (p *TTSProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 	if f.Kind() == frames.KindSystem {
 		sf := f.(frames.SystemFrame)
 		if sf.Name() == "call_end" {
-			if streamID == "" {
-				streamID = p.streamForCall(sf.Meta()[frames.MetaCallSID])
-			}
-			if streamID != "" {
-				p.CloseStream(streamID)
-			}
+			p.OnSessionEnd(sf.Meta())
 			return []frames.Frame{f}, nil
 		}
 	}
@@ -141,6 +152,7 @@ func (p *TTSProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 	if f.Kind() == frames.KindControl {
 		cf := f.(frames.ControlFrame)
 		if cf.Code() == frames.ControlStartInterruption {
+			p.forgetSpeech(streamID)
 			p.withSessions(streamID, func(ttsSession tts.StreamingTTS) {
 				ttsSession.Flush()
 				p.logger.Info("tts interruption received",
@@ -183,7 +195,7 @@ func (p *TTSProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 			p.CloseStream(streamID)
 		} else if cf.Code() == frames.ControlAudioReady {
 			p.logger.Debug("tts webhook flush",
-				slog.String("stream_id", cf.Meta()[frames.MetaStreamID]))
+				slog.String("stream_id", cf.Metadata().StreamID()))
 			drain()
 		}
 		out = append(out, f)
@@ -300,6 +312,7 @@ func (p *TTSProcessor) Process(f frames.Frame) ([]frames.Frame, error) {
 		}
 
 		p.breaker.OnSuccess()
+		p.markText(streamID)
 		p.logger.Debug("tts request successful",
 			slog.String("stream_id", streamID))
 		if flushRequested {
@@ -367,6 +380,7 @@ func (p *TTSProcessor) CloseStream(streamID string) {
 	for key, ttsSession := range p.sessions {
 		if strings.HasPrefix(key, streamID+"|") || key == streamID {
 			_ = ttsSession.Close()
+			discardTTS(ttsSession)
 			delete(p.sessions, key)
 		}
 	}
@@ -378,6 +392,30 @@ func (p *TTSProcessor) CloseStream(streamID string) {
 	}
 	delete(p.first, streamID)
 	delete(p.trace, streamID)
+	delete(p.speech, streamID)
+}
+
+// MigrateStream implements pipeline.StreamMigrator. Vendor sessions emit
+// audio tagged with their stream, so the old ones are closed rather than
+// moved; the next utterance opens a session on the new stream.
+func (p *TTSProcessor) MigrateStream(oldStreamID, newStreamID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, ttsSession := range p.sessions {
+		if strings.HasPrefix(key, oldStreamID+"|") || key == oldStreamID {
+			_ = ttsSession.Close()
+			discardTTS(ttsSession)
+			delete(p.sessions, key)
+		}
+	}
+	if callSID := p.streamCall[oldStreamID]; callSID != "" {
+		p.callStream[callSID] = newStreamID
+		p.streamCall[newStreamID] = callSID
+		delete(p.streamCall, oldStreamID)
+	}
+	delete(p.first, oldStreamID)
+	moveKey(p.trace, oldStreamID, newStreamID)
+	delete(p.speech, oldStreamID)
 }
 
 func (p *TTSProcessor) streamForCall(callSID string) string {
@@ -389,17 +427,36 @@ func (p *TTSProcessor) streamForCall(callSID string) string {
 	return p.callStream[callSID]
 }
 
+// OnSessionEnd implements pipeline.SessionEndHandler.
+func (p *TTSProcessor) OnSessionEnd(meta map[string]string) {
+	streamID := meta[frames.MetaStreamID]
+	if streamID == "" {
+		streamID = p.streamForCall(meta[frames.MetaCallSID])
+	}
+	if streamID != "" {
+		p.CloseStream(streamID)
+	}
+}
+
+// Close implements pipeline.ProcessorCloser.
+func (p *TTSProcessor) Close() error {
+	p.CloseAll()
+	return nil
+}
+
 func (p *TTSProcessor) CloseAll() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	for id, ttsSession := range p.sessions {
 		_ = ttsSession.Close()
+		discardTTS(ttsSession)
 		delete(p.sessions, id)
 	}
 	p.first = make(map[string]bool)
 	p.trace = make(map[string]string)
 	p.callStream = make(map[string]string)
 	p.streamCall = make(map[string]string)
+	p.speech = make(map[string]*ttsSpeech)
 }
 
 func drainTTS(ch <-chan frames.Frame) []frames.Frame {
@@ -417,7 +474,17 @@ func drainTTS(ch <-chan frames.Frame) []frames.Frame {
 	}
 }
 
+// discardTTS releases audio a closed session produced but nobody drained.
+func discardTTS(sess tts.StreamingTTS) {
+	for _, f := range drainTTS(sess.Results()) {
+		frames.ReleaseAudioFrame(f)
+	}
+}
+
 var _ pipeline.FrameProcessor = (*TTSProcessor)(nil)
+var _ pipeline.SessionEndHandler = (*TTSProcessor)(nil)
+var _ pipeline.StreamMigrator = (*TTSProcessor)(nil)
+var _ pipeline.ProcessorCloser = (*TTSProcessor)(nil)
 
 func sessionKey(streamID, lang string) string {
 	if streamID == "" {
@@ -470,9 +537,52 @@ func (p *TTSProcessor) drainAll(streamID string) []frames.Frame {
 	p.withSessions(streamID, func(sess tts.StreamingTTS) {
 		out = append(out, drainTTS(sess.Results())...)
 	})
+	for _, f := range out {
+		if f.Kind() == frames.KindAudio {
+			p.mu.Lock()
+			if st := p.speech[streamID]; st != nil {
+				st.audioAt = time.Now()
+			}
+			p.mu.Unlock()
+			break
+		}
+	}
 	return out
 }
 
+func (p *TTSProcessor) markText(streamID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := p.speech[streamID]
+	if st == nil {
+		st = &ttsSpeech{}
+		p.speech[streamID] = st
+	}
+	st.textAt = time.Now()
+}
+
+func (p *TTSProcessor) forgetSpeech(streamID string) {
+	p.mu.Lock()
+	delete(p.speech, streamID)
+	p.mu.Unlock()
+}
+
+// Speaking reports whether text sent on the stream may still be turning
+// into audio. The engine waits for it before hanging up, so a goodbye that
+// is slow to synthesize is still heard.
+func (p *TTSProcessor) Speaking(streamID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := p.speech[streamID]
+	if st == nil {
+		return false
+	}
+	if st.audioAt.Before(st.textAt) {
+		return time.Since(st.textAt) < ttsFirstAudioWait
+	}
+	return time.Since(st.audioAt) < ttsTailGap
+}
+
 func (p *TTSProcessor) recordFirst(streamID string) {
 	if p.obs == nil {
 		return
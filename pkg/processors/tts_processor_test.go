@@ -55,3 +55,44 @@ func TestTTSProcessorInterruptionFlush(t *testing.T) {
 		t.Fatalf("expected flush to be called on interruption")
 	}
 }
+
+func TestTTSProcessorSpeakingUntilAudioStops(t *testing.T) {
+	mock := &mockTTS{out: make(chan frames.Frame, 4)}
+	proc := NewTTSProcessor(func(callSID, streamID string) tts.StreamingTTS { return mock })
+
+	meta := map[string]string{frames.MetaStreamID: "stream-1", frames.MetaSource: "llm"}
+	if proc.Speaking("stream-1") {
+		t.Fatalf("expected idle stream before any text")
+	}
+	if _, err := proc.Process(frames.NewTextFrame("stream-1", time.Now().UnixNano(), "Goodbye!", meta)); err != nil {
+		t.Fatalf("process text: %v", err)
+	}
+	if !proc.Speaking("stream-1") {
+		t.Fatalf("expected speaking while waiting for first audio")
+	}
+
+	mock.out <- frames.NewAudioFrame("stream-1", time.Now().UnixNano(), make([]byte, 320), 16000, 1, meta)
+	tick := frames.NewControlFrame("stream-1", time.Now().UnixNano(), frames.ControlFlush, map[string]string{frames.MetaStreamID: "stream-1"})
+	out, err := proc.Process(tick)
+	if err != nil || len(out) == 0 {
+		t.Fatalf("expected drained audio, got %v err=%v", out, err)
+	}
+	if !proc.Speaking("stream-1") {
+		t.Fatalf("expected speaking right after audio")
+	}
+	time.Sleep(ttsTailGap + 50*time.Millisecond)
+	if proc.Speaking("stream-1") {
+		t.Fatalf("expected utterance finished once audio stopped")
+	}
+
+	if _, err := proc.Process(frames.NewTextFrame("stream-1", time.Now().UnixNano(), "One more thing.", meta)); err != nil {
+		t.Fatalf("process text: %v", err)
+	}
+	interrupt := frames.NewControlFrame("stream-1", time.Now().UnixNano(), frames.ControlStartInterruption, map[string]string{frames.MetaStreamID: "stream-1"})
+	if _, err := proc.Process(interrupt); err != nil {
+		t.Fatalf("process interruption: %v", err)
+	}
+	if proc.Speaking("stream-1") {
+		t.Fatalf("expected interruption to end the utterance")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/harunnryd/ranya/pkg/adapters/stt"
@@ -245,18 +246,14 @@ func (c *callback) Message(mr *msginterfaces.MessageResponse) error {
 	if c.parent.cfg.TraceID != "" {
 		meta[frames.MetaTraceID] = c.parent.cfg.TraceID
 	}
-	if isFinal {
-		meta[frames.MetaIsFinal] = "true"
-	} else {
-		meta[frames.MetaIsFinal] = "false"
-	}
 
 	c.parent.logger.Debug("transcript_received",
 		slog.String("stream_id", c.parent.cfg.StreamID),
 		slog.String("transcript", transcript),
 		slog.Bool("is_final", isFinal))
 
-	f := frames.NewTextFrame(c.parent.cfg.StreamID, time.Now().UnixNano(), transcript, meta)
+	tr := toTranscript(mr, alt, isFinal)
+	f := frames.NewTranscriptFrame(c.parent.cfg.StreamID, time.Now().UnixNano(), tr, meta)
 
 	select {
 	case c.parent.out <- f:
@@ -289,6 +286,59 @@ func (c *callback) Message(mr *msginterfaces.MessageResponse) error {
 	return nil
 }
 
+// toTranscript converts a Deepgram result into the typed transcript. Deepgram
+// reports times in seconds from the start of the stream.
+// toTranscript maps a Deepgram alternative to a transcript. Language is set
+// only when Deepgram detected one, never from the configured model language,
+// so downstream language detection still runs.
+func toTranscript(mr *msginterfaces.MessageResponse, alt msginterfaces.Alternative, isFinal bool) frames.Transcript {
+	tr := frames.Transcript{
+		Text:       alt.Transcript,
+		Final:      isFinal,
+		Confidence: alt.Confidence,
+		Start:      seconds(mr.Start),
+		Duration:   seconds(mr.Duration),
+		Words:      make([]frames.Word, 0, len(alt.Words)),
+	}
+	speaker, sameSpeaker := "", true
+	lang, sameLang := "", true
+	for i, w := range alt.Words {
+		word := frames.Word{
+			Text:       w.Word,
+			Punctuated: w.PunctuatedWord,
+			Start:      seconds(w.Start),
+			End:        seconds(w.End),
+			Confidence: w.Confidence,
+			Language:   w.Language,
+		}
+		if w.Speaker != nil {
+			word.Speaker = strconv.Itoa(*w.Speaker)
+		}
+		if i == 0 {
+			speaker = word.Speaker
+			lang = word.Language
+		} else {
+			sameSpeaker = sameSpeaker && word.Speaker == speaker
+			sameLang = sameLang && word.Language == lang
+		}
+		tr.Words = append(tr.Words, word)
+	}
+	if sameSpeaker {
+		tr.Speaker = speaker
+	}
+	switch {
+	case len(alt.Languages) > 0 && alt.Languages[0] != "":
+		tr.Language = alt.Languages[0]
+	case sameLang:
+		tr.Language = lang
+	}
+	return tr
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
 func (c *callback) Metadata(md *msginterfaces.MetadataResponse) error {
 	if !c.parent.metaLogged {
 		c.parent.metaLogged = true
@@ -2,7 +2,6 @@ package elevenlabs
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"log/slog"
@@ -244,7 +243,7 @@ func (s *ElevenLabsTTS) handleMessage(data []byte) {
 			return
 		}
 	}
-	raw, err := base64.StdEncoding.DecodeString(audio)
+	raw, err := frames.DecodeAudioBase64(audio)
 	if err != nil {
 		slog.Error("tts audio decode error", "error", err)
 		return
@@ -272,7 +271,7 @@ func (s *ElevenLabsTTS) handleMessage(data []byte) {
 	}
 
 	// Create AudioFrame with native format metadata
-	f := frames.NewAudioFrame(s.cfg.StreamID, time.Now().UnixNano(), raw, s.cfg.SampleRate, 1, meta)
+	f := frames.NewAudioFrameFromBuf(s.cfg.StreamID, time.Now().UnixNano(), raw, s.cfg.SampleRate, 1, meta)
 
 	select {
 	case s.out <- f:
@@ -283,6 +282,7 @@ func (s *ElevenLabsTTS) handleMessage(data []byte) {
 	default:
 		slog.Warn("tts output buffer full",
 			slog.String("stream_id", s.cfg.StreamID))
+		frames.ReleaseAudioFrame(f)
 	}
 }
 
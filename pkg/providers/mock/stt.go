@@ -3,6 +3,7 @@ package mock
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,6 +20,12 @@ type STTConfig struct {
 	EmitInterim       bool
 	EmitVAD           bool
 	EmitUtteranceEnd  bool
+	// Confidence, Language and Speaker populate the typed transcript. Words
+	// get synthetic timings of WordDuration each (default 300ms).
+	Confidence   float64
+	Language     string
+	Speaker      string
+	WordDuration time.Duration
 }
 
 type StreamingSTT struct {
@@ -35,6 +42,12 @@ func NewSTT(cfg STTConfig) *StreamingSTT {
 	if cfg.Transcript == "" {
 		cfg.Transcript = "mock transcript"
 	}
+	if cfg.Confidence <= 0 {
+		cfg.Confidence = 0.99
+	}
+	if cfg.WordDuration <= 0 {
+		cfg.WordDuration = 300 * time.Millisecond
+	}
 	return &StreamingSTT{cfg: cfg, out: make(chan frames.Frame, 16)}
 }
 
@@ -101,24 +114,22 @@ func (s *StreamingSTT) SendAudio(frame frames.AudioFrame) error {
 			frames.MetaStreamID: s.cfg.StreamID,
 			frames.MetaCallSID:  s.cfg.CallSID,
 			frames.MetaSource:   "stt",
-			frames.MetaIsFinal:  "false",
 		}
 		if traceID != "" {
 			meta[frames.MetaTraceID] = traceID
 		}
-		s.out <- frames.NewTextFrame(s.cfg.StreamID, time.Now().UnixNano(), interim, meta)
+		s.out <- frames.NewTranscriptFrame(s.cfg.StreamID, time.Now().UnixNano(), s.transcript(interim, false), meta)
 	}
 
 	finalMeta := map[string]string{
 		frames.MetaStreamID: s.cfg.StreamID,
 		frames.MetaCallSID:  s.cfg.CallSID,
 		frames.MetaSource:   "stt",
-		frames.MetaIsFinal:  "true",
 	}
 	if traceID != "" {
 		finalMeta[frames.MetaTraceID] = traceID
 	}
-	s.out <- frames.NewTextFrame(s.cfg.StreamID, time.Now().UnixNano(), s.cfg.Transcript, finalMeta)
+	s.out <- frames.NewTranscriptFrame(s.cfg.StreamID, time.Now().UnixNano(), s.transcript(s.cfg.Transcript, true), finalMeta)
 
 	flushMeta := map[string]string{
 		frames.MetaStreamID: s.cfg.StreamID,
@@ -148,4 +159,32 @@ func (s *StreamingSTT) SendAudio(frame frames.AudioFrame) error {
 
 func (s *StreamingSTT) Results() <-chan frames.Frame { return s.out }
 
+// transcript builds a deterministic typed transcript with evenly spaced words.
+func (s *StreamingSTT) transcript(text string, final bool) frames.Transcript {
+	tr := frames.Transcript{
+		Text:       text,
+		Final:      final,
+		Confidence: s.cfg.Confidence,
+		Language:   s.cfg.Language,
+		Speaker:    s.cfg.Speaker,
+	}
+	if !final {
+		tr.Stability = 0.5
+	}
+	for i, w := range strings.Fields(text) {
+		start := time.Duration(i) * s.cfg.WordDuration
+		tr.Words = append(tr.Words, frames.Word{
+			Text:       strings.ToLower(strings.Trim(w, ".,!?")),
+			Punctuated: w,
+			Start:      start,
+			End:        start + s.cfg.WordDuration,
+			Confidence: s.cfg.Confidence,
+			Speaker:    s.cfg.Speaker,
+			Language:   s.cfg.Language,
+		})
+	}
+	tr.Duration = time.Duration(len(tr.Words)) * s.cfg.WordDuration
+	return tr
+}
+
 var _ stt.StreamingSTT = (*StreamingSTT)(nil)
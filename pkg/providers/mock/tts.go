@@ -76,13 +76,14 @@ func (s *StreamingTTS) SendText(text string) error {
 	}
 
 	// Emit a deterministic silent audio frame.
-	pcm := make([]byte, 320)
+	pcm := frames.AcquireAudioBuf(320)
+	clear(pcm)
 	meta := map[string]string{
 		frames.MetaStreamID: s.cfg.StreamID,
 		frames.MetaCallSID:  s.cfg.CallSID,
 		frames.MetaSource:   "tts",
 	}
-	f := frames.NewAudioFrame(s.cfg.StreamID, time.Now().UnixNano(), pcm, s.cfg.SampleRate, s.cfg.Channels, meta)
+	f := frames.NewAudioFrameFromBuf(s.cfg.StreamID, time.Now().UnixNano(), pcm, s.cfg.SampleRate, s.cfg.Channels, meta)
 	s.out <- f
 	if s.cfg.EmitAudioReady {
 		ready := frames.NewControlFrame(s.cfg.StreamID, time.Now().UnixNano(), frames.ControlAudioReady, map[string]string{
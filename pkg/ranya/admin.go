@@ -0,0 +1,369 @@
+package ranya
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/pipeline"
+	"github.com/harunnryd/ranya/pkg/processors"
+	"github.com/harunnryd/ranya/pkg/redact"
+)
+
+// FrameAdminMessage is the system frame carrying a message injected through
+// the admin API into a call's LLM context.
+const FrameAdminMessage = "admin_message"
+
+// Errors returned by the admin and supervisor actions.
+var (
+	// ErrSessionNotFound means the call is not live.
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrSessionBusy means the session's input queue is full and the action
+	// was not queued; it can be retried.
+	ErrSessionBusy = errors.New("session busy")
+	// ErrNotTakenOver means a supervisor action needs a takeover first.
+	ErrNotTakenOver = errors.New("call is not taken over")
+)
+
+// liveSession holds the processors of a session that the admin API and
+// hangups read.
+type liveSession struct {
+	// orch is the session's orchestrator, which tells a session apart from
+	// a rival built for the same call.
+	orch pipeline.Orchestrator
+	llm  *processors.LLMProcessor
+	ctx  *processors.ContextProcessor
+	turn *processors.TurnProcessor
+	tts  *processors.TTSProcessor
+	// supervisor is who has taken the call over, nil while the agent talks.
+	// Changes and Say hold supervising, so the state always matches the
+	// last supervisor frame queued.
+	supervisor  atomic.Pointer[string]
+	supervising sync.Mutex
+}
+
+// liveSessions indexes liveSession by call SID. The session factory stages
+// a liveSession, and it only goes live once its session wins registration,
+// so processors of a session that lost a creation race are never reachable.
+type liveSessions struct {
+	m sync.Map
+
+	mu      sync.Mutex
+	pending map[pipeline.Orchestrator]*liveSession
+}
+
+func (l *liveSessions) add(callSID string, s *liveSession) {
+	l.m.Store(callSID, s)
+}
+
+// stage holds s until promote or discard.
+func (l *liveSessions) stage(s *liveSession) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.pending == nil {
+		l.pending = make(map[pipeline.Orchestrator]*liveSession)
+	}
+	l.pending[s.orch] = s
+}
+
+// promote makes the staged processors of a newly registered session live.
+func (l *liveSessions) promote(sess *pipeline.Session) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s, ok := l.pending[sess.Orch]; ok {
+		delete(l.pending, sess.Orch)
+		l.add(sess.CallSID, s)
+	}
+}
+
+// discard drops whatever is staged or live for the session running orch,
+// leaving another session's entry for the same call alone.
+func (l *liveSessions) discard(callSID string, orch pipeline.Orchestrator) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.pending, orch)
+	if s := l.get(callSID); s != nil && s.orch == orch {
+		l.m.CompareAndDelete(callSID, s)
+	}
+}
+
+func (l *liveSessions) get(callSID string) *liveSession {
+	v, ok := l.m.Load(callSID)
+	if !ok {
+		return nil
+	}
+	return v.(*liveSession)
+}
+
+// AdminSession summarizes a live call.
+type AdminSession struct {
+	CallSID      string    `json:"call_sid"`
+	StreamID     string    `json:"stream_id"`
+	TraceID      string    `json:"trace_id,omitempty"`
+	Transport    string    `json:"transport,omitempty"`
+	Agent        string    `json:"agent,omitempty"`
+	Language     string    `json:"language,omitempty"`
+	TurnState    string    `json:"turn_state,omitempty"`
+	Started      time.Time `json:"started"`
+	DurationMS   int64     `json:"duration_ms"`
+	PendingTools []string  `json:"pending_tools,omitempty"`
+	Supervisor   string    `json:"supervisor,omitempty"`
+	Debug        bool      `json:"debug"`
+}
+
+// AdminSessionDetail adds a call's LLM history and shared context. Message
+// contents are redacted when redaction is enabled.
+type AdminSessionDetail struct {
+	AdminSession
+	History []map[string]any  `json:"history"`
+	Globals map[string]string `json:"globals"`
+}
+
+// Sessions lists the live calls, oldest first.
+func (e *Engine) Sessions() []AdminSession {
+	sessions := e.registry.Sessions()
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Created.Before(sessions[j].Created) })
+	out := make([]AdminSession, 0, len(sessions))
+	for _, sess := range sessions {
+		summary, _ := e.inspect(sess)
+		out = append(out, summary)
+	}
+	return out
+}
+
+// Session returns the detail of one live call.
+func (e *Engine) Session(callSID string) (AdminSessionDetail, bool) {
+	sess, ok := e.registry.Get(callSID)
+	if !ok {
+		return AdminSessionDetail{}, false
+	}
+	summary, snap := e.inspect(sess)
+	detail := AdminSessionDetail{AdminSession: summary, History: snap.History, Globals: map[string]string{}}
+	for _, msg := range detail.History {
+		if content, ok := msg["content"].(string); ok {
+			msg["content"] = redact.Text(content)
+		}
+	}
+	if live := e.live.get(callSID); live != nil && live.ctx != nil {
+		detail.Globals = live.ctx.Globals(callSID, sess.Stream())
+	}
+	return detail, true
+}
+
+func (e *Engine) inspect(sess *pipeline.Session) (AdminSession, processors.LLMSnapshot) {
+	streamID := sess.Stream()
+	summary := AdminSession{
+		CallSID:    sess.CallSID,
+		StreamID:   streamID,
+		TraceID:    sess.TraceID,
+		Transport:  e.transports.nameForCall(sess.CallSID),
+		Started:    sess.Created,
+		DurationMS: time.Since(sess.Created).Milliseconds(),
+		Debug:      e.debug.active(sess.CallSID),
+	}
+	var snap processors.LLMSnapshot
+	live := e.live.get(sess.CallSID)
+	if live == nil {
+		return summary, snap
+	}
+	if live.llm != nil {
+		snap = live.llm.Snapshot(sess.CallSID, streamID)
+		summary.Agent = snap.Agent
+		summary.Language = snap.Language
+		summary.PendingTools = snap.PendingTools
+	}
+	if supervisor := live.supervisor.Load(); supervisor != nil {
+		summary.Supervisor = *supervisor
+	}
+	if live.turn != nil {
+		summary.TurnState = live.turn.Manager().State().String()
+	}
+	return summary, snap
+}
+
+// InjectMessage adds text to the call's LLM context as a system message.
+// The agent sees it from its next turn on.
+func (e *Engine) InjectMessage(callSID, text string) error {
+	return e.sendToSession(callSID, func(meta map[string]string, streamID string) frames.Frame {
+		meta[frames.MetaSystemMessage] = text
+		return frames.NewSystemFrame(streamID, time.Now().UnixNano(), FrameAdminMessage, meta)
+	})
+}
+
+// Handoff moves the call to agent, as a handoff tool would.
+func (e *Engine) Handoff(callSID, agent string) error {
+	return e.sendToSession(callSID, func(meta map[string]string, streamID string) frames.Frame {
+		meta[frames.MetaHandoffAgent] = agent
+		return frames.NewControlFrame(streamID, time.Now().UnixNano(), frames.ControlHandoff, meta)
+	})
+}
+
+// EndCall hangs the call up once the agent has finished speaking.
+func (e *Engine) EndCall(callSID string) error {
+	return e.sendToSession(callSID, func(meta map[string]string, streamID string) frames.Frame {
+		meta[frames.MetaReason] = "admin"
+		meta[frames.MetaCallEndReason] = "admin_hangup"
+		return frames.NewControlFrame(streamID, time.Now().UnixNano(), frames.ControlHangup, meta)
+	})
+}
+
+// SetSessionDebug turns debug logging on or off for one call, whatever the
+// configured log level. It stays on until turned off or the call ends.
+func (e *Engine) SetSessionDebug(callSID string, on bool) error {
+	if _, ok := e.registry.Get(callSID); !ok {
+		return ErrSessionNotFound
+	}
+	e.debug.enable(callSID, on)
+	return nil
+}
+
+// sendToSession queues a frame built by build on the session's input. It
+// never blocks a request on a congested call.
+func (e *Engine) sendToSession(callSID string, build func(meta map[string]string, streamID string) frames.Frame) error {
+	sess, ok := e.registry.Get(callSID)
+	if !ok {
+		return ErrSessionNotFound
+	}
+	streamID := sess.Stream()
+	meta := map[string]string{
+		frames.MetaStreamID: streamID,
+		frames.MetaCallSID:  sess.CallSID,
+	}
+	if sess.TraceID != "" {
+		meta[frames.MetaTraceID] = sess.TraceID
+	}
+	if !nonBlockingSend(sess.Orch.In(), build(meta, streamID)) {
+		return ErrSessionBusy
+	}
+	return nil
+}
+
+// AdminHandler serves the admin API. Every request must carry token as a
+// bearer token; an empty token refuses everything.
+//
+//	GET  /sessions                    live calls
+//	GET  /sessions/{call_sid}         one call with LLM history and globals
+//	GET  /sessions/{call_sid}/tap     live events, see TapSession
+//	POST /sessions/{call_sid}/message {"text": "..."} inject a system message
+//	POST /sessions/{call_sid}/handoff {"agent": "..."} force a handoff
+//	POST /sessions/{call_sid}/end     hang up
+//	POST /sessions/{call_sid}/debug   {"enabled": true} toggle debug logging
+//
+// and the supervisor actions, see Whisper, Takeover, Say and Release:
+//
+//	POST /sessions/{call_sid}/whisper  {"supervisor": "...", "text": "..."}
+//	POST /sessions/{call_sid}/takeover {"supervisor": "..."}
+//	POST /sessions/{call_sid}/say      {"text": "..."}
+//	POST /sessions/{call_sid}/release
+func (e *Engine) AdminHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sessions", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"sessions": e.Sessions()})
+	})
+	mux.HandleFunc("GET /sessions/{call_sid}", func(w http.ResponseWriter, r *http.Request) {
+		detail, ok := e.Session(r.PathValue("call_sid"))
+		if !ok {
+			writeError(w, http.StatusNotFound, "session not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, detail)
+	})
+	mux.HandleFunc("GET /sessions/{call_sid}/tap", e.serveTap)
+	mux.HandleFunc("POST /sessions/{call_sid}/message", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text string `json:"text"`
+		}
+		if !readJSON(w, r, &body) {
+			return
+		}
+		if strings.TrimSpace(body.Text) == "" {
+			writeError(w, http.StatusBadRequest, "text is required")
+			return
+		}
+		e.adminResult(w, e.InjectMessage(r.PathValue("call_sid"), body.Text))
+	})
+	mux.HandleFunc("POST /sessions/{call_sid}/handoff", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Agent string `json:"agent"`
+		}
+		if !readJSON(w, r, &body) {
+			return
+		}
+		agent := strings.TrimSpace(body.Agent)
+		if agent == "" {
+			writeError(w, http.StatusBadRequest, "agent is required")
+			return
+		}
+		if _, ok := e.agents[agent]; len(e.agents) > 0 && !ok {
+			writeError(w, http.StatusBadRequest, "unknown agent")
+			return
+		}
+		e.adminResult(w, e.Handoff(r.PathValue("call_sid"), agent))
+	})
+	mux.HandleFunc("POST /sessions/{call_sid}/end", func(w http.ResponseWriter, r *http.Request) {
+		e.adminResult(w, e.EndCall(r.PathValue("call_sid")))
+	})
+	mux.HandleFunc("POST /sessions/{call_sid}/debug", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Enabled bool `json:"enabled"`
+		}
+		if !readJSON(w, r, &body) {
+			return
+		}
+		e.adminResult(w, e.SetSessionDebug(r.PathValue("call_sid"), body.Enabled))
+	})
+	e.supervisorRoutes(mux)
+	return bearerAuth(token, mux)
+}
+
+func (e *Engine) adminResult(w http.ResponseWriter, err error) {
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "accepted"})
+	case errors.Is(err, ErrSessionBusy):
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, ErrNotTakenOver):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeError(w, http.StatusNotFound, err.Error())
+	}
+}
+
+// bearerAuth rejects requests whose bearer token does not match token.
+func bearerAuth(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(got)), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
@@ -0,0 +1,336 @@
+package ranya
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/aggregators"
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/metrics"
+	"github.com/harunnryd/ranya/pkg/observers"
+	"github.com/harunnryd/ranya/pkg/pipeline"
+	"github.com/harunnryd/ranya/pkg/processors"
+	"github.com/harunnryd/ranya/pkg/transports/mock"
+)
+
+func TestAdminHandler(t *testing.T) {
+	out := make(chan frames.Frame, 8)
+	registry := pipeline.NewSessionRegistry(func(ctx context.Context, callSID, streamID, traceID string) (pipeline.Orchestrator, error) {
+		orch := pipeline.New(pipeline.Config{HighCapacity: 8, LowCapacity: 8, StageBuffer: 8})
+		orch.SetContext(ctx)
+		orch.SetSink(func(f frames.Frame) { out <- f })
+		return orch, nil
+	})
+	defer registry.CloseAll()
+	if _, _, err := registry.GetOrCreate("call-1", "stream-1", "trace-1"); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	ctxProc := processors.NewContextProcessor(aggregators.AggregatorConfig{}, "")
+	_, _ = ctxProc.Process(frames.NewSystemFrame("stream-1", 0, "call_start", map[string]string{
+		frames.MetaStreamID:                    "stream-1",
+		frames.MetaCallSID:                     "call-1",
+		frames.MetaGlobalPrefix + "account_id": "A-7",
+	}))
+	live := &liveSessions{}
+	live.add("call-1", &liveSession{llm: processors.NewLLMProcessor(nil, "", nil), ctx: ctxProc})
+	e := &Engine{
+		registry:   registry,
+		transports: newTransportRouter(mock.New(), nil),
+		live:       live,
+		debug:      newSessionDebug(),
+		agents:     map[string]processors.AgentConfig{"billing": {}},
+	}
+	srv := httptest.NewServer(e.AdminHandler("secret"))
+	defer srv.Close()
+	do := func(method, path, token, body string) *http.Response {
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	if resp := do("GET", "/sessions", "", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", resp.StatusCode)
+	}
+	if resp := do("GET", "/sessions", "wrong", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with a wrong token, got %d", resp.StatusCode)
+	}
+
+	var list struct {
+		Sessions []AdminSession `json:"sessions"`
+	}
+	_ = json.NewDecoder(do("GET", "/sessions", "secret", "").Body).Decode(&list)
+	if len(list.Sessions) != 1 || list.Sessions[0].CallSID != "call-1" || list.Sessions[0].StreamID != "stream-1" {
+		t.Fatalf("unexpected sessions %+v", list.Sessions)
+	}
+
+	var detail AdminSessionDetail
+	_ = json.NewDecoder(do("GET", "/sessions/call-1", "secret", "").Body).Decode(&detail)
+	if detail.Globals["account_id"] != "A-7" {
+		t.Fatalf("expected globals in detail, got %+v", detail.Globals)
+	}
+	if resp := do("GET", "/sessions/call-9", "secret", ""); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown call, got %d", resp.StatusCode)
+	}
+
+	if resp := do("POST", "/sessions/call-1/message", "secret", `{"text":"Offer the loyalty discount."}`); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202 for message, got %d", resp.StatusCode)
+	}
+	select {
+	case f := <-out:
+		sf, ok := f.(frames.SystemFrame)
+		if !ok || sf.Name() != FrameAdminMessage || sf.Meta()[frames.MetaSystemMessage] != "Offer the loyalty discount." || sf.Meta()[frames.MetaCallSID] != "call-1" {
+			t.Fatalf("unexpected injected frame %#v", f)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("injected message never reached the session")
+	}
+
+	if resp := do("POST", "/sessions/call-1/handoff", "secret", `{"agent":"sales"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown agent, got %d", resp.StatusCode)
+	}
+	if resp := do("POST", "/sessions/call-1/handoff", "secret", `{"agent":"billing"}`); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202 for handoff, got %d", resp.StatusCode)
+	}
+	select {
+	case f := <-out:
+		cf, ok := f.(frames.ControlFrame)
+		if !ok || cf.Code() != frames.ControlHandoff || cf.Metadata().Get(frames.MetaHandoffAgent) != "billing" {
+			t.Fatalf("unexpected handoff frame %#v", f)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("handoff never reached the session")
+	}
+
+	if resp := do("POST", "/sessions/call-1/say", "secret", `{"text":"Hello"}`); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409 for say before takeover, got %d", resp.StatusCode)
+	}
+	do("POST", "/sessions/call-1/takeover", "secret", `{"supervisor":"rina"}`)
+	if resp := do("POST", "/sessions/call-1/say", "secret", `{"text":"Hello, this is Rina."}`); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202 for say during takeover, got %d", resp.StatusCode)
+	}
+	var names []string
+	for range 2 {
+		select {
+		case f := <-out:
+			sf, ok := f.(frames.SystemFrame)
+			if !ok || sf.Meta()[frames.MetaSupervisor] != "rina" {
+				t.Fatalf("unexpected supervisor frame %#v", f)
+			}
+			names = append(names, sf.Name())
+		case <-time.After(time.Second):
+			t.Fatalf("supervisor frames never reached the session")
+		}
+	}
+	if names[0] != processors.FrameSupervisorTakeover || names[1] != processors.FrameSupervisorSay {
+		t.Fatalf("unexpected supervisor frames %v", names)
+	}
+	_ = json.NewDecoder(do("GET", "/sessions", "secret", "").Body).Decode(&list)
+	if list.Sessions[0].Supervisor != "rina" {
+		t.Fatalf("expected the session to show its supervisor, got %+v", list.Sessions[0])
+	}
+}
+
+func TestAdminTapStreamsSessionEvents(t *testing.T) {
+	registry := pipeline.NewSessionRegistry(func(ctx context.Context, callSID, streamID, traceID string) (pipeline.Orchestrator, error) {
+		orch := pipeline.New(pipeline.Config{HighCapacity: 8, LowCapacity: 8, StageBuffer: 8})
+		orch.SetContext(ctx)
+		return orch, nil
+	})
+	defer registry.CloseAll()
+	if _, _, err := registry.GetOrCreate("call-1", "stream-1", "trace-1"); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	tap := observers.NewTap()
+	e := &Engine{registry: registry, transports: newTransportRouter(mock.New(), nil), live: &liveSessions{}, debug: newSessionDebug(), tap: tap}
+	srv := httptest.NewServer(e.AdminHandler("secret"))
+	defer srv.Close()
+
+	req, _ := http.NewRequest("GET", srv.URL+"/sessions/call-1/tap", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("tap: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", ct)
+	}
+	for tap.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	tap.RecordEvent(metrics.MetricsEvent{Name: "frame_in", Time: time.Now(), Tags: map[string]string{frames.MetaTraceID: "trace-2", "kind": "text"}})
+	tap.RecordEvent(metrics.MetricsEvent{Name: "frame_in", Time: time.Now(), Tags: map[string]string{frames.MetaTraceID: "trace-1", "kind": "audio"}})
+	tap.RecordEvent(metrics.MetricsEvent{Name: "frame_in", Time: time.Now(), Tags: map[string]string{frames.MetaTraceID: "trace-1", "kind": "text"}, Fields: map[string]any{"text": "hello"}})
+
+	lines := bufio.NewScanner(resp.Body)
+	var data []string
+	for lines.Scan() {
+		line := lines.Text()
+		if line == "event: end" {
+			break
+		}
+		if rest, ok := strings.CutPrefix(line, "data: "); ok {
+			data = append(data, rest)
+			registry.End("call-1", nil)
+		}
+	}
+	if len(data) != 1 || !strings.Contains(data[0], `"text":"hello"`) {
+		t.Fatalf("expected only the session's text frame, got %v", data)
+	}
+}
+
+// busyOrchestrator reports a full input queue.
+type busyOrchestrator struct {
+	pipeline.Orchestrator
+	in chan frames.Frame
+}
+
+func (o busyOrchestrator) In() chan frames.Frame { return o.in }
+
+func TestAdminActionsOnBusySession(t *testing.T) {
+	busy := make(chan frames.Frame, 1)
+	busy <- frames.NewSystemFrame("stream-1", 0, "filler", nil)
+	registry := pipeline.NewSessionRegistry(func(ctx context.Context, callSID, streamID, traceID string) (pipeline.Orchestrator, error) {
+		return busyOrchestrator{Orchestrator: pipeline.New(pipeline.Config{HighCapacity: 8, LowCapacity: 8, StageBuffer: 8}), in: busy}, nil
+	})
+	defer registry.CloseAll()
+	if _, _, err := registry.GetOrCreate("call-1", "stream-1", "trace-1"); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	live := &liveSessions{}
+	live.add("call-1", &liveSession{})
+	e := &Engine{registry: registry, transports: newTransportRouter(mock.New(), nil), live: live, debug: newSessionDebug()}
+	srv := httptest.NewServer(e.AdminHandler("secret"))
+	defer srv.Close()
+	post := func(path, body string) *http.Response {
+		req, _ := http.NewRequest("POST", srv.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	if resp := post("/sessions/call-1/message", `{"text":"hi"}`); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 for a full session queue, got %d", resp.StatusCode)
+	}
+	if resp := post("/sessions/call-1/takeover", `{"supervisor":"rina"}`); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 for a takeover on a full session queue, got %d", resp.StatusCode)
+	}
+	if live.get("call-1").supervisor.Load() != nil {
+		t.Fatalf("expected no supervisor when the takeover was not queued")
+	}
+	if resp := post("/sessions/call-1/say", `{"text":"Hello"}`); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409 for say without a takeover, got %d", resp.StatusCode)
+	}
+}
+
+func TestSessionDebugLogger(t *testing.T) {
+	var buf strings.Builder
+	next := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})
+	d := newSessionDebug()
+	on := slog.New(&debugHandler{next: next, debug: d, callSID: "call-1"})
+	off := slog.New(&debugHandler{next: next, debug: d, callSID: "call-2"})
+
+	on.Debug("before")
+	d.enable("call-1", true)
+	on.Debug("debugged")
+	off.Debug("quiet")
+	d.forget("call-1")
+	on.Debug("after")
+
+	if got := buf.String(); !strings.Contains(got, "debugged") || strings.Contains(got, "before") || strings.Contains(got, "quiet") || strings.Contains(got, "after") {
+		t.Fatalf("unexpected debug output %q", got)
+	}
+}
+
+func TestLiveSessionsKeepOnlyTheRegisteredSession(t *testing.T) {
+	cfg := pipeline.Config{HighCapacity: 1, LowCapacity: 1, StageBuffer: 1}
+	winner, loser := pipeline.New(cfg), pipeline.New(cfg)
+	live := &liveSessions{}
+	live.stage(&liveSession{orch: winner})
+	live.stage(&liveSession{orch: loser})
+
+	live.promote(&pipeline.Session{CallSID: "call-1", Orch: winner})
+	live.discard("call-1", loser)
+	if s := live.get("call-1"); s == nil || s.orch != winner {
+		t.Fatalf("expected the registered session to stay live, got %+v", s)
+	}
+	live.promote(&pipeline.Session{CallSID: "call-1", Orch: loser})
+	if s := live.get("call-1"); s.orch != winner {
+		t.Fatalf("expected a discarded session never to go live")
+	}
+
+	live.discard("call-1", winner)
+	if live.get("call-1") != nil {
+		t.Fatalf("expected the session to be gone once it ended")
+	}
+}
+
+func TestSupervisorStateMatchesLastQueuedFrame(t *testing.T) {
+	var mu sync.Mutex
+	var last string
+	registry := pipeline.NewSessionRegistry(func(ctx context.Context, callSID, streamID, traceID string) (pipeline.Orchestrator, error) {
+		orch := pipeline.New(pipeline.Config{HighCapacity: 256, LowCapacity: 256, StageBuffer: 8})
+		orch.SetContext(ctx)
+		orch.SetSink(func(f frames.Frame) {
+			if sf, ok := f.(frames.SystemFrame); ok {
+				mu.Lock()
+				last = sf.Name()
+				mu.Unlock()
+			}
+		})
+		return orch, nil
+	})
+	defer registry.CloseAll()
+	if _, _, err := registry.GetOrCreate("call-1", "stream-1", "trace-1"); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	live := &liveSessions{}
+	live.add("call-1", &liveSession{})
+	e := &Engine{registry: registry, live: live, debug: newSessionDebug()}
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				if i%2 == 0 {
+					_ = e.Takeover("call-1", "rina")
+				} else {
+					_ = e.Release("call-1")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	want := processors.FrameSupervisorRelease
+	if live.get("call-1").supervisor.Load() != nil {
+		want = processors.FrameSupervisorTakeover
+	}
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if last != want {
+		t.Fatalf("supervisor state says %s, but the last frame was %s", want, last)
+	}
+}
@@ -0,0 +1,272 @@
+package ranya
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/metrics"
+	"github.com/harunnryd/ranya/pkg/transports"
+)
+
+const (
+	// admissionReservation is how long an admitted call may take to open
+	// its media stream before its slot is given back.
+	admissionReservation = 30 * time.Second
+	// queueAbandonSlack is how long past its hold a queued caller may take
+	// to come back before it is dropped as abandoned.
+	queueAbandonSlack  = 30 * time.Second
+	defaultQueueHoldMS = 10000
+)
+
+type admittedCall struct {
+	transport string
+	since     time.Time
+	started   bool
+}
+
+type queuedCall struct {
+	callSID   string
+	transport string
+	since     time.Time
+	seen      time.Time
+}
+
+// callAdmission enforces admission limits. A call holds a slot from the
+// moment it is admitted until it ends; queued calls are admitted in arrival
+// order as slots free up.
+type callAdmission struct {
+	cfg AdmissionConfig
+	obs metrics.Observer
+	now func() time.Time
+
+	mu      sync.Mutex
+	calls   map[string]admittedCall
+	queue   []*queuedCall
+	refused map[string]time.Time
+}
+
+func newCallAdmission(cfg AdmissionConfig, obs metrics.Observer) *callAdmission {
+	if cfg.MaxSessions <= 0 && len(cfg.MaxSessionsByTransport) == 0 {
+		return nil
+	}
+	return &callAdmission{
+		cfg:     cfg,
+		obs:     obs,
+		now:     time.Now,
+		calls:   make(map[string]admittedCall),
+		refused: make(map[string]time.Time),
+	}
+}
+
+// admit decides on a new call arriving on transport. Outbound calls are
+// always admitted but take a slot.
+func (a *callAdmission) admit(transport string, req transports.AdmissionRequest) transports.AdmissionDecision {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	a.prune(now)
+	if req.CallSID != "" {
+		if _, ok := a.calls[req.CallSID]; ok {
+			return transports.AdmissionDecision{Action: transports.AdmissionAccept}
+		}
+		if _, ok := a.refused[req.CallSID]; ok {
+			return transports.AdmissionDecision{Action: transports.AdmissionReject}
+		}
+	}
+	pos := a.position(req.CallSID)
+	if req.Outbound || a.free(transport) > a.ahead(transport, pos) {
+		if pos >= 0 {
+			q := a.queue[pos]
+			a.queue = slices.Delete(a.queue, pos, pos+1)
+			a.record(metrics.EventCallDequeued, transport, req.CallSID, nil, map[string]any{"wait_ms": now.Sub(q.since).Milliseconds()})
+		}
+		if req.CallSID != "" {
+			a.calls[req.CallSID] = admittedCall{transport: transport, since: now}
+		}
+		return transports.AdmissionDecision{Action: transports.AdmissionAccept}
+	}
+	if a.cfg.Overflow != "queue" || !req.CanHold || req.CallSID == "" {
+		return a.refuse(transport, req.CallSID, pos, "capacity")
+	}
+	if pos < 0 {
+		if a.cfg.Queue.MaxSize > 0 && len(a.queue) >= a.cfg.Queue.MaxSize {
+			return a.refuse(transport, req.CallSID, pos, "queue_full")
+		}
+		a.queue = append(a.queue, &queuedCall{callSID: req.CallSID, transport: transport, since: now, seen: now})
+		pos = len(a.queue) - 1
+		a.record(metrics.EventCallQueued, transport, req.CallSID, nil, map[string]any{"position": a.ahead(transport, pos) + 1})
+	} else {
+		q := a.queue[pos]
+		if wait := time.Duration(a.cfg.Queue.MaxWaitMS) * time.Millisecond; wait > 0 && now.Sub(q.since) >= wait {
+			return a.refuse(transport, req.CallSID, pos, "queue_timeout")
+		}
+		q.seen = now
+	}
+	position := strconv.Itoa(a.ahead(transport, pos) + 1)
+	return transports.AdmissionDecision{
+		Action:       transports.AdmissionQueue,
+		Message:      strings.ReplaceAll(a.cfg.Queue.Message, "{position}", position),
+		HoldAudioURL: a.cfg.Queue.HoldAudioURL,
+		RetryAfter:   a.hold(),
+	}
+}
+
+// refuse turns a call away with a busy signal, or with the overflow message
+// when one is configured and the policy is not a plain reject.
+func (a *callAdmission) refuse(transport, callSID string, pos int, reason string) transports.AdmissionDecision {
+	if pos >= 0 {
+		a.queue = slices.Delete(a.queue, pos, pos+1)
+	}
+	decision := transports.AdmissionDecision{Action: transports.AdmissionReject}
+	if a.cfg.Overflow != "reject" && strings.TrimSpace(a.cfg.Message) != "" {
+		decision = transports.AdmissionDecision{Action: transports.AdmissionMessage, Message: a.cfg.Message}
+	}
+	if callSID != "" {
+		a.refused[callSID] = a.now()
+	}
+	a.record(metrics.EventCallRejected, transport, callSID, map[string]string{"reason": reason, "action": string(decision.Action)}, nil)
+	return decision
+}
+
+// started counts a session that opened on transport, whether or not the
+// transport asked for admission first.
+func (a *callAdmission) started(transport, callSID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	c, ok := a.calls[callSID]
+	if !ok {
+		c = admittedCall{transport: transport, since: a.now()}
+	}
+	c.started = true
+	a.calls[callSID] = c
+}
+
+// release frees the call's slot or queue entry.
+func (a *callAdmission) release(callSID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.calls, callSID)
+	delete(a.refused, callSID)
+	if pos := a.position(callSID); pos >= 0 {
+		a.queue = slices.Delete(a.queue, pos, pos+1)
+	}
+}
+
+// prune drops reservations whose stream never opened and queued callers
+// that stopped coming back.
+func (a *callAdmission) prune(now time.Time) {
+	for sid, c := range a.calls {
+		if !c.started && now.Sub(c.since) > admissionReservation {
+			delete(a.calls, sid)
+		}
+	}
+	for sid, at := range a.refused {
+		if now.Sub(at) > admissionReservation {
+			delete(a.refused, sid)
+		}
+	}
+	stale := a.hold() + queueAbandonSlack
+	a.queue = slices.DeleteFunc(a.queue, func(q *queuedCall) bool {
+		return now.Sub(q.seen) > stale
+	})
+}
+
+func (a *callAdmission) position(callSID string) int {
+	if callSID == "" {
+		return -1
+	}
+	return slices.IndexFunc(a.queue, func(q *queuedCall) bool { return q.callSID == callSID })
+}
+
+// free returns how many more calls transport may take.
+func (a *callAdmission) free(transport string) int {
+	free := math.MaxInt
+	if a.cfg.MaxSessions > 0 {
+		free = a.cfg.MaxSessions - len(a.calls)
+	}
+	if limit := a.cfg.MaxSessionsByTransport[transport]; limit > 0 {
+		n := 0
+		for _, c := range a.calls {
+			if c.transport == transport {
+				n++
+			}
+		}
+		free = min(free, limit-n)
+	}
+	return free
+}
+
+// ahead counts queued calls that go before the one at pos (or before a new
+// arrival when pos is -1) and compete for the same slots.
+func (a *callAdmission) ahead(transport string, pos int) int {
+	if pos < 0 {
+		pos = len(a.queue)
+	}
+	n := 0
+	for _, q := range a.queue[:pos] {
+		if a.cfg.MaxSessions > 0 || q.transport == transport {
+			n++
+		}
+	}
+	return n
+}
+
+func (a *callAdmission) hold() time.Duration {
+	ms := a.cfg.Queue.HoldMS
+	if ms <= 0 {
+		ms = defaultQueueHoldMS
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+func (a *callAdmission) record(name, transport, callSID string, tags map[string]string, fields map[string]any) {
+	slog.Info(name, "transport", transport, "call_sid", callSID, "queue_length", len(a.queue), "active_calls", len(a.calls))
+	if a.obs == nil {
+		return
+	}
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	tags["transport"] = transport
+	tags[frames.MetaCallSID] = callSID
+	if fields == nil {
+		fields = make(map[string]any)
+	}
+	fields["queue_length"] = len(a.queue)
+	fields["active_calls"] = len(a.calls)
+	a.obs.RecordEvent(metrics.MetricsEvent{Name: name, Time: a.now(), Tags: tags, Fields: fields})
+}
+
+// admitSession applies admission to a new session whose transport did not
+// ask first (transports without transports.CallAdmitter). Refused calls are
+// hung up once.
+func (e *Engine) admitSession(name, callSID string) bool {
+	if e.admission == nil {
+		return true
+	}
+	if d := e.admission.admit(name, transports.AdmissionRequest{CallSID: callSID}); d.Action == transports.AdmissionAccept {
+		return true
+	}
+	if _, loaded := e.refused.LoadOrStore(callSID, struct{}{}); loaded {
+		return false
+	}
+	hanger, ok := e.transports.get(name).(transports.CallHanger)
+	if !ok {
+		return false
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), hangupTimeout)
+		defer cancel()
+		if err := hanger.HangupCall(ctx, callSID); err != nil {
+			slog.Warn("call_overflow_hangup_failed", "call_sid", callSID, "transport", name, "error", err)
+		}
+	}()
+	return false
+}
@@ -0,0 +1,90 @@
+package ranya
+
+import (
+	"testing"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/metrics"
+	"github.com/harunnryd/ranya/pkg/transports"
+)
+
+type eventLog struct{ names []string }
+
+func (l *eventLog) RecordEvent(evt metrics.MetricsEvent) { l.names = append(l.names, evt.Name) }
+
+func TestCallAdmissionQueue(t *testing.T) {
+	obs := &eventLog{}
+	a := newCallAdmission(AdmissionConfig{
+		MaxSessions: 1,
+		Overflow:    "queue",
+		Message:     "We are busy, please call back.",
+		Queue:       QueueConfig{Message: "You are number {position} in line.", MaxSize: 2},
+	}, obs)
+	now := time.Unix(100, 0)
+	a.now = func() time.Time { return now }
+	hold := func(sid string) transports.AdmissionRequest {
+		return transports.AdmissionRequest{CallSID: sid, CanHold: true}
+	}
+
+	if d := a.admit("default", hold("c1")); d.Action != transports.AdmissionAccept {
+		t.Fatalf("expected first call to be admitted, got %v", d)
+	}
+	if d := a.admit("default", hold("c2")); d.Action != transports.AdmissionQueue || d.Message != "You are number 1 in line." || d.RetryAfter != 10*time.Second {
+		t.Fatalf("expected c2 to be queued first, got %+v", d)
+	}
+	if d := a.admit("default", hold("c3")); d.Action != transports.AdmissionQueue || d.Message != "You are number 2 in line." {
+		t.Fatalf("expected c3 to be queued second, got %+v", d)
+	}
+	if d := a.admit("default", hold("c4")); d.Action != transports.AdmissionMessage || d.Message != "We are busy, please call back." {
+		t.Fatalf("expected the full queue to refuse c4, got %+v", d)
+	}
+	// Transports that cannot hold get the overflow message instead.
+	if d := a.admit("sip", transports.AdmissionRequest{CallSID: "c5"}); d.Action != transports.AdmissionMessage {
+		t.Fatalf("expected c5 to be refused, got %+v", d)
+	}
+	// Outbound calls take a slot without waiting.
+	if d := a.admit("default", transports.AdmissionRequest{CallSID: "o1", Outbound: true}); d.Action != transports.AdmissionAccept {
+		t.Fatalf("expected outbound call to be admitted, got %+v", d)
+	}
+	a.release("o1")
+
+	a.started("default", "c1")
+	a.release("c1")
+	// c3 may not jump ahead of c2.
+	if d := a.admit("default", hold("c3")); d.Action != transports.AdmissionQueue || d.Message != "You are number 2 in line." {
+		t.Fatalf("expected c3 to keep waiting, got %+v", d)
+	}
+	if d := a.admit("default", hold("c2")); d.Action != transports.AdmissionAccept {
+		t.Fatalf("expected c2 to be admitted from the queue, got %+v", d)
+	}
+	if d := a.admit("default", hold("c3")); d.Message != "You are number 1 in line." {
+		t.Fatalf("expected c3 to move up, got %+v", d)
+	}
+
+	// Reservations whose stream never opens give their slot back.
+	now = now.Add(admissionReservation + time.Second)
+	if d := a.admit("default", hold("c3")); d.Action != transports.AdmissionAccept {
+		t.Fatalf("expected c3 to be admitted after c2's reservation lapsed, got %+v", d)
+	}
+
+	want := []string{metrics.EventCallQueued, metrics.EventCallQueued, metrics.EventCallRejected, metrics.EventCallRejected, metrics.EventCallDequeued, metrics.EventCallDequeued}
+	if len(obs.names) != len(want) {
+		t.Fatalf("unexpected events %v", obs.names)
+	}
+	for i := range want {
+		if obs.names[i] != want[i] {
+			t.Fatalf("unexpected events %v", obs.names)
+		}
+	}
+}
+
+func TestCallAdmissionPerTransport(t *testing.T) {
+	a := newCallAdmission(AdmissionConfig{MaxSessionsByTransport: map[string]int{"web": 1}, Overflow: "reject"}, nil)
+	a.started("web", "w1")
+	if d := a.admit("web", transports.AdmissionRequest{CallSID: "w2"}); d.Action != transports.AdmissionReject {
+		t.Fatalf("expected web to be full, got %+v", d)
+	}
+	if d := a.admit("default", transports.AdmissionRequest{CallSID: "p1"}); d.Action != transports.AdmissionAccept {
+		t.Fatalf("expected other transports to be unlimited, got %+v", d)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/harunnryd/ranya/pkg/pipeline"
 	"github.com/spf13/viper"
@@ -24,6 +25,14 @@ type Config struct {
 	Recovery      RecoveryConfig        `mapstructure:"recovery"`
 	Confirmation  ConfirmationConfig    `mapstructure:"confirmation"`
 	Router        RouterConfig          `mapstructure:"router"`
+	Transfer      TransferConfig        `mapstructure:"transfer"`
+	Hangup        HangupConfig          `mapstructure:"hangup"`
+	Drain         DrainConfig           `mapstructure:"drain"`
+	Health        HealthConfig          `mapstructure:"health"`
+	Admin         AdminConfig           `mapstructure:"admin"`
+	Admission     AdmissionConfig       `mapstructure:"admission"`
+	AMD           AMDConfig             `mapstructure:"amd"`
+	DTMFGather    DTMFGatherConfig      `mapstructure:"dtmf_gather"`
 	Environment   string                `mapstructure:"environment"`
 	LogLevel      string                `mapstructure:"log_level"`
 	LogFormat     string                `mapstructure:"log_format"`
@@ -50,6 +59,30 @@ type VendorsConfig struct {
 type TransportsConfig struct {
 	Provider string         `mapstructure:"provider"`
 	Settings map[string]any `mapstructure:"settings"`
+	// Named declares additional transports served by the same engine,
+	// e.g. Twilio for phone calls next to a WebSocket for web clients.
+	// Callers build them and pass them in EngineOptions.Transports; the
+	// engine only applies their overrides.
+	Named map[string]NamedTransportConfig `mapstructure:"named"`
+}
+
+// NamedTransportConfig is one entry of transports.named.
+type NamedTransportConfig struct {
+	Provider  string             `mapstructure:"provider"`
+	Settings  map[string]any     `mapstructure:"settings"`
+	Overrides TransportOverrides `mapstructure:"overrides"`
+}
+
+// TransportOverrides adjusts the session config for calls arriving on one
+// transport. Zero fields inherit the engine config.
+type TransportOverrides struct {
+	STT        *VendorConfig `mapstructure:"stt"`
+	TTS        *VendorConfig `mapstructure:"tts"`
+	LLM        *VendorConfig `mapstructure:"llm"`
+	BasePrompt string        `mapstructure:"base_prompt"`
+	Language   string        `mapstructure:"language"`
+	// Greeting is spoken as soon as a session starts on this transport.
+	Greeting string `mapstructure:"greeting"`
 }
 
 type VisionConfig struct {
@@ -88,9 +121,22 @@ type SilenceRepromptConfig struct {
 }
 
 type ObservabilityConfig struct {
-	ArtifactsDir  string `mapstructure:"artifacts_dir"`
-	RecordAudio   bool   `mapstructure:"record_audio"`
-	RetentionDays int    `mapstructure:"retention_days"`
+	ArtifactsDir  string          `mapstructure:"artifacts_dir"`
+	RecordAudio   bool            `mapstructure:"record_audio"`
+	RetentionDays int             `mapstructure:"retention_days"`
+	Recording     RecordingConfig `mapstructure:"recording"`
+}
+
+// RecordingConfig writes a stereo file per call (caller left, agent right).
+type RecordingConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Dir defaults to <artifacts_dir>/recordings.
+	Dir string `mapstructure:"dir"`
+	// Format is "wav" or "opus"; opus needs EngineOptions.RecordingEncoder.
+	Format     string `mapstructure:"format"`
+	SampleRate int    `mapstructure:"sample_rate"`
+	// RetentionDays defaults to observability.retention_days.
+	RetentionDays int `mapstructure:"retention_days"`
 }
 
 type PrivacyConfig struct {
@@ -139,9 +185,135 @@ type RouterConfig struct {
 	MaxTurns int    `mapstructure:"max_turns"`
 }
 
+// TransferConfig enables the built-in transfer_call tool. The LLM may only
+// pick one of Targets, never an arbitrary number.
+type TransferConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Mode is the default transfer mode: "cold" or "warm".
+	Mode string `mapstructure:"mode"`
+	// Targets maps names the LLM can choose (e.g. "billing") to phone
+	// numbers or SIP URIs.
+	Targets       map[string]string `mapstructure:"targets"`
+	DefaultTarget string            `mapstructure:"default_target"`
+	CallerID      string            `mapstructure:"caller_id"`
+	Announcement  string            `mapstructure:"announcement"`
+}
+
+// HangupConfig enables the built-in end_call tool. The call is hung up once
+// the agent's last audio has finished playing.
+type HangupConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// GraceMS is how long playback must stay idle before hanging up.
+	GraceMS int `mapstructure:"grace_ms"`
+	// MaxWaitMS caps the wait for playback to finish.
+	MaxWaitMS int `mapstructure:"max_wait_ms"`
+}
+
+// DrainConfig controls shutdown. New calls are refused right away; calls in
+// progress may finish on their own until the deadline.
+type DrainConfig struct {
+	// TimeoutMS is how long calls in progress may continue. Zero cuts them
+	// off right away.
+	TimeoutMS int `mapstructure:"timeout_ms"`
+	// Message is spoken to callers still connected at the deadline before
+	// they are hung up.
+	Message string `mapstructure:"message"`
+}
+
+// HealthConfig serves liveness and readiness probes on their own port.
+type HealthConfig struct {
+	// Addr is the listen address, e.g. ":8081". Empty disables the server.
+	Addr string `mapstructure:"addr"`
+}
+
+// AdminConfig serves the admin API for inspecting and steering live calls.
+type AdminConfig struct {
+	// Addr is the listen address, e.g. ":8082". Empty disables the server.
+	Addr string `mapstructure:"addr"`
+	// Token is the bearer token every request must carry. The server does
+	// not start without one.
+	Token string `mapstructure:"token"`
+}
+
+// AdmissionConfig caps concurrent calls. Calls over a limit are turned away
+// at the transport's webhook according to Overflow.
+type AdmissionConfig struct {
+	// MaxSessions caps calls across all transports. Zero means no limit.
+	MaxSessions int `mapstructure:"max_sessions"`
+	// MaxSessionsByTransport caps calls per transport name; the transport
+	// from transports.provider is "default".
+	MaxSessionsByTransport map[string]int `mapstructure:"max_sessions_by_transport"`
+	// Overflow is "reject" (busy), "message" (speak Message and hang up) or
+	// "queue" (hold until capacity frees).
+	Overflow string      `mapstructure:"overflow"`
+	Message  string      `mapstructure:"message"`
+	Queue    QueueConfig `mapstructure:"queue"`
+}
+
+// QueueConfig holds callers when admission.overflow is "queue".
+type QueueConfig struct {
+	// Message is spoken on every hold cycle; {position} becomes the
+	// caller's place in line.
+	Message      string `mapstructure:"message"`
+	HoldAudioURL string `mapstructure:"hold_audio_url"`
+	// HoldMS is the time between admission checks: the pause length
+	// without hold audio, and roughly the clip length with it.
+	HoldMS int `mapstructure:"hold_ms"`
+	// MaxSize and MaxWaitMS bound the queue; callers beyond them get the
+	// overflow message, or a busy signal without one.
+	MaxSize   int `mapstructure:"max_size"`
+	MaxWaitMS int `mapstructure:"max_wait_ms"`
+}
+
+// AMDConfig enables answering machine detection on outbound calls, those
+// whose call_start carries call_direction "outbound".
+type AMDConfig struct {
+	Enabled                bool `mapstructure:"enabled"`
+	InitialSilenceMS       int  `mapstructure:"initial_silence_ms"`
+	GreetingMS             int  `mapstructure:"greeting_ms"`
+	AfterGreetingSilenceMS int  `mapstructure:"after_greeting_silence_ms"`
+	TotalAnalysisMS        int  `mapstructure:"total_analysis_ms"`
+	MaxWords               int  `mapstructure:"max_words"`
+	// Phrases replace the built-in voicemail phrases when set.
+	Phrases          []string        `mapstructure:"phrases"`
+	SilenceThreshold float64         `mapstructure:"silence_threshold"`
+	Voicemail        VoicemailConfig `mapstructure:"voicemail"`
+}
+
+// VoicemailConfig is the message left on answering machines. Without a
+// message, machines are only reported with a machine_detected frame.
+type VoicemailConfig struct {
+	Message             string            `mapstructure:"message"`
+	MessageByLanguage   map[string]string `mapstructure:"message_by_language"`
+	BeepTimeoutMS       int               `mapstructure:"beep_timeout_ms"`
+	MessageEndSilenceMS int               `mapstructure:"message_end_silence_ms"`
+}
+
+// DTMFGatherConfig enables the built-in gather_digits tool, which collects
+// keypad input into one result. Fields are defaults a gather can override.
+type DTMFGatherConfig struct {
+	Enabled             bool   `mapstructure:"enabled"`
+	MinDigits           int    `mapstructure:"min_digits"`
+	MaxDigits           int    `mapstructure:"max_digits"`
+	Terminator          string `mapstructure:"terminator"`
+	InterDigitTimeoutMS int    `mapstructure:"inter_digit_timeout_ms"`
+	TimeoutMS           int    `mapstructure:"timeout_ms"`
+	Mask                bool   `mapstructure:"mask"`
+	// AcceptSpoken also collects digit-only transcripts during a gather.
+	AcceptSpoken bool `mapstructure:"accept_spoken"`
+}
+
+// ErrorPolicyConfig is the file representation of a pipeline.ErrorPolicy.
+type ErrorPolicyConfig struct {
+	Action    string `mapstructure:"action"`
+	Retries   int    `mapstructure:"retries"`
+	BackoffMS int    `mapstructure:"backoff_ms"`
+}
+
 type DebugConfig struct {
 	SimulateBadNet  bool `mapstructure:"simulate_bad_network"`
 	SimulateHandoff bool `mapstructure:"simulate_handoff"`
+	FramePool       bool `mapstructure:"debug_frame_pool"`
 }
 
 func LoadConfig(path string) (Config, error) {
@@ -153,8 +325,10 @@ func LoadConfig(path string) (Config, error) {
 	v.SetDefault("pipeline.lowcapacity", 512)
 	v.SetDefault("pipeline.fairnessratio", 3)
 	v.SetDefault("pipeline.backpressure", "drop")
+	v.SetDefault("pipeline.default_error_policy.action", "drop")
 	v.SetDefault("engine.samplerate", 8000)
 	v.SetDefault("engine.stt_replay_chunks", 50)
+	v.SetDefault("engine.session_idle_timeout_ms", 0)
 	v.SetDefault("stt.forward_interim", false)
 	v.SetDefault("turn.barge_in_threshold_ms", 500)
 	v.SetDefault("turn.min_barge_in_ms", 300)
@@ -177,6 +351,26 @@ func LoadConfig(path string) (Config, error) {
 	v.SetDefault("confirmation.timeout_ms", 600)
 	v.SetDefault("router.mode", "full")
 	v.SetDefault("router.max_turns", 2)
+	v.SetDefault("transfer.enabled", false)
+	v.SetDefault("transfer.mode", "cold")
+	v.SetDefault("hangup.enabled", false)
+	v.SetDefault("hangup.grace_ms", 1000)
+	v.SetDefault("hangup.max_wait_ms", 15000)
+	v.SetDefault("drain.timeout_ms", 20000)
+	v.SetDefault("admission.overflow", "reject")
+	v.SetDefault("admission.queue.hold_ms", 10000)
+	v.SetDefault("amd.enabled", false)
+	v.SetDefault("amd.initial_silence_ms", 2500)
+	v.SetDefault("amd.greeting_ms", 1500)
+	v.SetDefault("amd.after_greeting_silence_ms", 800)
+	v.SetDefault("amd.total_analysis_ms", 5000)
+	v.SetDefault("amd.max_words", 6)
+	v.SetDefault("amd.voicemail.beep_timeout_ms", 30000)
+	v.SetDefault("amd.voicemail.message_end_silence_ms", 2500)
+	v.SetDefault("dtmf_gather.enabled", false)
+	v.SetDefault("dtmf_gather.terminator", "#")
+	v.SetDefault("dtmf_gather.inter_digit_timeout_ms", 5000)
+	v.SetDefault("dtmf_gather.timeout_ms", 30000)
 	v.SetDefault("environment", "development")
 	v.SetDefault("log_level", "info")
 	v.SetDefault("log_format", "text")
@@ -185,6 +379,9 @@ func LoadConfig(path string) (Config, error) {
 	v.SetDefault("observability.artifacts_dir", "")
 	v.SetDefault("observability.record_audio", false)
 	v.SetDefault("observability.retention_days", 0)
+	v.SetDefault("observability.recording.enabled", false)
+	v.SetDefault("observability.recording.format", "wav")
+	v.SetDefault("observability.recording.sample_rate", 8000)
 	v.SetDefault("privacy.redact_pii", true)
 
 	if err := v.ReadInConfig(); err != nil {
@@ -199,6 +396,9 @@ func LoadConfig(path string) (Config, error) {
 			LowCapacity   int    `mapstructure:"lowcapacity"`
 			FairnessRatio int    `mapstructure:"fairnessratio"`
 			Backpressure  string `mapstructure:"backpressure"`
+
+			DefaultErrorPolicy ErrorPolicyConfig            `mapstructure:"default_error_policy"`
+			ErrorPolicies      map[string]ErrorPolicyConfig `mapstructure:"error_policies"`
 		} `mapstructure:"pipeline"`
 		Engine          pipeline.EngineConfig `mapstructure:"engine"`
 		Vendors         VendorsConfig         `mapstructure:"vendors"`
@@ -210,6 +410,14 @@ func LoadConfig(path string) (Config, error) {
 		Recovery        RecoveryConfig        `mapstructure:"recovery"`
 		Confirmation    ConfirmationConfig    `mapstructure:"confirmation"`
 		Router          RouterConfig          `mapstructure:"router"`
+		Transfer        TransferConfig        `mapstructure:"transfer"`
+		Hangup          HangupConfig          `mapstructure:"hangup"`
+		Drain           DrainConfig           `mapstructure:"drain"`
+		Health          HealthConfig          `mapstructure:"health"`
+		Admin           AdminConfig           `mapstructure:"admin"`
+		Admission       AdmissionConfig       `mapstructure:"admission"`
+		AMD             AMDConfig             `mapstructure:"amd"`
+		DTMFGather      DTMFGatherConfig      `mapstructure:"dtmf_gather"`
 		Environment     string                `mapstructure:"environment"`
 		LogLevel        string                `mapstructure:"log_level"`
 		LogFormat       string                `mapstructure:"log_format"`
@@ -223,6 +431,7 @@ func LoadConfig(path string) (Config, error) {
 		ImageMime       string                `mapstructure:"image_mime"`
 		SimulateBadNet  bool                  `mapstructure:"simulate_bad_network"`
 		SimulateHandoff bool                  `mapstructure:"simulate_handoff"`
+		DebugFramePool  bool                  `mapstructure:"debug_frame_pool"`
 	}
 	if err := v.Unmarshal(&raw); err != nil {
 		return Config{}, fmt.Errorf("unmarshal: %w", err)
@@ -236,6 +445,9 @@ func LoadConfig(path string) (Config, error) {
 			LowCapacity:   raw.Pipeline.LowCapacity,
 			FairnessRatio: raw.Pipeline.FairnessRatio,
 			Backpressure:  parseBackpressure(raw.Pipeline.Backpressure),
+
+			DefaultErrorPolicy: parseErrorPolicy(raw.Pipeline.DefaultErrorPolicy),
+			ErrorPolicies:      parseErrorPolicies(raw.Pipeline.ErrorPolicies),
 		},
 		Engine:        raw.Engine,
 		Vendors:       raw.Vendors,
@@ -247,6 +459,14 @@ func LoadConfig(path string) (Config, error) {
 		Recovery:      raw.Recovery,
 		Confirmation:  raw.Confirmation,
 		Router:        raw.Router,
+		Transfer:      raw.Transfer,
+		Hangup:        raw.Hangup,
+		Drain:         raw.Drain,
+		Health:        raw.Health,
+		Admin:         raw.Admin,
+		Admission:     raw.Admission,
+		AMD:           raw.AMD,
+		DTMFGather:    raw.DTMFGather,
 		Environment:   raw.Environment,
 		LogLevel:      raw.LogLevel,
 		LogFormat:     raw.LogFormat,
@@ -263,6 +483,7 @@ func LoadConfig(path string) (Config, error) {
 		Debug: DebugConfig{
 			SimulateBadNet:  raw.SimulateBadNet,
 			SimulateHandoff: raw.SimulateHandoff,
+			FramePool:       raw.DebugFramePool,
 		},
 	}
 
@@ -282,9 +503,14 @@ func LoadConfig(path string) (Config, error) {
 }
 
 func (c *Config) Validate() error {
-	if strings.TrimSpace(c.Transports.Provider) == "" {
+	if strings.TrimSpace(c.Transports.Provider) == "" && len(c.Transports.Named) == 0 {
 		return fmt.Errorf("transports.provider is required")
 	}
+	for name, tc := range c.Transports.Named {
+		if strings.TrimSpace(tc.Provider) == "" {
+			return fmt.Errorf("transports.named.%s.provider is required", name)
+		}
+	}
 	if strings.TrimSpace(c.Vendors.STT.Provider) == "" {
 		return fmt.Errorf("vendors.stt.provider is required")
 	}
@@ -304,6 +530,19 @@ func expandEnvStrings(cfg *Config) {
 	cfg.Vendors.TTS.Settings = expandSettings(cfg.Vendors.TTS.Settings)
 	cfg.Vendors.LLM.Settings = expandSettings(cfg.Vendors.LLM.Settings)
 	cfg.Transports.Settings = expandSettings(cfg.Transports.Settings)
+	for name, tc := range cfg.Transports.Named {
+		// Map values are not addressable, so expandValue skips them.
+		tc.Provider = os.ExpandEnv(tc.Provider)
+		tc.Settings = expandSettings(tc.Settings)
+		tc.Overrides.BasePrompt = os.ExpandEnv(tc.Overrides.BasePrompt)
+		tc.Overrides.Greeting = os.ExpandEnv(tc.Overrides.Greeting)
+		for _, vc := range []*VendorConfig{tc.Overrides.STT, tc.Overrides.TTS, tc.Overrides.LLM} {
+			if vc != nil {
+				vc.Settings = expandSettings(vc.Settings)
+			}
+		}
+		cfg.Transports.Named[name] = tc
+	}
 	expandLanguageOverrides(cfg)
 }
 
@@ -409,3 +648,37 @@ func parseBackpressure(v string) pipeline.BackpressureMode {
 	}
 	return pipeline.BackpressureDrop
 }
+
+func parseErrorPolicies(in map[string]ErrorPolicyConfig) map[string]pipeline.ErrorPolicy {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make(map[string]pipeline.ErrorPolicy, len(in))
+	for name, pc := range in {
+		out[name] = parseErrorPolicy(pc)
+	}
+	return out
+}
+
+func parseErrorPolicy(pc ErrorPolicyConfig) pipeline.ErrorPolicy {
+	return pipeline.ErrorPolicy{
+		Action:  parseErrorAction(pc.Action),
+		Retries: pc.Retries,
+		Backoff: time.Duration(pc.BackoffMS) * time.Millisecond,
+	}
+}
+
+func parseErrorAction(v string) pipeline.ErrorAction {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "pass_through", "passthrough":
+		return pipeline.ErrorActionPassThrough
+	case "retry":
+		return pipeline.ErrorActionRetry
+	case "fallback":
+		return pipeline.ErrorActionFallback
+	case "end_call":
+		return pipeline.ErrorActionEndCall
+	default:
+		return pipeline.ErrorActionDrop
+	}
+}
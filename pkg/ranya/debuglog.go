@@ -0,0 +1,110 @@
+package ranya
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/redact"
+)
+
+// sessionDebug turns on debug logging for individual calls. Only loggers
+// handed out by logger honor it; the process-wide default is left alone.
+type sessionDebug struct {
+	mu    sync.Mutex
+	calls map[string]struct{}
+	set   atomic.Pointer[map[string]struct{}]
+}
+
+func newSessionDebug() *sessionDebug {
+	return &sessionDebug{calls: make(map[string]struct{})}
+}
+
+// enable turns debug logging on or off for callSID.
+func (d *sessionDebug) enable(callSID string, on bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if on {
+		d.calls[callSID] = struct{}{}
+	} else {
+		delete(d.calls, callSID)
+	}
+	snap := make(map[string]struct{}, len(d.calls))
+	for k := range d.calls {
+		snap[k] = struct{}{}
+	}
+	d.set.Store(&snap)
+}
+
+// active reports whether callSID has debug logging on.
+func (d *sessionDebug) active(callSID string) bool {
+	if d == nil || callSID == "" {
+		return false
+	}
+	set := d.set.Load()
+	if set == nil {
+		return false
+	}
+	_, ok := (*set)[callSID]
+	return ok
+}
+
+// logger returns a logger for callSID's session. It writes through the
+// current default handler, and also writes debug records while callSID has
+// debug logging on.
+func (d *sessionDebug) logger(callSID string) *slog.Logger {
+	h := &debugHandler{next: slog.Default().Handler(), debug: d, callSID: callSID}
+	return slog.New(h).With(frames.MetaCallSID, callSID)
+}
+
+// forget drops callSID once its session has ended.
+func (d *sessionDebug) forget(callSID string) {
+	if d.active(callSID) {
+		d.enable(callSID, false)
+	}
+}
+
+// frameDebugAttrs describes a non-audio frame for session debug logs.
+func frameDebugAttrs(f frames.Frame) []any {
+	attrs := []any{"stream_id", frames.StreamIDOf(f), "kind", string(f.Kind())}
+	switch v := f.(type) {
+	case frames.TextFrame:
+		attrs = append(attrs, "text", redact.Text(v.Text()))
+	case frames.ControlFrame:
+		attrs = append(attrs, "code", string(v.Code()))
+	case frames.SystemFrame:
+		attrs = append(attrs, "name", v.Name())
+	}
+	return attrs
+}
+
+// debugHandler lets records below the wrapped handler's level through while
+// its call has debug logging on.
+type debugHandler struct {
+	next    slog.Handler
+	debug   *sessionDebug
+	callSID string
+}
+
+func (h *debugHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level) || h.debug.active(h.callSID)
+}
+
+func (h *debugHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.next.Enabled(ctx, r.Level) && !h.debug.active(h.callSID) {
+		return nil
+	}
+	// The wrapped handler filters by level in Enabled only, so handing it
+	// the record directly gets it written.
+	return h.next.Handle(ctx, r)
+}
+
+func (h *debugHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &debugHandler{next: h.next.WithAttrs(attrs), debug: h.debug, callSID: h.callSID}
+}
+
+func (h *debugHandler) WithGroup(name string) slog.Handler {
+	return &debugHandler{next: h.next.WithGroup(name), debug: h.debug, callSID: h.callSID}
+}
@@ -15,12 +15,14 @@ import (
 
 type ToolDispatcher struct {
 	registry llm.ToolRegistry
+	builtins map[string]BuiltinTool
 	in       chan frames.Frame
 	tasks    chan map[string]string
 	opts     ToolDispatcherOptions
 
 	mu          sync.Mutex
 	streamLocks map[string]*sync.Mutex
+	closed      bool
 }
 
 type ToolDispatcherOptions struct {
@@ -33,6 +35,11 @@ type ToolDispatcherOptions struct {
 
 var ErrToolTimeout = errors.New("tool timeout")
 
+// BuiltinTool handles a tool call inside the pipeline instead of the
+// ToolRegistry. meta is the tool_call frame metadata; returned frames are
+// emitted downstream. An error is reported back to the LLM as a tool_result.
+type BuiltinTool func(meta map[string]string, args map[string]any) ([]frames.Frame, error)
+
 func NewToolDispatcher(registry llm.ToolRegistry, in chan frames.Frame) *ToolDispatcher {
 	return NewToolDispatcherWithOptions(registry, in, ToolDispatcherOptions{})
 }
@@ -61,6 +68,16 @@ func (d *ToolDispatcher) Name() string { return "tool_dispatcher" }
 
 func (d *ToolDispatcher) SetInput(in chan frames.Frame) { d.in = in }
 
+// RegisterBuiltin routes tool calls named name to fn.
+func (d *ToolDispatcher) RegisterBuiltin(name string, fn BuiltinTool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.builtins == nil {
+		d.builtins = make(map[string]BuiltinTool)
+	}
+	d.builtins[name] = fn
+}
+
 func (d *ToolDispatcher) Process(f frames.Frame) ([]frames.Frame, error) {
 	if f.Kind() != frames.KindControl {
 		return []frames.Frame{f}, nil
@@ -70,9 +87,20 @@ func (d *ToolDispatcher) Process(f frames.Frame) ([]frames.Frame, error) {
 		return []frames.Frame{f}, nil
 	}
 	meta := cf.Meta()
+	d.mu.Lock()
+	builtin := d.builtins[meta[frames.MetaToolName]]
+	d.mu.Unlock()
+	if builtin != nil {
+		return d.runBuiltin(f, meta, builtin), nil
+	}
 	if d.registry == nil || d.in == nil {
 		return []frames.Frame{f}, nil
 	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return []frames.Frame{f}, nil
+	}
 	select {
 	case d.tasks <- meta:
 	default:
@@ -81,6 +109,53 @@ func (d *ToolDispatcher) Process(f frames.Frame) ([]frames.Frame, error) {
 	return []frames.Frame{f}, nil
 }
 
+func (d *ToolDispatcher) runBuiltin(f frames.Frame, meta map[string]string, fn BuiltinTool) []frames.Frame {
+	args := map[string]any{}
+	_ = json.Unmarshal([]byte(meta[frames.MetaToolArgs]), &args)
+	out, err := fn(meta, args)
+	if err != nil {
+		slog.Warn("builtin_tool_failed", "tool_name", meta[frames.MetaToolName], "stream_id", meta[frames.MetaStreamID], "error", err)
+		if d.in != nil {
+			select {
+			case d.in <- toolResultFrame(meta, "error", "error", err):
+			default:
+			}
+		}
+		return []frames.Frame{f}
+	}
+	return append([]frames.Frame{f}, out...)
+}
+
+// OnSessionEnd implements pipeline.SessionEndHandler.
+func (d *ToolDispatcher) OnSessionEnd(meta map[string]string) {
+	d.mu.Lock()
+	delete(d.streamLocks, meta[frames.MetaStreamID])
+	d.mu.Unlock()
+}
+
+// MigrateStream implements pipeline.StreamMigrator, keeping tool calls
+// serialized across a reconnect.
+func (d *ToolDispatcher) MigrateStream(oldStreamID, newStreamID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if lock, ok := d.streamLocks[oldStreamID]; ok {
+		d.streamLocks[newStreamID] = lock
+		delete(d.streamLocks, oldStreamID)
+	}
+}
+
+// Close implements pipeline.ProcessorCloser. It stops the worker pool once
+// queued tool calls have drained.
+func (d *ToolDispatcher) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.closed {
+		d.closed = true
+		close(d.tasks)
+	}
+	return nil
+}
+
 func (d *ToolDispatcher) worker() {
 	for meta := range d.tasks {
 		d.exec(meta)
@@ -119,10 +194,19 @@ func (d *ToolDispatcher) exec(meta map[string]string) {
 			result = "error"
 		}
 	}
+	select {
+	case d.in <- toolResultFrame(meta, result, status, err):
+	default:
+	}
+}
+
+// toolResultFrame builds the tool_result system frame answering the
+// tool_call described by meta.
+func toolResultFrame(meta map[string]string, result, status string, err error) frames.Frame {
 	outMeta := map[string]string{
 		frames.MetaStreamID:   meta[frames.MetaStreamID],
-		frames.MetaToolCallID: callID,
-		frames.MetaToolName:   name,
+		frames.MetaToolCallID: meta[frames.MetaToolCallID],
+		frames.MetaToolName:   meta[frames.MetaToolName],
 		frames.MetaToolResult: result,
 		frames.MetaToolStatus: status,
 	}
@@ -138,11 +222,7 @@ func (d *ToolDispatcher) exec(meta map[string]string) {
 	if lang := meta[frames.MetaLanguage]; lang != "" {
 		outMeta[frames.MetaLanguage] = lang
 	}
-	sf := frames.NewSystemFrame(meta[frames.MetaStreamID], time.Now().UnixNano(), "tool_result", outMeta)
-	select {
-	case d.in <- sf:
-	default:
-	}
+	return frames.NewSystemFrame(meta[frames.MetaStreamID], time.Now().UnixNano(), "tool_result", outMeta)
 }
 
 func (d *ToolDispatcher) callWithRetry(name string, args map[string]any) (string, error) {
@@ -212,3 +292,6 @@ func (d *ToolDispatcher) idempotencyKey(meta map[string]string) string {
 }
 
 var _ pipeline.FrameProcessor = (*ToolDispatcher)(nil)
+var _ pipeline.SessionEndHandler = (*ToolDispatcher)(nil)
+var _ pipeline.StreamMigrator = (*ToolDispatcher)(nil)
+var _ pipeline.ProcessorCloser = (*ToolDispatcher)(nil)
@@ -0,0 +1,149 @@
+package ranya
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/transports"
+)
+
+// FrameDrainNotice carries drain.message to callers still connected when the
+// drain deadline passes.
+const FrameDrainNotice = "drain_notice"
+
+// drainSlack covers hanging up the remaining calls and stopping transports
+// once the drain deadline has passed.
+const drainSlack = 5 * time.Second
+
+// drain refuses new calls and lets calls in progress end on their own until
+// drain.timeout_ms passes. Callers still connected then hear drain.message
+// and are hung up; anything left after that is cut off. Without a timeout
+// calls are cut off right away.
+func (e *Engine) drain() error {
+	e.registry.SetDraining(true)
+	for _, name := range e.transports.names {
+		if d, ok := e.transports.get(name).(transports.CallDrainer); ok {
+			d.DrainCalls()
+		}
+	}
+	timeout := time.Duration(e.cfg.Drain.TimeoutMS) * time.Millisecond
+	slog.Info("drain_started", "active_calls", e.registry.Count(), "timeout_ms", e.cfg.Drain.TimeoutMS)
+	if e.registry.Count() > 0 && timeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		_ = e.registry.WaitForEmpty(ctx, 200*time.Millisecond)
+		cancel()
+		if remaining := e.registry.Count(); remaining > 0 {
+			slog.Info("drain_deadline", "active_calls", remaining)
+			e.endRemaining()
+			ctx, cancel := context.WithTimeout(context.Background(), hangupBudget(e.cfg.Hangup))
+			_ = e.registry.WaitForEmpty(ctx, 200*time.Millisecond)
+			cancel()
+		}
+	}
+	for _, name := range e.transports.names {
+		_ = e.transports.get(name).Stop()
+	}
+	e.registry.CloseAll()
+	if e.cancel != nil {
+		e.cancel()
+	}
+	return nil
+}
+
+// endRemaining speaks drain.message on every live call and asks the sink to
+// hang up once it has played.
+func (e *Engine) endRemaining() {
+	message := strings.TrimSpace(e.cfg.Drain.Message)
+	for _, sess := range e.registry.Sessions() {
+		streamID := sess.Stream()
+		meta := map[string]string{
+			frames.MetaStreamID: streamID,
+			frames.MetaCallSID:  sess.CallSID,
+		}
+		if sess.TraceID != "" {
+			meta[frames.MetaTraceID] = sess.TraceID
+		}
+		if message != "" {
+			notice := maps.Clone(meta)
+			notice[frames.MetaGreetingText] = message
+			nonBlockingSend(sess.Orch.In(), frames.NewSystemFrame(streamID, time.Now().UnixNano(), FrameDrainNotice, notice))
+		}
+		meta[frames.MetaReason] = "drain"
+		meta[frames.MetaCallEndReason] = "shutdown"
+		nonBlockingSend(sess.Orch.In(), frames.NewControlFrame(streamID, time.Now().UnixNano(), frames.ControlHangup, meta))
+	}
+}
+
+// hangupBudget bounds how long hangups started at the drain deadline may take.
+func hangupBudget(cfg HangupConfig) time.Duration {
+	wait := time.Duration(cfg.MaxWaitMS) * time.Millisecond
+	if wait <= 0 {
+		wait = defaultHangupMaxWait
+	}
+	grace := time.Duration(cfg.GraceMS) * time.Millisecond
+	if grace <= 0 {
+		grace = defaultHangupGrace
+	}
+	return wait + grace + hangupTimeout
+}
+
+// drainTimeout is the lifecycle runner's budget for drain.
+func drainTimeout(cfg Config) time.Duration {
+	return time.Duration(cfg.Drain.TimeoutMS)*time.Millisecond + hangupBudget(cfg.Hangup) + drainSlack
+}
+
+// HealthStatus is the body served by the liveness and readiness probes.
+type HealthStatus struct {
+	Status      string `json:"status"`
+	Draining    bool   `json:"draining"`
+	ActiveCalls int64  `json:"active_calls"`
+}
+
+// Ready reports whether the engine accepts new calls. It fails once a drain
+// has started, while Health keeps passing until the engine has stopped.
+func (e *Engine) Ready() error {
+	if err := e.Health(); err != nil {
+		return err
+	}
+	if e.registry.Draining() {
+		return errors.New("draining")
+	}
+	return nil
+}
+
+// HealthHandler serves /livez and /readyz with a HealthStatus body. Point the
+// load balancer at /readyz so a draining instance stops receiving calls
+// while the orchestrator waits on /livez.
+func (e *Engine) HealthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/livez", func(w http.ResponseWriter, r *http.Request) {
+		e.writeHealth(w, e.Health())
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		e.writeHealth(w, e.Ready())
+	})
+	return mux
+}
+
+func (e *Engine) writeHealth(w http.ResponseWriter, err error) {
+	status := HealthStatus{
+		Status:      "ok",
+		Draining:    e.registry.Draining(),
+		ActiveCalls: e.registry.Count(),
+	}
+	code := http.StatusOK
+	if err != nil {
+		status.Status = err.Error()
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,107 @@
+package ranya
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/pipeline"
+	"github.com/harunnryd/ranya/pkg/transports/mock"
+)
+
+func TestDrainWaitsThenEndsRemainingCalls(t *testing.T) {
+	tr := mock.New()
+	router := newTransportRouter(tr, nil)
+	hangups := &callHangups{cfg: HangupConfig{GraceMS: 10}, router: router}
+	var mu sync.Mutex
+	var notices []string
+	registry := pipeline.NewSessionRegistry(func(ctx context.Context, callSID, streamID, traceID string) (pipeline.Orchestrator, error) {
+		orch := pipeline.New(pipeline.Config{HighCapacity: 8, LowCapacity: 8, StageBuffer: 8})
+		orch.SetContext(ctx)
+		orch.SetSink(func(f frames.Frame) {
+			switch v := f.(type) {
+			case frames.SystemFrame:
+				mu.Lock()
+				notices = append(notices, v.Meta()[frames.MetaGreetingText])
+				mu.Unlock()
+			case frames.ControlFrame:
+				if v.Code() == frames.ControlHangup {
+					hangups.start(callSID, v)
+				}
+			}
+		})
+		return orch, nil
+	})
+	hangups.registry = registry
+	for _, sid := range []string{"call-1", "call-2"} {
+		if _, _, err := registry.GetOrCreate(sid, "stream-"+sid, ""); err != nil {
+			t.Fatalf("create session: %v", err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &Engine{
+		cfg: Config{
+			Drain:  DrainConfig{TimeoutMS: 150, Message: "We need to end the call now."},
+			Hangup: HangupConfig{GraceMS: 10},
+		},
+		registry:   registry,
+		transports: router,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	srv := httptest.NewServer(e.HealthHandler())
+	defer srv.Close()
+	probe := func(path string) (int, HealthStatus) {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("get %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		var st HealthStatus
+		_ = json.NewDecoder(resp.Body).Decode(&st)
+		return resp.StatusCode, st
+	}
+	if code, st := probe("/readyz"); code != http.StatusOK || st.ActiveCalls != 2 {
+		t.Fatalf("expected ready with 2 calls, got %d %+v", code, st)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_ = e.drain()
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if !tr.Draining() {
+		t.Fatalf("expected the transport to refuse new calls")
+	}
+	if code, st := probe("/readyz"); code != http.StatusServiceUnavailable || !st.Draining {
+		t.Fatalf("expected readiness to fail while draining, got %d %+v", code, st)
+	}
+	if code, _ := probe("/livez"); code != http.StatusOK {
+		t.Fatalf("expected liveness to hold while draining, got %d", code)
+	}
+	// call-2 ends on its own before the deadline.
+	registry.End("call-2", nil)
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("drain did not finish")
+	}
+	if got := tr.Hangups(); len(got) != 1 || got[0] != "call-1" {
+		t.Fatalf("expected only call-1 to be hung up, got %v", got)
+	}
+	mu.Lock()
+	if len(notices) != 1 || notices[0] != "We need to end the call now." {
+		t.Fatalf("unexpected drain notices %v", notices)
+	}
+	mu.Unlock()
+	if code, _ := probe("/livez"); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected liveness to fail once stopped, got %d", code)
+	}
+}
@@ -3,16 +3,20 @@ package ranya
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/harunnryd/ranya/pkg/adapters/stt"
 	"github.com/harunnryd/ranya/pkg/adapters/tts"
 	"github.com/harunnryd/ranya/pkg/aggregators"
+	"github.com/harunnryd/ranya/pkg/errorsx"
 	"github.com/harunnryd/ranya/pkg/frames"
 	"github.com/harunnryd/ranya/pkg/llm"
 	"github.com/harunnryd/ranya/pkg/metrics"
@@ -26,14 +30,26 @@ import (
 )
 
 type Engine struct {
-	cfg       Config
-	registry  *pipeline.SessionRegistry
-	transport transports.Transport
-	providers *ProviderRegistry
-	runner    *pipeline.Runner
-	asyncObs  *metrics.AsyncObserver
-	ctx       context.Context
-	cancel    context.CancelFunc
+	cfg        Config
+	registry   *pipeline.SessionRegistry
+	transport  transports.Transport
+	transports *transportRouter
+	overrides  map[string]TransportOverrides
+	providers  *ProviderRegistry
+	runner     *pipeline.Runner
+	asyncObs   *metrics.AsyncObserver
+	recorder   *observers.CallRecorder
+	playback   *playbackTracker
+	admission  *callAdmission
+	refused    sync.Map // callSID -> struct{}, calls hung up by admitSession
+	hooks      *engineHooks
+	health     *http.Server
+	admin      *http.Server
+	live       *liveSessions
+	tap        *observers.Tap
+	debug      *sessionDebug
+	ctx        context.Context
+	cancel     context.CancelFunc
 
 	// Customization
 	tools  llm.ToolRegistry
@@ -65,12 +81,25 @@ type EngineOptions struct {
 	STTFactoriesByLang map[string]func(callSID, streamID string) stt.StreamingSTT
 	ToolOptions        ToolDispatcherOptions
 	SilenceReprompt    *processors.SilenceRepromptConfig
+	// Transports are served alongside Transport (registered as "default").
+	// Each session remembers the transport it arrived on and outbound frames
+	// are routed back to it.
+	Transports map[string]transports.Transport
+	// TransportOverrides adjust the session config per transport name and
+	// take precedence over transports.named.<name>.overrides.
+	TransportOverrides map[string]TransportOverrides
+	// RecordingEncoder builds the Opus encoder for
+	// observability.recording.format=opus.
+	RecordingEncoder func(sampleRate, channels int) (observers.OpusEncoder, error)
+	// Hooks receive typed call events off the call path; see EngineHooks.
+	Hooks EngineHooks
 }
 
 func NewEngine(opts EngineOptions) *Engine {
 	cfg := opts.Config
 	SetDefaultLogger(cfg.LogLevel)
 	redact.SetEnabled(cfg.Privacy.RedactPII)
+	frames.SetPoolDebug(cfg.Debug.FramePool)
 
 	slog.Info("ranya_init",
 		"environment", cfg.Environment,
@@ -86,15 +115,23 @@ func NewEngine(opts EngineOptions) *Engine {
 	logObs := observers.NewLoggerObserver(slog.Default())
 	var timelineObs *observers.TimelineObserver
 	var costObs *observers.CostObserver
-	obsList := []metrics.Observer{latencyObs, logObs}
+	var deadLetterObs *observers.DeadLetterObserver
+	tap := observers.NewTap()
+	obsList := []metrics.Observer{latencyObs, logObs, tap}
+	eventHooks := newEngineHooks(opts.Hooks)
+	if eventHooks != nil && eventHooks.cost != nil {
+		obsList = append(obsList, eventHooks.cost)
+	}
 	if dir := strings.TrimSpace(cfg.Observability.ArtifactsDir); dir != "" {
 		if cfg.Observability.RetentionDays > 0 {
 			_, _ = observers.PurgeArtifacts(dir, time.Duration(cfg.Observability.RetentionDays)*24*time.Hour)
 		}
 		timelineObs = observers.NewTimelineObserver(dir)
 		costObs = observers.NewCostObserver(dir)
-		obsList = append(obsList, timelineObs, costObs)
+		deadLetterObs = observers.NewDeadLetterObserver(dir)
+		obsList = append(obsList, timelineObs, costObs, deadLetterObs)
 	}
+	recorder := newCallRecorder(cfg.Observability, opts.RecordingEncoder)
 	multiObs := observers.NewMultiObserver(obsList...)
 	asyncObs := metrics.NewAsyncObserver(multiObs, 2048)
 
@@ -103,12 +140,66 @@ func NewEngine(opts EngineOptions) *Engine {
 		providers = NewProviderRegistry()
 	}
 
-	var sink func(frames.Frame)
-	if opts.Transport != nil {
-		sink = func(f frames.Frame) {
+	router := newTransportRouter(opts.Transport, opts.Transports)
+	for _, name := range router.missing(cfg.Transports) {
+		slog.Warn("named_transport_missing", "name", name)
+	}
+	overrides := transportOverrides(cfg, opts.TransportOverrides)
+	baseCfg := cfg
+
+	var registry *pipeline.SessionRegistry
+	transfers := &callTransfers{cfg: cfg.Transfer, router: router, obs: asyncObs}
+	playback := newPlaybackTracker()
+	hangups := &callHangups{cfg: cfg.Hangup, router: router, playback: playback, obs: asyncObs}
+	live := &liveSessions{}
+	hangups.speaking = func(callSID, streamID string) bool {
+		sess := live.get(callSID)
+		return sess != nil && sess.tts != nil && sess.tts.Speaking(streamID)
+	}
+	debug := newSessionDebug()
+	admission := newCallAdmission(cfg.Admission, asyncObs)
+	if admission != nil {
+		for _, name := range router.names {
+			if a, ok := router.get(name).(transports.CallAdmitter); ok {
+				a.SetAdmission(func(req transports.AdmissionRequest) transports.AdmissionDecision {
+					return admission.admit(name, req)
+				})
+			}
+		}
+	}
+	// sink receives each session's outbound frames; callSID is the session's,
+	// so routing does not depend on processors copying it into metadata.
+	var sink func(callSID string, f frames.Frame)
+	if !router.empty() {
+		sink = func(callSID string, f frames.Frame) {
+			if f.Kind() != frames.KindAudio && debug.active(callSID) {
+				debug.logger(callSID).Debug("session_frame_out", frameDebugAttrs(f)...)
+			}
+			if f.Kind() == frames.KindControl {
+				switch cf := f.(frames.ControlFrame); cf.Code() {
+				case frames.ControlTransfer:
+					transfers.start(callSID, cf)
+					return
+				case frames.ControlHangup:
+					hangups.start(callSID, cf)
+					return
+				case frames.ControlRecordingPause, frames.ControlRecordingResume:
+					if recorder != nil {
+						if cf.Code() == frames.ControlRecordingPause {
+							recorder.Pause(callSID)
+						} else {
+							recorder.Resume(callSID)
+						}
+					}
+					return
+				}
+			}
+			if isEndCallError(f) {
+				hangups.start(callSID, errorHangup(f))
+			}
 			if asyncObs != nil && f.Kind() == frames.KindAudio {
 				af := f.(frames.AudioFrame)
-				meta := f.Meta()
+				meta := af.Metadata()
 				fields := map[string]any{
 					"sample_rate": af.Rate(),
 					"channels":    af.Channels(),
@@ -117,9 +208,9 @@ func NewEngine(opts EngineOptions) *Engine {
 					fields["payload_b64"] = base64.StdEncoding.EncodeToString(af.RawPayload())
 				}
 				tags := map[string]string{
-					"stream_id":        meta[frames.MetaStreamID],
-					frames.MetaTraceID: meta[frames.MetaTraceID],
-					frames.MetaCallSID: meta[frames.MetaCallSID],
+					"stream_id":        meta.StreamID(),
+					frames.MetaTraceID: meta.TraceID(),
+					frames.MetaCallSID: meta.CallSID(),
 					"component":        "transport",
 				}
 				asyncObs.RecordEvent(metrics.MetricsEvent{
@@ -129,12 +220,28 @@ func NewEngine(opts EngineOptions) *Engine {
 					Fields: fields,
 				})
 			}
-			_ = opts.Transport.Send(f)
+			// Voicemail drops hang up through the same playback-aware path.
+			if cfg.Hangup.Enabled || cfg.AMD.Enabled {
+				playback.observe(f)
+			}
+			if recorder != nil {
+				recordAgent(recorder, callSID, f)
+			}
+			if t := router.lookup(frames.StreamIDOf(f), callSID); t != nil {
+				_ = t.Send(f)
+			}
 		}
 	}
 
 	// Registry Factory
-	registry := pipeline.NewSessionRegistry(func(ctx context.Context, callSID, streamID, traceID string) (pipeline.Orchestrator, error) {
+	registry = pipeline.NewSessionRegistry(func(ctx context.Context, callSID, streamID, traceID string) (pipeline.Orchestrator, error) {
+		override := overrides[router.nameForCall(callSID)]
+		cfg := applyTransportOverrides(baseCfg, override)
+		sessionLanguage := opts.DefaultLanguage
+		if strings.TrimSpace(override.Language) != "" {
+			sessionLanguage = override.Language
+		}
+
 		// Build STT processor.
 		sttFactory, err := providers.BuildSTTFactory(cfg.Vendors.STT.Provider, cfg, traceID)
 		if err != nil {
@@ -146,7 +253,7 @@ func NewEngine(opts EngineOptions) *Engine {
 			sttFactories = buildSTTLanguageFactories(cfg, providers, traceID)
 		}
 		if len(sttFactories) > 0 {
-			sttProc.SetLanguageFactories(sttFactories, defaultLanguage(cfg, opts.DefaultLanguage))
+			sttProc.SetLanguageFactories(sttFactories, defaultLanguage(cfg, sessionLanguage))
 		}
 		sttProc.SetCodeSwitching(cfg.Languages.CodeSwitching)
 		sttProc.SetForwardInterim(cfg.STT.ForwardInterim)
@@ -171,6 +278,17 @@ func NewEngine(opts EngineOptions) *Engine {
 		if opts.Tools != nil {
 			tools = opts.Tools.Tools()
 		}
+		if transferEnabled(cfg.Transfer) {
+			tools = append(tools, transferCallTool(cfg.Transfer))
+		}
+		if cfg.Hangup.Enabled {
+			tools = append(tools, endCallTool())
+		}
+		var gatherer *processors.DTMFGatherer
+		if cfg.DTMFGather.Enabled {
+			gatherer = processors.NewDTMFGatherer(gatherDefaults(cfg.DTMFGather))
+			tools = append(tools, gatherDigitsTool())
+		}
 
 		llmProc := processors.NewLLMProcessor(llmAdapter, "", tools)
 		if cfg.Context.MaxHistory > 0 || cfg.Context.MaxTokens > 0 {
@@ -203,10 +321,11 @@ func NewEngine(opts EngineOptions) *Engine {
 			ttsFactories = buildTTSLanguageFactories(cfg, providers)
 		}
 		if len(ttsFactories) > 0 {
-			ttsProc.SetLanguageFactories(ttsFactories, defaultLanguage(cfg, opts.DefaultLanguage))
+			ttsProc.SetLanguageFactories(ttsFactories, defaultLanguage(cfg, sessionLanguage))
 		}
 		ttsProc.SetObserver(asyncObs)
 		ttsProc.SetContext(ctx)
+		ttsProc.SetLogger(debug.logger(callSID))
 
 		// 4. Dispatcher
 		toolOpts := opts.ToolOptions
@@ -214,6 +333,15 @@ func NewEngine(opts EngineOptions) *Engine {
 			toolOpts = toolOptionsFromConfig(cfg)
 		}
 		dispatcher := NewToolDispatcherWithOptions(opts.Tools, nil, toolOpts)
+		if transferEnabled(cfg.Transfer) {
+			dispatcher.RegisterBuiltin(TransferCallTool, transferCallBuiltin(cfg.Transfer))
+		}
+		if cfg.Hangup.Enabled {
+			dispatcher.RegisterBuiltin(EndCallTool, endCallBuiltin)
+		}
+		if gatherer != nil {
+			dispatcher.RegisterBuiltin(GatherDigitsTool, gatherDigitsBuiltin(gatherer))
+		}
 
 		// 5. Context / Aggregator
 		maxHistory := 10
@@ -245,6 +373,14 @@ func NewEngine(opts EngineOptions) *Engine {
 		ctxProc.SetTurnManager(turnProc.Manager())
 
 		builder := pipeline.NewVoiceAgentBuilder()
+		// AMD goes first so it sees raw caller audio and can hold the
+		// greeting until a human answers.
+		var amd *processors.AMDProcessor
+		if cfg.AMD.Enabled {
+			amd = processors.NewAMDProcessor(amdFromConfig(cfg.AMD))
+			amd.SetObserver(asyncObs)
+			builder = builder.WithAcoustic(amd)
+		}
 		for _, p := range opts.PreProcessors {
 			if p != nil {
 				builder = builder.WithAcoustic(p)
@@ -257,16 +393,33 @@ func NewEngine(opts EngineOptions) *Engine {
 				MaxChars:   cfg.Summary.MaxChars,
 			})
 			summaryProc.SetObserver(asyncObs)
+			if eventHooks != nil {
+				summaryProc.SetOnSummary(eventHooks.summary)
+			}
 			beforeTTS = append(beforeTTS, summaryProc)
 		}
-		builder = builder.WithSTT(sttProc).
-			WithTurnManager(turnProc).
-			WithProcessorList(opts.BeforeContext).
-			WithContext(ctxProc).
+		builder = builder.WithSTT(sttProc)
+		if amd != nil {
+			builder = builder.WithProcessor(amd.Gate())
+		}
+		builder = builder.WithTurnManager(turnProc).
+			WithProcessorList(opts.BeforeContext)
+		// The gatherer sits after BeforeContext so it sees DTMFDisambiguator
+		// marks, and ahead of the context so gathered keys never become turns.
+		if gatherer != nil {
+			builder = builder.WithProcessor(gatherer)
+		}
+		builder = builder.WithContext(ctxProc).
 			WithRouter(configureRouter(opts)).
-			WithProcessorList(opts.BeforeLLM).
-			WithLLM(llmProc).
-			WithProcessor(dispatcher).
+			WithProcessorList(opts.BeforeLLM)
+		if eventHooks != nil {
+			builder = builder.WithProcessor(eventHooks.inputTap(callSID, router.nameForCall(callSID))).
+				WithLLM(llmProc).
+				WithProcessor(eventHooks.outputTap(callSID))
+		} else {
+			builder = builder.WithLLM(llmProc)
+		}
+		builder = builder.WithProcessor(dispatcher).
 			WithProcessorList(beforeTTS).
 			WithTTS(ttsProc)
 		if opts.Filler != nil {
@@ -279,34 +432,64 @@ func NewEngine(opts EngineOptions) *Engine {
 		}
 
 		orch := builder.Build(cfg.Pipeline)
+		live.stage(&liveSession{orch: orch, llm: llmProc, ctx: ctxProc, turn: turnProc, tts: ttsProc})
+		// A session that loses the creation race is cancelled unregistered.
+		context.AfterFunc(ctx, func() { live.discard(callSID, orch) })
 		orch.SetContext(ctx)
 		orch.SetObserver(asyncObs)
 		dispatcher.SetInput(orch.In())
+		if gatherer != nil {
+			gatherer.SetInput(orch.In())
+		}
 
 		if sink != nil {
-			orch.SetSink(sink)
+			orch.SetSink(func(f frames.Frame) { sink(callSID, f) })
 		}
 
-		go func() {
-			<-ctx.Done()
-			sttProc.CloseAll()
-			ttsProc.CloseAll()
-		}()
-
 		return orch, nil
 	})
+	registry.SetObserver(asyncObs)
+	transfers.registry = registry
+	hangups.registry = registry
+	registry.SetOnReap(hangups.reaped)
+	registry.SetOnEnd(func(sess *pipeline.Session) {
+		if admission != nil {
+			admission.release(sess.CallSID)
+		}
+		live.discard(sess.CallSID, sess.Orch)
+		eventHooks.callEnd(sess)
+		debug.forget(sess.CallSID)
+		router.unbind(sess.CallSID, sess.Stream())
+		playback.forget(sess.Stream())
+		if recorder != nil {
+			if path, err := recorder.Finish(sess.CallSID); err != nil {
+				slog.Warn("recording_failed", "call_sid", sess.CallSID, "error", err)
+			} else if path != "" {
+				slog.Info("recording_saved", "call_sid", sess.CallSID, "path", path)
+			}
+		}
+	})
 
 	hooks := runner.Hooks{
 		OnStart: func() {
 			fields := []any{"message", "Ranya Engine Ready"}
-			if rr, ok := opts.Transport.(transports.ReadyReporter); ok {
+			for _, name := range router.names {
+				rr, ok := router.get(name).(transports.ReadyReporter)
+				if !ok {
+					continue
+				}
 				for k, v := range rr.ReadyFields() {
+					// Prefix fields once more than one transport is served.
+					if len(router.names) > 1 {
+						k = name + "." + k
+					}
 					fields = append(fields, k, v)
 				}
 			}
 			slog.Info("engine_ready", fields...)
 		},
 		OnStop: func() {
+			eventHooks.close()
 			if asyncObs != nil {
 				asyncObs.Close()
 			}
@@ -316,39 +499,129 @@ func NewEngine(opts EngineOptions) *Engine {
 			if costObs != nil {
 				_ = costObs.Close()
 			}
+			if deadLetterObs != nil {
+				_ = deadLetterObs.Close()
+			}
+			if recorder != nil {
+				_ = recorder.Close()
+			}
+			if frames.PoolDebug() {
+				stats := frames.AudioPoolStats()
+				slog.Info("audio_pool_stats", "acquired", stats.Acquired, "released", stats.Released, "live", stats.Live, "double_releases", stats.DoubleReleases)
+				for _, leak := range frames.LeakedAudioFrames(time.Second) {
+					slog.Warn("audio_frame_leak", "frame_id", leak.ID, "age", leak.Age, "stack", leak.Stack)
+				}
+			}
 			slog.Info("shutdown", "goroutines", runtime.NumGoroutine(), "active_calls", registry.Count())
 		},
 	}
 
-	drainer := pipeline.DrainerFunc(func() error {
-		if opts.Transport != nil {
-			_ = opts.Transport.Stop()
-		}
-		registry.SetDraining(true)
-		registry.CloseAll()
-		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
-		defer cancel()
-		_ = registry.WaitForEmpty(ctx, 200*time.Millisecond)
-		return nil
-	})
+	ctx, cancel := context.WithCancel(context.Background())
 
-	lr := pipeline.NewDrainRunner(drainer, hooks, 30*time.Second)
+	e := &Engine{
+		cfg:        cfg,
+		registry:   registry,
+		transport:  opts.Transport,
+		transports: router,
+		overrides:  overrides,
+		providers:  providers,
+		asyncObs:   asyncObs,
+		recorder:   recorder,
+		playback:   playback,
+		admission:  admission,
+		live:       live,
+		tap:        tap,
+		debug:      debug,
+		hooks:      eventHooks,
+		ctx:        ctx,
+		cancel:     cancel,
+		tools:      opts.Tools,
+		agents:     opts.Agents,
+		router:     opts.Router,
+	}
+	e.runner = pipeline.NewDrainRunner(pipeline.DrainerFunc(e.drain), hooks, drainTimeout(cfg))
+	return e
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// isEndCallError reports whether f is an error frame whose policy ends the call.
+func isEndCallError(f frames.Frame) bool {
+	if f.Kind() != frames.KindSystem {
+		return false
+	}
+	sf := f.(frames.SystemFrame)
+	if sf.Name() != pipeline.ErrorFrameName {
+		return false
+	}
+	return sf.Metadata().Get(frames.MetaErrorAction) == pipeline.ErrorActionEndCall.String()
+}
 
-	return &Engine{
-		cfg:       cfg,
-		registry:  registry,
-		transport: opts.Transport,
-		providers: providers,
-		runner:    lr,
-		asyncObs:  asyncObs,
-		ctx:       ctx,
-		cancel:    cancel,
-		tools:     opts.Tools,
-		agents:    opts.Agents,
-		router:    opts.Router,
+// errorHangup turns an end_call error frame into the ControlHangup that
+// hangs up the transport call and ends the session as processor_error.
+func errorHangup(f frames.Frame) frames.ControlFrame {
+	md := frames.MetadataOf(f)
+	meta := map[string]string{
+		frames.MetaStreamID:      md.StreamID(),
+		frames.MetaReason:        md.Get(frames.MetaErrorReason),
+		frames.MetaCallEndReason: string(errorsx.ReasonProcessor),
+	}
+	for _, key := range []string{frames.MetaCallSID, frames.MetaTraceID} {
+		if v := md.Get(key); v != "" {
+			meta[key] = v
+		}
 	}
+	return frames.NewControlFrame(md.StreamID(), time.Now().UnixNano(), frames.ControlHangup, meta)
+}
+
+// transportOverrides merges config overrides with EngineOptions ones, which
+// win field by field.
+func transportOverrides(cfg Config, opts map[string]TransportOverrides) map[string]TransportOverrides {
+	out := make(map[string]TransportOverrides, len(cfg.Transports.Named)+len(opts))
+	for name, tc := range cfg.Transports.Named {
+		out[name] = tc.Overrides
+	}
+	for name, ov := range opts {
+		merged := out[name]
+		if ov.STT != nil {
+			merged.STT = ov.STT
+		}
+		if ov.TTS != nil {
+			merged.TTS = ov.TTS
+		}
+		if ov.LLM != nil {
+			merged.LLM = ov.LLM
+		}
+		if ov.BasePrompt != "" {
+			merged.BasePrompt = ov.BasePrompt
+		}
+		if ov.Language != "" {
+			merged.Language = ov.Language
+		}
+		if ov.Greeting != "" {
+			merged.Greeting = ov.Greeting
+		}
+		out[name] = merged
+	}
+	return out
+}
+
+// applyTransportOverrides returns the session config for a transport.
+func applyTransportOverrides(cfg Config, ov TransportOverrides) Config {
+	if ov.STT != nil {
+		cfg.Vendors.STT = mergeVendorConfig(cfg.Vendors.STT, ov.STT)
+	}
+	if ov.TTS != nil {
+		cfg.Vendors.TTS = mergeVendorConfig(cfg.Vendors.TTS, ov.TTS)
+	}
+	if ov.LLM != nil {
+		cfg.Vendors.LLM = mergeVendorConfig(cfg.Vendors.LLM, ov.LLM)
+	}
+	if strings.TrimSpace(ov.BasePrompt) != "" {
+		cfg.BasePrompt = ov.BasePrompt
+	}
+	if strings.TrimSpace(ov.Language) != "" {
+		cfg.Languages.Default = strings.TrimSpace(ov.Language)
+	}
+	return cfg
 }
 
 func configureRouter(opts EngineOptions) pipeline.FrameProcessor {
@@ -377,6 +650,23 @@ func defaultLanguage(cfg Config, override string) string {
 	return "id"
 }
 
+func amdFromConfig(cfg AMDConfig) processors.AMDConfig {
+	ms := func(v int) time.Duration { return time.Duration(v) * time.Millisecond }
+	return processors.AMDConfig{
+		InitialSilence:       ms(cfg.InitialSilenceMS),
+		Greeting:             ms(cfg.GreetingMS),
+		AfterGreetingSilence: ms(cfg.AfterGreetingSilenceMS),
+		TotalAnalysis:        ms(cfg.TotalAnalysisMS),
+		MaxWords:             cfg.MaxWords,
+		Phrases:              cfg.Phrases,
+		SilenceThreshold:     cfg.SilenceThreshold,
+		VoicemailMessage:     cfg.Voicemail.Message,
+		VoicemailByLanguage:  cfg.Voicemail.MessageByLanguage,
+		BeepTimeout:          ms(cfg.Voicemail.BeepTimeoutMS),
+		MessageEndSilence:    ms(cfg.Voicemail.MessageEndSilenceMS),
+	}
+}
+
 func silenceRepromptFromConfig(cfg Config) *processors.SilenceRepromptConfig {
 	sr := cfg.Turn.SilenceReprompt
 	if sr.TimeoutMS == 0 && sr.MaxAttempts == 0 && sr.PromptText == "" && len(sr.PromptByLanguage) == 0 {
@@ -489,15 +779,43 @@ func mergeVendorConfig(base VendorConfig, override *VendorConfig) VendorConfig {
 	return out
 }
 
+// Start serves the transports until ctx is cancelled or Stop is called;
+// either one drains calls in progress before the transports shut down.
 func (e *Engine) Start(ctx context.Context) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	if e.transport != nil {
-		if err := e.transport.Start(ctx); err != nil {
-			return err
+	// Transports run on the engine context so that cancelling ctx drains
+	// instead of cutting live calls.
+	for _, name := range e.transports.names {
+		t := e.transports.get(name)
+		if err := t.Start(e.ctx); err != nil {
+			return fmt.Errorf("start transport %s: %w", name, err)
+		}
+		go e.routeTransport(e.ctx, name, t)
+	}
+	if idle := time.Duration(e.cfg.Engine.SessionIdleTimeoutMS) * time.Millisecond; idle > 0 {
+		go e.registry.RunReaper(e.ctx, idle, 0)
+	}
+	if addr := strings.TrimSpace(e.cfg.Health.Addr); addr != "" {
+		e.health = &http.Server{Addr: addr, ReadHeaderTimeout: 5 * time.Second, Handler: e.HealthHandler()}
+		go func() {
+			if err := e.health.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("health_server_error", "error", err.Error())
+			}
+		}()
+	}
+	if addr := strings.TrimSpace(e.cfg.Admin.Addr); addr != "" {
+		if e.cfg.Admin.Token == "" {
+			slog.Error("admin_server_disabled", "reason", "admin.token is required")
+		} else {
+			e.admin = &http.Server{Addr: addr, ReadHeaderTimeout: 5 * time.Second, Handler: e.AdminHandler(e.cfg.Admin.Token)}
+			go func() {
+				if err := e.admin.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					slog.Error("admin_server_error", "error", err.Error())
+				}
+			}()
 		}
-		go e.routeTransport(ctx)
 	}
 	go func() {
 		_ = e.runner.Run(ctx)
@@ -505,27 +823,47 @@ func (e *Engine) Start(ctx context.Context) error {
 	return nil
 }
 
+// Stop drains calls in progress (see DrainConfig) and shuts the engine down.
 func (e *Engine) Stop() error {
+	err := e.runner.Stop()
 	if e.cancel != nil {
 		e.cancel()
 	}
-	return e.runner.Stop()
+	if e.health != nil {
+		_ = e.health.Close()
+	}
+	if e.admin != nil {
+		_ = e.admin.Close()
+	}
+	return err
 }
 
-func (e *Engine) routeTransport(ctx context.Context) {
+func (e *Engine) routeTransport(ctx context.Context, name string, t transports.Transport) {
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case f, ok := <-e.transport.Recv():
+		case f, ok := <-t.Recv():
 			if !ok {
 				return
 			}
-			meta := f.Meta()
-			callSID := meta[frames.MetaCallSID]
-			streamID := meta[frames.MetaStreamID]
-			traceID := meta[frames.MetaTraceID]
+			meta := frames.MetadataOf(f)
+			callSID := meta.CallSID()
+			streamID := meta.StreamID()
+			traceID := meta.TraceID()
+			if callSID != "" && f.Kind() == frames.KindSystem && f.(frames.SystemFrame).Name() == "call_end" {
+				if e.admission != nil {
+					e.admission.release(callSID)
+					e.refused.Delete(callSID)
+				}
+				if streamID == "" {
+					if _, ok := e.registry.Get(callSID); !ok {
+						e.hooks.unansweredCallEnd(callSID, meta.Map())
+					}
+				}
+			}
 			if callSID == "" || streamID == "" {
+				frames.ReleaseAudioFrame(f)
 				continue
 			}
 			if e.asyncObs != nil && f.Kind() == frames.KindAudio {
@@ -550,26 +888,119 @@ func (e *Engine) routeTransport(ctx context.Context) {
 					Fields: fields,
 				})
 			}
+			if e.recorder != nil && f.Kind() == frames.KindAudio {
+				e.recorder.Caller(callSID, f.(frames.AudioFrame))
+			}
+			e.transports.bind(name, callSID, streamID)
 			if f.Kind() == frames.KindSystem {
 				sf := f.(frames.SystemFrame)
-				if sf.Name() == "call_end" {
-					e.registry.Remove(callSID)
+				switch sf.Name() {
+				case "call_end":
+					if !e.registry.End(callSID, meta.Map()) {
+						e.transports.unbind(callSID, streamID)
+					}
 					continue
+				case "call_start", pipeline.FrameCallReconnect:
+					if sess, ok := e.registry.Get(callSID); ok {
+						e.resumeStream(sess, sf)
+						continue
+					}
 				}
 			}
-			sess, _, err := e.registry.GetOrCreate(callSID, streamID, traceID)
+			if _, ok := e.registry.Get(callSID); !ok && !e.admitSession(name, callSID) {
+				frames.ReleaseAudioFrame(f)
+				continue
+			}
+			sess, created, err := e.registry.GetOrCreate(callSID, streamID, traceID)
 			if err != nil {
+				frames.ReleaseAudioFrame(f)
 				continue
 			}
 			nonBlockingSend(sess.Orch.In(), f)
+			if created {
+				e.live.promote(sess)
+				if e.admission != nil {
+					e.admission.started(name, callSID)
+				}
+				e.sendGreeting(sess, name)
+			}
 		}
 	}
 }
 
-func nonBlockingSend(ch chan frames.Frame, f frames.Frame) {
+// resumeStream handles a call_start or call_reconnect for a call that already
+// has a session: the transport reconnected the call on a new media stream.
+// The session is rebound and a single call_reconnect frame goes down the
+// pipeline, so stateful processors migrate their per-stream state and the
+// agent carries on mid-conversation instead of greeting again. Transports
+// announce a reconnect with both frames; whichever arrives second is a no-op.
+func (e *Engine) resumeStream(sess *pipeline.Session, sf frames.SystemFrame) {
+	streamID := frames.StreamIDOf(sf)
+	oldStreamID, moved := e.registry.Rebind(sess.CallSID, streamID)
+	if !moved {
+		return
+	}
+	e.transports.unbindStream(oldStreamID)
+	// Audio queued on the old stream was discarded with its socket.
+	e.playback.forget(oldStreamID)
+	meta := sf.Meta()
+	meta[frames.MetaOldStreamID] = oldStreamID
+	// Without call_reconnect processors keep state under the old stream, so
+	// wait for room rather than drop it when the session is busy.
+	ctx, cancel := context.WithTimeout(sess.Ctx, reconnectSendTimeout)
+	defer cancel()
+	if !sendFrame(ctx, sess.Orch.In(), frames.NewSystemFrame(streamID, time.Now().UnixNano(), pipeline.FrameCallReconnect, meta)) {
+		slog.Warn("call_reconnect_dropped", "call_sid", sess.CallSID, "stream_id", streamID, "old_stream_id", oldStreamID)
+	}
+}
+
+// sendGreeting speaks the per-transport greeting when a session starts.
+func (e *Engine) sendGreeting(sess *pipeline.Session, transportName string) {
+	greeting := strings.TrimSpace(e.overrides[transportName].Greeting)
+	if greeting == "" {
+		return
+	}
+	meta := map[string]string{
+		frames.MetaStreamID:     sess.Stream(),
+		frames.MetaCallSID:      sess.CallSID,
+		frames.MetaGreetingText: greeting,
+	}
+	if sess.TraceID != "" {
+		meta[frames.MetaTraceID] = sess.TraceID
+	}
+	nonBlockingSend(sess.Orch.In(), frames.NewSystemFrame(sess.Stream(), time.Now().UnixNano(), "greeting", meta))
+}
+
+// reconnectSendTimeout bounds how long a transport receive loop waits to
+// queue call_reconnect on a busy session.
+const reconnectSendTimeout = 2 * time.Second
+
+// sendFrame hands f to ch, waiting until ctx is done. Pooled audio is
+// released when f could not be queued.
+func sendFrame(ctx context.Context, ch chan frames.Frame, f frames.Frame) bool {
+	select {
+	case ch <- f:
+		return true
+	default:
+	}
+	select {
+	case ch <- f:
+		return true
+	case <-ctx.Done():
+		frames.ReleaseAudioFrame(f)
+		return false
+	}
+}
+
+// nonBlockingSend hands f to ch, releasing pooled audio when ch is full.
+// It reports whether f was queued.
+func nonBlockingSend(ch chan frames.Frame, f frames.Frame) bool {
 	select {
 	case ch <- f:
+		return true
 	default:
+		frames.ReleaseAudioFrame(f)
+		return false
 	}
 }
 
@@ -590,10 +1021,30 @@ func (e *Engine) ProviderRegistry() *ProviderRegistry {
 	return e.providers
 }
 
+// Transport returns EngineOptions.Transport.
 func (e *Engine) Transport() transports.Transport {
 	return e.transport
 }
 
+// TransportByName returns a served transport; DefaultTransportName is
+// EngineOptions.Transport.
+func (e *Engine) TransportByName(name string) transports.Transport {
+	return e.transports.get(name)
+}
+
+// TransportNames lists served transports in sorted order.
+func (e *Engine) TransportNames() []string {
+	return append([]string(nil), e.transports.names...)
+}
+
+// TransportForCall returns the transport a call arrived on, or nil.
+func (e *Engine) TransportForCall(callSID string) transports.Transport {
+	if name := e.transports.nameForCall(callSID); name != "" {
+		return e.transports.get(name)
+	}
+	return nil
+}
+
 func (e *Engine) Config() Config {
 	return e.cfg
 }
@@ -609,9 +1060,14 @@ func (e *Engine) Context() context.Context {
 	return e.ctx
 }
 
+// Health reports whether the engine is alive: it has a transport and has not
+// finished shutting down.
 func (e *Engine) Health() error {
-	if e.transport == nil {
+	if e.transports.empty() {
 		return fmt.Errorf("missing transport")
 	}
+	if e.ctx != nil && e.ctx.Err() != nil {
+		return fmt.Errorf("stopped")
+	}
 	return nil
 }
@@ -0,0 +1,94 @@
+package ranya
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/llm"
+	"github.com/harunnryd/ranya/pkg/processors"
+)
+
+// GatherDigitsTool is the built-in tool that collects keypad digits.
+const GatherDigitsTool = "gather_digits"
+
+func gatherDigitsTool() llm.Tool {
+	return llm.Tool{
+		Name: GatherDigitsTool,
+		Description: "Collect digits the caller types on their keypad, such as an account or card number. " +
+			"First ask the caller to enter them; the result arrives once they finish.",
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"min_digits":             map[string]any{"type": "integer", "description": "Fewest digits accepted."},
+				"max_digits":             map[string]any{"type": "integer", "description": "Input completes once this many digits are entered."},
+				"terminator":             map[string]any{"type": "string", "description": "Key that ends input, default #. Use none to disable."},
+				"inter_digit_timeout_ms": map[string]any{"type": "integer", "description": "Pause after a digit that ends input."},
+				"timeout_ms":             map[string]any{"type": "integer", "description": "Limit for the whole entry."},
+				"mask":                   map[string]any{"type": "boolean", "description": "Hide all but the last four digits from you."},
+			},
+		},
+	}
+}
+
+// gatherDigitsBuiltin arms g for the calling stream. The gatherer answers
+// the tool call with a tool_result once input ends.
+func gatherDigitsBuiltin(g *processors.DTMFGatherer) BuiltinTool {
+	return func(meta map[string]string, args map[string]any) ([]frames.Frame, error) {
+		if meta[frames.MetaStreamID] == "" {
+			return nil, errors.New("gather_digits: missing stream")
+		}
+		var opts processors.GatherOptions
+		if raw, err := json.Marshal(args); err == nil {
+			if err := json.Unmarshal(raw, &opts); err != nil {
+				return nil, err
+			}
+		}
+		g.Arm(meta, opts)
+		return nil, nil
+	}
+}
+
+func gatherDefaults(cfg DTMFGatherConfig) processors.GatherOptions {
+	return processors.GatherOptions{
+		MinDigits:           cfg.MinDigits,
+		MaxDigits:           cfg.MaxDigits,
+		Terminator:          cfg.Terminator,
+		InterDigitTimeoutMS: cfg.InterDigitTimeoutMS,
+		TimeoutMS:           cfg.TimeoutMS,
+		Mask:                cfg.Mask,
+		AcceptSpoken:        cfg.AcceptSpoken,
+	}
+}
+
+// GatherDigits arms keypad digit gathering on callSID, for application code
+// that decides when to collect input. The result arrives as a dtmf_gathered
+// system frame. Requires dtmf_gather.enabled.
+func (e *Engine) GatherDigits(callSID string, opts processors.GatherOptions) error {
+	if !e.cfg.DTMFGather.Enabled {
+		return errors.New("dtmf gathering is disabled")
+	}
+	sess, ok := e.registry.Get(callSID)
+	if !ok {
+		return errors.New("no active session for call")
+	}
+	raw, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+	meta := map[string]string{
+		frames.MetaStreamID:      sess.Stream(),
+		frames.MetaCallSID:       sess.CallSID,
+		frames.MetaGatherOptions: string(raw),
+	}
+	if sess.TraceID != "" {
+		meta[frames.MetaTraceID] = sess.TraceID
+	}
+	select {
+	case sess.Orch.In() <- frames.NewSystemFrame(sess.Stream(), time.Now().UnixNano(), processors.FrameGatherDigits, meta):
+		return nil
+	default:
+		return errors.New("session input full")
+	}
+}
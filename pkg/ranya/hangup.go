@@ -0,0 +1,187 @@
+package ranya
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/llm"
+	"github.com/harunnryd/ranya/pkg/metrics"
+	"github.com/harunnryd/ranya/pkg/pipeline"
+	"github.com/harunnryd/ranya/pkg/transports"
+)
+
+// EndCallTool is the built-in tool that ends the call after a goodbye.
+const EndCallTool = "end_call"
+
+const (
+	defaultHangupGrace   = time.Second
+	defaultHangupMaxWait = 15 * time.Second
+	hangupTimeout        = 10 * time.Second
+)
+
+func endCallTool() llm.Tool {
+	return llm.Tool{
+		Name:        EndCallTool,
+		Description: "End the call. Say goodbye first; the line is closed once your last words have finished playing.",
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"reason": map[string]any{"type": "string", "description": "Why the call is ending."},
+			},
+		},
+	}
+}
+
+// endCallBuiltin turns an end_call tool call into a ControlHangup frame.
+func endCallBuiltin(meta map[string]string, args map[string]any) ([]frames.Frame, error) {
+	streamID := meta[frames.MetaStreamID]
+	out := map[string]string{
+		frames.MetaStreamID:   streamID,
+		frames.MetaToolCallID: meta[frames.MetaToolCallID],
+		frames.MetaToolName:   meta[frames.MetaToolName],
+	}
+	if reason, _ := args["reason"].(string); strings.TrimSpace(reason) != "" {
+		out[frames.MetaReason] = strings.TrimSpace(reason)
+	}
+	for _, key := range []string{frames.MetaCallSID, frames.MetaTraceID} {
+		if v := meta[key]; v != "" {
+			out[key] = v
+		}
+	}
+	return []frames.Frame{frames.NewControlFrame(streamID, time.Now().UnixNano(), frames.ControlHangup, out)}, nil
+}
+
+// callHangups executes ControlHangup frames that reach the sink: it waits for
+// queued playback to finish, hangs up through the transport and ends the
+// session with call_end_reason=agent_hangup.
+type callHangups struct {
+	cfg      HangupConfig
+	registry *pipeline.SessionRegistry
+	router   *transportRouter
+	playback *playbackTracker
+	obs      metrics.Observer
+	// speaking reports whether TTS is still producing audio for the stream.
+	speaking func(callSID, streamID string) bool
+
+	pending sync.Map // callSID -> struct{}
+}
+
+func (c *callHangups) start(callSID string, cf frames.ControlFrame) {
+	if callSID == "" {
+		callSID = cf.Metadata().CallSID()
+	}
+	if _, loaded := c.pending.LoadOrStore(callSID, struct{}{}); loaded {
+		return
+	}
+	go func() {
+		defer c.pending.Delete(callSID)
+		c.run(callSID, cf.Meta())
+	}()
+}
+
+func (c *callHangups) run(callSID string, meta map[string]string) {
+	streamID := meta[frames.MetaStreamID]
+	c.waitPlayback(callSID, streamID)
+	if _, ok := c.registry.Get(callSID); !ok {
+		// The caller hung up first.
+		return
+	}
+	err := c.hangup(callSID, streamID)
+	status := "ok"
+	if err != nil {
+		status = "error"
+		slog.Warn("call_hangup_failed", "call_sid", callSID, "stream_id", streamID, "error", err)
+	} else {
+		slog.Info("call_hangup", "call_sid", callSID, "stream_id", streamID, "reason", meta[frames.MetaReason])
+	}
+	if c.obs != nil {
+		c.obs.RecordEvent(metrics.MetricsEvent{
+			Name: "call_hangup",
+			Time: time.Now(),
+			Tags: map[string]string{
+				frames.MetaCallSID:  callSID,
+				frames.MetaStreamID: streamID,
+				frames.MetaTraceID:  meta[frames.MetaTraceID],
+				"status":            status,
+			},
+		})
+	}
+	// End the session even if the transport failed; the agent said goodbye.
+	reason := meta[frames.MetaCallEndReason]
+	if reason == "" {
+		reason = "agent_hangup"
+	}
+	c.registry.End(callSID, map[string]string{frames.MetaCallEndReason: reason})
+}
+
+// waitPlayback blocks until TTS has finished the stream's last utterance and
+// its playback has been idle for the grace period, the session ends, or the
+// max wait elapses.
+func (c *callHangups) waitPlayback(callSID, streamID string) {
+	grace := time.Duration(c.cfg.GraceMS) * time.Millisecond
+	if grace <= 0 {
+		grace = defaultHangupGrace
+	}
+	maxWait := time.Duration(c.cfg.MaxWaitMS) * time.Millisecond
+	if maxWait <= 0 {
+		maxWait = defaultHangupMaxWait
+	}
+	requested := time.Now()
+	deadline := requested.Add(maxWait)
+	for {
+		idle := requested
+		if c.speaking != nil && c.speaking(callSID, streamID) {
+			idle = time.Now()
+		}
+		if c.playback != nil {
+			if end := c.playback.endsAt(streamID); end.After(idle) {
+				idle = end
+			}
+		}
+		ready := idle.Add(grace)
+		if ready.After(deadline) {
+			ready = deadline
+		}
+		wait := time.Until(ready)
+		if wait <= 0 {
+			return
+		}
+		if _, ok := c.registry.Get(callSID); !ok {
+			return
+		}
+		time.Sleep(wait)
+	}
+}
+
+func (c *callHangups) hangup(callSID, streamID string) error {
+	if callSID == "" {
+		return errors.New("call sid required")
+	}
+	return hangupWith(c.router.lookup(streamID, callSID), callSID)
+}
+
+// reaped hangs up a call whose session is being reaped for inactivity, so
+// the line does not stay open with nobody on it.
+func (c *callHangups) reaped(sess *pipeline.Session) {
+	t := c.router.lookup(sess.Stream(), sess.CallSID)
+	go func() {
+		if err := hangupWith(t, sess.CallSID); err != nil {
+			slog.Warn("reaped_call_hangup_failed", "call_sid", sess.CallSID, "stream_id", sess.Stream(), "error", err)
+		}
+	}()
+}
+
+func hangupWith(t transports.Transport, callSID string) error {
+	hanger, ok := t.(transports.CallHanger)
+	if !ok {
+		return errors.New("transport does not support hangup")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), hangupTimeout)
+	defer cancel()
+	return hanger.HangupCall(ctx, callSID)
+}
@@ -0,0 +1,166 @@
+package ranya
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/errorsx"
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/pipeline"
+	"github.com/harunnryd/ranya/pkg/transports/mock"
+)
+
+func TestPlaybackTracker(t *testing.T) {
+	now := time.Unix(100, 0)
+	p := newPlaybackTracker()
+	p.now = func() time.Time { return now }
+
+	meta := map[string]string{frames.MetaStreamID: "stream-1", frames.MetaEncoding: "pcm16"}
+	// 320 bytes of 16kHz pcm16 is 10ms.
+	for i := 0; i < 3; i++ {
+		p.observe(frames.NewAudioFrame("stream-1", 0, make([]byte, 320), 16000, 1, meta))
+	}
+	if got := p.endsAt("stream-1").Sub(now); got != 30*time.Millisecond {
+		t.Fatalf("expected 30ms of playback, got %v", got)
+	}
+	// 8kHz mulaw is one byte per sample.
+	p.observe(frames.NewAudioFrame("stream-1", 0, make([]byte, 160), 8000, 1, map[string]string{frames.MetaStreamID: "stream-1"}))
+	if got := p.endsAt("stream-1").Sub(now); got != 50*time.Millisecond {
+		t.Fatalf("expected 50ms of playback, got %v", got)
+	}
+	p.observe(frames.NewControlFrame("stream-1", 0, frames.ControlStartInterruption, map[string]string{frames.MetaStreamID: "stream-1"}))
+	if got := p.endsAt("stream-1"); !got.Equal(now) {
+		t.Fatalf("expected interruption to clear playback, got %v", got.Sub(now))
+	}
+}
+
+func TestCallHangupsWaitForPlayback(t *testing.T) {
+	tr := mock.New()
+	registry := newTransferRegistry()
+	if _, _, err := registry.GetOrCreate("call-1", "stream-1", "trace-1"); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	playback := newPlaybackTracker()
+	playback.onAudio("stream-1", 150*time.Millisecond)
+	hangups := &callHangups{
+		cfg:      HangupConfig{GraceMS: 20},
+		registry: registry,
+		router:   newTransportRouter(tr, nil),
+		playback: playback,
+	}
+	out, err := endCallBuiltin(map[string]string{frames.MetaStreamID: "stream-1", frames.MetaCallSID: "call-1"}, map[string]any{"reason": "resolved"})
+	if err != nil || len(out) != 1 {
+		t.Fatalf("unexpected builtin result %v err=%v", out, err)
+	}
+	cf := out[0].(frames.ControlFrame)
+	if cf.Code() != frames.ControlHangup || cf.Meta()[frames.MetaReason] != "resolved" {
+		t.Fatalf("unexpected hangup frame %v", cf.Meta())
+	}
+
+	begin := time.Now()
+	hangups.start("call-1", cf)
+	hangups.start("call-1", cf)
+	time.Sleep(50 * time.Millisecond)
+	if len(tr.Hangups()) != 0 {
+		t.Fatalf("hung up before playback finished")
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(tr.Hangups()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := tr.Hangups(); len(got) != 1 || got[0] != "call-1" {
+		t.Fatalf("unexpected hangups %v", got)
+	}
+	if elapsed := time.Since(begin); elapsed < 150*time.Millisecond {
+		t.Fatalf("hung up after %v, before playback ended", elapsed)
+	}
+	for _, ok := registry.Get("call-1"); ok && time.Now().Before(deadline); _, ok = registry.Get("call-1") {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, ok := registry.Get("call-1"); ok {
+		t.Fatalf("expected session to end after hangup")
+	}
+}
+
+func TestCallHangupsWaitForSpeech(t *testing.T) {
+	tr := mock.New()
+	registry := newTransferRegistry()
+	if _, _, err := registry.GetOrCreate("call-1", "stream-1", "trace-1"); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	speakUntil := time.Now().Add(150 * time.Millisecond)
+	hangups := &callHangups{
+		cfg:      HangupConfig{GraceMS: 20},
+		registry: registry,
+		router:   newTransportRouter(tr, nil),
+		playback: newPlaybackTracker(),
+		speaking: func(callSID, streamID string) bool {
+			return callSID == "call-1" && streamID == "stream-1" && time.Now().Before(speakUntil)
+		},
+	}
+	cf := frames.NewControlFrame("stream-1", 0, frames.ControlHangup, map[string]string{frames.MetaStreamID: "stream-1", frames.MetaCallSID: "call-1"})
+	hangups.start("call-1", cf)
+	time.Sleep(80 * time.Millisecond)
+	if len(tr.Hangups()) != 0 {
+		t.Fatalf("hung up while the goodbye was still being synthesized")
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(tr.Hangups()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := tr.Hangups(); len(got) != 1 {
+		t.Fatalf("unexpected hangups %v", got)
+	}
+	if time.Now().Before(speakUntil) {
+		t.Fatalf("hung up before TTS finished")
+	}
+}
+
+func TestEndCallErrorHangsUpTransport(t *testing.T) {
+	tr := mock.New()
+	registry := newTransferRegistry()
+	if _, _, err := registry.GetOrCreate("call-1", "stream-1", "trace-1"); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	hangups := &callHangups{cfg: HangupConfig{GraceMS: 1}, registry: registry, router: newTransportRouter(tr, nil)}
+	// The failing frame carried no call_sid; the sink's call SID is used.
+	src := frames.NewTextFrame("stream-1", 0, "hi", map[string]string{frames.MetaStreamID: "stream-1"})
+	f := pipeline.NewErrorFrame(src, "llm", errorsx.ReasonProcessor, errors.New("boom"), pipeline.ErrorActionEndCall)
+	if !isEndCallError(f) {
+		t.Fatalf("expected end_call error frame")
+	}
+	cf := errorHangup(f)
+	if cf.Code() != frames.ControlHangup || cf.Metadata().Get(frames.MetaCallEndReason) != string(errorsx.ReasonProcessor) {
+		t.Fatalf("unexpected hangup frame %v", cf.Meta())
+	}
+	hangups.start("call-1", cf)
+	deadline := time.Now().Add(time.Second)
+	for len(tr.Hangups()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := tr.Hangups(); len(got) != 1 || got[0] != "call-1" {
+		t.Fatalf("unexpected hangups %v", got)
+	}
+}
+
+func TestReapedCallsAreHungUp(t *testing.T) {
+	tr := mock.New()
+	registry := newTransferRegistry()
+	hangups := &callHangups{registry: registry, router: newTransportRouter(tr, nil)}
+	registry.SetOnReap(hangups.reaped)
+	if _, _, err := registry.GetOrCreate("call-1", "stream-1", "trace-1"); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := registry.ReapIdle(10 * time.Millisecond); n != 1 {
+		t.Fatalf("expected the idle session to be reaped, got %d", n)
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(tr.Hangups()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := tr.Hangups(); len(got) != 1 || got[0] != "call-1" {
+		t.Fatalf("expected reaped call to be hung up, got %v", got)
+	}
+}
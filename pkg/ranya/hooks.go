@@ -0,0 +1,427 @@
+package ranya
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/observers"
+	"github.com/harunnryd/ranya/pkg/pipeline"
+)
+
+// hookQueueSize bounds the events waiting for slow hooks; beyond it events
+// are dropped rather than slowing calls down.
+const hookQueueSize = 1024
+
+// reportedTTL is how long an ended call is remembered for deduplicating
+// call_end reports that arrive after the session is gone.
+const reportedTTL = 10 * time.Minute
+
+// EngineHooks are typed callbacks for what happens on calls, for integration
+// code that should not depend on frames. Any of them may be nil. Hooks run
+// one at a time on a goroutine of their own, in the order the events
+// happened, so a slow hook delays later hooks but never a call. A hook that
+// panics is logged and skipped.
+type EngineHooks struct {
+	OnCallStart      func(CallStartEvent)
+	OnUserTranscript func(TranscriptEvent)
+	OnAgentUtterance func(UtteranceEvent)
+	OnToolCall       func(ToolCallEvent)
+	OnToolResult     func(ToolResultEvent)
+	OnHandoff        func(HandoffEvent)
+	OnInterruption   func(InterruptionEvent)
+	OnCallEnd        func(CallEndEvent)
+}
+
+func (h EngineHooks) empty() bool {
+	return h.OnCallStart == nil && h.OnUserTranscript == nil && h.OnAgentUtterance == nil &&
+		h.OnToolCall == nil && h.OnToolResult == nil && h.OnHandoff == nil &&
+		h.OnInterruption == nil && h.OnCallEnd == nil
+}
+
+// CallEvent identifies the call an event belongs to.
+type CallEvent struct {
+	CallSID  string
+	StreamID string
+	TraceID  string
+	Time     time.Time
+}
+
+// CallStartEvent reports a call whose media stream opened. Metadata holds
+// the call_start frame metadata, including custom stream parameters.
+type CallStartEvent struct {
+	CallEvent
+	Transport string
+	From      string
+	To        string
+	Metadata  map[string]string
+}
+
+// TranscriptEvent is a caller turn as the agent receives it.
+type TranscriptEvent struct {
+	CallEvent
+	Text     string
+	Language string
+}
+
+// UtteranceEvent is a complete agent reply, greeting or prompt sent to TTS.
+type UtteranceEvent struct {
+	CallEvent
+	Agent    string
+	Text     string
+	Language string
+}
+
+// ToolCallEvent is a tool call the agent made.
+type ToolCallEvent struct {
+	CallEvent
+	ID        string
+	Name      string
+	Arguments map[string]any
+}
+
+// ToolResultEvent is the outcome of a tool call. Status is "ok" on success.
+type ToolResultEvent struct {
+	CallEvent
+	ID     string
+	Name   string
+	Status string
+	Result string
+	Error  string
+}
+
+// HandoffEvent reports the call moving from one agent to another.
+type HandoffEvent struct {
+	CallEvent
+	From string
+	To   string
+}
+
+// InterruptionEvent reports the caller barging in on the agent.
+type InterruptionEvent struct {
+	CallEvent
+	Reason string
+}
+
+// CallEndEvent reports a finished call, including outbound calls that were
+// never answered (busy, no answer), which have no StreamID. Summary is set
+// when summary.enabled is on. Cost holds the call's speech-to-text and
+// text-to-speech seconds. Metadata holds the call_end metadata, such as
+// call_end_reason.
+type CallEndEvent struct {
+	CallEvent
+	Reason   string
+	Duration time.Duration
+	Summary  string
+	Cost     observers.CostSummary
+	Metadata map[string]string
+}
+
+// engineHooks queues hook calls and runs them off the pipeline.
+type engineHooks struct {
+	h    EngineHooks
+	cost *observers.CostObserver
+
+	mu     sync.Mutex
+	queue  chan func()
+	closed bool
+	done   chan struct{}
+
+	ends sync.Map // callSID -> *hookCallEnd
+
+	// reported remembers calls whose session end was reported, so a late
+	// stream-less call_end for the same call is not reported again.
+	reported map[string]time.Time
+}
+
+// hookCallEnd gathers what OnCallEnd reports while the session shuts down.
+type hookCallEnd struct {
+	mu      sync.Mutex
+	summary string
+	meta    map[string]string
+}
+
+func newEngineHooks(h EngineHooks) *engineHooks {
+	if h.empty() {
+		return nil
+	}
+	e := &engineHooks{h: h, queue: make(chan func(), hookQueueSize), done: make(chan struct{}), reported: make(map[string]time.Time)}
+	if h.OnCallEnd != nil {
+		e.cost = observers.NewCostObserver("")
+	}
+	go e.loop()
+	return e
+}
+
+func (e *engineHooks) loop() {
+	defer close(e.done)
+	for fn := range e.queue {
+		e.run(fn)
+	}
+}
+
+func (e *engineHooks) run(fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("engine_hook_panic", "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+		}
+	}()
+	fn()
+}
+
+func (e *engineHooks) emit(name string, fn func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return
+	}
+	select {
+	case e.queue <- fn:
+	default:
+		slog.Warn("engine_hook_dropped", "hook", name)
+	}
+}
+
+// close runs the hooks already queued and stops.
+func (e *engineHooks) close() {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.queue)
+	}
+	e.mu.Unlock()
+	<-e.done
+}
+
+func (e *engineHooks) end(callSID string) *hookCallEnd {
+	v, _ := e.ends.LoadOrStore(callSID, &hookCallEnd{})
+	return v.(*hookCallEnd)
+}
+
+// summary keeps the call's summary for OnCallEnd.
+func (e *engineHooks) summary(callSID, summary string) {
+	if e == nil || callSID == "" || e.h.OnCallEnd == nil {
+		return
+	}
+	end := e.end(callSID)
+	end.mu.Lock()
+	end.summary = summary
+	end.mu.Unlock()
+}
+
+// callEnd reports a session that has ended, whatever ended it.
+func (e *engineHooks) callEnd(sess *pipeline.Session) {
+	if e == nil || e.h.OnCallEnd == nil {
+		return
+	}
+	ev := CallEndEvent{
+		CallEvent: CallEvent{CallSID: sess.CallSID, StreamID: sess.Stream(), TraceID: sess.TraceID, Time: time.Now()},
+		Duration:  time.Since(sess.Created),
+	}
+	if v, ok := e.ends.LoadAndDelete(sess.CallSID); ok {
+		end := v.(*hookCallEnd)
+		end.mu.Lock()
+		ev.Summary = end.summary
+		ev.Metadata = end.meta
+		end.mu.Unlock()
+	}
+	ev.Reason = ev.Metadata[frames.MetaCallEndReason]
+	if ev.Reason == "" {
+		ev.Reason = ev.Metadata[frames.MetaReason]
+	}
+	e.markReported(sess.CallSID)
+	id := sess.TraceID
+	if id == "" {
+		id = ev.StreamID
+	}
+	e.emit("on_call_end", func() {
+		ev.Cost, _ = e.cost.Take(id)
+		e.h.OnCallEnd(ev)
+	})
+}
+
+// unansweredCallEnd reports a call_end for a call that never had a session,
+// such as a busy or unanswered outbound call.
+func (e *engineHooks) unansweredCallEnd(callSID string, meta map[string]string) {
+	if e == nil || e.h.OnCallEnd == nil {
+		return
+	}
+	if e.markReported(callSID) {
+		return
+	}
+	ev := CallEndEvent{
+		CallEvent: CallEvent{CallSID: callSID, Time: time.Now()},
+		Reason:    meta[frames.MetaCallEndReason],
+		Metadata:  meta,
+	}
+	e.emit("on_call_end", func() { e.h.OnCallEnd(ev) })
+}
+
+// markReported records callSID as reported and tells whether it already was.
+func (e *engineHooks) markReported(callSID string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := time.Now()
+	for sid, at := range e.reported {
+		if now.Sub(at) > reportedTTL {
+			delete(e.reported, sid)
+		}
+	}
+	_, seen := e.reported[callSID]
+	e.reported[callSID] = now
+	return seen
+}
+
+// hookTap is a pass-through processor that turns a session's frames into
+// hook events. The input tap sits ahead of the LLM and sees call starts and
+// caller turns; the output tap sits right after it and sees everything the
+// agent does.
+type hookTap struct {
+	hooks     *engineHooks
+	callSID   string
+	transport string
+	output    bool
+	utterance strings.Builder
+}
+
+func (e *engineHooks) inputTap(callSID, transport string) *hookTap {
+	return &hookTap{hooks: e, callSID: callSID, transport: transport}
+}
+
+func (e *engineHooks) outputTap(callSID string) *hookTap {
+	return &hookTap{hooks: e, callSID: callSID, output: true}
+}
+
+func (t *hookTap) Name() string {
+	if t.output {
+		return "hooks_output"
+	}
+	return "hooks_input"
+}
+
+func (t *hookTap) Process(f frames.Frame) ([]frames.Frame, error) {
+	if t.output {
+		t.observeOutput(f)
+	} else {
+		t.observeInput(f)
+	}
+	return []frames.Frame{f}, nil
+}
+
+func (t *hookTap) event(meta map[string]string) CallEvent {
+	return CallEvent{CallSID: t.callSID, StreamID: meta[frames.MetaStreamID], TraceID: meta[frames.MetaTraceID], Time: time.Now()}
+}
+
+func (t *hookTap) observeInput(f frames.Frame) {
+	h := t.hooks.h
+	switch v := f.(type) {
+	case frames.SystemFrame:
+		if v.Name() != "call_start" || h.OnCallStart == nil {
+			return
+		}
+		meta := v.Meta()
+		ev := CallStartEvent{
+			CallEvent: t.event(meta),
+			Transport: t.transport,
+			From:      meta[frames.MetaFromNumber],
+			To:        meta[frames.MetaToNumber],
+			Metadata:  meta,
+		}
+		t.hooks.emit("on_call_start", func() { h.OnCallStart(ev) })
+	case frames.TextFrame:
+		meta := v.Meta()
+		if meta[frames.MetaSource] == "llm" || h.OnUserTranscript == nil {
+			return
+		}
+		ev := TranscriptEvent{CallEvent: t.event(meta), Text: v.Text(), Language: meta[frames.MetaLanguage]}
+		t.hooks.emit("on_user_transcript", func() { h.OnUserTranscript(ev) })
+	}
+}
+
+func (t *hookTap) observeOutput(f frames.Frame) {
+	h := t.hooks.h
+	switch v := f.(type) {
+	case frames.TextFrame:
+		meta := v.Meta()
+		if meta[frames.MetaSource] != "llm" || h.OnAgentUtterance == nil {
+			return
+		}
+		t.utterance.WriteString(v.Text())
+		// Replies stream in chunks ending with a flush; greetings and
+		// prompts come whole.
+		if meta[frames.MetaTTSFlush] != "true" && meta[frames.MetaGreetingText] == "" {
+			return
+		}
+		text := strings.TrimSpace(t.utterance.String())
+		t.utterance.Reset()
+		if text == "" {
+			return
+		}
+		ev := UtteranceEvent{CallEvent: t.event(meta), Agent: meta[frames.MetaAgent], Text: text, Language: meta[frames.MetaLanguage]}
+		t.hooks.emit("on_agent_utterance", func() { h.OnAgentUtterance(ev) })
+	case frames.ControlFrame:
+		meta := v.Meta()
+		switch v.Code() {
+		case frames.ControlToolCall:
+			if h.OnToolCall == nil {
+				return
+			}
+			ev := ToolCallEvent{CallEvent: t.event(meta), ID: meta[frames.MetaToolCallID], Name: meta[frames.MetaToolName]}
+			_ = json.Unmarshal([]byte(meta[frames.MetaToolArgs]), &ev.Arguments)
+			t.hooks.emit("on_tool_call", func() { h.OnToolCall(ev) })
+		case frames.ControlHandoff:
+			if h.OnHandoff == nil || meta[frames.MetaHandoffAgent] == "" {
+				return
+			}
+			ev := HandoffEvent{CallEvent: t.event(meta), From: meta[frames.MetaAgent], To: meta[frames.MetaHandoffAgent]}
+			t.hooks.emit("on_handoff", func() { h.OnHandoff(ev) })
+		case frames.ControlCancel:
+			if h.OnInterruption == nil || meta[frames.MetaReason] != "barge_in" {
+				return
+			}
+			ev := InterruptionEvent{CallEvent: t.event(meta), Reason: meta[frames.MetaReason]}
+			t.hooks.emit("on_interruption", func() { h.OnInterruption(ev) })
+		}
+	case frames.SystemFrame:
+		if v.Name() != "tool_result" || h.OnToolResult == nil {
+			return
+		}
+		meta := v.Meta()
+		ev := ToolResultEvent{
+			CallEvent: t.event(meta),
+			ID:        meta[frames.MetaToolCallID],
+			Name:      meta[frames.MetaToolName],
+			Status:    meta[frames.MetaToolStatus],
+			Result:    meta[frames.MetaToolResult],
+			Error:     meta[frames.MetaToolError],
+		}
+		if ev.Status == "" {
+			ev.Status = "ok"
+		}
+		t.hooks.emit("on_tool_result", func() { h.OnToolResult(ev) })
+	}
+}
+
+// OnSessionEnd implements pipeline.SessionEndHandler. It keeps the call_end
+// metadata for OnCallEnd, which fires once the session is gone.
+func (t *hookTap) OnSessionEnd(meta map[string]string) {
+	if !t.output || t.hooks.h.OnCallEnd == nil {
+		return
+	}
+	end := t.hooks.end(t.callSID)
+	end.mu.Lock()
+	end.meta = maps.Clone(meta)
+	end.mu.Unlock()
+}
+
+var _ pipeline.FrameProcessor = (*hookTap)(nil)
+var _ pipeline.SessionEndHandler = (*hookTap)(nil)
@@ -0,0 +1,123 @@
+package ranya
+
+import (
+	"encoding/base64"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/metrics"
+	"github.com/harunnryd/ranya/pkg/pipeline"
+)
+
+func TestEngineHooksReportCallEvents(t *testing.T) {
+	var got []string
+	var end CallEndEvent
+	hooks := newEngineHooks(EngineHooks{
+		OnCallStart: func(ev CallStartEvent) { got = append(got, "start:"+ev.Transport+":"+ev.From) },
+		OnUserTranscript: func(ev TranscriptEvent) {
+			got = append(got, "user:"+ev.Text)
+			panic("hook bug")
+		},
+		OnAgentUtterance: func(ev UtteranceEvent) { got = append(got, "agent:"+ev.Agent+":"+ev.Text) },
+		OnToolCall:       func(ev ToolCallEvent) { got = append(got, "tool:"+ev.Name+":"+ev.Arguments["id"].(string)) },
+		OnToolResult:     func(ev ToolResultEvent) { got = append(got, "result:"+ev.Name+":"+ev.Status) },
+		OnHandoff:        func(ev HandoffEvent) { got = append(got, "handoff:"+ev.From+">"+ev.To) },
+		OnInterruption:   func(ev InterruptionEvent) { got = append(got, "interrupt") },
+		OnCallEnd:        func(ev CallEndEvent) { end = ev },
+	})
+	in := hooks.inputTap("call-1", "twilio")
+	out := hooks.outputTap("call-1")
+	meta := func(kv ...string) map[string]string {
+		m := map[string]string{frames.MetaStreamID: "stream-1", frames.MetaTraceID: "trace-1"}
+		for i := 0; i+1 < len(kv); i += 2 {
+			m[kv[i]] = kv[i+1]
+		}
+		return m
+	}
+	steps := []struct {
+		tap *hookTap
+		f   frames.Frame
+	}{
+		{in, frames.NewSystemFrame("stream-1", 0, "call_start", meta(frames.MetaFromNumber, "+1555"))},
+		{in, frames.NewTextFrame("stream-1", 0, "where is my order", meta())},
+		{out, frames.NewControlFrame("stream-1", 0, frames.ControlToolCall, meta(frames.MetaToolName, "lookup", frames.MetaToolArgs, `{"id":"42"}`))},
+		{out, frames.NewSystemFrame("stream-1", 0, "tool_result", meta(frames.MetaToolName, "lookup"))},
+		{out, frames.NewTextFrame("stream-1", 0, "It ships ", meta(frames.MetaSource, "llm", frames.MetaAgent, "support"))},
+		{out, frames.NewTextFrame("stream-1", 0, "today.", meta(frames.MetaSource, "llm", frames.MetaAgent, "support", frames.MetaTTSFlush, "true"))},
+		{out, frames.NewControlFrame("stream-1", 0, frames.ControlHandoff, meta(frames.MetaAgent, "support", frames.MetaHandoffAgent, "billing"))},
+		{out, frames.NewControlFrame("stream-1", 0, frames.ControlCancel, meta(frames.MetaReason, "barge_in"))},
+	}
+	for _, s := range steps {
+		res, err := s.tap.Process(s.f)
+		if err != nil || len(res) != 1 {
+			t.Fatalf("tap must pass frames through, got %v err=%v", res, err)
+		}
+	}
+	hooks.cost.RecordEvent(metrics.MetricsEvent{Name: "audio_in", Tags: map[string]string{"trace_id": "trace-1"}, Fields: map[string]any{"payload_b64": base64.StdEncoding.EncodeToString(make([]byte, 12000)), "sample_rate": 8000}})
+	hooks.summary("call-1", "Order 42 ships today.")
+	out.OnSessionEnd(meta(frames.MetaCallEndReason, "completed"))
+	hooks.callEnd(&pipeline.Session{CallSID: "call-1", StreamID: "stream-1", TraceID: "trace-1", Created: time.Now().Add(-time.Minute)})
+	hooks.close()
+
+	want := []string{
+		"start:twilio:+1555",
+		"user:where is my order",
+		"tool:lookup:42",
+		"result:lookup:ok",
+		"agent:support:It ships today.",
+		"handoff:support>billing",
+		"interrupt",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected events %v, got %v", want, got)
+		}
+	}
+	if end.CallSID != "call-1" || end.Reason != "completed" || end.Summary != "Order 42 ships today." {
+		t.Fatalf("unexpected call end %+v", end)
+	}
+	if end.Duration < time.Minute || end.Cost.STTAudioSec != 1.5 {
+		t.Fatalf("unexpected call end duration %v cost %+v", end.Duration, end.Cost)
+	}
+}
+
+func TestEngineHooksDisabledWithoutCallbacks(t *testing.T) {
+	hooks := newEngineHooks(EngineHooks{})
+	if hooks != nil {
+		t.Fatalf("expected no hooks without callbacks")
+	}
+	hooks.callEnd(&pipeline.Session{CallSID: "call-1"})
+	hooks.close()
+}
+
+func TestEngineHooksReportUnansweredCallsOnce(t *testing.T) {
+	var mu sync.Mutex
+	var ends []CallEndEvent
+	hooks := newEngineHooks(EngineHooks{OnCallEnd: func(ev CallEndEvent) {
+		mu.Lock()
+		ends = append(ends, ev)
+		mu.Unlock()
+		panic("hook bug")
+	}})
+	hooks.unansweredCallEnd("call-1", map[string]string{frames.MetaCallSID: "call-1", frames.MetaCallEndReason: "busy"})
+	hooks.unansweredCallEnd("call-1", map[string]string{frames.MetaCallSID: "call-1", frames.MetaCallEndReason: "busy"})
+	hooks.callEnd(&pipeline.Session{CallSID: "call-2", StreamID: "stream-2", Created: time.Now()})
+	// Twilio reports the final status again once the stream has gone.
+	hooks.unansweredCallEnd("call-2", map[string]string{frames.MetaCallSID: "call-2", frames.MetaCallEndReason: "completed"})
+	hooks.close()
+
+	if len(ends) != 2 {
+		t.Fatalf("expected one event per call, got %+v", ends)
+	}
+	if ends[0].CallSID != "call-1" || ends[0].Reason != "busy" || ends[0].StreamID != "" {
+		t.Fatalf("unexpected unanswered call end %+v", ends[0])
+	}
+	if ends[1].CallSID != "call-2" || ends[1].StreamID != "stream-2" {
+		t.Fatalf("unexpected session call end %+v", ends[1])
+	}
+}
@@ -0,0 +1,74 @@
+package ranya
+
+import (
+	"sync"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/audio"
+	"github.com/harunnryd/ranya/pkg/frames"
+)
+
+// playbackTracker estimates when audio sent to a transport has finished
+// playing on the far end. Transports play in real time, so each outbound
+// frame pushes the end of playback out by its duration.
+type playbackTracker struct {
+	mu      sync.Mutex
+	streams map[string]time.Time // streamID -> estimated end of playback
+	now     func() time.Time
+}
+
+func newPlaybackTracker() *playbackTracker {
+	return &playbackTracker{streams: make(map[string]time.Time), now: time.Now}
+}
+
+// observe updates the clock for frames on their way to the transport.
+func (p *playbackTracker) observe(f frames.Frame) {
+	streamID := frames.StreamIDOf(f)
+	if streamID == "" {
+		return
+	}
+	switch f.Kind() {
+	case frames.KindAudio:
+		p.onAudio(streamID, audio.Duration(f.(frames.AudioFrame)))
+	case frames.KindControl:
+		switch f.(frames.ControlFrame).Code() {
+		case frames.ControlFlush, frames.ControlCancel, frames.ControlStartInterruption:
+			p.clear(streamID)
+		}
+	}
+}
+
+func (p *playbackTracker) onAudio(streamID string, d time.Duration) {
+	now := p.now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	end := p.streams[streamID]
+	if end.Before(now) {
+		end = now
+	}
+	p.streams[streamID] = end.Add(d)
+}
+
+// clear drops queued playback, as transports do on flush and barge-in.
+func (p *playbackTracker) clear(streamID string) {
+	now := p.now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if end, ok := p.streams[streamID]; ok && end.After(now) {
+		p.streams[streamID] = now
+	}
+}
+
+func (p *playbackTracker) forget(streamID string) {
+	p.mu.Lock()
+	delete(p.streams, streamID)
+	p.mu.Unlock()
+}
+
+// endsAt returns the estimated end of playback, or the zero time if no audio
+// was sent on the stream.
+func (p *playbackTracker) endsAt(streamID string) time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.streams[streamID]
+}
@@ -0,0 +1,76 @@
+package ranya
+
+import (
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/observers"
+)
+
+// newCallRecorder returns nil unless recording is enabled and has somewhere
+// to write. Old recordings are purged like the other artifacts.
+func newCallRecorder(cfg ObservabilityConfig, newEncoder func(sampleRate, channels int) (observers.OpusEncoder, error)) *observers.CallRecorder {
+	rc := cfg.Recording
+	if !rc.Enabled {
+		return nil
+	}
+	dir := strings.TrimSpace(rc.Dir)
+	if dir == "" && strings.TrimSpace(cfg.ArtifactsDir) != "" {
+		dir = filepath.Join(cfg.ArtifactsDir, "recordings")
+	}
+	if dir == "" {
+		slog.Warn("recording_disabled", "reason", "no recording dir or artifacts_dir")
+		return nil
+	}
+	days := rc.RetentionDays
+	if days <= 0 {
+		days = cfg.RetentionDays
+	}
+	if days > 0 {
+		_, _ = observers.PurgeArtifacts(dir, time.Duration(days)*24*time.Hour)
+	}
+	format := strings.ToLower(strings.TrimSpace(rc.Format))
+	if format == observers.RecordingOpus && newEncoder == nil {
+		slog.Warn("recording_opus_encoder_missing", "fallback", observers.RecordingWAV)
+		format = observers.RecordingWAV
+	}
+	return observers.NewCallRecorder(observers.RecorderConfig{
+		Dir:            dir,
+		Format:         format,
+		SampleRate:     rc.SampleRate,
+		NewOpusEncoder: newEncoder,
+	})
+}
+
+// recordAgent mirrors what the transport plays: outbound audio is recorded
+// and flushes or barge-ins drop agent audio that has not played yet.
+func recordAgent(r *observers.CallRecorder, callSID string, f frames.Frame) {
+	switch f.Kind() {
+	case frames.KindAudio:
+		r.Agent(callSID, f.(frames.AudioFrame))
+	case frames.KindControl:
+		switch f.(frames.ControlFrame).Code() {
+		case frames.ControlFlush, frames.ControlCancel, frames.ControlStartInterruption:
+			r.ClearAgent(callSID)
+		}
+	}
+}
+
+// PauseRecording stops recording callSID (e.g. while a card number is read
+// out) until ResumeRecording. The gap is kept as silence. Processors can do
+// the same by emitting ControlRecordingPause and ControlRecordingResume.
+func (e *Engine) PauseRecording(callSID string) {
+	if e.recorder != nil {
+		e.recorder.Pause(callSID)
+	}
+}
+
+// ResumeRecording resumes recording callSID.
+func (e *Engine) ResumeRecording(callSID string) {
+	if e.recorder != nil {
+		e.recorder.Resume(callSID)
+	}
+}
@@ -0,0 +1,129 @@
+package ranya
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/processors"
+)
+
+const (
+	whisperPrefix  = "Supervisor guidance, not heard by the caller: "
+	takeoverNotice = "A human supervisor has taken over the call and is speaking to the caller."
+	releaseNotice  = "The supervisor has handed the call back to you. Continue from the conversation so far."
+)
+
+// Whisper gives the agent guidance from supervisor as a system message. The
+// caller does not hear it; the agent follows it from its next turn on.
+func (e *Engine) Whisper(callSID, supervisor, text string) error {
+	return e.sendToSession(callSID, func(meta map[string]string, streamID string) frames.Frame {
+		meta[frames.MetaSupervisor] = supervisor
+		meta[frames.MetaSystemMessage] = whisperPrefix + text
+		return frames.NewSystemFrame(streamID, time.Now().UnixNano(), processors.FrameSupervisorWhisper, meta)
+	})
+}
+
+// Takeover pauses the agent so supervisor can talk to the caller with Say.
+// What the caller says meanwhile is kept in the agent's history. Release
+// hands the call back. The call counts as taken over only once the takeover
+// is queued.
+func (e *Engine) Takeover(callSID, supervisor string) error {
+	live := e.live.get(callSID)
+	if live == nil {
+		return ErrSessionNotFound
+	}
+	live.supervising.Lock()
+	defer live.supervising.Unlock()
+	err := e.sendToSession(callSID, func(meta map[string]string, streamID string) frames.Frame {
+		meta[frames.MetaSupervisor] = supervisor
+		meta[frames.MetaSystemMessage] = takeoverNotice
+		return frames.NewSystemFrame(streamID, time.Now().UnixNano(), processors.FrameSupervisorTakeover, meta)
+	})
+	if err == nil {
+		live.supervisor.Store(&supervisor)
+	}
+	return err
+}
+
+// Say speaks text to the caller through TTS. It only works during a takeover.
+func (e *Engine) Say(callSID, text string) error {
+	live := e.live.get(callSID)
+	if live == nil {
+		return ErrSessionNotFound
+	}
+	live.supervising.Lock()
+	defer live.supervising.Unlock()
+	supervisor := live.supervisor.Load()
+	if supervisor == nil {
+		return ErrNotTakenOver
+	}
+	return e.sendToSession(callSID, func(meta map[string]string, streamID string) frames.Frame {
+		meta[frames.MetaSupervisor] = *supervisor
+		meta[frames.MetaGreetingText] = text
+		return frames.NewSystemFrame(streamID, time.Now().UnixNano(), processors.FrameSupervisorSay, meta)
+	})
+}
+
+// Release hands a taken over call back to the agent.
+func (e *Engine) Release(callSID string) error {
+	live := e.live.get(callSID)
+	if live == nil {
+		return ErrSessionNotFound
+	}
+	live.supervising.Lock()
+	defer live.supervising.Unlock()
+	supervisor := live.supervisor.Load()
+	if supervisor == nil {
+		return ErrNotTakenOver
+	}
+	err := e.sendToSession(callSID, func(meta map[string]string, streamID string) frames.Frame {
+		meta[frames.MetaSupervisor] = *supervisor
+		meta[frames.MetaSystemMessage] = releaseNotice
+		return frames.NewSystemFrame(streamID, time.Now().UnixNano(), processors.FrameSupervisorRelease, meta)
+	})
+	if err == nil {
+		live.supervisor.Store(nil)
+	}
+	return err
+}
+
+func (e *Engine) supervisorRoutes(mux *http.ServeMux) {
+	type body struct {
+		Supervisor string `json:"supervisor"`
+		Text       string `json:"text"`
+	}
+	read := func(w http.ResponseWriter, r *http.Request, needText bool) (body, bool) {
+		var b body
+		if !readJSON(w, r, &b) {
+			return b, false
+		}
+		b.Supervisor = strings.TrimSpace(b.Supervisor)
+		if needText && strings.TrimSpace(b.Text) == "" {
+			writeError(w, http.StatusBadRequest, "text is required")
+			return b, false
+		}
+		return b, true
+	}
+	mux.HandleFunc("POST /sessions/{call_sid}/whisper", func(w http.ResponseWriter, r *http.Request) {
+		if b, ok := read(w, r, true); ok {
+			e.adminResult(w, e.Whisper(r.PathValue("call_sid"), b.Supervisor, b.Text))
+		}
+	})
+	mux.HandleFunc("POST /sessions/{call_sid}/takeover", func(w http.ResponseWriter, r *http.Request) {
+		if b, ok := read(w, r, false); ok {
+			e.adminResult(w, e.Takeover(r.PathValue("call_sid"), b.Supervisor))
+		}
+	})
+	mux.HandleFunc("POST /sessions/{call_sid}/say", func(w http.ResponseWriter, r *http.Request) {
+		b, ok := read(w, r, true)
+		if !ok {
+			return
+		}
+		e.adminResult(w, e.Say(r.PathValue("call_sid"), b.Text))
+	})
+	mux.HandleFunc("POST /sessions/{call_sid}/release", func(w http.ResponseWriter, r *http.Request) {
+		e.adminResult(w, e.Release(r.PathValue("call_sid")))
+	})
+}
@@ -0,0 +1,130 @@
+package ranya
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/metrics"
+	"github.com/harunnryd/ranya/pkg/observers"
+)
+
+const (
+	tapBuffer    = 512
+	tapKeepAlive = 15 * time.Second
+)
+
+var tapUpgrader = websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 4096}
+
+// TapSession follows the metrics events of callSID as they happen: frames
+// with their kind, control code, text and tool details, stage latencies and
+// call events. Audio frames and their stage latencies are left out unless
+// audio is set. Text is redacted when redaction is enabled. The returned
+// channel closes when the call ends; Close the subscription when done.
+func (e *Engine) TapSession(callSID string, audio bool) (*observers.TapSubscription, <-chan struct{}, bool) {
+	sess, ok := e.registry.Get(callSID)
+	if !ok || e.tap == nil {
+		return nil, nil, false
+	}
+	sub := e.tap.Subscribe(func(ev metrics.MetricsEvent) bool {
+		if !audio && (ev.Tags["kind"] == string(frames.KindAudio) || ev.Name == "audio_in" || ev.Name == "audio_out") {
+			return false
+		}
+		if sid := ev.Tags[frames.MetaCallSID]; sid != "" {
+			return sid == callSID
+		}
+		if trace := ev.Tags[frames.MetaTraceID]; trace != "" && sess.TraceID != "" {
+			return trace == sess.TraceID
+		}
+		return ev.Tags[frames.MetaStreamID] != "" && ev.Tags[frames.MetaStreamID] == sess.Stream()
+	}, tapBuffer)
+	return sub, sess.Ctx.Done(), true
+}
+
+// serveTap streams TapSession events as server-sent events, or as JSON
+// WebSocket messages when the client asks to upgrade. ?audio=1 includes
+// audio frames.
+func (e *Engine) serveTap(w http.ResponseWriter, r *http.Request) {
+	audio, _ := strconv.ParseBool(r.URL.Query().Get("audio"))
+	sub, ended, ok := e.TapSession(r.PathValue("call_sid"), audio)
+	if !ok {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	defer sub.Close()
+	if websocket.IsWebSocketUpgrade(r) {
+		e.tapWebSocket(w, r, sub, ended)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	keepAlive := time.NewTicker(tapKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ended:
+			fmt.Fprint(w, "event: end\ndata: {}\n\n")
+			flusher.Flush()
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case ev := <-sub.C:
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (e *Engine) tapWebSocket(w http.ResponseWriter, r *http.Request, sub *observers.TapSubscription, ended <-chan struct{}) {
+	conn, err := tapUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	// Reading notices the client going away; the tap takes no input.
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case <-gone:
+			return
+		case <-ended:
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "call ended"))
+			return
+		case ev := <-sub.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(tapKeepAlive))
+			if err := conn.WriteJSON(ev); err != nil {
+				return
+			}
+		}
+	}
+}
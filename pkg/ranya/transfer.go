@@ -0,0 +1,188 @@
+package ranya
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/llm"
+	"github.com/harunnryd/ranya/pkg/metrics"
+	"github.com/harunnryd/ranya/pkg/pipeline"
+	"github.com/harunnryd/ranya/pkg/transports"
+)
+
+// TransferCallTool is the built-in tool that hands the caller to a human.
+const TransferCallTool = "transfer_call"
+
+// transferTimeout bounds the transport's transfer request.
+const transferTimeout = 15 * time.Second
+
+func transferEnabled(cfg TransferConfig) bool {
+	return cfg.Enabled && len(cfg.Targets) > 0
+}
+
+func transferTargetNames(cfg TransferConfig) []string {
+	names := make([]string, 0, len(cfg.Targets))
+	for name := range cfg.Targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func transferCallTool(cfg TransferConfig) llm.Tool {
+	return llm.Tool{
+		Name:        TransferCallTool,
+		Description: "Transfer the caller to a human agent. Use when the caller asks for a person or the request is outside what you can handle.",
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"target": map[string]any{"type": "string", "enum": transferTargetNames(cfg)},
+				"reason": map[string]any{"type": "string", "description": "Why the caller is being transferred."},
+				"mode":   map[string]any{"type": "string", "enum": []string{string(transports.TransferCold), string(transports.TransferWarm)}},
+			},
+			"required": []string{"reason"},
+		},
+	}
+}
+
+// transferCallBuiltin turns a transfer_call tool call into a ControlTransfer
+// frame. The target must be one of the configured names.
+func transferCallBuiltin(cfg TransferConfig) BuiltinTool {
+	return func(meta map[string]string, args map[string]any) ([]frames.Frame, error) {
+		target, _ := args["target"].(string)
+		target = strings.TrimSpace(target)
+		if target == "" {
+			target = cfg.DefaultTarget
+		}
+		if _, ok := cfg.Targets[target]; !ok {
+			return nil, fmt.Errorf("unknown transfer target %q", target)
+		}
+		mode := transferMode(cfg.Mode)
+		if raw, _ := args["mode"].(string); raw != "" {
+			mode = transferMode(raw)
+		}
+		reason, _ := args["reason"].(string)
+		streamID := meta[frames.MetaStreamID]
+		out := map[string]string{
+			frames.MetaStreamID:       streamID,
+			frames.MetaTransferTarget: target,
+			frames.MetaTransferMode:   string(mode),
+			frames.MetaToolCallID:     meta[frames.MetaToolCallID],
+			frames.MetaToolName:       meta[frames.MetaToolName],
+		}
+		if reason = strings.TrimSpace(reason); reason != "" {
+			out[frames.MetaReason] = reason
+		}
+		for _, key := range []string{frames.MetaCallSID, frames.MetaTraceID, frames.MetaLanguage} {
+			if v := meta[key]; v != "" {
+				out[key] = v
+			}
+		}
+		return []frames.Frame{frames.NewControlFrame(streamID, time.Now().UnixNano(), frames.ControlTransfer, out)}, nil
+	}
+}
+
+func transferMode(raw string) transports.TransferMode {
+	if strings.EqualFold(strings.TrimSpace(raw), string(transports.TransferWarm)) {
+		return transports.TransferWarm
+	}
+	return transports.TransferCold
+}
+
+// callTransfers executes ControlTransfer frames that reach the sink.
+type callTransfers struct {
+	cfg      TransferConfig
+	registry *pipeline.SessionRegistry
+	router   *transportRouter
+	obs      metrics.Observer
+}
+
+func (c *callTransfers) start(callSID string, cf frames.ControlFrame) {
+	go c.run(callSID, cf.Meta())
+}
+
+func (c *callTransfers) run(callSID string, meta map[string]string) {
+	if callSID == "" {
+		callSID = meta[frames.MetaCallSID]
+	}
+	opts := transports.TransferOptions{
+		Target:       meta[frames.MetaTransferTarget],
+		Mode:         transferMode(meta[frames.MetaTransferMode]),
+		CallerID:     c.cfg.CallerID,
+		Announcement: c.cfg.Announcement,
+	}
+	// Frames from the built-in tool carry a target name; frames emitted by
+	// custom processors may carry a number directly.
+	if number, ok := c.cfg.Targets[opts.Target]; ok {
+		opts.Target = number
+	}
+	if opts.Mode == transports.TransferWarm {
+		opts.Briefing = transferBriefing(meta)
+	}
+	err := c.transfer(callSID, meta, opts)
+	status := "ok"
+	if err != nil {
+		status = "error"
+		slog.Warn("call_transfer_failed", "call_sid", callSID, "stream_id", meta[frames.MetaStreamID], "mode", opts.Mode, "error", err)
+	} else {
+		slog.Info("call_transferred", "call_sid", callSID, "stream_id", meta[frames.MetaStreamID], "mode", opts.Mode)
+	}
+	if c.obs != nil {
+		c.obs.RecordEvent(metrics.MetricsEvent{
+			Name: "call_transfer",
+			Time: time.Now(),
+			Tags: map[string]string{
+				frames.MetaCallSID:  callSID,
+				frames.MetaStreamID: meta[frames.MetaStreamID],
+				frames.MetaTraceID:  meta[frames.MetaTraceID],
+				"mode":              string(opts.Mode),
+				"status":            status,
+			},
+		})
+	}
+	if err == nil {
+		c.registry.End(callSID, map[string]string{frames.MetaCallEndReason: "transferred"})
+		return
+	}
+	// Let the LLM tell the caller the transfer did not go through.
+	if meta[frames.MetaToolCallID] == "" {
+		return
+	}
+	if sess, ok := c.registry.Get(callSID); ok {
+		select {
+		case sess.Orch.In() <- toolResultFrame(meta, "transfer failed", "error", err):
+		default:
+		}
+	}
+}
+
+func (c *callTransfers) transfer(callSID string, meta map[string]string, opts transports.TransferOptions) error {
+	if callSID == "" {
+		return errors.New("call sid required")
+	}
+	transferer, ok := c.router.lookup(meta[frames.MetaStreamID], callSID).(transports.CallTransferer)
+	if !ok {
+		return errors.New("transport does not support call transfer")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), transferTimeout)
+	defer cancel()
+	return transferer.TransferCall(ctx, callSID, opts)
+}
+
+// transferBriefing is what the human hears before a warm transfer bridges.
+func transferBriefing(meta map[string]string) string {
+	var parts []string
+	if reason := strings.TrimSpace(meta[frames.MetaReason]); reason != "" {
+		parts = append(parts, "Transfer reason: "+reason+".")
+	}
+	if summary := strings.TrimSpace(meta[frames.MetaCallSummary]); summary != "" {
+		parts = append(parts, summary)
+	}
+	return strings.Join(parts, " ")
+}
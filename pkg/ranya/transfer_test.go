@@ -0,0 +1,108 @@
+package ranya
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/pipeline"
+	"github.com/harunnryd/ranya/pkg/transports"
+	"github.com/harunnryd/ranya/pkg/transports/mock"
+)
+
+func newTransferRegistry() *pipeline.SessionRegistry {
+	return pipeline.NewSessionRegistry(func(ctx context.Context, callSID, streamID, traceID string) (pipeline.Orchestrator, error) {
+		orch := pipeline.New(pipeline.Config{HighCapacity: 8, LowCapacity: 8, StageBuffer: 8})
+		orch.SetContext(ctx)
+		return orch, nil
+	})
+}
+
+func TestTransferCallBuiltin(t *testing.T) {
+	cfg := TransferConfig{Enabled: true, Mode: "cold", DefaultTarget: "support", Targets: map[string]string{"support": "+15550001", "billing": "+15550002"}}
+	builtin := transferCallBuiltin(cfg)
+	meta := map[string]string{frames.MetaStreamID: "stream-1", frames.MetaCallSID: "call-1", frames.MetaToolCallID: "tc-1"}
+
+	out, err := builtin(meta, map[string]any{"target": "billing", "mode": "warm", "reason": "refund"})
+	if err != nil || len(out) != 1 {
+		t.Fatalf("unexpected builtin result %v err=%v", out, err)
+	}
+	cf := out[0].(frames.ControlFrame)
+	got := cf.Meta()
+	if cf.Code() != frames.ControlTransfer || got[frames.MetaTransferTarget] != "billing" || got[frames.MetaTransferMode] != "warm" || got[frames.MetaReason] != "refund" {
+		t.Fatalf("unexpected transfer frame %v", got)
+	}
+	if out, _ := builtin(meta, map[string]any{}); out[0].(frames.ControlFrame).Meta()[frames.MetaTransferTarget] != "support" {
+		t.Fatalf("expected default target")
+	}
+	if _, err := builtin(meta, map[string]any{"target": "+19995550000"}); err == nil {
+		t.Fatalf("expected arbitrary numbers to be rejected")
+	}
+}
+
+func TestCallTransfersEndSession(t *testing.T) {
+	tr := mock.New()
+	router := newTransportRouter(tr, nil)
+	registry := newTransferRegistry()
+	if _, _, err := registry.GetOrCreate("call-1", "stream-1", "trace-1"); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	transfers := &callTransfers{
+		cfg:      TransferConfig{Targets: map[string]string{"support": "+15550001"}, CallerID: "+15559999"},
+		registry: registry,
+		router:   router,
+	}
+	meta := map[string]string{
+		frames.MetaStreamID:       "stream-1",
+		frames.MetaTransferTarget: "support",
+		frames.MetaTransferMode:   "warm",
+		frames.MetaReason:         "billing question",
+		frames.MetaCallSummary:    "Summary: User said \"refund\".",
+	}
+	transfers.run("call-1", meta)
+
+	got := tr.Transfers()
+	if len(got) != 1 || got[0].CallSID != "call-1" || got[0].Options.Target != "+15550001" || got[0].Options.Mode != transports.TransferWarm {
+		t.Fatalf("unexpected transfers %+v", got)
+	}
+	if want := "Transfer reason: billing question. Summary: User said \"refund\"."; got[0].Options.Briefing != want {
+		t.Fatalf("unexpected briefing %q", got[0].Options.Briefing)
+	}
+	if _, ok := registry.Get("call-1"); ok {
+		t.Fatalf("expected session to end after transfer")
+	}
+}
+
+func TestCallTransfersReportFailure(t *testing.T) {
+	tr := mock.New()
+	tr.SetTransferError(errors.New("busy"))
+	registry := newTransferRegistry()
+	sess, _, err := registry.GetOrCreate("call-1", "stream-1", "trace-1")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	transfers := &callTransfers{registry: registry, router: newTransportRouter(tr, nil)}
+	out := make(chan frames.Frame, 1)
+	sess.Orch.SetSink(func(f frames.Frame) {
+		select {
+		case out <- f:
+		default:
+		}
+	})
+	transfers.run("call-1", map[string]string{frames.MetaStreamID: "stream-1", frames.MetaTransferTarget: "+15550001", frames.MetaToolCallID: "tc-1", frames.MetaToolName: TransferCallTool})
+
+	select {
+	case f := <-out:
+		sf, ok := f.(frames.SystemFrame)
+		if !ok || sf.Name() != "tool_result" || sf.Meta()[frames.MetaToolStatus] != "error" {
+			t.Fatalf("unexpected frame %v", frames.MetadataOf(f).Map())
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected tool_result after failed transfer")
+	}
+	if _, ok := registry.Get("call-1"); !ok {
+		t.Fatalf("session should survive a failed transfer")
+	}
+}
@@ -0,0 +1,118 @@
+package ranya
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/harunnryd/ranya/pkg/transports"
+)
+
+// DefaultTransportName is the name EngineOptions.Transport is registered
+// under when several transports are served.
+const DefaultTransportName = "default"
+
+// transportRouter remembers which transport each call and stream arrived on
+// so outbound frames go back the same way.
+type transportRouter struct {
+	byName map[string]transports.Transport
+	names  []string
+
+	calls   sync.Map // callSID -> transport name
+	streams sync.Map // streamID -> transport name
+}
+
+func newTransportRouter(def transports.Transport, named map[string]transports.Transport) *transportRouter {
+	r := &transportRouter{byName: make(map[string]transports.Transport)}
+	if def != nil {
+		r.byName[DefaultTransportName] = def
+	}
+	for name, t := range named {
+		name = strings.TrimSpace(name)
+		if name == "" || t == nil {
+			continue
+		}
+		r.byName[name] = t
+	}
+	for name := range r.byName {
+		r.names = append(r.names, name)
+	}
+	sort.Strings(r.names)
+	return r
+}
+
+// missing returns the transports.named entries in cfg that were not passed
+// in. The engine only reads their overrides; building them is up to the
+// caller.
+func (r *transportRouter) missing(cfg TransportsConfig) []string {
+	var out []string
+	for name := range cfg.Named {
+		if r.get(strings.TrimSpace(name)) == nil {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (r *transportRouter) empty() bool {
+	return len(r.byName) == 0
+}
+
+func (r *transportRouter) get(name string) transports.Transport {
+	return r.byName[name]
+}
+
+// bind records the transport for a call and its current stream.
+func (r *transportRouter) bind(name, callSID, streamID string) {
+	if callSID != "" {
+		if v, ok := r.calls.Load(callSID); !ok || v.(string) != name {
+			r.calls.Store(callSID, name)
+		}
+	}
+	if streamID != "" {
+		if _, ok := r.streams.Load(streamID); !ok {
+			r.streams.Store(streamID, name)
+		}
+	}
+}
+
+func (r *transportRouter) unbindStream(streamID string) {
+	if streamID != "" {
+		r.streams.Delete(streamID)
+	}
+}
+
+func (r *transportRouter) unbind(callSID, streamID string) {
+	if callSID != "" {
+		r.calls.Delete(callSID)
+	}
+	r.unbindStream(streamID)
+}
+
+// nameForCall returns the transport a call arrived on, or "" if unknown.
+func (r *transportRouter) nameForCall(callSID string) string {
+	if v, ok := r.calls.Load(callSID); ok {
+		return v.(string)
+	}
+	return ""
+}
+
+// lookup resolves by stream, then by call, then the only transport if there
+// is just one.
+func (r *transportRouter) lookup(streamID, callSID string) transports.Transport {
+	if streamID != "" {
+		if v, ok := r.streams.Load(streamID); ok {
+			return r.byName[v.(string)]
+		}
+	}
+	if callSID != "" {
+		if v, ok := r.calls.Load(callSID); ok {
+			return r.byName[v.(string)]
+		}
+	}
+	if len(r.names) == 1 {
+		return r.byName[r.names[0]]
+	}
+	return nil
+}
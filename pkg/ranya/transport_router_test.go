@@ -0,0 +1,100 @@
+package ranya
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/transports"
+	"github.com/harunnryd/ranya/pkg/transports/mock"
+)
+
+func TestTransportRouterRoutesBySession(t *testing.T) {
+	phone, web := mock.New(), mock.New()
+	r := newTransportRouter(phone, map[string]transports.Transport{"web": web})
+	if got := r.names; len(got) != 2 || got[0] != DefaultTransportName || got[1] != "web" {
+		t.Fatalf("unexpected names %v", got)
+	}
+	r.bind(DefaultTransportName, "call-1", "stream-1")
+	r.bind("web", "call-2", "stream-2")
+
+	if r.lookup("stream-2", "") != web {
+		t.Fatalf("expected stream-2 to route to web")
+	}
+	if r.lookup("", "call-1") != phone {
+		t.Fatalf("expected call-1 to route to phone by call sid")
+	}
+	if r.lookup("stream-x", "") != nil {
+		t.Fatalf("expected unknown stream to be dropped with several transports")
+	}
+
+	r.unbind("call-2", "stream-2")
+	if r.nameForCall("call-2") != "" {
+		t.Fatalf("expected call-2 to be unbound")
+	}
+
+	single := newTransportRouter(phone, nil)
+	if single.lookup("stream-x", "") != phone {
+		t.Fatalf("expected a single transport to receive unrouted frames")
+	}
+}
+
+func TestTransportOverridesApply(t *testing.T) {
+	cfg := Config{BasePrompt: "base"}
+	cfg.Vendors.STT = VendorConfig{Provider: "deepgram", Settings: map[string]any{"model": "nova-2", "language": "id"}}
+	cfg.Transports.Named = map[string]NamedTransportConfig{
+		"web": {Provider: "websocket", Overrides: TransportOverrides{
+			STT:      &VendorConfig{Settings: map[string]any{"model": "nova-3"}},
+			Greeting: "from config",
+		}},
+	}
+	overrides := transportOverrides(cfg, map[string]TransportOverrides{"web": {Greeting: "from options"}})
+	ov := overrides["web"]
+	if ov.Greeting != "from options" || ov.STT == nil {
+		t.Fatalf("unexpected merged overrides %+v", ov)
+	}
+	out := applyTransportOverrides(cfg, ov)
+	if out.Vendors.STT.Provider != "deepgram" || out.Vendors.STT.Settings["model"] != "nova-3" || out.Vendors.STT.Settings["language"] != "id" {
+		t.Fatalf("unexpected stt config %+v", out.Vendors.STT)
+	}
+	if cfg.Vendors.STT.Settings["model"] != "nova-2" {
+		t.Fatalf("base config must not be mutated")
+	}
+	if out.BasePrompt != "base" {
+		t.Fatalf("expected base prompt to be inherited, got %q", out.BasePrompt)
+	}
+}
+
+func TestSendFrameWaitsForRoom(t *testing.T) {
+	ch := make(chan frames.Frame, 1)
+	ch <- frames.NewTextFrame("stream-1", 0, "busy", nil)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		<-ch
+	}()
+	reconnect := frames.NewSystemFrame("stream-2", 0, "call_reconnect", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if !sendFrame(ctx, ch, reconnect) {
+		t.Fatalf("expected call_reconnect to be queued once there was room")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if sendFrame(ctx, ch, reconnect) {
+		t.Fatalf("expected the send to give up when the context ends")
+	}
+}
+
+func TestTransportRouterReportsMissingNamedTransports(t *testing.T) {
+	r := newTransportRouter(mock.New(), map[string]transports.Transport{"web": mock.New()})
+	got := r.missing(TransportsConfig{Named: map[string]NamedTransportConfig{
+		"web":    {Provider: "websocket"},
+		"sip":    {Provider: "sip"},
+		"vonage": {Provider: "vonage"},
+	}})
+	if len(got) != 2 || got[0] != "sip" || got[1] != "vonage" {
+		t.Fatalf("unexpected missing transports %v", got)
+	}
+}
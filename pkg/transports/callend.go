@@ -0,0 +1,31 @@
+package transports
+
+import (
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+)
+
+// CallEndSendTimeout bounds how long SendCallEnd waits for room on a
+// transport's Recv channel.
+const CallEndSendTimeout = 2 * time.Second
+
+// SendCallEnd hands a call_end frame to ch, waiting up to CallEndSendTimeout
+// when ch is full. Idle reaping is off by default, so a dropped call_end
+// would leave the call's session running; transports deliver it with this
+// instead of a non-blocking send. It reports whether f was queued.
+func SendCallEnd(ch chan<- frames.Frame, f frames.Frame) bool {
+	select {
+	case ch <- f:
+		return true
+	default:
+	}
+	timer := time.NewTimer(CallEndSendTimeout)
+	defer timer.Stop()
+	select {
+	case ch <- f:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
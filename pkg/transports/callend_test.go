@@ -0,0 +1,23 @@
+package transports
+
+import (
+	"testing"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+)
+
+func TestSendCallEndWaitsForRoom(t *testing.T) {
+	ch := make(chan frames.Frame, 1)
+	ch <- frames.NewSystemFrame("s1", 0, "call_start", nil)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		<-ch
+	}()
+	if !SendCallEnd(ch, frames.NewSystemFrame("s1", 1, "call_end", nil)) {
+		t.Fatalf("expected call_end to be queued once the channel drained")
+	}
+	if f := <-ch; f.(frames.SystemFrame).Name() != "call_end" {
+		t.Fatalf("unexpected frame %#v", f)
+	}
+}
@@ -0,0 +1,161 @@
+// Package dtmf synthesizes in-band DTMF tones (ITU-T Q.23) as telephony
+// audio, for transports that cannot signal digits out of band without
+// disturbing the media stream.
+package dtmf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/audio"
+)
+
+// Audio encodings Synthesize can produce.
+const (
+	EncodingMuLaw = "mulaw"
+	EncodingALaw  = "alaw"
+	EncodingPCM16 = "pcm16"
+)
+
+// Timing defaults. Q.24 asks receivers to accept 40ms of tone and 40ms of
+// silence; 100ms of each is what carriers and IVRs detect reliably.
+const (
+	DefaultToneDuration = 100 * time.Millisecond
+	DefaultGap          = 100 * time.Millisecond
+	// DefaultPause is the silence for "w" in a digit string, as in Twilio's
+	// sendDigits.
+	DefaultPause = 500 * time.Millisecond
+)
+
+// Tone levels per group, as linear PCM16 peaks (about -13 and -11 dBFS). The
+// high group is 2dB louder to offset line attenuation ("twist").
+const (
+	lowLevel  = 7000
+	highLevel = 8800
+)
+
+// ramp fades each tone in and out to avoid clicks that can confuse
+// detectors.
+const ramp = 2 * time.Millisecond
+
+var (
+	rowFreqs = [4]float64{697, 770, 852, 941}
+	colFreqs = [4]float64{1209, 1336, 1477, 1633}
+	keypad   = [4]string{"123A", "456B", "789C", "*0#D"}
+)
+
+// Options controls the generated audio. Zero values use 8kHz μ-law and the
+// default timings.
+type Options struct {
+	SampleRate   int
+	Encoding     string
+	ToneDuration time.Duration
+	Gap          time.Duration
+	Pause        time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.SampleRate <= 0 {
+		o.SampleRate = 8000
+	}
+	if o.Encoding == "" {
+		o.Encoding = EncodingMuLaw
+	}
+	if o.ToneDuration <= 0 {
+		o.ToneDuration = DefaultToneDuration
+	}
+	if o.Gap <= 0 {
+		o.Gap = DefaultGap
+	}
+	if o.Pause <= 0 {
+		o.Pause = DefaultPause
+	}
+	return o
+}
+
+// Frequencies returns the low and high tone for digit.
+func Frequencies(digit rune) (low, high float64, ok bool) {
+	if digit >= 'a' && digit <= 'd' {
+		digit -= 'a' - 'A'
+	}
+	for r, row := range keypad {
+		for c, d := range row {
+			if d == digit {
+				return rowFreqs[r], colFreqs[c], true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// Synthesize renders digits as tones, each followed by a gap. "w" or "W"
+// inserts a pause; any other character outside 0-9, *, # and A-D is an
+// error.
+func Synthesize(digits string, opts Options) ([]byte, error) {
+	opts = opts.withDefaults()
+	bps, err := bytesPerSample(opts.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	var pcm []int16
+	for _, d := range digits {
+		if d == 'w' || d == 'W' {
+			pcm = appendSilence(pcm, samples(opts.Pause, opts.SampleRate))
+			continue
+		}
+		low, high, ok := Frequencies(d)
+		if !ok {
+			return nil, fmt.Errorf("dtmf: invalid digit %q", d)
+		}
+		pcm = appendTone(pcm, low, high, samples(opts.ToneDuration, opts.SampleRate), samples(ramp, opts.SampleRate), opts.SampleRate)
+		pcm = appendSilence(pcm, samples(opts.Gap, opts.SampleRate))
+	}
+	out := make([]byte, 0, len(pcm)*bps)
+	for _, s := range pcm {
+		switch opts.Encoding {
+		case EncodingMuLaw:
+			out = append(out, audio.LinearToULaw(s))
+		case EncodingALaw:
+			out = append(out, audio.LinearToALaw(s))
+		default:
+			out = binary.LittleEndian.AppendUint16(out, uint16(s))
+		}
+	}
+	return out, nil
+}
+
+func bytesPerSample(encoding string) (int, error) {
+	switch encoding {
+	case EncodingMuLaw, EncodingALaw:
+		return 1, nil
+	case EncodingPCM16:
+		return 2, nil
+	}
+	return 0, fmt.Errorf("dtmf: unsupported encoding %q", encoding)
+}
+
+func samples(d time.Duration, rate int) int {
+	return int(d * time.Duration(rate) / time.Second)
+}
+
+func appendSilence(pcm []int16, n int) []int16 {
+	return append(pcm, make([]int16, n)...)
+}
+
+func appendTone(pcm []int16, low, high float64, n, fade, rate int) []int16 {
+	for i := 0; i < n; i++ {
+		t := float64(i) / float64(rate)
+		v := lowLevel*math.Sin(2*math.Pi*low*t) + highLevel*math.Sin(2*math.Pi*high*t)
+		if fade > 0 {
+			if i < fade {
+				v *= float64(i) / float64(fade)
+			} else if n-1-i < fade {
+				v *= float64(n-1-i) / float64(fade)
+			}
+		}
+		pcm = append(pcm, int16(v))
+	}
+	return pcm
+}
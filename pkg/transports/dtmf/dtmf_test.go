@@ -0,0 +1,69 @@
+package dtmf
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/audio"
+)
+
+// goertzel returns the power of freq in pcm.
+func goertzel(pcm []int16, freq float64, rate int) float64 {
+	coeff := 2 * math.Cos(2*math.Pi*freq/float64(rate))
+	var s1, s2 float64
+	for _, v := range pcm {
+		s := float64(v) + coeff*s1 - s2
+		s2, s1 = s1, s
+	}
+	return s1*s1 + s2*s2 - coeff*s1*s2
+}
+
+func TestSynthesizeTones(t *testing.T) {
+	all := append(rowFreqs[:], colFreqs[:]...)
+	for _, digit := range "0123456789*#ABCD" {
+		tones, err := Synthesize(string(digit), Options{Encoding: EncodingPCM16, Gap: time.Millisecond})
+		if err != nil {
+			t.Fatalf("synthesize %q: %v", digit, err)
+		}
+		pcm := make([]int16, len(tones)/2)
+		for i := range pcm {
+			pcm[i] = int16(binary.LittleEndian.Uint16(tones[2*i:]))
+		}
+		low, high, _ := Frequencies(digit)
+		want := math.Min(goertzel(pcm, low, 8000), goertzel(pcm, high, 8000))
+		for _, f := range all {
+			if f == low || f == high {
+				continue
+			}
+			if p := goertzel(pcm, f, 8000); p*10 > want {
+				t.Fatalf("digit %q: %vHz leaks (%.0f vs %.0f)", digit, f, p, want)
+			}
+		}
+	}
+}
+
+func TestSynthesizeTiming(t *testing.T) {
+	// Two tones and gaps of 100ms plus a 500ms pause at 8kHz μ-law.
+	tones, err := Synthesize("1w2", Options{})
+	if err != nil {
+		t.Fatalf("synthesize: %v", err)
+	}
+	if len(tones) != 2*(800+800)+4000 {
+		t.Fatalf("unexpected length %d", len(tones))
+	}
+	if tones[len(tones)-1] != audio.LinearToULaw(0) {
+		t.Fatalf("expected trailing silence")
+	}
+	wide, err := Synthesize("5", Options{SampleRate: 16000, Encoding: EncodingPCM16, ToneDuration: 60 * time.Millisecond, Gap: 40 * time.Millisecond})
+	if err != nil || len(wide) != (960+640)*2 {
+		t.Fatalf("unexpected pcm16 length %d err=%v", len(wide), err)
+	}
+	if _, err := Synthesize("12x", Options{}); err == nil {
+		t.Fatalf("expected invalid digit error")
+	}
+	if _, err := Synthesize("1", Options{Encoding: "opus"}); err == nil {
+		t.Fatalf("expected unsupported encoding error")
+	}
+}
@@ -2,19 +2,43 @@ package mock
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"sync/atomic"
 
 	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/transports"
 )
 
 // Transport is an in-memory transport for local testing and integration.
 // It implements the transports.Transport interface without any network dependency.
 type Transport struct {
-	recvCh chan frames.Frame
-	sentCh chan frames.Frame
-	closed atomic.Bool
-	mu     sync.Mutex
+	recvCh   chan frames.Frame
+	sentCh   chan frames.Frame
+	closed   atomic.Bool
+	draining atomic.Bool
+	mu       sync.Mutex
+
+	transfers   []Transfer
+	transferErr error
+	hangups     []string
+	dials       []Dial
+	dialErr     error
+}
+
+// Dial records a DialWithOptions request.
+type Dial struct {
+	CallSID string
+	To      string
+	From    string
+	URL     string
+	Options transports.DialOptions
+}
+
+// Transfer records a TransferCall request.
+type Transfer struct {
+	CallSID string
+	Options transports.TransferOptions
 }
 
 func New() *Transport {
@@ -49,13 +73,19 @@ func (t *Transport) Stop() error {
 
 func (t *Transport) Recv() <-chan frames.Frame { return t.recvCh }
 
+// Send records f for Sent. Pooled audio frames are retained, since the
+// pipeline releases them once Send returns.
 func (t *Transport) Send(f frames.Frame) error {
 	if t.closed.Load() {
 		return nil
 	}
+	if af, ok := f.(frames.AudioFrame); ok && af.Pooled() && !frames.RetainAudioFrame(af) {
+		return nil
+	}
 	select {
 	case t.sentCh <- f:
 	default:
+		frames.ReleaseAudioFrame(f)
 	}
 	return nil
 }
@@ -71,5 +101,97 @@ func (t *Transport) Push(f frames.Frame) {
 	}
 }
 
-// Sent exposes outbound frames for inspection.
+// Sent exposes outbound frames for inspection. Readers must call
+// frames.ReleaseAudioFrame on audio frames they receive.
 func (t *Transport) Sent() <-chan frames.Frame { return t.sentCh }
+
+// TransferCall records the request and returns the error set by
+// SetTransferError, if any.
+func (t *Transport) TransferCall(ctx context.Context, callSID string, opts transports.TransferOptions) error {
+	_ = ctx
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.transferErr != nil {
+		return t.transferErr
+	}
+	t.transfers = append(t.transfers, Transfer{CallSID: callSID, Options: opts})
+	return nil
+}
+
+// SetTransferError makes subsequent TransferCall requests fail with err.
+func (t *Transport) SetTransferError(err error) {
+	t.mu.Lock()
+	t.transferErr = err
+	t.mu.Unlock()
+}
+
+// Transfers returns the transfer requests received so far.
+func (t *Transport) Transfers() []Transfer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Transfer(nil), t.transfers...)
+}
+
+// HangupCall records the call SID.
+func (t *Transport) HangupCall(ctx context.Context, callSID string) error {
+	_ = ctx
+	t.mu.Lock()
+	t.hangups = append(t.hangups, callSID)
+	t.mu.Unlock()
+	return nil
+}
+
+// Hangups returns the call SIDs hung up so far.
+func (t *Transport) Hangups() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.hangups...)
+}
+
+// Dial records the request and returns a generated call SID.
+func (t *Transport) Dial(ctx context.Context, to, from, url string) (string, error) {
+	return t.DialWithOptions(ctx, to, from, url, transports.DialOptions{})
+}
+
+// DialWithOptions records the request and returns a generated call SID, or
+// the error set by SetDialError.
+func (t *Transport) DialWithOptions(ctx context.Context, to, from, url string, opts transports.DialOptions) (string, error) {
+	_ = ctx
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.dialErr != nil {
+		return "", t.dialErr
+	}
+	sid := fmt.Sprintf("mock-call-%d", len(t.dials)+1)
+	t.dials = append(t.dials, Dial{CallSID: sid, To: to, From: from, URL: url, Options: opts})
+	return sid, nil
+}
+
+// SetDialError makes subsequent dials fail with err.
+func (t *Transport) SetDialError(err error) {
+	t.mu.Lock()
+	t.dialErr = err
+	t.mu.Unlock()
+}
+
+// Dials returns the dial requests received so far.
+func (t *Transport) Dials() []Dial {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Dial(nil), t.dials...)
+}
+
+// DrainCalls records that the transport stopped accepting new calls.
+func (t *Transport) DrainCalls() {
+	t.draining.Store(true)
+}
+
+// Draining reports whether DrainCalls was called.
+func (t *Transport) Draining() bool {
+	return t.draining.Load()
+}
+
+var _ transports.OutboundDialerWithOptions = (*Transport)(nil)
+var _ transports.CallTransferer = (*Transport)(nil)
+var _ transports.CallHanger = (*Transport)(nil)
+var _ transports.CallDrainer = (*Transport)(nil)
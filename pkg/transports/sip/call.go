@@ -0,0 +1,325 @@
+package sip
+
+import (
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harunnryd/ranya/pkg/audio"
+	"github.com/harunnryd/ranya/pkg/frames"
+)
+
+// SIP timer values from RFC 3261 for retransmitting the 200 OK over UDP.
+const (
+	timerT1 = 500 * time.Millisecond
+	timerT2 = 4 * time.Second
+	timerH  = 64 * timerT1
+)
+
+// call is one answered dialog and its media session.
+type call struct {
+	t        *Transport
+	id       string
+	streamID string
+	traceID  string
+	from     string
+	localTag string
+	host     string
+
+	invite    *message
+	inviteSeq uint32
+	answer    []byte
+	sig       sigConn
+
+	codec  Codec
+	dtmfPT uint8
+	rtp    *net.UDPConn
+	pacer  *pacer
+
+	remoteMu  sync.Mutex
+	remoteRTP *net.UDPAddr
+	latched   bool
+
+	lastEventTS uint32
+	haveEvent   bool
+
+	// sdpSession is the o= session id of every answer on the dialog;
+	// sdpVersion goes up with each re-INVITE answer (RFC 3264 §8).
+	sdpSession int64
+	sdpVersion atomic.Int64
+
+	localSeq atomic.Uint32
+	acked    chan struct{}
+	ackOnce  sync.Once
+	done     chan struct{}
+	ended    atomic.Bool
+}
+
+func newCall(t *Transport, id string, invite *message, sig sigConn, codec Codec, dtmfPT uint8, rtpConn *net.UDPConn) *call {
+	seq, _ := invite.cseq()
+	c := &call{
+		t:          t,
+		id:         id,
+		streamID:   uuid.NewString(),
+		traceID:    uuid.NewString(),
+		from:       uriUser(addrURI(invite.get("From"))),
+		localTag:   newTag(),
+		invite:     invite,
+		inviteSeq:  seq,
+		sig:        sig,
+		codec:      codec,
+		dtmfPT:     dtmfPT,
+		rtp:        rtpConn,
+		sdpSession: time.Now().Unix(),
+		acked:      make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	c.sdpVersion.Store(c.sdpSession)
+	c.pacer = newPacer(c.writeRTP, codec, uint16(rand.Intn(1<<16)), rand.Uint32(), rand.Uint32())
+	return c
+}
+
+func (c *call) frameMeta() map[string]string {
+	meta := map[string]string{
+		frames.MetaStreamID: c.streamID,
+		frames.MetaCallSID:  c.id,
+		frames.MetaTraceID:  c.traceID,
+		frames.MetaSource:   "transport",
+	}
+	if c.from != "" {
+		meta[frames.MetaFromNumber] = c.from
+	}
+	return meta
+}
+
+// audioMeta describes inbound audio, which is always delivered as μ-law.
+func (c *call) audioMeta() map[string]string {
+	meta := c.frameMeta()
+	meta[frames.MetaEncoding] = "mulaw"
+	meta[frames.MetaCodec] = "ulaw"
+	meta[frames.MetaFormat] = "ulaw_8000_1ch_8bit"
+	return meta
+}
+
+// setRemote points media at the address in an SDP offer, unless symmetric
+// RTP has already latched onto the address packets really come from.
+func (c *call) setRemote(offer sdpOffer) {
+	ip := net.ParseIP(offer.host)
+	if ip == nil {
+		if addrs, err := net.LookupIP(offer.host); err == nil && len(addrs) > 0 {
+			ip = addrs[0]
+		}
+	}
+	c.remoteMu.Lock()
+	defer c.remoteMu.Unlock()
+	if ip == nil || ip.IsUnspecified() || offer.port == 0 {
+		// Hold (c=0.0.0.0 or port 0): stop sending until media resumes.
+		c.remoteRTP = nil
+		c.latched = false
+		return
+	}
+	if !c.latched {
+		c.remoteRTP = &net.UDPAddr{IP: ip, Port: offer.port}
+	}
+}
+
+// latch switches media to the source of inbound RTP, so callers behind NAT
+// hear us even when their SDP advertises a private address.
+func (c *call) latch(addr *net.UDPAddr) {
+	c.remoteMu.Lock()
+	if !c.latched {
+		c.remoteRTP = addr
+		c.latched = true
+	}
+	c.remoteMu.Unlock()
+}
+
+func (c *call) writeRTP(b []byte) {
+	c.remoteMu.Lock()
+	addr := c.remoteRTP
+	c.remoteMu.Unlock()
+	if addr != nil {
+		_, _ = c.rtp.WriteToUDP(b, addr)
+	}
+}
+
+func (c *call) readRTP() {
+	defer c.t.loops.Done()
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := c.rtp.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		h, payload, err := parseRTP(buf[:n])
+		if err != nil {
+			continue
+		}
+		c.latch(addr)
+		switch {
+		case h.pt == c.codec.PayloadType:
+			c.emitAudio(payload)
+		case c.dtmfPT != 0 && h.pt == c.dtmfPT:
+			c.handleEvent(h, payload)
+		}
+	}
+}
+
+func (c *call) emitAudio(payload []byte) {
+	if len(payload) == 0 {
+		return
+	}
+	buf := frames.AcquireAudioBuf(len(payload))
+	copy(buf, payload)
+	// The pipeline carries telephony audio as μ-law (as Twilio does), so
+	// PCMA calls are transcoded at the edge.
+	if c.codec == CodecPCMA {
+		audio.ALawToULaw(buf)
+	}
+	af := frames.NewAudioFrameFromBuf(c.streamID, time.Now().UnixNano(), buf, 8000, 1, c.audioMeta())
+	if !nonBlockingSend(c.t.recvCh, af) {
+		frames.ReleaseAudioFrame(af)
+	}
+}
+
+// handleEvent emits one DTMF frame per RFC 4733 event. The end packet is
+// sent three times, so events are de-duplicated by RTP timestamp.
+func (c *call) handleEvent(h rtpHeader, payload []byte) {
+	ev, ok := parseDTMFEvent(payload)
+	if !ok || !ev.end {
+		return
+	}
+	if c.haveEvent && c.lastEventTS == h.timestamp {
+		return
+	}
+	c.haveEvent = true
+	c.lastEventTS = h.timestamp
+	digit, ok := dtmfDigit(ev.event)
+	if !ok {
+		return
+	}
+	meta := c.frameMeta()
+	meta[frames.MetaDTMFDigit] = digit
+	nonBlockingSend(c.t.recvCh, frames.NewControlFrame(c.streamID, time.Now().UnixNano(), frames.ControlDTMF, meta))
+}
+
+func (c *call) ack() {
+	c.ackOnce.Do(func() { close(c.acked) })
+}
+
+// retransmitAnswer resends the 200 OK over UDP until the ACK arrives. A
+// dialog that is never confirmed is torn down with a BYE.
+func (c *call) retransmitAnswer() {
+	defer c.t.loops.Done()
+	interval := timerT1
+	deadline := time.NewTimer(timerH)
+	defer deadline.Stop()
+	for {
+		timer := time.NewTimer(interval)
+		select {
+		case <-c.acked:
+			timer.Stop()
+			return
+		case <-c.done:
+			timer.Stop()
+			return
+		case <-deadline.C:
+			timer.Stop()
+			c.sendBye()
+			c.t.endCall(c, "failed")
+			return
+		case <-timer.C:
+			_ = c.sig.send(c.answer)
+			interval *= 2
+			if interval > timerT2 {
+				interval = timerT2
+			}
+		}
+	}
+}
+
+// sendBye ends the dialog from our side. It is best effort: the call is torn
+// down locally whether or not the BYE is answered.
+func (c *call) sendBye() {
+	if c.ended.Load() {
+		return
+	}
+	target := addrURI(c.invite.get("Contact"))
+	if target == "" {
+		target = addrURI(c.invite.get("From"))
+	}
+	req := newRequest("BYE", target)
+	local := c.sig.remote()
+	if c.sig.reliable() {
+		if addr := c.t.TCPAddr(); addr != nil {
+			local = addr
+		}
+	} else if addr := c.t.UDPAddr(); addr != nil {
+		local = addr
+	}
+	_, port, _ := net.SplitHostPort(local.String())
+	req.add("Via", "SIP/2.0/"+c.sig.network()+" "+net.JoinHostPort(c.host, port)+";branch=z9hG4bK"+newTag()+";rport")
+	req.add("Max-Forwards", "70")
+	for _, rr := range c.invite.getAll("Record-Route") {
+		req.add("Route", rr)
+	}
+	to := c.invite.get("To")
+	if headerParam(to, "tag") == "" {
+		to += ";tag=" + c.localTag
+	}
+	req.add("From", to)
+	req.add("To", c.invite.get("From"))
+	req.add("Call-ID", c.id)
+	req.add("CSeq", strconv.FormatUint(uint64(c.localSeq.Add(1)), 10)+" BYE")
+	req.add("User-Agent", c.t.cfg.UserAgent)
+	_ = c.sig.send(req.bytes())
+}
+
+// end stops media; it reports false if the call had already ended.
+func (c *call) end() bool {
+	if !c.ended.CompareAndSwap(false, true) {
+		return false
+	}
+	close(c.done)
+	c.pacer.clear()
+	_ = c.rtp.Close()
+	return true
+}
+
+// encodeOutbound converts a pipeline audio frame into codec bytes.
+func encodeOutbound(af frames.AudioFrame, codec Codec) ([]byte, bool) {
+	data := af.RawPayload()
+	switch audio.EncodingOf(af) {
+	case audio.EncodingMuLaw:
+		out := append([]byte(nil), data...)
+		if codec == CodecPCMA {
+			audio.ULawToALaw(out)
+		}
+		return out, true
+	case audio.EncodingALaw:
+		out := append([]byte(nil), data...)
+		if codec == CodecPCMU {
+			audio.ALawToULaw(out)
+		}
+		return out, true
+	case audio.EncodingPCM16:
+		if af.Rate() != 8000 || af.Channels() > 1 {
+			return nil, false
+		}
+		pcm := audio.Decode(data, audio.EncodingPCM16, 1)
+		out := make([]byte, len(pcm))
+		for i, s := range pcm {
+			if codec == CodecPCMA {
+				out[i] = audio.LinearToALaw(s)
+			} else {
+				out[i] = audio.LinearToULaw(s)
+			}
+		}
+		return out, true
+	}
+	return nil, false
+}
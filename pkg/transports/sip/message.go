@@ -0,0 +1,252 @@
+package sip
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// message is a parsed SIP request or response. Headers keep their order so
+// Via and Record-Route lists can be echoed back unchanged.
+type message struct {
+	request bool
+	method  string
+	uri     string
+	status  int
+	reason  string
+	headers []header
+	body    []byte
+}
+
+type header struct {
+	name  string
+	value string
+}
+
+var compactHeaders = map[string]string{
+	"v": "Via",
+	"f": "From",
+	"t": "To",
+	"i": "Call-ID",
+	"m": "Contact",
+	"l": "Content-Length",
+	"c": "Content-Type",
+	"k": "Supported",
+}
+
+var canonicalHeaders = map[string]string{
+	"call-id": "Call-ID",
+	"cseq":    "CSeq",
+}
+
+func canonicalName(name string) string {
+	name = strings.TrimSpace(name)
+	lower := strings.ToLower(name)
+	if full, ok := compactHeaders[lower]; ok {
+		return full
+	}
+	if full, ok := canonicalHeaders[lower]; ok {
+		return full
+	}
+	return textproto.CanonicalMIMEHeaderKey(name)
+}
+
+func newRequest(method, uri string) *message {
+	return &message{request: true, method: method, uri: uri}
+}
+
+func (m *message) get(name string) string {
+	name = canonicalName(name)
+	for _, h := range m.headers {
+		if h.name == name {
+			return h.value
+		}
+	}
+	return ""
+}
+
+func (m *message) getAll(name string) []string {
+	name = canonicalName(name)
+	var out []string
+	for _, h := range m.headers {
+		if h.name == name {
+			out = append(out, h.value)
+		}
+	}
+	return out
+}
+
+func (m *message) add(name, value string) {
+	m.headers = append(m.headers, header{name: canonicalName(name), value: value})
+}
+
+func (m *message) set(name, value string) {
+	name = canonicalName(name)
+	out := m.headers[:0]
+	for _, h := range m.headers {
+		if h.name != name {
+			out = append(out, h)
+		}
+	}
+	m.headers = append(out, header{name: name, value: value})
+}
+
+// cseq returns the CSeq number and method.
+func (m *message) cseq() (uint32, string) {
+	fields := strings.Fields(m.get("CSeq"))
+	if len(fields) != 2 {
+		return 0, ""
+	}
+	n, _ := strconv.ParseUint(fields[0], 10, 32)
+	return uint32(n), strings.ToUpper(fields[1])
+}
+
+// bytes serializes the message with a correct Content-Length.
+func (m *message) bytes() []byte {
+	var b bytes.Buffer
+	if m.request {
+		fmt.Fprintf(&b, "%s %s SIP/2.0\r\n", m.method, m.uri)
+	} else {
+		fmt.Fprintf(&b, "SIP/2.0 %d %s\r\n", m.status, m.reason)
+	}
+	for _, h := range m.headers {
+		if h.name == "Content-Length" {
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %s\r\n", h.name, h.value)
+	}
+	fmt.Fprintf(&b, "Content-Length: %d\r\n\r\n", len(m.body))
+	b.Write(m.body)
+	return b.Bytes()
+}
+
+// parseMessage parses a complete datagram.
+func parseMessage(b []byte) (*message, error) {
+	return readMessage(bufio.NewReader(bytes.NewReader(b)))
+}
+
+// readMessage reads one message from a stream, using Content-Length to find
+// the end of the body.
+func readMessage(r *bufio.Reader) (*message, error) {
+	tp := textproto.NewReader(r)
+	var line string
+	var err error
+	// Skip CRLF keep-alives between messages.
+	for line == "" {
+		line, err = tp.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+	}
+	m := &message{}
+	if strings.HasPrefix(line, "SIP/2.0 ") {
+		parts := strings.SplitN(line, " ", 3)
+		if len(parts) < 2 {
+			return nil, errors.New("sip: malformed status line")
+		}
+		m.status, err = strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, errors.New("sip: malformed status code")
+		}
+		if len(parts) == 3 {
+			m.reason = parts[2]
+		}
+	} else {
+		parts := strings.Split(line, " ")
+		if len(parts) != 3 || parts[2] != "SIP/2.0" {
+			return nil, errors.New("sip: malformed request line")
+		}
+		m.request = true
+		m.method = strings.ToUpper(parts[0])
+		m.uri = parts[1]
+	}
+	for {
+		hl, err := tp.ReadContinuedLine()
+		if err != nil {
+			return nil, err
+		}
+		if hl == "" {
+			break
+		}
+		name, value, ok := strings.Cut(hl, ":")
+		if !ok {
+			return nil, errors.New("sip: malformed header")
+		}
+		m.add(name, strings.TrimSpace(value))
+	}
+	if cl := m.get("Content-Length"); cl != "" {
+		n, err := strconv.Atoi(cl)
+		if err != nil || n < 0 || n > maxBodySize {
+			return nil, errors.New("sip: bad content-length")
+		}
+		m.body = make([]byte, n)
+		if _, err := io.ReadFull(r, m.body); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+const maxBodySize = 64 << 10
+
+// headerParam returns a ;name=value parameter from a header value.
+func headerParam(value, name string) string {
+	// Parameters after a <uri> belong to the header, not the URI.
+	if i := strings.LastIndexByte(value, '>'); i >= 0 {
+		value = value[i+1:]
+	}
+	for _, p := range strings.Split(value, ";")[1:] {
+		k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// addrURI extracts the URI from a name-addr such as `"Bob" <sip:bob@host>;tag=1`.
+func addrURI(value string) string {
+	if i := strings.IndexByte(value, '<'); i >= 0 {
+		if j := strings.IndexByte(value[i:], '>'); j > 0 {
+			return value[i+1 : i+j]
+		}
+	}
+	if i := strings.IndexByte(value, ';'); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value)
+}
+
+// uriUser returns the user part of a sip: URI.
+func uriUser(uri string) string {
+	uri = strings.TrimPrefix(strings.TrimPrefix(uri, "sips:"), "sip:")
+	if user, _, ok := strings.Cut(uri, "@"); ok {
+		return user
+	}
+	return ""
+}
+
+// uriHost returns the host part of a sip: URI, without port or parameters.
+func uriHost(uri string) string {
+	uri = strings.TrimPrefix(strings.TrimPrefix(uri, "sips:"), "sip:")
+	if _, host, ok := strings.Cut(uri, "@"); ok {
+		uri = host
+	}
+	if i := strings.IndexAny(uri, ";?"); i >= 0 {
+		uri = uri[:i]
+	}
+	if strings.HasPrefix(uri, "[") {
+		if i := strings.IndexByte(uri, ']'); i > 0 {
+			return uri[1:i]
+		}
+	}
+	if host, _, ok := strings.Cut(uri, ":"); ok {
+		return host
+	}
+	return uri
+}
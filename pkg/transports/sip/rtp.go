@@ -0,0 +1,255 @@
+package sip
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+)
+
+// packetTime is the RTP packetization interval; 20ms of 8kHz G.711 is 160
+// bytes per packet.
+const (
+	packetTime    = 20 * time.Millisecond
+	samplesPerPkt = 160
+)
+
+type rtpHeader struct {
+	marker    bool
+	pt        uint8
+	seq       uint16
+	timestamp uint32
+	ssrc      uint32
+}
+
+func (h rtpHeader) marshal(payload []byte) []byte {
+	b := make([]byte, 12+len(payload))
+	b[0] = 0x80
+	b[1] = h.pt & 0x7F
+	if h.marker {
+		b[1] |= 0x80
+	}
+	binary.BigEndian.PutUint16(b[2:], h.seq)
+	binary.BigEndian.PutUint32(b[4:], h.timestamp)
+	binary.BigEndian.PutUint32(b[8:], h.ssrc)
+	copy(b[12:], payload)
+	return b
+}
+
+var errShortRTP = errors.New("sip: short rtp packet")
+
+// parseRTP returns the header and payload of an RTP packet, skipping CSRCs,
+// header extensions and padding.
+func parseRTP(b []byte) (rtpHeader, []byte, error) {
+	if len(b) < 12 || b[0]>>6 != 2 {
+		return rtpHeader{}, nil, errShortRTP
+	}
+	h := rtpHeader{
+		marker:    b[1]&0x80 != 0,
+		pt:        b[1] & 0x7F,
+		seq:       binary.BigEndian.Uint16(b[2:]),
+		timestamp: binary.BigEndian.Uint32(b[4:]),
+		ssrc:      binary.BigEndian.Uint32(b[8:]),
+	}
+	off := 12 + 4*int(b[0]&0x0F)
+	if b[0]&0x10 != 0 {
+		if len(b) < off+4 {
+			return h, nil, errShortRTP
+		}
+		off += 4 + 4*int(binary.BigEndian.Uint16(b[off+2:]))
+	}
+	end := len(b)
+	if b[0]&0x20 != 0 && end > 0 {
+		end -= int(b[end-1])
+	}
+	if off > end {
+		return h, nil, errShortRTP
+	}
+	return h, b[off:end], nil
+}
+
+// dtmfEvent is an RFC 4733 telephone-event payload.
+type dtmfEvent struct {
+	event    uint8
+	end      bool
+	volume   uint8
+	duration uint16
+}
+
+func parseDTMFEvent(b []byte) (dtmfEvent, bool) {
+	if len(b) < 4 {
+		return dtmfEvent{}, false
+	}
+	return dtmfEvent{
+		event:    b[0],
+		end:      b[1]&0x80 != 0,
+		volume:   b[1] & 0x3F,
+		duration: binary.BigEndian.Uint16(b[2:]),
+	}, true
+}
+
+func (e dtmfEvent) marshal() []byte {
+	b := make([]byte, 4)
+	b[0] = e.event
+	b[1] = e.volume & 0x3F
+	if e.end {
+		b[1] |= 0x80
+	}
+	binary.BigEndian.PutUint16(b[2:], e.duration)
+	return b
+}
+
+const dtmfDigits = "0123456789*#ABCD"
+
+func dtmfDigit(event uint8) (string, bool) {
+	if int(event) >= len(dtmfDigits) {
+		return "", false
+	}
+	return dtmfDigits[event : event+1], true
+}
+
+func dtmfCode(digit rune) (uint8, bool) {
+	for i, d := range dtmfDigits {
+		if d == digit {
+			return uint8(i), true
+		}
+	}
+	return 0, false
+}
+
+// rtpPacket is one queued outbound packet. advance is how many samples the
+// timestamp moves after it is sent; DTMF event updates share a timestamp.
+type rtpPacket struct {
+	pt      uint8
+	payload []byte
+	marker  bool
+	advance uint32
+}
+
+// pacer sends queued packets on a packetTime clock so playback is paced
+// the way a phone expects, regardless of how fast TTS produces audio.
+type pacer struct {
+	send  func([]byte)
+	codec Codec
+
+	mu    sync.Mutex
+	queue []rtpPacket
+	// carry holds a partial packet until more audio arrives or the queue
+	// runs dry.
+	carry []byte
+
+	seq       uint16
+	timestamp uint32
+	ssrc      uint32
+	idle      bool
+}
+
+func newPacer(send func([]byte), codec Codec, seq uint16, timestamp, ssrc uint32) *pacer {
+	return &pacer{send: send, codec: codec, seq: seq, timestamp: timestamp, ssrc: ssrc, idle: true}
+}
+
+// enqueueAudio splits payload, already in the negotiated codec, into
+// packet-sized chunks.
+func (p *pacer) enqueueAudio(payload []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	data := append(p.carry, payload...)
+	p.carry = nil
+	for len(data) >= samplesPerPkt {
+		chunk := make([]byte, samplesPerPkt)
+		copy(chunk, data)
+		p.queue = append(p.queue, rtpPacket{pt: p.codec.PayloadType, payload: chunk, advance: samplesPerPkt})
+		data = data[samplesPerPkt:]
+	}
+	if len(data) > 0 {
+		p.carry = append([]byte(nil), data...)
+	}
+}
+
+// enqueueDTMF queues the RFC 4733 packets for one digit: updates every
+// packetTime, then three end packets, all sharing the start timestamp.
+func (p *pacer) enqueueDTMF(pt uint8, event uint8, duration time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.flushCarryLocked()
+	total := uint16(duration / time.Millisecond * 8)
+	var d uint16
+	first := true
+	for d+samplesPerPkt < total {
+		d += samplesPerPkt
+		p.queue = append(p.queue, rtpPacket{pt: pt, payload: dtmfEvent{event: event, volume: 10, duration: d}.marshal(), marker: first})
+		first = false
+	}
+	for i := 0; i < 3; i++ {
+		pkt := rtpPacket{pt: pt, payload: dtmfEvent{event: event, end: true, volume: 10, duration: total}.marshal(), marker: first}
+		first = false
+		if i == 2 {
+			pkt.advance = uint32(total)
+		}
+		p.queue = append(p.queue, pkt)
+	}
+}
+
+// clear drops queued audio, used on barge-in.
+func (p *pacer) clear() {
+	p.mu.Lock()
+	p.queue = nil
+	p.carry = nil
+	p.mu.Unlock()
+}
+
+// flushCarryLocked pads the partial packet with silence and queues it.
+func (p *pacer) flushCarryLocked() {
+	if len(p.carry) == 0 {
+		return
+	}
+	chunk := make([]byte, samplesPerPkt)
+	n := copy(chunk, p.carry)
+	for i := n; i < len(chunk); i++ {
+		chunk[i] = p.codec.Silence
+	}
+	p.carry = nil
+	p.queue = append(p.queue, rtpPacket{pt: p.codec.PayloadType, payload: chunk, advance: samplesPerPkt})
+}
+
+// tick sends at most one packet. When nothing is queued the timestamp still
+// advances so the next talkspurt starts at the right media time.
+func (p *pacer) tick() {
+	p.mu.Lock()
+	if len(p.queue) == 0 {
+		p.flushCarryLocked()
+	}
+	if len(p.queue) == 0 {
+		p.timestamp += samplesPerPkt
+		p.idle = true
+		p.mu.Unlock()
+		return
+	}
+	pkt := p.queue[0]
+	p.queue = p.queue[1:]
+	h := rtpHeader{
+		marker:    pkt.marker || (p.idle && pkt.pt == p.codec.PayloadType),
+		pt:        pkt.pt,
+		seq:       p.seq,
+		timestamp: p.timestamp,
+		ssrc:      p.ssrc,
+	}
+	p.seq++
+	p.timestamp += pkt.advance
+	p.idle = false
+	p.mu.Unlock()
+	p.send(h.marshal(pkt.payload))
+}
+
+func (p *pacer) run(done <-chan struct{}) {
+	ticker := time.NewTicker(packetTime)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			p.tick()
+		}
+	}
+}
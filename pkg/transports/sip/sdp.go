@@ -0,0 +1,158 @@
+package sip
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Codec is a negotiated G.711 variant.
+type Codec struct {
+	Name        string
+	PayloadType uint8
+	// Silence is one byte of silence in this encoding.
+	Silence byte
+}
+
+var (
+	CodecPCMU = Codec{Name: "PCMU", PayloadType: 0, Silence: 0xFF}
+	CodecPCMA = Codec{Name: "PCMA", PayloadType: 8, Silence: 0xD5}
+)
+
+func codecByName(name string) (Codec, bool) {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "PCMU":
+		return CodecPCMU, true
+	case "PCMA":
+		return CodecPCMA, true
+	}
+	return Codec{}, false
+}
+
+// sdpOffer is the audio part of a remote session description.
+type sdpOffer struct {
+	host string
+	port int
+	// payloads lists offered payload types in preference order.
+	payloads []uint8
+	rtpmap   map[uint8]string
+}
+
+var errNoAudio = errors.New("sip: sdp has no audio media")
+
+func parseSDP(body []byte) (sdpOffer, error) {
+	offer := sdpOffer{rtpmap: make(map[uint8]string)}
+	var sessionHost string
+	inAudio := false
+	seenAudio := false
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if len(line) < 2 || line[1] != '=' {
+			continue
+		}
+		val := line[2:]
+		switch line[0] {
+		case 'c':
+			fields := strings.Fields(val)
+			if len(fields) == 3 {
+				if inAudio {
+					offer.host = fields[2]
+				} else if !seenAudio {
+					sessionHost = fields[2]
+				}
+			}
+		case 'm':
+			fields := strings.Fields(val)
+			inAudio = len(fields) >= 4 && fields[0] == "audio" && !seenAudio
+			if !inAudio {
+				continue
+			}
+			seenAudio = true
+			port, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return offer, fmt.Errorf("sip: bad media port %q", fields[1])
+			}
+			offer.port = port
+			for _, f := range fields[3:] {
+				if pt, err := strconv.Atoi(f); err == nil && pt >= 0 && pt < 128 {
+					offer.payloads = append(offer.payloads, uint8(pt))
+				}
+			}
+		case 'a':
+			if !inAudio || !strings.HasPrefix(val, "rtpmap:") {
+				continue
+			}
+			ptStr, enc, ok := strings.Cut(strings.TrimPrefix(val, "rtpmap:"), " ")
+			if !ok {
+				continue
+			}
+			if pt, err := strconv.Atoi(ptStr); err == nil && pt >= 0 && pt < 128 {
+				offer.rtpmap[uint8(pt)] = strings.ToLower(enc)
+			}
+		}
+	}
+	if !seenAudio {
+		return offer, errNoAudio
+	}
+	if offer.host == "" {
+		offer.host = sessionHost
+	}
+	return offer, nil
+}
+
+// negotiate picks the first codec from prefs the offer supports, and the
+// offered telephone-event payload type (0 when absent).
+func (o sdpOffer) negotiate(prefs []Codec) (Codec, uint8, bool) {
+	var dtmf uint8
+	for _, pt := range o.payloads {
+		if strings.HasPrefix(o.rtpmap[pt], "telephone-event/8000") {
+			dtmf = pt
+			break
+		}
+	}
+	for _, c := range prefs {
+		for _, pt := range o.payloads {
+			if o.offers(pt, c) {
+				return c, dtmf, true
+			}
+		}
+	}
+	return Codec{}, 0, false
+}
+
+func (o sdpOffer) offers(pt uint8, c Codec) bool {
+	if enc, ok := o.rtpmap[pt]; ok {
+		return strings.HasPrefix(enc, strings.ToLower(c.Name)+"/8000")
+	}
+	// Static payload types may be offered without rtpmap.
+	return pt == c.PayloadType
+}
+
+// buildSDP returns a session description for our side of the call.
+func buildSDP(host string, port int, codec Codec, dtmfPT uint8, sessionID, version int64) []byte {
+	addrType := "IP4"
+	if strings.Contains(host, ":") {
+		addrType = "IP6"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "v=0\r\n")
+	fmt.Fprintf(&b, "o=ranya %d %d IN %s %s\r\n", sessionID, version, addrType, host)
+	fmt.Fprintf(&b, "s=ranya\r\n")
+	fmt.Fprintf(&b, "c=IN %s %s\r\n", addrType, host)
+	fmt.Fprintf(&b, "t=0 0\r\n")
+	if dtmfPT != 0 {
+		fmt.Fprintf(&b, "m=audio %d RTP/AVP %d %d\r\n", port, codec.PayloadType, dtmfPT)
+	} else {
+		fmt.Fprintf(&b, "m=audio %d RTP/AVP %d\r\n", port, codec.PayloadType)
+	}
+	fmt.Fprintf(&b, "a=rtpmap:%d %s/8000\r\n", codec.PayloadType, codec.Name)
+	if dtmfPT != 0 {
+		fmt.Fprintf(&b, "a=rtpmap:%d telephone-event/8000\r\n", dtmfPT)
+		fmt.Fprintf(&b, "a=fmtp:%d 0-15\r\n", dtmfPT)
+	}
+	fmt.Fprintf(&b, "a=ptime:%d\r\n", packetTime/time.Millisecond)
+	fmt.Fprintf(&b, "a=sendrecv\r\n")
+	return []byte(b.String())
+}
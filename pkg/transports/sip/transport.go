@@ -0,0 +1,729 @@
+// Package sip terminates SIP calls directly, without a telephony vendor.
+//
+// It acts as a minimal UAS behind an SBC or PBX: INVITEs are answered with
+// PCMU or PCMA, media flows as RTP on a per-call UDP port, and RFC 4733
+// telephone-events carry DTMF both ways. Each dialog maps to one pipeline
+// session: the Call-ID becomes call_sid and a fresh stream ID is assigned.
+// Registration, authentication and SRTP are left to the SBC in front.
+package sip
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/transports"
+)
+
+type Config struct {
+	ListenAddr string `mapstructure:"listen_addr"`
+	// Network is "udp", "tcp" or "both".
+	Network string `mapstructure:"network"`
+	// PublicHost is advertised in Contact and SDP. Defaults to the host the
+	// caller addressed in the Request-URI.
+	PublicHost string `mapstructure:"public_host"`
+	// RTPPortMin and RTPPortMax bound media ports; zero picks ephemeral ports.
+	RTPPortMin int `mapstructure:"rtp_port_min"`
+	RTPPortMax int `mapstructure:"rtp_port_max"`
+	// Codecs lists accepted codecs in preference order ("PCMU", "PCMA").
+	Codecs    []string `mapstructure:"codecs"`
+	UserAgent string   `mapstructure:"user_agent"`
+	// AllowedPeers restricts signaling to these IPs or CIDRs (typically the
+	// SBC). Empty allows any peer.
+	AllowedPeers []string `mapstructure:"allowed_peers"`
+}
+
+func (c Config) withDefaults() Config {
+	if c.ListenAddr == "" {
+		c.ListenAddr = ":5060"
+	}
+	c.Network = strings.ToLower(strings.TrimSpace(c.Network))
+	if c.Network == "" {
+		c.Network = "udp"
+	}
+	if len(c.Codecs) == 0 {
+		c.Codecs = []string{"PCMU", "PCMA"}
+	}
+	if c.UserAgent == "" {
+		c.UserAgent = "ranya"
+	}
+	return c
+}
+
+type Transport struct {
+	cfg     Config
+	codecs  []Codec
+	allowed []*net.IPNet
+	recvCh  chan frames.Frame
+
+	udp net.PacketConn
+	tcp net.Listener
+
+	mu        sync.Mutex
+	calls     map[string]*call // by Call-ID
+	streams   map[string]*call // by stream ID
+	tcpConns  map[net.Conn]struct{}
+	stopped   bool
+	draining  bool
+	nextPort  int
+	admission transports.AdmissionFunc
+
+	loops    sync.WaitGroup
+	stopOnce sync.Once
+}
+
+func New(cfg Config) *Transport {
+	cfg = cfg.withDefaults()
+	t := &Transport{
+		cfg:      cfg,
+		recvCh:   make(chan frames.Frame, 512),
+		calls:    make(map[string]*call),
+		streams:  make(map[string]*call),
+		tcpConns: make(map[net.Conn]struct{}),
+	}
+	for _, name := range cfg.Codecs {
+		if c, ok := codecByName(name); ok {
+			t.codecs = append(t.codecs, c)
+		} else {
+			slog.Warn("sip_unknown_codec", "codec", name)
+		}
+	}
+	for _, peer := range cfg.AllowedPeers {
+		peer = strings.TrimSpace(peer)
+		if !strings.Contains(peer, "/") {
+			if strings.Contains(peer, ":") {
+				peer += "/128"
+			} else {
+				peer += "/32"
+			}
+		}
+		if _, n, err := net.ParseCIDR(peer); err == nil {
+			t.allowed = append(t.allowed, n)
+		} else {
+			slog.Warn("sip_invalid_allowed_peer", "peer", peer)
+		}
+	}
+	return t
+}
+
+func (t *Transport) Name() string { return "sip" }
+
+func (t *Transport) Recv() <-chan frames.Frame { return t.recvCh }
+
+func (t *Transport) ReadyFields() map[string]any {
+	fields := map[string]any{"sip_network": t.cfg.Network}
+	if addr := t.UDPAddr(); addr != nil {
+		fields["sip_udp_addr"] = addr.String()
+	}
+	if addr := t.TCPAddr(); addr != nil {
+		fields["sip_tcp_addr"] = addr.String()
+	}
+	return fields
+}
+
+// UDPAddr returns the bound UDP signaling address, or nil.
+func (t *Transport) UDPAddr() net.Addr {
+	if t.udp == nil {
+		return nil
+	}
+	return t.udp.LocalAddr()
+}
+
+// TCPAddr returns the bound TCP signaling address, or nil.
+func (t *Transport) TCPAddr() net.Addr {
+	if t.tcp == nil {
+		return nil
+	}
+	return t.tcp.Addr()
+}
+
+// Start binds the signaling sockets synchronously so configuration errors
+// surface immediately.
+func (t *Transport) Start(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if len(t.codecs) == 0 {
+		return errors.New("sip: no supported codecs configured")
+	}
+	network := t.cfg.Network
+	if network != "udp" && network != "tcp" && network != "both" {
+		return fmt.Errorf("sip: unknown network %q", network)
+	}
+	if network == "udp" || network == "both" {
+		pc, err := net.ListenPacket("udp", t.cfg.ListenAddr)
+		if err != nil {
+			return fmt.Errorf("sip: listen udp: %w", err)
+		}
+		t.udp = pc
+		t.loops.Add(1)
+		go t.serveUDP()
+	}
+	if network == "tcp" || network == "both" {
+		ln, err := net.Listen("tcp", t.cfg.ListenAddr)
+		if err != nil {
+			t.closeListeners()
+			return fmt.Errorf("sip: listen tcp: %w", err)
+		}
+		t.tcp = ln
+		t.loops.Add(1)
+		go t.serveTCP()
+	}
+	go func() {
+		<-ctx.Done()
+		t.closeListeners()
+	}()
+	return nil
+}
+
+// Stop hangs up every active call, closes sockets and then closes Recv.
+func (t *Transport) Stop() error {
+	t.stopOnce.Do(func() {
+		t.mu.Lock()
+		t.stopped = true
+		calls := make([]*call, 0, len(t.calls))
+		for _, c := range t.calls {
+			calls = append(calls, c)
+		}
+		t.mu.Unlock()
+		for _, c := range calls {
+			c.sendBye()
+			t.endCall(c, "shutdown")
+		}
+		t.closeListeners()
+		done := make(chan struct{})
+		go func() {
+			t.loops.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			close(t.recvCh)
+		case <-time.After(2 * time.Second):
+			slog.Warn("sip_transport_stop_timeout")
+		}
+	})
+	return nil
+}
+
+// SetAdmission makes new INVITEs consult fn. Calls over capacity are
+// answered with 486 Busy Here, since nothing can be played before answer.
+func (t *Transport) SetAdmission(fn transports.AdmissionFunc) {
+	t.mu.Lock()
+	t.admission = fn
+	t.mu.Unlock()
+}
+
+// DrainCalls answers new INVITEs with 503 so the SBC tries another
+// instance. Established dialogs, including re-INVITEs, carry on.
+func (t *Transport) DrainCalls() {
+	t.mu.Lock()
+	t.draining = true
+	t.mu.Unlock()
+}
+
+func (t *Transport) closeListeners() {
+	if t.udp != nil {
+		_ = t.udp.Close()
+	}
+	if t.tcp != nil {
+		_ = t.tcp.Close()
+	}
+	t.mu.Lock()
+	for c := range t.tcpConns {
+		_ = c.Close()
+	}
+	t.mu.Unlock()
+}
+
+func (t *Transport) serveUDP() {
+	defer t.loops.Done()
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := t.udp.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if len(strings.TrimSpace(string(buf[:n]))) == 0 {
+			continue
+		}
+		msg, err := parseMessage(buf[:n])
+		if err != nil {
+			slog.Debug("sip_bad_message", "remote", addr.String(), "error", err.Error())
+			continue
+		}
+		t.handle(msg, &udpConn{pc: t.udp, addr: addr})
+	}
+}
+
+func (t *Transport) serveTCP() {
+	defer t.loops.Done()
+	for {
+		conn, err := t.tcp.Accept()
+		if err != nil {
+			return
+		}
+		t.mu.Lock()
+		if t.stopped {
+			t.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		t.tcpConns[conn] = struct{}{}
+		t.loops.Add(1)
+		t.mu.Unlock()
+		go t.serveTCPConn(conn)
+	}
+}
+
+func (t *Transport) serveTCPConn(conn net.Conn) {
+	defer t.loops.Done()
+	defer func() {
+		t.mu.Lock()
+		delete(t.tcpConns, conn)
+		t.mu.Unlock()
+		_ = conn.Close()
+	}()
+	sig := &tcpConn{conn: conn}
+	r := bufio.NewReader(conn)
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			return
+		}
+		t.handle(msg, sig)
+	}
+}
+
+func (t *Transport) handle(msg *message, sig sigConn) {
+	if !msg.request {
+		// Only responses to our own BYEs arrive here; nothing to do.
+		return
+	}
+	if !t.peerAllowed(sig.remote()) {
+		slog.Warn("sip_peer_rejected", "remote", sig.remote().String())
+		if msg.method != "ACK" {
+			t.respond(sig, msg, 403, "Forbidden", "")
+		}
+		return
+	}
+	switch msg.method {
+	case "INVITE":
+		t.handleInvite(msg, sig)
+	case "ACK":
+		if c := t.call(msg.get("Call-ID")); c != nil {
+			c.ack()
+		}
+	case "BYE":
+		c := t.call(msg.get("Call-ID"))
+		if c == nil {
+			t.respond(sig, msg, 481, "Call/Transaction Does Not Exist", "")
+			return
+		}
+		t.respond(sig, msg, 200, "OK", c.localTag)
+		t.endCall(c, "completed")
+	case "CANCEL":
+		// INVITEs are answered synchronously, so a CANCEL always arrives
+		// after the final response and has no effect on the dialog.
+		if c := t.call(msg.get("Call-ID")); c != nil {
+			t.respond(sig, msg, 200, "OK", c.localTag)
+			return
+		}
+		t.respond(sig, msg, 481, "Call/Transaction Does Not Exist", "")
+	case "OPTIONS":
+		t.respond(sig, msg, 200, "OK", "")
+	default:
+		t.respond(sig, msg, 405, "Method Not Allowed", "")
+	}
+}
+
+func (t *Transport) handleInvite(msg *message, sig sigConn) {
+	callID := msg.get("Call-ID")
+	if callID == "" {
+		t.respond(sig, msg, 400, "Bad Request", "")
+		return
+	}
+	if c := t.call(callID); c != nil {
+		t.reinvite(c, msg, sig)
+		return
+	}
+	t.mu.Lock()
+	refuse := t.stopped || t.draining
+	admit := t.admission
+	t.mu.Unlock()
+	if refuse {
+		t.respond(sig, msg, 503, "Service Unavailable", "")
+		return
+	}
+	if admit != nil {
+		if d := admit(transports.AdmissionRequest{CallSID: callID}); d.Action != transports.AdmissionAccept {
+			slog.Info("sip_call_overflow", "call_id", callID, "action", string(d.Action))
+			t.respond(sig, msg, 486, "Busy Here", "")
+			return
+		}
+	}
+	t.respond(sig, msg, 100, "Trying", "")
+
+	offer, err := parseSDP(msg.body)
+	if err != nil {
+		t.respond(sig, msg, 488, "Not Acceptable Here", "")
+		return
+	}
+	codec, dtmfPT, ok := offer.negotiate(t.codecs)
+	if !ok {
+		slog.Warn("sip_no_common_codec", "call_id", callID)
+		t.respond(sig, msg, 488, "Not Acceptable Here", "")
+		return
+	}
+	host := t.advertisedHost(msg)
+	rtpConn, err := t.listenRTP()
+	if err != nil {
+		slog.Error("sip_rtp_listen_failed", "error", err.Error())
+		t.respond(sig, msg, 503, "Service Unavailable", "")
+		return
+	}
+	c := newCall(t, callID, msg, sig, codec, dtmfPT, rtpConn)
+	c.setRemote(offer)
+	c.host = host
+
+	resp := t.newResponse(msg, 200, "OK", c.localTag)
+	resp.add("Contact", t.contact(host, sig))
+	resp.add("Content-Type", "application/sdp")
+	resp.body = buildSDP(host, rtpConn.LocalAddr().(*net.UDPAddr).Port, codec, dtmfPT, c.sdpSession, c.sdpVersion.Load())
+	c.answer = resp.bytes()
+
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		_ = rtpConn.Close()
+		t.respond(sig, msg, 503, "Service Unavailable", "")
+		return
+	}
+	t.calls[callID] = c
+	t.streams[c.streamID] = c
+	t.loops.Add(2)
+	if !sig.reliable() {
+		t.loops.Add(1)
+	}
+	t.mu.Unlock()
+
+	_ = sig.send(c.answer)
+	go c.readRTP()
+	go func() {
+		defer t.loops.Done()
+		c.pacer.run(c.done)
+	}()
+	if !sig.reliable() {
+		go c.retransmitAnswer()
+	}
+	slog.Info("sip_call_answered", "call_id", callID, "stream_id", c.streamID, "codec", codec.Name, "dtmf_pt", dtmfPT)
+	nonBlockingSend(t.recvCh, frames.NewSystemFrame(c.streamID, time.Now().UnixNano(), "call_start", c.frameMeta()))
+}
+
+// reinvite handles INVITE retransmissions and in-dialog re-INVITEs (hold,
+// media moves). The negotiated codec is kept.
+func (t *Transport) reinvite(c *call, msg *message, sig sigConn) {
+	seq, _ := msg.cseq()
+	if seq == c.inviteSeq {
+		_ = sig.send(c.answer)
+		return
+	}
+	if len(msg.body) > 0 {
+		offer, err := parseSDP(msg.body)
+		if err != nil {
+			t.respond(sig, msg, 488, "Not Acceptable Here", c.localTag)
+			return
+		}
+		if _, _, ok := offer.negotiate([]Codec{c.codec}); !ok {
+			t.respond(sig, msg, 488, "Not Acceptable Here", c.localTag)
+			return
+		}
+		c.setRemote(offer)
+	}
+	resp := t.newResponse(msg, 200, "OK", c.localTag)
+	resp.add("Contact", t.contact(c.host, sig))
+	resp.add("Content-Type", "application/sdp")
+	resp.body = buildSDP(c.host, c.rtp.LocalAddr().(*net.UDPAddr).Port, c.codec, c.dtmfPT, c.sdpSession, c.sdpVersion.Add(1))
+	_ = sig.send(resp.bytes())
+}
+
+func (t *Transport) endCall(c *call, reason string) {
+	if !c.end() {
+		return
+	}
+	t.mu.Lock()
+	delete(t.calls, c.id)
+	delete(t.streams, c.streamID)
+	t.mu.Unlock()
+	slog.Info("sip_call_ended", "call_id", c.id, "stream_id", c.streamID, "reason", reason)
+	meta := c.frameMeta()
+	meta[frames.MetaCallEndReason] = reason
+	if !transports.SendCallEnd(t.recvCh, frames.NewSystemFrame(c.streamID, time.Now().UnixNano(), "call_end", meta)) {
+		slog.Warn("sip_call_end_dropped", "call_id", c.id, "stream_id", c.streamID)
+	}
+}
+
+// Send plays pipeline audio on the call owning the frame's stream ID.
+// Audio is expected as 8kHz μ-law (the telephony default); A-law and 8kHz
+// PCM16 are converted to the negotiated codec.
+func (t *Transport) Send(f frames.Frame) error {
+	c := t.stream(frames.StreamIDOf(f))
+	if c == nil {
+		return nil
+	}
+	switch f.Kind() {
+	case frames.KindAudio:
+		af := f.(frames.AudioFrame)
+		payload, ok := encodeOutbound(af, c.codec)
+		if !ok {
+			slog.Debug("sip_unsupported_audio", "stream_id", c.streamID, "encoding", af.Metadata().Get(frames.MetaEncoding), "rate", af.Rate())
+			return nil
+		}
+		c.pacer.enqueueAudio(payload)
+	case frames.KindControl:
+		switch f.(frames.ControlFrame).Code() {
+		case frames.ControlFlush, frames.ControlCancel, frames.ControlStartInterruption:
+			c.pacer.clear()
+		}
+	}
+	return nil
+}
+
+// dtmfDuration is how long each outbound RFC 4733 digit lasts.
+const dtmfDuration = 100 * time.Millisecond
+
+// SendDTMF sends digits as RFC 4733 telephone-events on an active call.
+func (t *Transport) SendDTMF(ctx context.Context, callSID, digits string) error {
+	_ = ctx
+	if strings.TrimSpace(callSID) == "" {
+		return errors.New("call sid required")
+	}
+	if strings.TrimSpace(digits) == "" {
+		return errors.New("digits required")
+	}
+	c := t.call(callSID)
+	if c == nil {
+		return fmt.Errorf("sip: unknown call %q", callSID)
+	}
+	if c.dtmfPT == 0 {
+		return errors.New("sip: remote did not offer telephone-event")
+	}
+	codes := make([]uint8, 0, len(digits))
+	for _, d := range strings.ToUpper(digits) {
+		code, ok := dtmfCode(d)
+		if !ok {
+			return fmt.Errorf("sip: invalid dtmf digit %q", d)
+		}
+		codes = append(codes, code)
+	}
+	for _, code := range codes {
+		c.pacer.enqueueDTMF(c.dtmfPT, code, dtmfDuration)
+	}
+	return nil
+}
+
+// HangupCall sends BYE and tears the call down locally.
+func (t *Transport) HangupCall(ctx context.Context, callSID string) error {
+	_ = ctx
+	if strings.TrimSpace(callSID) == "" {
+		return errors.New("call sid required")
+	}
+	c := t.call(callSID)
+	if c == nil {
+		return fmt.Errorf("sip: unknown call %q", callSID)
+	}
+	c.sendBye()
+	t.endCall(c, "agent_hangup")
+	return nil
+}
+
+func (t *Transport) call(callID string) *call {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.calls[callID]
+}
+
+func (t *Transport) stream(streamID string) *call {
+	if streamID == "" {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.streams[streamID]
+}
+
+func (t *Transport) peerAllowed(addr net.Addr) bool {
+	if len(t.allowed) == 0 {
+		return true
+	}
+	ip := addrIP(addr)
+	for _, n := range t.allowed {
+		if ip != nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Transport) advertisedHost(req *message) string {
+	if t.cfg.PublicHost != "" {
+		return t.cfg.PublicHost
+	}
+	if host := uriHost(req.uri); host != "" {
+		return host
+	}
+	host, _, _ := net.SplitHostPort(t.cfg.ListenAddr)
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return host
+}
+
+func (t *Transport) contact(host string, sig sigConn) string {
+	var port int
+	if sig.reliable() {
+		port = t.TCPAddr().(*net.TCPAddr).Port
+		return "<sip:ranya@" + net.JoinHostPort(host, strconv.Itoa(port)) + ";transport=tcp>"
+	}
+	port = t.UDPAddr().(*net.UDPAddr).Port
+	return "<sip:ranya@" + net.JoinHostPort(host, strconv.Itoa(port)) + ">"
+}
+
+// listenRTP opens a media socket, honoring the configured port range.
+func (t *Transport) listenRTP() (*net.UDPConn, error) {
+	host, _, _ := net.SplitHostPort(t.cfg.ListenAddr)
+	ip := net.ParseIP(host)
+	min, max := t.cfg.RTPPortMin, t.cfg.RTPPortMax
+	if min <= 0 || max < min {
+		return net.ListenUDP("udp", &net.UDPAddr{IP: ip})
+	}
+	// RTP conventionally uses even ports.
+	min += min % 2
+	span := (max-min)/2 + 1
+	t.mu.Lock()
+	start := t.nextPort
+	t.nextPort = (t.nextPort + 1) % span
+	t.mu.Unlock()
+	for i := 0; i < span; i++ {
+		port := min + 2*((start+i)%span)
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: ip, Port: port})
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, fmt.Errorf("sip: no free rtp port in %d-%d", t.cfg.RTPPortMin, t.cfg.RTPPortMax)
+}
+
+// newResponse builds a response that echoes the request's dialog headers.
+func (t *Transport) newResponse(req *message, status int, reason, toTag string) *message {
+	resp := &message{status: status, reason: reason}
+	for _, via := range req.getAll("Via") {
+		resp.add("Via", via)
+	}
+	for _, rr := range req.getAll("Record-Route") {
+		if status >= 200 && status < 300 {
+			resp.add("Record-Route", rr)
+		}
+	}
+	resp.add("From", req.get("From"))
+	to := req.get("To")
+	if toTag != "" && headerParam(to, "tag") == "" {
+		to += ";tag=" + toTag
+	}
+	resp.add("To", to)
+	resp.add("Call-ID", req.get("Call-ID"))
+	resp.add("CSeq", req.get("CSeq"))
+	resp.add("Server", t.cfg.UserAgent)
+	if status == 405 || (req.method == "OPTIONS" && status == 200) {
+		resp.add("Allow", "INVITE, ACK, BYE, CANCEL, OPTIONS")
+	}
+	return resp
+}
+
+func (t *Transport) respond(sig sigConn, req *message, status int, reason, toTag string) {
+	if err := sig.send(t.newResponse(req, status, reason, toTag).bytes()); err != nil {
+		slog.Debug("sip_send_failed", "remote", sig.remote().String(), "error", err.Error())
+	}
+}
+
+func newTag() string {
+	return strings.ReplaceAll(uuid.NewString(), "-", "")[:16]
+}
+
+// sigConn is where a request came from and where its responses go.
+type sigConn interface {
+	send([]byte) error
+	remote() net.Addr
+	// reliable reports a stream transport, where retransmission is not
+	// needed.
+	reliable() bool
+	network() string
+}
+
+type udpConn struct {
+	pc   net.PacketConn
+	addr net.Addr
+}
+
+func (u *udpConn) send(b []byte) error {
+	_, err := u.pc.WriteTo(b, u.addr)
+	return err
+}
+func (u *udpConn) remote() net.Addr { return u.addr }
+func (u *udpConn) reliable() bool   { return false }
+func (u *udpConn) network() string  { return "UDP" }
+
+type tcpConn struct {
+	conn net.Conn
+	mu   sync.Mutex
+}
+
+func (c *tcpConn) send(b []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	_, err := c.conn.Write(b)
+	return err
+}
+func (c *tcpConn) remote() net.Addr { return c.conn.RemoteAddr() }
+func (c *tcpConn) reliable() bool   { return true }
+func (c *tcpConn) network() string  { return "TCP" }
+
+func addrIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP
+	case *net.TCPAddr:
+		return a.IP
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
+}
+
+func nonBlockingSend(ch chan frames.Frame, f frames.Frame) bool {
+	select {
+	case ch <- f:
+		return true
+	default:
+		return false
+	}
+}
+
+var _ transports.Transport = (*Transport)(nil)
+var _ transports.DTMFSender = (*Transport)(nil)
+var _ transports.CallHanger = (*Transport)(nil)
+var _ transports.ReadyReporter = (*Transport)(nil)
+var _ transports.CallDrainer = (*Transport)(nil)
+var _ transports.CallAdmitter = (*Transport)(nil)
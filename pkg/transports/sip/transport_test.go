@@ -0,0 +1,290 @@
+package sip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/audio"
+	"github.com/harunnryd/ranya/pkg/frames"
+)
+
+// ua is a minimal loopback SIP user agent driving the transport over UDP.
+type ua struct {
+	t      *testing.T
+	sig    *net.UDPConn
+	rtp    *net.UDPConn
+	server *net.UDPAddr
+	callID string
+	toTag  string
+}
+
+func newUA(t *testing.T, tr *Transport) *ua {
+	t.Helper()
+	sig, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listen sig: %v", err)
+	}
+	rtp, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listen rtp: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = sig.Close()
+		_ = rtp.Close()
+	})
+	return &ua{t: t, sig: sig, rtp: rtp, server: tr.UDPAddr().(*net.UDPAddr), callID: fmt.Sprintf("call-%d@test", time.Now().UnixNano())}
+}
+
+func (u *ua) offer(payloads string, rtpmap ...string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "v=0\r\no=- 1 1 IN IP4 127.0.0.1\r\ns=-\r\nc=IN IP4 127.0.0.1\r\nt=0 0\r\n")
+	fmt.Fprintf(&b, "m=audio %d RTP/AVP %s\r\n", u.rtp.LocalAddr().(*net.UDPAddr).Port, payloads)
+	for _, m := range rtpmap {
+		fmt.Fprintf(&b, "a=rtpmap:%s\r\n", m)
+	}
+	return []byte(b.String())
+}
+
+func (u *ua) send(method string, seq int, body []byte) {
+	u.t.Helper()
+	req := newRequest(method, "sip:agent@127.0.0.1")
+	req.add("Via", "SIP/2.0/UDP "+u.sig.LocalAddr().String()+";branch=z9hG4bK"+newTag())
+	req.add("From", "<sip:+15550100@127.0.0.1>;tag=caller")
+	to := "<sip:agent@127.0.0.1>"
+	if u.toTag != "" {
+		to += ";tag=" + u.toTag
+	}
+	req.add("To", to)
+	req.add("Call-ID", u.callID)
+	req.add("CSeq", fmt.Sprintf("%d %s", seq, method))
+	req.add("Contact", "<sip:caller@"+u.sig.LocalAddr().String()+">")
+	if body != nil {
+		req.add("Content-Type", "application/sdp")
+		req.body = body
+	}
+	if _, err := u.sig.WriteToUDP(req.bytes(), u.server); err != nil {
+		u.t.Fatalf("write %s: %v", method, err)
+	}
+}
+
+func (u *ua) read() *message {
+	u.t.Helper()
+	buf := make([]byte, 65535)
+	_ = u.sig.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := u.sig.ReadFromUDP(buf)
+	if err != nil {
+		u.t.Fatalf("read sip: %v", err)
+	}
+	msg, err := parseMessage(buf[:n])
+	if err != nil {
+		u.t.Fatalf("parse sip: %v", err)
+	}
+	return msg
+}
+
+// readFinal skips provisional responses.
+func (u *ua) readFinal() *message {
+	u.t.Helper()
+	for {
+		msg := u.read()
+		if msg.request || msg.status >= 200 {
+			return msg
+		}
+	}
+}
+
+func (u *ua) readRTP() (rtpHeader, []byte) {
+	u.t.Helper()
+	buf := make([]byte, 1500)
+	_ = u.rtp.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := u.rtp.Read(buf)
+	if err != nil {
+		u.t.Fatalf("read rtp: %v", err)
+	}
+	h, payload, err := parseRTP(buf[:n])
+	if err != nil {
+		u.t.Fatalf("parse rtp: %v", err)
+	}
+	return h, append([]byte(nil), payload...)
+}
+
+func startTransport(t *testing.T, cfg Config) *Transport {
+	t.Helper()
+	cfg.ListenAddr = "127.0.0.1:0"
+	tr := New(cfg)
+	if err := tr.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	t.Cleanup(func() { _ = tr.Stop() })
+	return tr
+}
+
+func recvFrame(t *testing.T, tr *Transport) frames.Frame {
+	t.Helper()
+	select {
+	case f := <-tr.Recv():
+		return f
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for frame")
+		return nil
+	}
+}
+
+func TestLoopbackCall(t *testing.T) {
+	tr := startTransport(t, Config{})
+	u := newUA(t, tr)
+
+	u.send("INVITE", 1, u.offer("0 101", "0 PCMU/8000", "101 telephone-event/8000"))
+	ok := u.readFinal()
+	if ok.status != 200 {
+		t.Fatalf("expected 200, got %d %s", ok.status, ok.reason)
+	}
+	u.toTag = headerParam(ok.get("To"), "tag")
+	answer, err := parseSDP(ok.body)
+	if err != nil || u.toTag == "" {
+		t.Fatalf("bad answer tag=%q err=%v", u.toTag, err)
+	}
+	if codec, dtmf, _ := answer.negotiate([]Codec{CodecPCMU, CodecPCMA}); codec != CodecPCMU || dtmf != 101 {
+		t.Fatalf("unexpected answer codec=%v dtmf=%d", codec, dtmf)
+	}
+	u.send("ACK", 1, nil)
+
+	start := recvFrame(t, tr).(frames.SystemFrame)
+	md := start.Metadata()
+	if start.Name() != "call_start" || md.CallSID() != u.callID || md.StreamID() == "" || md.Get(frames.MetaFromNumber) != "+15550100" {
+		t.Fatalf("unexpected call_start %v", start.Meta())
+	}
+	streamID := md.StreamID()
+
+	server := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: answer.port}
+	audio := make([]byte, 160)
+	for i := range audio {
+		audio[i] = 0x7F
+	}
+	_, _ = u.rtp.WriteToUDP(rtpHeader{pt: 0, seq: 1, timestamp: 160, ssrc: 9}.marshal(audio), server)
+	af := recvFrame(t, tr).(frames.AudioFrame)
+	if af.Rate() != 8000 || len(af.RawPayload()) != 160 || af.Metadata().Get(frames.MetaEncoding) != "mulaw" {
+		t.Fatalf("unexpected audio frame rate=%d meta=%v", af.Rate(), af.Meta())
+	}
+	frames.ReleaseAudioFrame(af)
+
+	// The end packet is repeated three times; only one digit is emitted.
+	for i := 0; i < 3; i++ {
+		ev := dtmfEvent{event: 5, end: true, volume: 10, duration: 800}.marshal()
+		_, _ = u.rtp.WriteToUDP(rtpHeader{pt: 101, seq: uint16(2 + i), timestamp: 320, ssrc: 9}.marshal(ev), server)
+	}
+	if cf := recvFrame(t, tr).(frames.ControlFrame); cf.Code() != frames.ControlDTMF || cf.Metadata().Get(frames.MetaDTMFDigit) != "5" {
+		t.Fatalf("unexpected dtmf frame %v", cf.Meta())
+	}
+
+	out := map[string]string{frames.MetaStreamID: streamID, frames.MetaEncoding: "mulaw"}
+	_ = tr.Send(frames.NewAudioFrameFromPool(streamID, 1, make([]byte, 320), 8000, 1, out))
+	h, payload := u.readRTP()
+	if h.pt != 0 || !h.marker || len(payload) != 160 {
+		t.Fatalf("unexpected outbound rtp pt=%d marker=%v len=%d", h.pt, h.marker, len(payload))
+	}
+	h2, _ := u.readRTP()
+	if h2.seq != h.seq+1 || h2.timestamp != h.timestamp+160 {
+		t.Fatalf("unexpected rtp sequence %+v after %+v", h2, h)
+	}
+
+	if err := tr.SendDTMF(context.Background(), u.callID, "9"); err != nil {
+		t.Fatalf("send dtmf: %v", err)
+	}
+	for {
+		h, payload := u.readRTP()
+		if h.pt != 101 {
+			continue
+		}
+		ev, _ := parseDTMFEvent(payload)
+		if ev.event != 9 {
+			t.Fatalf("unexpected dtmf event %d", ev.event)
+		}
+		if ev.end {
+			break
+		}
+	}
+
+	u.send("BYE", 2, nil)
+	if resp := u.readFinal(); resp.status != 200 {
+		t.Fatalf("expected 200 to BYE, got %d", resp.status)
+	}
+	end := recvFrame(t, tr).(frames.SystemFrame)
+	if end.Name() != "call_end" || end.Metadata().Get(frames.MetaCallEndReason) != "completed" {
+		t.Fatalf("unexpected call_end %v", end.Meta())
+	}
+}
+
+func TestInviteCodecNegotiation(t *testing.T) {
+	tr := startTransport(t, Config{})
+
+	u := newUA(t, tr)
+	u.send("INVITE", 1, u.offer("8", "8 PCMA/8000"))
+	ok := u.readFinal()
+	answer, _ := parseSDP(ok.body)
+	if codec, dtmf, _ := answer.negotiate([]Codec{CodecPCMU, CodecPCMA}); ok.status != 200 || codec != CodecPCMA || dtmf != 0 {
+		t.Fatalf("expected PCMA answer, got %d codec=%v", ok.status, codec)
+	}
+
+	u2 := newUA(t, tr)
+	u2.send("INVITE", 1, u2.offer("18", "18 G729/8000"))
+	if resp := u2.readFinal(); resp.status != 488 {
+		t.Fatalf("expected 488 for G.729 only offer, got %d", resp.status)
+	}
+}
+
+func TestReinviteKeepsSDPSession(t *testing.T) {
+	tr := startTransport(t, Config{})
+	u := newUA(t, tr)
+	origin := func(msg *message) (string, int64) {
+		t.Helper()
+		for _, line := range strings.Split(string(msg.body), "\r\n") {
+			var id string
+			var version int64
+			if _, err := fmt.Sscanf(line, "o=ranya %s %d", &id, &version); err == nil {
+				return id, version
+			}
+		}
+		t.Fatalf("no origin line in %q", msg.body)
+		return "", 0
+	}
+
+	u.send("INVITE", 1, u.offer("0", "0 PCMU/8000"))
+	ok := u.readFinal()
+	u.toTag = headerParam(ok.get("To"), "tag")
+	u.send("ACK", 1, nil)
+	recvFrame(t, tr)
+	id, version := origin(ok)
+
+	for i := int64(1); i <= 2; i++ {
+		u.send("INVITE", 1+int(i), u.offer("0", "0 PCMU/8000"))
+		re := u.readFinal()
+		if re.status != 200 {
+			t.Fatalf("expected 200 to re-INVITE, got %d", re.status)
+		}
+		if gotID, gotVersion := origin(re); gotID != id || gotVersion != version+i {
+			t.Fatalf("re-INVITE %d answered with o=%s %d, want %s %d", i, gotID, gotVersion, id, version+i)
+		}
+	}
+}
+
+func TestAllowedPeersRejectsOthers(t *testing.T) {
+	tr := startTransport(t, Config{AllowedPeers: []string{"10.0.0.0/8"}})
+	u := newUA(t, tr)
+	u.send("INVITE", 1, u.offer("0", "0 PCMU/8000"))
+	if resp := u.readFinal(); resp.status != 403 {
+		t.Fatalf("expected 403, got %d", resp.status)
+	}
+}
+
+func TestG711Transcoding(t *testing.T) {
+	silence := []byte{CodecPCMU.Silence}
+	audio.ULawToALaw(silence)
+	if silence[0] != CodecPCMA.Silence {
+		t.Fatalf("ulaw silence should map to alaw silence, got %#x", silence[0])
+	}
+}
@@ -0,0 +1,139 @@
+package telnyx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/transports"
+)
+
+// apiClient is a thin Call Control REST client.
+type apiClient struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+func newAPIClient(cfg Config) *apiClient {
+	return &apiClient{
+		baseURL: cfg.APIBaseURL,
+		apiKey:  cfg.APIKey,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// action runs a call command such as answer, send_dtmf or hangup.
+func (c *apiClient) action(ctx context.Context, callControlID, name string, body map[string]any) error {
+	path := "/calls/" + url.PathEscape(callControlID) + "/actions/" + name
+	return c.post(ctx, path, body, nil)
+}
+
+func (c *apiClient) post(ctx context.Context, path string, body any, out any) error {
+	if c.apiKey == "" {
+		return errors.New("missing telnyx api key")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("telnyx api %s: status %d: %s", path, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+// Dialer provides outbound call creation via Telnyx Call Control.
+type Dialer struct {
+	cfg Config
+	api *apiClient
+}
+
+// NewDialer creates a new Telnyx dialer.
+func NewDialer(cfg Config) *Dialer {
+	cfg = cfg.withDefaults()
+	return &Dialer{cfg: cfg, api: newAPIClient(cfg)}
+}
+
+// Dial places an outbound call using Telnyx.
+func (d *Dialer) Dial(ctx context.Context, to, from, url string) (string, error) {
+	return d.DialWithOptions(ctx, to, from, url, transports.DialOptions{})
+}
+
+// DialWithOptions places an outbound call. url overrides the media stream
+// URL; by default media streams to the transport's WebSocket path. The
+// Dialer ignores SendDigits; Transport.DialWithOptions plays them once the
+// call is answered.
+func (d *Dialer) DialWithOptions(ctx context.Context, to, from, url string, opts transports.DialOptions) (string, error) {
+	_ = opts
+	if to == "" || from == "" {
+		return "", errors.New("to/from required")
+	}
+	if d.cfg.ConnectionID == "" {
+		return "", errors.New("missing telnyx connection id")
+	}
+	if url == "" {
+		url = d.streamURL()
+	}
+	body := map[string]any{
+		"connection_id":              d.cfg.ConnectionID,
+		"to":                         to,
+		"from":                       from,
+		"stream_url":                 url,
+		"stream_track":               "inbound_track",
+		"stream_bidirectional_mode":  "rtp",
+		"stream_bidirectional_codec": "PCMU",
+	}
+	if d.cfg.PublicURL != "" {
+		body["webhook_url"] = "https://" + normalizePublicURL(d.cfg.PublicURL) + d.cfg.WebhookPath
+	}
+	var resp struct {
+		Data struct {
+			CallControlID string `json:"call_control_id"`
+		} `json:"data"`
+	}
+	if err := d.api.post(ctx, "/calls", body, &resp); err != nil {
+		return "", err
+	}
+	if resp.Data.CallControlID == "" {
+		return "", errors.New("missing call control id")
+	}
+	return resp.Data.CallControlID, nil
+}
+
+func (d *Dialer) streamURL() string {
+	if d.cfg.PublicURL != "" {
+		return "wss://" + normalizePublicURL(d.cfg.PublicURL) + d.cfg.WebsocketPath
+	}
+	addr := d.cfg.ServerAddr
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+	return "ws://" + addr + d.cfg.WebsocketPath
+}
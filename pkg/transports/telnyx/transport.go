@@ -0,0 +1,977 @@
+package telnyx
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/harunnryd/ranya/pkg/errorsx"
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/transports"
+)
+
+type Config struct {
+	ServerAddr string `mapstructure:"server_addr"`
+	PublicURL  string `mapstructure:"public_url"`
+	// APIKey authenticates Call Control requests (answer, dial, DTMF).
+	APIKey string `mapstructure:"api_key"`
+	// PublicKey is the base64 ed25519 key from the Telnyx portal used to
+	// verify webhook signatures. Without it every webhook is rejected unless
+	// InsecureSkipVerify is set.
+	PublicKey string `mapstructure:"public_key"`
+	// InsecureSkipVerify accepts unsigned webhooks when PublicKey is empty.
+	// Use it for local testing only.
+	InsecureSkipVerify bool `mapstructure:"insecure_skip_verify"`
+	// ConnectionID is the Call Control application used for outbound calls.
+	ConnectionID string `mapstructure:"connection_id"`
+	// VoicePath answers TeXML applications.
+	VoicePath string `mapstructure:"voice_path"`
+	// WebhookPath receives Call Control events.
+	WebhookPath    string   `mapstructure:"webhook_path"`
+	WebsocketPath  string   `mapstructure:"ws_path"`
+	APIBaseURL     string   `mapstructure:"api_base_url"`
+	AllowAnyOrigin bool     `mapstructure:"allow_any_origin"`
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+}
+
+func (c Config) withDefaults() Config {
+	if c.ServerAddr == "" {
+		c.ServerAddr = ":8080"
+	}
+	if c.VoicePath == "" {
+		c.VoicePath = "/voice"
+	}
+	if c.WebhookPath == "" {
+		c.WebhookPath = "/webhook"
+	}
+	if c.WebsocketPath == "" {
+		c.WebsocketPath = "/ws"
+	}
+	if c.APIBaseURL == "" {
+		c.APIBaseURL = "https://api.telnyx.com/v2"
+	}
+	c.APIBaseURL = strings.TrimRight(c.APIBaseURL, "/")
+	return c
+}
+
+// signatureTolerance bounds webhook replay: Telnyx signs the timestamp
+// together with the body.
+const signatureTolerance = 5 * time.Minute
+
+type Transport struct {
+	cfg       Config
+	publicKey ed25519.PublicKey
+	server    *http.Server
+	upgrader  websocket.Upgrader
+	recvCh    chan frames.Frame
+	api       *apiClient
+
+	mu            sync.Mutex
+	sessions      map[string]*session
+	callSIDs      map[string]string
+	callStreams   map[string]string
+	traceIDs      map[string]string
+	fromNumbers   map[string]string
+	pendingDigits map[string]string
+	// dialed holds call control IDs of calls placed by DialWithOptions.
+	dialed    map[string]bool
+	admission transports.AdmissionFunc
+	handlers  sync.WaitGroup
+
+	draining    atomic.Bool
+	rejectCalls atomic.Bool
+	stopOnce    sync.Once
+}
+
+func New(cfg Config) *Transport {
+	cfg = cfg.withDefaults()
+	t := &Transport{
+		cfg: cfg,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  4096,
+			WriteBufferSize: 4096,
+		},
+		recvCh:        make(chan frames.Frame, 512),
+		api:           newAPIClient(cfg),
+		sessions:      make(map[string]*session),
+		callSIDs:      make(map[string]string),
+		callStreams:   make(map[string]string),
+		traceIDs:      make(map[string]string),
+		fromNumbers:   make(map[string]string),
+		pendingDigits: make(map[string]string),
+		dialed:        make(map[string]bool),
+	}
+	t.upgrader.CheckOrigin = t.checkOrigin
+	if cfg.PublicKey != "" {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(cfg.PublicKey))
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			// Keep an unusable key so every webhook is rejected rather than
+			// silently accepted.
+			slog.Error("telnyx_invalid_public_key")
+			key = make([]byte, ed25519.PublicKeySize)
+		}
+		t.publicKey = key
+	}
+	return t
+}
+
+func (t *Transport) Name() string { return "telnyx" }
+
+func (t *Transport) Recv() <-chan frames.Frame { return t.recvCh }
+
+func (t *Transport) ReadyFields() map[string]any {
+	return map[string]any{
+		"texml_url":   t.publicHTTPURL(t.cfg.VoicePath),
+		"webhook_url": t.publicHTTPURL(t.cfg.WebhookPath),
+	}
+}
+
+func (t *Transport) Start(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if t.publicKey == nil {
+		if t.cfg.InsecureSkipVerify {
+			slog.Warn("telnyx_webhook_verification_disabled", "detail", "unsigned webhooks are accepted; set public_key in production")
+		} else {
+			slog.Error("telnyx_public_key_missing", "detail", "all webhooks are rejected until public_key is set")
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(t.cfg.VoicePath, t.handleVoice)
+	mux.HandleFunc(t.cfg.WebhookPath, t.handleWebhook)
+	mux.Handle(t.cfg.WebsocketPath, t)
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/ready", t.handleReady)
+	t.server = &http.Server{
+		Addr:              t.cfg.ServerAddr,
+		ReadHeaderTimeout: 5 * time.Second,
+		Handler:           mux,
+	}
+	go func() {
+		<-ctx.Done()
+		_ = t.server.Close()
+	}()
+	go func() {
+		if err := t.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("telnyx_transport_server_error", "error", err.Error())
+		}
+	}()
+	return nil
+}
+
+func (t *Transport) Stop() error {
+	t.stopOnce.Do(func() {
+		t.mu.Lock()
+		t.draining.Store(true)
+		sessions := t.sessions
+		t.sessions = make(map[string]*session)
+		t.mu.Unlock()
+		if t.server != nil {
+			_ = t.server.Close()
+		}
+		for _, sess := range sessions {
+			_ = sess.close()
+		}
+		done := make(chan struct{})
+		go func() {
+			t.handlers.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			close(t.recvCh)
+		case <-time.After(2 * time.Second):
+			slog.Warn("telnyx_transport_stop_timeout")
+		}
+	})
+	return nil
+}
+
+// DrainCalls stops answering new calls: the TeXML endpoint answers 503 and
+// Call Control calls are rejected as busy. Calls in progress carry on.
+func (t *Transport) DrainCalls() {
+	t.rejectCalls.Store(true)
+}
+
+func (t *Transport) handleReady(w http.ResponseWriter, r *http.Request) {
+	if t.rejectCalls.Load() || t.draining.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// ServeHTTP runs one Telnyx media stream.
+func (t *Transport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t.mu.Lock()
+	if t.draining.Load() {
+		t.mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	t.handlers.Add(1)
+	t.mu.Unlock()
+	defer t.handlers.Done()
+
+	conn, err := t.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var streamID string
+	encoding := "mulaw"
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		var evt StreamEvent
+		if err := json.Unmarshal(msg, &evt); err != nil {
+			continue
+		}
+		switch evt.Event {
+		case "start":
+			if evt.Start == nil {
+				continue
+			}
+			streamID = evt.StreamID
+			callSID := evt.Start.CallControlID
+			if evt.Start.MediaFormat != nil {
+				encoding = mediaEncoding(evt.Start.MediaFormat.Encoding)
+			}
+			traceID := uuid.NewString()
+			oldStream, oldSess := t.attach(streamID, callSID, traceID, evt.Start.From, conn)
+			if oldSess != nil {
+				_ = oldSess.close()
+			}
+			meta := map[string]string{
+				frames.MetaStreamID:      streamID,
+				frames.MetaCallSID:       callSID,
+				frames.MetaTraceID:       traceID,
+				frames.MetaFromNumber:    evt.Start.From,
+				frames.MetaCallDirection: t.direction(callSID),
+				frames.MetaSource:        "transport",
+			}
+			nonBlockingSend(t.recvCh, frames.NewSystemFrame(streamID, time.Now().UnixNano(), "call_start", meta))
+			if oldStream != "" {
+				reconnectMeta := map[string]string{
+					frames.MetaStreamID:    streamID,
+					frames.MetaCallSID:     callSID,
+					frames.MetaTraceID:     traceID,
+					frames.MetaOldStreamID: oldStream,
+					frames.MetaSource:      "transport",
+				}
+				nonBlockingSend(t.recvCh, frames.NewSystemFrame(streamID, time.Now().UnixNano(), "call_reconnect", reconnectMeta))
+			}
+		case "media":
+			// With both tracks streamed, only the caller's audio is input.
+			if evt.Media == nil || streamID == "" || (evt.Media.Track != "" && evt.Media.Track != "inbound") {
+				continue
+			}
+			payload, err := frames.DecodeAudioBase64(evt.Media.Payload)
+			if err != nil {
+				continue
+			}
+			meta := t.metaForStream(streamID)
+			setAudioFormat(meta, encoding)
+			af := frames.NewAudioFrameFromBuf(streamID, time.Now().UnixNano(), payload, sampleRate(encoding), 1, meta)
+			if !nonBlockingSend(t.recvCh, af) {
+				frames.ReleaseAudioFrame(af)
+			}
+		case "dtmf":
+			if evt.DTMF == nil || streamID == "" {
+				continue
+			}
+			meta := t.metaForStream(streamID)
+			meta[frames.MetaDTMFDigit] = evt.DTMF.Digit
+			nonBlockingSend(t.recvCh, frames.NewControlFrame(streamID, time.Now().UnixNano(), frames.ControlDTMF, meta))
+		case "mark":
+			if evt.Mark == nil || streamID == "" {
+				continue
+			}
+			meta := t.metaForStream(streamID)
+			meta[frames.MetaMarkName] = evt.Mark.Name
+			nonBlockingSend(t.recvCh, frames.NewControlFrame(streamID, time.Now().UnixNano(), frames.ControlAudioReady, meta))
+		case "error":
+			if evt.Payload != nil {
+				slog.Warn("telnyx_stream_error", "stream_id", streamID, "code", evt.Payload.Code, "detail", evt.Payload.Detail)
+			}
+		case "stop":
+			if streamID == "" {
+				return
+			}
+			t.endStream(streamID, "completed")
+			return
+		}
+	}
+	if streamID != "" {
+		t.endStream(streamID, normalizeCallEndReason("transport_closed"))
+	}
+}
+
+// endStream emits call_end once; the hangup webhook and the stream closing
+// race, and whichever arrives first wins.
+func (t *Transport) endStream(streamID, reason string) {
+	meta := t.metaForStream(streamID)
+	if !t.detach(streamID) {
+		return
+	}
+	meta[frames.MetaCallEndReason] = reason
+	if !transports.SendCallEnd(t.recvCh, frames.NewSystemFrame(streamID, time.Now().UnixNano(), "call_end", meta)) {
+		slog.Warn("telnyx_call_end_dropped", "call_sid", meta[frames.MetaCallSID], "stream_id", streamID)
+	}
+}
+
+func (t *Transport) Send(f frames.Frame) error {
+	if f.Kind() == frames.KindControl {
+		cf := f.(frames.ControlFrame)
+		streamID := cf.Metadata().StreamID()
+		switch cf.Code() {
+		case frames.ControlFallback:
+			return t.sendFallback(streamID)
+		case frames.ControlFlush, frames.ControlCancel, frames.ControlStartInterruption:
+			return t.clearBuffer(streamID)
+		default:
+			return nil
+		}
+	}
+	if f.Kind() != frames.KindAudio {
+		return nil
+	}
+	af := f.(frames.AudioFrame)
+	sess := t.session(af.Metadata().StreamID())
+	if sess == nil {
+		return nil
+	}
+	return sess.enqueue(mediaMessage(af.RawPayload()))
+}
+
+// Dial places an outbound call through Call Control, streaming media back to
+// this transport once answered.
+func (t *Transport) Dial(ctx context.Context, to, from, url string) (string, error) {
+	return t.DialWithOptions(ctx, to, from, url, transports.DialOptions{})
+}
+
+// DialWithOptions places an outbound call. Telnyx has no dial-time digits,
+// so SendDigits are played with send_dtmf when call.answered arrives.
+func (t *Transport) DialWithOptions(ctx context.Context, to, from, url string, opts transports.DialOptions) (string, error) {
+	dialer := NewDialer(t.cfg)
+	dialer.api = t.api
+	callSID, err := dialer.DialWithOptions(ctx, to, from, url, opts)
+	if err != nil {
+		return "", err
+	}
+	t.mu.Lock()
+	t.dialed[callSID] = true
+	if digits := strings.TrimSpace(opts.SendDigits); digits != "" {
+		t.pendingDigits[callSID] = digits
+	}
+	t.mu.Unlock()
+	return callSID, nil
+}
+
+// SendDTMF sends DTMF digits on an active call through Call Control.
+func (t *Transport) SendDTMF(ctx context.Context, callSID, digits string) error {
+	if strings.TrimSpace(callSID) == "" {
+		return errors.New("call sid required")
+	}
+	if strings.TrimSpace(digits) == "" {
+		return errors.New("digits required")
+	}
+	return t.api.action(ctx, callSID, "send_dtmf", map[string]any{"digits": digits})
+}
+
+// HangupCall ends an active call through the Call Control API.
+func (t *Transport) HangupCall(ctx context.Context, callSID string) error {
+	if strings.TrimSpace(callSID) == "" {
+		return errors.New("call sid required")
+	}
+	return t.api.action(ctx, callSID, "hangup", map[string]any{})
+}
+
+// handleVoice answers TeXML applications by connecting a bidirectional
+// media stream.
+func (t *Transport) handleVoice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if t.rejectCalls.Load() {
+		slog.Info("telnyx_call_rejected", "reason", "draining")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if _, ok := t.verifiedBody(r); !ok {
+		slog.Warn("telnyx_invalid_signature", "reason_code", string(errorsx.ReasonTransportInvalidSignature))
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if !t.admitTeXML(w, r) {
+		return
+	}
+	texml := `<?xml version="1.0" encoding="UTF-8"?><Response><Connect><Stream url="` + xmlEscape(t.websocketURL(r)) + `" bidirectionalMode="rtp" bidirectionalCodec="PCMU"/></Connect></Response>`
+	w.Header().Set("Content-Type", "text/xml")
+	_, _ = w.Write([]byte(texml))
+}
+
+// SetAdmission makes new calls consult fn before they are answered. TeXML
+// calls can be held in a queue; Call Control calls over capacity are
+// rejected as busy.
+func (t *Transport) SetAdmission(fn transports.AdmissionFunc) {
+	t.mu.Lock()
+	t.admission = fn
+	t.mu.Unlock()
+}
+
+func (t *Transport) admissionFunc() transports.AdmissionFunc {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.admission
+}
+
+// admitTeXML applies the admission decision to a TeXML request. When the call
+// may not connect yet it writes the overflow TeXML and returns false.
+func (t *Transport) admitTeXML(w http.ResponseWriter, r *http.Request) bool {
+	fn := t.admissionFunc()
+	if fn == nil {
+		return true
+	}
+	_ = r.ParseForm()
+	callSID := r.PostForm.Get("CallSid")
+	decision := fn(transports.AdmissionRequest{
+		CallSID:  callSID,
+		Outbound: strings.HasPrefix(strings.ToLower(r.PostForm.Get("Direction")), "outbound"),
+		CanHold:  true,
+	})
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><Response>`)
+	say := func(text string) {
+		if text = strings.TrimSpace(text); text != "" {
+			b.WriteString(`<Say>` + xmlEscape(text) + `</Say>`)
+		}
+	}
+	switch decision.Action {
+	case transports.AdmissionReject:
+		b.WriteString(`<Reject reason="busy"/>`)
+	case transports.AdmissionMessage:
+		say(decision.Message)
+		b.WriteString(`<Hangup/>`)
+	case transports.AdmissionQueue:
+		say(decision.Message)
+		if decision.HoldAudioURL != "" {
+			b.WriteString(`<Play>` + xmlEscape(decision.HoldAudioURL) + `</Play>`)
+		} else {
+			b.WriteString(`<Pause length="` + strconv.Itoa(max(1, int(decision.RetryAfter.Round(time.Second)/time.Second))) + `"/>`)
+		}
+		redirect := t.cfg.VoicePath
+		if r.URL.RawQuery != "" {
+			redirect += "?" + r.URL.RawQuery
+		}
+		b.WriteString(`<Redirect method="POST">` + xmlEscape(redirect) + `</Redirect>`)
+	default:
+		return true
+	}
+	b.WriteString(`</Response>`)
+	slog.Info("telnyx_call_overflow", "call_sid", callSID, "action", string(decision.Action))
+	w.Header().Set("Content-Type", "text/xml")
+	_, _ = w.Write([]byte(b.String()))
+	return false
+}
+
+// handleWebhook processes Call Control events: inbound calls are answered
+// with streaming enabled, and hangups are mapped to call_end.
+func (t *Transport) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	body, ok := t.verifiedBody(r)
+	if !ok {
+		slog.Warn("telnyx_webhook_invalid_signature", "reason_code", string(errorsx.ReasonTransportInvalidSignature))
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	var evt WebhookEvent
+	if err := json.Unmarshal(body, &evt); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	p := evt.Data.Payload
+	switch evt.Data.EventType {
+	case "call.initiated":
+		if p.Direction != "incoming" {
+			break
+		}
+		reason := ""
+		if t.rejectCalls.Load() {
+			reason = "draining"
+		} else if fn := t.admissionFunc(); fn != nil {
+			if d := fn(transports.AdmissionRequest{CallSID: p.CallControlID}); d.Action != transports.AdmissionAccept {
+				reason = "capacity"
+			}
+		}
+		if reason != "" {
+			slog.Info("telnyx_call_rejected", "call_sid", p.CallControlID, "reason", reason)
+			if err := t.api.action(r.Context(), p.CallControlID, "reject", map[string]any{"cause": "USER_BUSY"}); err != nil {
+				slog.Warn("telnyx_reject_failed", "call_sid", p.CallControlID, "error", err.Error())
+			}
+			break
+		}
+		err := t.api.action(r.Context(), p.CallControlID, "answer", map[string]any{
+			"stream_url":                 t.websocketURL(r),
+			"stream_track":               "inbound_track",
+			"stream_bidirectional_mode":  "rtp",
+			"stream_bidirectional_codec": "PCMU",
+		})
+		if err != nil {
+			slog.Error("telnyx_answer_failed", "call_sid", p.CallControlID, "error", err.Error())
+		}
+	case "call.answered":
+		t.mu.Lock()
+		digits := t.pendingDigits[p.CallControlID]
+		delete(t.pendingDigits, p.CallControlID)
+		t.mu.Unlock()
+		if digits != "" {
+			if err := t.SendDTMF(r.Context(), p.CallControlID, digits); err != nil {
+				slog.Warn("telnyx_send_digits_failed", "call_sid", p.CallControlID, "error", err.Error())
+			}
+		}
+	case "call.hangup":
+		t.mu.Lock()
+		delete(t.pendingDigits, p.CallControlID)
+		delete(t.dialed, p.CallControlID)
+		t.mu.Unlock()
+		reason := normalizeCallEndReason(p.HangupCause)
+		if reason == "" {
+			reason = "completed"
+		}
+		if streamID := t.streamForCall(p.CallControlID); streamID != "" {
+			t.endStream(streamID, reason)
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// verifiedBody reads the request body and checks the ed25519 signature over
+// "timestamp|body". It restores r.Body for later form parsing.
+func (t *Transport) verifiedBody(r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, false
+	}
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if t.publicKey == nil {
+		return body, t.cfg.InsecureSkipVerify
+	}
+	sig, err := base64.StdEncoding.DecodeString(r.Header.Get("Telnyx-Signature-Ed25519"))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, false
+	}
+	ts := r.Header.Get("Telnyx-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	if age := time.Since(time.Unix(sec, 0)); age > signatureTolerance || age < -signatureTolerance {
+		return nil, false
+	}
+	signed := make([]byte, 0, len(ts)+1+len(body))
+	signed = append(append(append(signed, ts...), '|'), body...)
+	if !ed25519.Verify(t.publicKey, signed, sig) {
+		return nil, false
+	}
+	return body, true
+}
+
+func (t *Transport) websocketURL(r *http.Request) string {
+	if t.cfg.PublicURL != "" {
+		return "wss://" + normalizePublicURL(t.cfg.PublicURL) + t.cfg.WebsocketPath
+	}
+	host := ""
+	if r != nil {
+		host = r.Host
+	}
+	if host == "" {
+		host = strings.TrimPrefix(t.cfg.ServerAddr, ":")
+	}
+	return "wss://" + host + t.cfg.WebsocketPath
+}
+
+func (t *Transport) publicHTTPURL(path string) string {
+	if t.cfg.PublicURL != "" {
+		return "https://" + normalizePublicURL(t.cfg.PublicURL) + path
+	}
+	addr := t.cfg.ServerAddr
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+	return "http://" + addr + path
+}
+
+func (t *Transport) attach(streamID, callSID, traceID, from string, conn *websocket.Conn) (string, *session) {
+	sess := &session{
+		conn:   conn,
+		sendCh: make(chan []byte, 256),
+	}
+	var oldStream string
+	var oldSess *session
+	t.mu.Lock()
+	if callSID != "" {
+		if existing := t.callStreams[callSID]; existing != "" && existing != streamID {
+			oldStream = existing
+			oldSess = t.sessions[existing]
+			delete(t.sessions, existing)
+			delete(t.callSIDs, existing)
+			delete(t.traceIDs, existing)
+			delete(t.fromNumbers, existing)
+		}
+		t.callStreams[callSID] = streamID
+	}
+	t.sessions[streamID] = sess
+	t.callSIDs[streamID] = callSID
+	t.traceIDs[streamID] = traceID
+	if from != "" {
+		t.fromNumbers[streamID] = from
+	}
+	t.mu.Unlock()
+	go sess.loop()
+	return oldStream, oldSess
+}
+
+// detach forgets a stream and reports whether it was still attached.
+func (t *Transport) detach(streamID string) bool {
+	t.mu.Lock()
+	sess, ok := t.sessions[streamID]
+	callSID := t.callSIDs[streamID]
+	delete(t.sessions, streamID)
+	delete(t.callSIDs, streamID)
+	delete(t.traceIDs, streamID)
+	delete(t.fromNumbers, streamID)
+	if callSID != "" && t.callStreams[callSID] == streamID {
+		delete(t.callStreams, callSID)
+		delete(t.dialed, callSID)
+	}
+	t.mu.Unlock()
+	if sess != nil {
+		_ = sess.close()
+	}
+	return ok
+}
+
+func (t *Transport) session(streamID string) *session {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessions[streamID]
+}
+
+func (t *Transport) streamForCall(callSID string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.callStreams[callSID]
+}
+
+// direction reports whether callSID was dialed by this transport.
+func (t *Transport) direction(callSID string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.dialed[callSID] {
+		return "outbound"
+	}
+	return "inbound"
+}
+
+func (t *Transport) metaForStream(streamID string) map[string]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	meta := map[string]string{frames.MetaStreamID: streamID}
+	if v := t.callSIDs[streamID]; v != "" {
+		meta[frames.MetaCallSID] = v
+	}
+	if v := t.traceIDs[streamID]; v != "" {
+		meta[frames.MetaTraceID] = v
+	}
+	if v := t.fromNumbers[streamID]; v != "" {
+		meta[frames.MetaFromNumber] = v
+	}
+	return meta
+}
+
+func (t *Transport) clearBuffer(streamID string) error {
+	sess := t.session(streamID)
+	if sess == nil {
+		return nil
+	}
+	return sess.enqueue(map[string]any{"event": "clear"})
+}
+
+func (t *Transport) sendFallback(streamID string) error {
+	sess := t.session(streamID)
+	if sess == nil {
+		return nil
+	}
+	silence := bytes.Repeat([]byte{0xFF}, 160)
+	for i := 0; i < 5; i++ {
+		_ = sess.enqueue(mediaMessage(silence))
+	}
+	return nil
+}
+
+func (t *Transport) checkOrigin(r *http.Request) bool {
+	if t.cfg.AllowAnyOrigin {
+		return true
+	}
+	origin := strings.TrimRight(strings.TrimSpace(r.Header.Get("Origin")), "/")
+	if origin == "" {
+		return true
+	}
+	originHost := strings.TrimPrefix(strings.TrimPrefix(origin, "https://"), "http://")
+	for _, allowed := range t.cfg.AllowedOrigins {
+		a := strings.TrimRight(strings.TrimSpace(allowed), "/")
+		if a == "" {
+			continue
+		}
+		if strings.HasPrefix(a, "http://") || strings.HasPrefix(a, "https://") {
+			if strings.EqualFold(a, origin) {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(a, originHost) {
+			return true
+		}
+	}
+	return false
+}
+
+func mediaMessage(payload []byte) map[string]any {
+	return map[string]any{
+		"event": "media",
+		"media": map[string]any{
+			"payload": base64.StdEncoding.EncodeToString(payload),
+		},
+	}
+}
+
+// mediaEncoding maps a Telnyx media_format encoding to frame metadata.
+func mediaEncoding(enc string) string {
+	switch strings.ToUpper(enc) {
+	case "PCMA":
+		return "alaw"
+	case "L16":
+		return "pcm16"
+	case "OPUS":
+		return "opus"
+	default:
+		return "mulaw"
+	}
+}
+
+func setAudioFormat(meta map[string]string, encoding string) {
+	meta[frames.MetaEncoding] = encoding
+	switch encoding {
+	case "mulaw":
+		meta[frames.MetaCodec] = "ulaw"
+		meta[frames.MetaFormat] = "ulaw_8000_1ch_8bit"
+	case "alaw":
+		meta[frames.MetaCodec] = "alaw"
+		meta[frames.MetaFormat] = "alaw_8000_1ch_8bit"
+	default:
+		meta[frames.MetaCodec] = encoding
+		meta[frames.MetaFormat] = encoding + "_" + strconv.Itoa(sampleRate(encoding)) + "_1ch"
+	}
+}
+
+func sampleRate(encoding string) int {
+	switch encoding {
+	case "pcm16", "opus":
+		return 16000
+	default:
+		return 8000
+	}
+}
+
+func xmlEscape(in string) string {
+	replacer := strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+		"\"", "&quot;",
+		"'", "&apos;",
+	)
+	return replacer.Replace(in)
+}
+
+// normalizeCallEndReason maps Telnyx hangup causes onto the reasons used by
+// every transport.
+func normalizeCallEndReason(raw string) string {
+	r := strings.ToLower(strings.TrimSpace(raw))
+	if r == "" {
+		return ""
+	}
+	switch r {
+	case "normal_clearing", "completed", "hangup":
+		return "completed"
+	case "user_busy", "busy":
+		return "busy"
+	case "timeout", "no_answer":
+		return "no_answer"
+	case "call_rejected", "originator_cancel", "not_found", "unallocated_number", "failed", "error", "transport_closed":
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+func normalizePublicURL(v string) string {
+	v = strings.TrimPrefix(strings.TrimPrefix(v, "https://"), "http://")
+	return strings.TrimRight(v, "/")
+}
+
+type session struct {
+	conn   *websocket.Conn
+	sendCh chan []byte
+	mu     sync.Mutex
+	closed atomic.Bool
+}
+
+func (s *session) enqueue(msg map[string]any) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed.Load() {
+		return nil
+	}
+	select {
+	case s.sendCh <- b:
+	default:
+	}
+	return nil
+}
+
+func (s *session) loop() {
+	for msg := range s.sendCh {
+		_ = s.conn.WriteMessage(websocket.TextMessage, msg)
+	}
+}
+
+func (s *session) close() error {
+	s.mu.Lock()
+	if s.closed.CompareAndSwap(false, true) {
+		close(s.sendCh)
+	}
+	s.mu.Unlock()
+	return s.conn.Close()
+}
+
+// StreamEvent is a message on the Telnyx media streaming WebSocket.
+type StreamEvent struct {
+	Event          string          `json:"event"`
+	SequenceNumber string          `json:"sequence_number,omitempty"`
+	StreamID       string          `json:"stream_id,omitempty"`
+	Start          *StreamStart    `json:"start,omitempty"`
+	Media          *StreamMedia    `json:"media,omitempty"`
+	DTMF           *StreamDTMF     `json:"dtmf,omitempty"`
+	Mark           *StreamMark     `json:"mark,omitempty"`
+	Stop           *StreamStop     `json:"stop,omitempty"`
+	Payload        *StreamErrorMsg `json:"payload,omitempty"`
+}
+
+type StreamStart struct {
+	CallControlID string       `json:"call_control_id"`
+	CallSessionID string       `json:"call_session_id"`
+	ClientState   string       `json:"client_state"`
+	From          string       `json:"from"`
+	To            string       `json:"to"`
+	MediaFormat   *MediaFormat `json:"media_format,omitempty"`
+}
+
+type MediaFormat struct {
+	Encoding   string `json:"encoding"`
+	SampleRate int    `json:"sample_rate"`
+	Channels   int    `json:"channels"`
+}
+
+type StreamMedia struct {
+	Track     string `json:"track"`
+	Chunk     string `json:"chunk"`
+	Timestamp string `json:"timestamp"`
+	Payload   string `json:"payload"`
+}
+
+type StreamDTMF struct {
+	Digit string `json:"digit"`
+}
+
+type StreamMark struct {
+	Name string `json:"name"`
+}
+
+type StreamStop struct {
+	CallControlID string `json:"call_control_id"`
+}
+
+type StreamErrorMsg struct {
+	Code   int    `json:"code"`
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+}
+
+// WebhookEvent is a Call Control webhook.
+type WebhookEvent struct {
+	Data struct {
+		EventType string         `json:"event_type"`
+		ID        string         `json:"id"`
+		Payload   WebhookPayload `json:"payload"`
+	} `json:"data"`
+}
+
+type WebhookPayload struct {
+	CallControlID string `json:"call_control_id"`
+	CallSessionID string `json:"call_session_id"`
+	ConnectionID  string `json:"connection_id"`
+	Direction     string `json:"direction"`
+	From          string `json:"from"`
+	To            string `json:"to"`
+	State         string `json:"state"`
+	HangupCause   string `json:"hangup_cause"`
+	HangupSource  string `json:"hangup_source"`
+	ClientState   string `json:"client_state"`
+}
+
+func nonBlockingSend(ch chan frames.Frame, f frames.Frame) bool {
+	select {
+	case ch <- f:
+		return true
+	default:
+		return false
+	}
+}
+
+var _ transports.Transport = (*Transport)(nil)
+var _ transports.DTMFSender = (*Transport)(nil)
+var _ transports.CallHanger = (*Transport)(nil)
+var _ transports.OutboundDialerWithOptions = (*Transport)(nil)
+var _ transports.ReadyReporter = (*Transport)(nil)
+var _ transports.CallDrainer = (*Transport)(nil)
+var _ transports.CallAdmitter = (*Transport)(nil)
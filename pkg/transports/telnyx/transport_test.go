@@ -0,0 +1,276 @@
+package telnyx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/transports"
+)
+
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return b
+}
+
+func recvFrame(t *testing.T, tr *Transport) frames.Frame {
+	t.Helper()
+	select {
+	case f := <-tr.Recv():
+		return f
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for frame")
+		return nil
+	}
+}
+
+// stubAPI records Call Control requests.
+type stubAPI struct {
+	mu       sync.Mutex
+	paths    []string
+	bodies   []map[string]any
+	response string
+}
+
+func (s *stubAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	s.mu.Lock()
+	s.paths = append(s.paths, r.URL.Path)
+	s.bodies = append(s.bodies, body)
+	s.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer key" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	_, _ = w.Write([]byte(s.response))
+}
+
+func (s *stubAPI) last(t *testing.T) (string, map[string]any) {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.paths) == 0 {
+		t.Fatalf("expected an api request")
+	}
+	return s.paths[len(s.paths)-1], s.bodies[len(s.bodies)-1]
+}
+
+func newStubAPI(t *testing.T, response string) (*stubAPI, string) {
+	stub := &stubAPI{response: response}
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+	return stub, srv.URL
+}
+
+func TestReplayRecordedMediaStream(t *testing.T) {
+	tr := New(Config{})
+	srv := httptest.NewServer(tr)
+	t.Cleanup(func() {
+		srv.Close()
+		_ = tr.Stop()
+	})
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	lines := bufio.NewScanner(bytes.NewReader(fixture(t, "stream_inbound.jsonl")))
+	lines.Buffer(make([]byte, 64<<10), 64<<10)
+	var events [][]byte
+	for lines.Scan() {
+		events = append(events, append([]byte(nil), lines.Bytes()...))
+	}
+	// Replay everything up to the stop event.
+	for _, evt := range events[:len(events)-1] {
+		if err := conn.WriteMessage(websocket.TextMessage, evt); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	start := recvFrame(t, tr).(frames.SystemFrame)
+	md := start.Metadata()
+	if start.Name() != "call_start" || md.CallSID() != "v3:ctrl-inbound-1" || md.Get(frames.MetaFromNumber) != "+13129457420" || md.Get(frames.MetaCallDirection) != "inbound" {
+		t.Fatalf("unexpected call_start %v", start.Meta())
+	}
+	streamID := md.StreamID()
+	af := recvFrame(t, tr).(frames.AudioFrame)
+	if len(af.RawPayload()) != 160 || af.Rate() != 8000 || af.Metadata().Get(frames.MetaEncoding) != "mulaw" {
+		t.Fatalf("unexpected audio frame len=%d meta=%v", len(af.RawPayload()), af.Meta())
+	}
+	frames.ReleaseAudioFrame(af)
+	// The outbound track echo is skipped, so DTMF comes next.
+	if cf := recvFrame(t, tr).(frames.ControlFrame); cf.Code() != frames.ControlDTMF || cf.Metadata().Get(frames.MetaDTMFDigit) != "7" {
+		t.Fatalf("unexpected dtmf frame %v", cf.Meta())
+	}
+	if cf := recvFrame(t, tr).(frames.ControlFrame); cf.Code() != frames.ControlAudioReady || cf.Metadata().Get(frames.MetaMarkName) != "m-1" {
+		t.Fatalf("unexpected mark frame %v", cf.Meta())
+	}
+
+	out := map[string]string{frames.MetaStreamID: streamID}
+	_ = tr.Send(frames.NewAudioFrame(streamID, 1, []byte{1, 2, 3}, 8000, 1, out))
+	_ = tr.Send(frames.NewControlFrame(streamID, 2, frames.ControlStartInterruption, out))
+	var media, clear StreamEvent
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	if err := conn.ReadJSON(&media); err != nil || media.Event != "media" || media.Media.Payload != base64.StdEncoding.EncodeToString([]byte{1, 2, 3}) {
+		t.Fatalf("expected media, got %+v err=%v", media, err)
+	}
+	if err := conn.ReadJSON(&clear); err != nil || clear.Event != "clear" {
+		t.Fatalf("expected clear, got %+v err=%v", clear, err)
+	}
+
+	_ = conn.WriteMessage(websocket.TextMessage, events[len(events)-1])
+	end := recvFrame(t, tr).(frames.SystemFrame)
+	if end.Name() != "call_end" || end.Metadata().Get(frames.MetaCallEndReason) != "completed" {
+		t.Fatalf("unexpected call_end %v", end.Meta())
+	}
+}
+
+func signedRequest(t *testing.T, priv ed25519.PrivateKey, path string, body []byte, ts time.Time) *http.Request {
+	t.Helper()
+	stamp := strconv.FormatInt(ts.Unix(), 10)
+	sig := ed25519.Sign(priv, append([]byte(stamp+"|"), body...))
+	req := httptest.NewRequest(http.MethodPost, "https://example.com"+path, bytes.NewReader(body))
+	req.Header.Set("Telnyx-Signature-Ed25519", base64.StdEncoding.EncodeToString(sig))
+	req.Header.Set("Telnyx-Timestamp", stamp)
+	return req
+}
+
+func TestWebhookSignatureAndAnswer(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("keygen: %v", err)
+	}
+	stub, apiURL := newStubAPI(t, `{"data":{"result":"ok"}}`)
+	tr := New(Config{
+		APIKey:     "key",
+		PublicKey:  base64.StdEncoding.EncodeToString(pub),
+		PublicURL:  "https://example.com",
+		APIBaseURL: apiURL,
+	})
+	body := fixture(t, "webhook_call_initiated.json")
+
+	w := httptest.NewRecorder()
+	tr.handleWebhook(w, signedRequest(t, priv, "/webhook", body, time.Now()))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	path, req := stub.last(t)
+	if path != "/calls/v3:ctrl-inbound-1/actions/answer" || req["stream_url"] != "wss://example.com/ws" {
+		t.Fatalf("unexpected answer request %s %v", path, req)
+	}
+
+	tampered := bytes.Replace(body, []byte("incoming"), []byte("outgoing"), 1)
+	req2 := signedRequest(t, priv, "/webhook", body, time.Now())
+	req2.Body = io.NopCloser(bytes.NewReader(tampered))
+	w = httptest.NewRecorder()
+	tr.handleWebhook(w, req2)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for tampered body, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	tr.handleWebhook(w, signedRequest(t, priv, "/webhook", body, time.Now().Add(-time.Hour)))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for stale timestamp, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	tr.handleVoice(w, signedRequest(t, priv, "/voice", []byte("CallSid=abc&From=%2B1"), time.Now()))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `<Stream url="wss://example.com/ws" bidirectionalMode="rtp"`) {
+		t.Fatalf("unexpected texml %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHangupWebhookMapsReason(t *testing.T) {
+	tr := New(Config{InsecureSkipVerify: true})
+	streamID := "stream-1"
+	tr.mu.Lock()
+	// A registered stream without a live socket is enough for mapping.
+	tr.sessions[streamID] = nil
+	tr.callStreams["v3:ctrl-inbound-1"] = streamID
+	tr.callSIDs[streamID] = "v3:ctrl-inbound-1"
+	tr.mu.Unlock()
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(fixture(t, "webhook_call_hangup.json")))
+	w := httptest.NewRecorder()
+	tr.handleWebhook(w, req)
+	end := recvFrame(t, tr).(frames.SystemFrame)
+	if end.Name() != "call_end" || end.Metadata().Get(frames.MetaCallEndReason) != "busy" || end.Metadata().CallSID() != "v3:ctrl-inbound-1" {
+		t.Fatalf("unexpected call_end %v", end.Meta())
+	}
+	if tr.streamForCall("v3:ctrl-inbound-1") != "" {
+		t.Fatalf("expected stream to be detached")
+	}
+}
+
+func TestWebhooksRejectedWithoutPublicKey(t *testing.T) {
+	tr := New(Config{})
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(fixture(t, "webhook_call_hangup.json")))
+	w := httptest.NewRecorder()
+	tr.handleWebhook(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected unsigned webhook to be rejected, got %d", w.Code)
+	}
+}
+
+func TestDialSendsDigitsOnAnswer(t *testing.T) {
+	stub, apiURL := newStubAPI(t, `{"data":{"call_control_id":"v3:ctrl-outbound-1"}}`)
+	tr := New(Config{APIKey: "key", ConnectionID: "conn-1", PublicURL: "https://example.com", APIBaseURL: apiURL, InsecureSkipVerify: true})
+
+	sid, err := tr.DialWithOptions(context.Background(), "+13129457420", "+13124457421", "", transports.DialOptions{SendDigits: "12#"})
+	if err != nil || sid != "v3:ctrl-outbound-1" {
+		t.Fatalf("dial sid=%q err=%v", sid, err)
+	}
+	path, req := stub.last(t)
+	if path != "/calls" || req["connection_id"] != "conn-1" || req["stream_url"] != "wss://example.com/ws" || req["webhook_url"] != "https://example.com/webhook" {
+		t.Fatalf("unexpected dial request %s %v", path, req)
+	}
+
+	w := httptest.NewRecorder()
+	tr.handleWebhook(w, httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(fixture(t, "webhook_call_answered.json"))))
+	path, req = stub.last(t)
+	if path != "/calls/v3:ctrl-outbound-1/actions/send_dtmf" || req["digits"] != "12#" {
+		t.Fatalf("unexpected dtmf request %s %v", path, req)
+	}
+
+	if _, err := NewDialer(Config{APIKey: "key"}).Dial(context.Background(), "+1", "+2", ""); err == nil {
+		t.Fatalf("expected missing connection id error")
+	}
+}
+
+func TestNormalizeCallEndReason(t *testing.T) {
+	cases := map[string]string{
+		"normal_clearing":   "completed",
+		"user_busy":         "busy",
+		"timeout":           "no_answer",
+		"originator_cancel": "failed",
+		"something_new":     "unknown",
+		"":                  "",
+	}
+	for in, want := range cases {
+		if got := normalizeCallEndReason(in); got != want {
+			t.Fatalf("normalizeCallEndReason(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package transports
 
 import (
 	"context"
+	"time"
 
 	"github.com/harunnryd/ranya/pkg/frames"
 )
@@ -29,6 +30,12 @@ type OutboundDialer interface {
 // DialOptions carries optional outbound dial settings.
 type DialOptions struct {
 	SendDigits string
+	// MachineDetection requests the carrier's answering machine detection,
+	// in the transport's own terms (Twilio: "Enable" or "DetectMessageEnd").
+	MachineDetection string
+	// Parameters are handed to the call's media stream as custom
+	// parameters (Twilio <Parameter>), e.g. a CRM or campaign ID.
+	Parameters map[string]string
 }
 
 // OutboundDialerWithOptions extends dialing with optional parameters.
@@ -36,6 +43,100 @@ type OutboundDialerWithOptions interface {
 	DialWithOptions(ctx context.Context, to, from, url string, opts DialOptions) (callSID string, err error)
 }
 
+// CallStatusReporter allows dialers to say whether calls that never open a
+// media stream (busy, no answer, failed) are still reported as call_end.
+// Dialers that do not implement it are assumed to report them.
+type CallStatusReporter interface {
+	ReportsCallStatus() bool
+}
+
+// TransferMode selects how a caller is handed to another party.
+type TransferMode string
+
+const (
+	// TransferCold bridges the caller straight to the target.
+	TransferCold TransferMode = "cold"
+	// TransferWarm briefs the target first, then bridges the caller.
+	TransferWarm TransferMode = "warm"
+)
+
+// TransferOptions carries call transfer settings.
+type TransferOptions struct {
+	// Target is a phone number (E.164) or SIP URI.
+	Target string
+	Mode   TransferMode
+	// CallerID is the number presented to the target; required by some
+	// transports for warm transfers.
+	CallerID string
+	// Announcement is played to the caller before bridging.
+	Announcement string
+	// Briefing is read to the target before a warm transfer bridges.
+	Briefing string
+}
+
+// CallTransferer allows transports to hand an active call to another party,
+// typically a human agent. The media stream ends once the transfer starts.
+type CallTransferer interface {
+	TransferCall(ctx context.Context, callSID string, opts TransferOptions) error
+}
+
+// CallHanger allows transports to end an active call from the agent side.
+type CallHanger interface {
+	HangupCall(ctx context.Context, callSID string) error
+}
+
+// CallDrainer allows transports to stop accepting new calls while calls in
+// progress carry on, typically ahead of a rolling deploy. Stop still ends
+// whatever is left.
+type CallDrainer interface {
+	DrainCalls()
+}
+
+// AdmissionAction is what a transport does with a new call.
+type AdmissionAction string
+
+const (
+	// AdmissionAccept lets the call in.
+	AdmissionAccept AdmissionAction = "accept"
+	// AdmissionReject refuses the call as busy.
+	AdmissionReject AdmissionAction = "reject"
+	// AdmissionMessage speaks Message and hangs up.
+	AdmissionMessage AdmissionAction = "message"
+	// AdmissionQueue speaks Message, holds the caller and asks again.
+	AdmissionQueue AdmissionAction = "queue"
+)
+
+// AdmissionRequest describes a new call awaiting admission.
+type AdmissionRequest struct {
+	CallSID string
+	// Outbound marks calls the application placed itself.
+	Outbound bool
+	// CanHold is set when the transport can hold the caller and ask again,
+	// so AdmissionQueue is an option.
+	CanHold bool
+}
+
+// AdmissionDecision tells the transport how to treat a new call.
+type AdmissionDecision struct {
+	Action  AdmissionAction
+	Message string
+	// HoldAudioURL is played while queued; without it the transport
+	// pauses for RetryAfter before asking again.
+	HoldAudioURL string
+	RetryAfter   time.Duration
+}
+
+// AdmissionFunc decides whether a new call may start. Queued calls are
+// decided again each time their hold ends.
+type AdmissionFunc func(req AdmissionRequest) AdmissionDecision
+
+// CallAdmitter allows transports to consult an AdmissionFunc before
+// answering new calls. Transports that cannot speak before answering treat
+// every refusal as busy.
+type CallAdmitter interface {
+	SetAdmission(fn AdmissionFunc)
+}
+
 // ReadyReporter allows transports to expose readiness metadata (e.g., webhook URLs).
 // Implementations are optional and used for informational logging only.
 type ReadyReporter interface {
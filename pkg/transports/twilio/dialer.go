@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	neturl "net/url"
 	"strings"
 
 	"github.com/harunnryd/ranya/pkg/transports"
@@ -43,6 +44,19 @@ func (d *Dialer) DialWithOptions(ctx context.Context, to, from, url string, opts
 	if url == "" {
 		url = d.voiceWebhookURL()
 	}
+	if len(opts.Parameters) > 0 {
+		// The voice webhook turns these back into <Parameter> entries.
+		u, err := neturl.Parse(url)
+		if err != nil {
+			return "", err
+		}
+		q := u.Query()
+		for name, v := range opts.Parameters {
+			q.Set(dialParamPrefix+name, v)
+		}
+		u.RawQuery = q.Encode()
+		url = u.String()
+	}
 	client := d.client
 	if client == nil {
 		rest := twilio.NewRestClientWithParams(twilio.ClientParams{
@@ -55,6 +69,24 @@ func (d *Dialer) DialWithOptions(ctx context.Context, to, from, url string, opts
 	params.SetTo(to)
 	params.SetFrom(from)
 	params.SetUrl(url)
+	if d.cfg.PublicURL != "" {
+		// Busy, no-answer and failed calls never open a media stream; the
+		// status callback is the only place their outcome is reported.
+		params.SetStatusCallback("https://" + normalizePublicURL(d.cfg.PublicURL) + d.cfg.StatusCallbackPath)
+	}
+	amd := d.cfg.MachineDetection
+	if opts.MachineDetection != "" {
+		amd = opts.MachineDetection
+	}
+	if amd != "" {
+		// Async AMD keeps the media stream running while Twilio listens; the
+		// result is posted to the AMD callback.
+		params.SetMachineDetection(amd)
+		params.SetAsyncAmd("true")
+		if d.cfg.PublicURL != "" {
+			params.SetAsyncAmdStatusCallback("https://" + normalizePublicURL(d.cfg.PublicURL) + d.cfg.AMDCallbackPath)
+		}
+	}
 	if strings.TrimSpace(opts.SendDigits) != "" {
 		params.SetSendDigits(opts.SendDigits)
 	}
@@ -68,6 +100,13 @@ func (d *Dialer) DialWithOptions(ctx context.Context, to, from, url string, opts
 	return *resp.Sid, nil
 }
 
+// ReportsCallStatus implements transports.CallStatusReporter. Twilio posts
+// call status only to a public status callback, so without public_url busy
+// and no-answer calls are never reported.
+func (d *Dialer) ReportsCallStatus() bool {
+	return d.cfg.PublicURL != ""
+}
+
 func (d *Dialer) voiceWebhookURL() string {
 	if d.cfg.PublicURL != "" {
 		return "https://" + normalizePublicURL(d.cfg.PublicURL) + d.cfg.VoicePath
@@ -49,6 +49,22 @@ func TestDialerDialUsesDefaults(t *testing.T) {
 	if stub.last.Url == nil {
 		t.Fatalf("expected Url param")
 	}
+	if stub.last.StatusCallback == nil || *stub.last.StatusCallback != "https://example.com/status" {
+		t.Fatalf("expected StatusCallback param, got %v", stub.last.StatusCallback)
+	}
+	if stub.last.MachineDetection != nil {
+		t.Fatalf("expected no machine detection by default")
+	}
+
+	if _, err := d.DialWithOptions(context.Background(), "+100", "+200", "", transports.DialOptions{MachineDetection: "DetectMessageEnd"}); err != nil {
+		t.Fatalf("dial error: %v", err)
+	}
+	if stub.last.MachineDetection == nil || *stub.last.MachineDetection != "DetectMessageEnd" || stub.last.AsyncAmd == nil || *stub.last.AsyncAmd != "true" {
+		t.Fatalf("expected async machine detection params")
+	}
+	if stub.last.AsyncAmdStatusCallback == nil || *stub.last.AsyncAmdStatusCallback != "https://example.com/amd" {
+		t.Fatalf("unexpected AMD callback %v", stub.last.AsyncAmdStatusCallback)
+	}
 }
 
 func TestDialerDialUsesOverrideURL(t *testing.T) {
@@ -73,11 +89,14 @@ func TestDialerDialWithOptionsSendDigits(t *testing.T) {
 	d := NewDialer(cfg)
 	d.client = stub
 
-	_, err := d.DialWithOptions(context.Background(), "+100", "+200", "https://example.com/voice", transports.DialOptions{SendDigits: "W123#"})
+	_, err := d.DialWithOptions(context.Background(), "+100", "+200", "https://example.com/voice", transports.DialOptions{SendDigits: "W123#", Parameters: map[string]string{"crm_id": "42"}})
 	if err != nil {
 		t.Fatalf("dial error: %v", err)
 	}
 	if stub.last == nil || stub.last.SendDigits == nil || *stub.last.SendDigits != "W123#" {
 		t.Fatalf("expected SendDigits param")
 	}
+	if stub.last.Url == nil || *stub.last.Url != "https://example.com/voice?param_crm_id=42" {
+		t.Fatalf("expected parameters in webhook url, got %v", stub.last.Url)
+	}
 }
@@ -10,6 +10,9 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	neturl "net/url"
+	"os"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -20,6 +23,7 @@ import (
 	"github.com/harunnryd/ranya/pkg/errorsx"
 	"github.com/harunnryd/ranya/pkg/frames"
 	"github.com/harunnryd/ranya/pkg/transports"
+	"github.com/harunnryd/ranya/pkg/transports/dtmf"
 	"github.com/twilio/twilio-go"
 	twilioclient "github.com/twilio/twilio-go/client"
 	api "github.com/twilio/twilio-go/rest/api/v2010"
@@ -37,8 +41,43 @@ type Config struct {
 	VoiceGreeting      string   `mapstructure:"voice_greeting"`
 	AllowAnyOrigin     bool     `mapstructure:"allow_any_origin"`
 	AllowedOrigins     []string `mapstructure:"allowed_origins"`
+	// DTMFMode selects how SendDTMF signals digits: DTMFModeInband (default)
+	// or DTMFModeREST.
+	DTMFMode string `mapstructure:"dtmf_mode"`
+	// MachineDetection requests Twilio answering machine detection on
+	// outbound dials: "Enable" or "DetectMessageEnd". Results arrive
+	// asynchronously on AMDCallbackPath as amd_result frames.
+	MachineDetection string `mapstructure:"machine_detection"`
+	AMDCallbackPath  string `mapstructure:"amd_callback_path"`
+	// TransferStatusPath receives the status of a warm transfer's target
+	// leg. If the target never answers, the caller is sent back to the
+	// voice webhook with a transfer_status stream parameter.
+	TransferStatusPath string `mapstructure:"transfer_status_path"`
+	// WarmTransferTimeoutSec is how long the target leg rings.
+	WarmTransferTimeoutSec int `mapstructure:"warm_transfer_timeout_sec"`
+	// StreamParameters adds <Parameter> entries to the generated <Stream>.
+	// Values are templates over the voice webhook's form fields, e.g.
+	// "${To}" or "crm-${CallerZip}".
+	StreamParameters map[string]string `mapstructure:"stream_parameters"`
 }
 
+// dialParamPrefix marks voice webhook query parameters that the dialer
+// forwards as stream parameters.
+const dialParamPrefix = "param_"
+
+// DTMF modes for SendDTMF.
+const (
+	// DTMFModeInband plays synthesized tones into the media stream.
+	DTMFModeInband = "inband"
+	// DTMFModeREST updates the call with <Play digits> TwiML. This replaces
+	// the running <Connect><Stream>, ending the media session.
+	DTMFModeREST = "rest"
+)
+
+// dtmfQueueWait bounds how long in-band DTMF waits for room in a stream's
+// send queue before giving up on the remaining tones.
+const dtmfQueueWait = 2 * time.Second
+
 func (c Config) withDefaults() Config {
 	if c.ServerAddr == "" {
 		c.ServerAddr = ":8080"
@@ -58,6 +97,18 @@ func (c Config) withDefaults() Config {
 	if !c.AllowAnyOrigin && len(c.AllowedOrigins) == 0 {
 		c.AllowAnyOrigin = true
 	}
+	if c.DTMFMode == "" {
+		c.DTMFMode = DTMFModeInband
+	}
+	if c.AMDCallbackPath == "" {
+		c.AMDCallbackPath = "/amd"
+	}
+	if c.TransferStatusPath == "" {
+		c.TransferStatusPath = "/transfer-status"
+	}
+	if c.WarmTransferTimeoutSec <= 0 {
+		c.WarmTransferTimeoutSec = 30
+	}
 	return c
 }
 
@@ -68,15 +119,18 @@ type Transport struct {
 	recvCh   chan frames.Frame
 
 	updateClient callUpdater
+	createClient callCreator
 
 	mu          sync.Mutex
 	sessions    map[string]*session
 	callSIDs    map[string]string
 	callStreams map[string]string
 	traceIDs    map[string]string
-	fromNumbers map[string]string
+	callMeta    map[string]map[string]string
+	admission   transports.AdmissionFunc
 
-	draining atomic.Bool
+	draining    atomic.Bool
+	rejectCalls atomic.Bool
 }
 
 type callUpdater interface {
@@ -97,7 +151,7 @@ func New(cfg Config) *Transport {
 		callSIDs:    make(map[string]string),
 		callStreams: make(map[string]string),
 		traceIDs:    make(map[string]string),
-		fromNumbers: make(map[string]string),
+		callMeta:    make(map[string]map[string]string),
 	}
 	t.upgrader.CheckOrigin = t.checkOrigin
 	return t
@@ -123,9 +177,12 @@ func (t *Transport) Start(ctx context.Context) error {
 	mux.Handle(t.cfg.WebsocketPath, t)
 	mux.HandleFunc(t.cfg.TTSWebhookPath, t.handleTTSWebhook)
 	mux.HandleFunc(t.cfg.StatusCallbackPath, t.handleStatusCallback)
+	mux.HandleFunc(t.cfg.AMDCallbackPath, t.handleAMDCallback)
+	mux.HandleFunc(t.cfg.TransferStatusPath, t.handleTransferStatus)
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
+	mux.HandleFunc("/ready", t.handleReady)
 	t.server = &http.Server{
 		Addr:              t.cfg.ServerAddr,
 		ReadHeaderTimeout: 5 * time.Second,
@@ -158,6 +215,21 @@ func (t *Transport) Stop() error {
 	return nil
 }
 
+// DrainCalls makes the voice webhook answer 503 so the carrier fails over to
+// another instance. Media streams of calls in progress, including stream
+// reconnects, are still served.
+func (t *Transport) DrainCalls() {
+	t.rejectCalls.Store(true)
+}
+
+func (t *Transport) handleReady(w http.ResponseWriter, r *http.Request) {
+	if t.rejectCalls.Load() || t.draining.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
 func (t *Transport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if t.draining.Load() {
 		w.WriteHeader(http.StatusServiceUnavailable)
@@ -188,16 +260,19 @@ func (t *Transport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			callSID = evt.Start.CallSID
 			streamID = evt.Start.StreamID
 			traceID := uuid.NewString()
-			oldStream, oldSess := t.attach(streamID, callSID, traceID, evt.Start.From, conn)
+			info := startMeta(evt.Start)
+			oldStream, oldSess := t.attach(streamID, callSID, traceID, info, conn)
 			if oldSess != nil {
 				_ = oldSess.close()
 			}
 			meta := map[string]string{
-				frames.MetaStreamID:   streamID,
-				frames.MetaCallSID:    callSID,
-				frames.MetaTraceID:    traceID,
-				frames.MetaFromNumber: evt.Start.From,
-				frames.MetaSource:     "transport",
+				frames.MetaStreamID: streamID,
+				frames.MetaCallSID:  callSID,
+				frames.MetaTraceID:  traceID,
+				frames.MetaSource:   "transport",
+			}
+			for k, v := range info {
+				meta[k] = v
 			}
 			nonBlockingSend(t.recvCh, frames.NewSystemFrame(streamID, time.Now().UnixNano(), "call_start", meta))
 			if oldStream != "" {
@@ -214,7 +289,7 @@ func (t *Transport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			if evt.Media == nil {
 				continue
 			}
-			payload, err := base64.StdEncoding.DecodeString(evt.Media.Payload)
+			payload, err := frames.DecodeAudioBase64(evt.Media.Payload)
 			if err != nil {
 				continue
 			}
@@ -222,8 +297,10 @@ func (t *Transport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			meta[frames.MetaEncoding] = "mulaw"
 			meta[frames.MetaCodec] = "ulaw"
 			meta[frames.MetaFormat] = "ulaw_8000_1ch_8bit"
-			af := frames.NewAudioFrame(streamID, time.Now().UnixNano(), payload, 8000, 1, meta)
-			nonBlockingSend(t.recvCh, af)
+			af := frames.NewAudioFrameFromBuf(streamID, time.Now().UnixNano(), payload, 8000, 1, meta)
+			if !nonBlockingSend(t.recvCh, af) {
+				frames.ReleaseAudioFrame(af)
+			}
 		case "dtmf":
 			if evt.DTMF == nil {
 				continue
@@ -243,7 +320,7 @@ func (t *Transport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			if reason != "" {
 				meta[frames.MetaCallEndReason] = reason
 			}
-			nonBlockingSend(t.recvCh, frames.NewSystemFrame(streamID, time.Now().UnixNano(), "call_end", meta))
+			t.sendCallEnd(frames.NewSystemFrame(streamID, time.Now().UnixNano(), "call_end", meta))
 			t.detach(streamID)
 			return
 		}
@@ -251,7 +328,7 @@ func (t *Transport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if streamID != "" {
 		meta := t.metaForStream(streamID)
 		meta[frames.MetaCallEndReason] = normalizeCallEndReason("transport_closed")
-		nonBlockingSend(t.recvCh, frames.NewSystemFrame(streamID, time.Now().UnixNano(), "call_end", meta))
+		t.sendCallEnd(frames.NewSystemFrame(streamID, time.Now().UnixNano(), "call_end", meta))
 		t.detach(streamID)
 	}
 }
@@ -259,7 +336,7 @@ func (t *Transport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 func (t *Transport) Send(f frames.Frame) error {
 	if f.Kind() == frames.KindControl {
 		cf := f.(frames.ControlFrame)
-		streamID := cf.Meta()[frames.MetaStreamID]
+		streamID := cf.Metadata().StreamID()
 		switch cf.Code() {
 		case frames.ControlFallback:
 			return t.sendFallback(streamID)
@@ -273,7 +350,7 @@ func (t *Transport) Send(f frames.Frame) error {
 		return nil
 	}
 	af := f.(frames.AudioFrame)
-	streamID := af.Meta()[frames.MetaStreamID]
+	streamID := af.Metadata().StreamID()
 	sess := t.session(streamID)
 	if sess == nil {
 		return nil
@@ -301,55 +378,263 @@ func (t *Transport) DialWithOptions(ctx context.Context, to, from, url string, o
 	return dialer.DialWithOptions(ctx, to, from, url, opts)
 }
 
-// SendDTMF sends DTMF digits on an active call using Twilio REST API.
+// ReportsCallStatus implements transports.CallStatusReporter.
+func (t *Transport) ReportsCallStatus() bool {
+	return NewDialer(t.cfg).ReportsCallStatus()
+}
+
+// SendDTMF sends DTMF digits on an active call. By default the tones are
+// synthesized into the call's media stream after any queued audio; with
+// DTMFModeREST the call is updated through the Twilio REST API instead.
 func (t *Transport) SendDTMF(ctx context.Context, callSID, digits string) error {
-	_ = ctx
 	if strings.TrimSpace(callSID) == "" {
 		return errors.New("call sid required")
 	}
 	if strings.TrimSpace(digits) == "" {
 		return errors.New("digits required")
 	}
+	if t.cfg.DTMFMode == DTMFModeREST {
+		return t.sendDTMFREST(callSID, digits)
+	}
+	streamID := t.streamForCall(callSID)
+	sess := t.session(streamID)
+	if sess == nil {
+		return fmt.Errorf("no active media stream for call %q", callSID)
+	}
+	audio, err := dtmf.Synthesize(strings.TrimSpace(digits), dtmf.Options{})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, dtmfQueueWait)
+	defer cancel()
+	for i := 0; i < len(audio); i += 160 {
+		end := min(i+160, len(audio))
+		msg := map[string]any{
+			"event":     "media",
+			"streamSid": streamID,
+			"media": map[string]any{
+				"payload": base64.StdEncoding.EncodeToString(audio[i:end]),
+			},
+		}
+		// Dropping a chunk would cut a tone short, so wait for room and
+		// fail instead.
+		if err := sess.enqueueWait(ctx, msg); err != nil {
+			return fmt.Errorf("send dtmf to call %q: %w", callSID, err)
+		}
+	}
+	return nil
+}
+
+func (t *Transport) sendDTMFREST(callSID, digits string) error {
 	if t.cfg.AccountSID == "" || t.cfg.AuthToken == "" {
 		return errors.New("missing twilio credentials")
 	}
-	updater := t.updateClient
-	if updater == nil {
-		rest := twilio.NewRestClientWithParams(twilio.ClientParams{
-			Username: t.cfg.AccountSID,
-			Password: t.cfg.AuthToken,
-		})
-		updater = rest.Api
-	}
 	params := &api.UpdateCallParams{}
 	params.SetTwiml(buildDTMFTwiml(digits))
-	_, err := updater.UpdateCall(callSID, params)
+	_, err := t.updater().UpdateCall(callSID, params)
 	return err
 }
 
+// TransferCall hands an active call to a human. Cold transfers redirect the
+// call to <Dial>. Warm transfers dial the target with the briefing first,
+// then move the caller into a conference the target joins after hearing it.
+// The target leg reports its status to TransferStatusPath, so warm transfers
+// need public_url.
+func (t *Transport) TransferCall(ctx context.Context, callSID string, opts transports.TransferOptions) error {
+	_ = ctx
+	if strings.TrimSpace(callSID) == "" {
+		return errors.New("call sid required")
+	}
+	if strings.TrimSpace(opts.Target) == "" {
+		return errors.New("transfer target required")
+	}
+	if t.cfg.AccountSID == "" || t.cfg.AuthToken == "" {
+		return errors.New("missing twilio credentials")
+	}
+	params := &api.UpdateCallParams{}
+	if opts.Mode != transports.TransferWarm {
+		params.SetTwiml(buildColdTransferTwiml(opts))
+		_, err := t.updater().UpdateCall(callSID, params)
+		return err
+	}
+	if strings.TrimSpace(opts.CallerID) == "" {
+		return errors.New("caller id required for warm transfer")
+	}
+	if t.cfg.PublicURL == "" {
+		// Without the target's status the caller would wait in the
+		// conference forever if nobody answers.
+		return errors.New("public_url required for warm transfer")
+	}
+	room := "transfer-" + callSID
+	create := &api.CreateCallParams{}
+	create.SetTo(opts.Target)
+	create.SetFrom(opts.CallerID)
+	create.SetTwiml(buildWarmTargetTwiml(room, opts.Briefing))
+	create.SetTimeout(t.cfg.WarmTransferTimeoutSec)
+	create.SetStatusCallback(t.transferStatusURL(callSID))
+	if _, err := t.creator().CreateCall(create); err != nil {
+		return err
+	}
+	params.SetTwiml(buildWarmCallerTwiml(room, opts.Announcement))
+	_, err := t.updater().UpdateCall(callSID, params)
+	return err
+}
+
+// HangupCall ends an active call by marking it completed.
+func (t *Transport) HangupCall(ctx context.Context, callSID string) error {
+	_ = ctx
+	if strings.TrimSpace(callSID) == "" {
+		return errors.New("call sid required")
+	}
+	if t.cfg.AccountSID == "" || t.cfg.AuthToken == "" {
+		return errors.New("missing twilio credentials")
+	}
+	params := &api.UpdateCallParams{}
+	params.SetStatus("completed")
+	_, err := t.updater().UpdateCall(callSID, params)
+	return err
+}
+
+func (t *Transport) updater() callUpdater {
+	if t.updateClient != nil {
+		return t.updateClient
+	}
+	return twilio.NewRestClientWithParams(twilio.ClientParams{
+		Username: t.cfg.AccountSID,
+		Password: t.cfg.AuthToken,
+	}).Api
+}
+
+func (t *Transport) creator() callCreator {
+	if t.createClient != nil {
+		return t.createClient
+	}
+	return twilio.NewRestClientWithParams(twilio.ClientParams{
+		Username: t.cfg.AccountSID,
+		Password: t.cfg.AuthToken,
+	}).Api
+}
+
 func (t *Transport) handleVoice(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
+	if t.rejectCalls.Load() {
+		slog.Info("twilio_call_rejected", "reason", "draining")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
 	if t.cfg.AuthToken != "" && !t.validateTwilioRequest(r) {
 		slog.Warn("twilio_invalid_signature", "reason_code", string(errorsx.ReasonTransportInvalidSignature))
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
-	wsURL := t.websocketURL(r)
-	greeting := strings.TrimSpace(t.cfg.VoiceGreeting)
-	if greeting != "" {
-		greeting = xmlEscape(greeting)
+	if !t.admit(w, r) {
+		return
 	}
-	var twiml string
-	if greeting != "" {
-		twiml = `<Response><Say>` + greeting + `</Say><Connect><Stream url="` + wsURL + `"/></Connect></Response>`
+	wsURL := t.websocketURL(r)
+	var b strings.Builder
+	b.WriteString(`<Response>`)
+	if greeting := strings.TrimSpace(t.cfg.VoiceGreeting); greeting != "" {
+		writeSay(&b, greeting)
+	}
+	b.WriteString(`<Connect><Stream url="` + wsURL + `"`)
+	params := t.streamParameters(r)
+	if len(params) == 0 {
+		b.WriteString(`/></Connect></Response>`)
 	} else {
-		twiml = `<Response><Connect><Stream url="` + wsURL + `"/></Connect></Response>`
+		b.WriteString(`>`)
+		names := make([]string, 0, len(params))
+		for name := range params {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			b.WriteString(`<Parameter name="` + xmlEscape(name) + `" value="` + xmlEscape(params[name]) + `"/>`)
+		}
+		b.WriteString(`</Stream></Connect></Response>`)
+	}
+	w.Header().Set("Content-Type", "text/xml")
+	_, _ = w.Write([]byte(b.String()))
+}
+
+// SetAdmission makes the voice webhook consult fn before connecting a call.
+func (t *Transport) SetAdmission(fn transports.AdmissionFunc) {
+	t.mu.Lock()
+	t.admission = fn
+	t.mu.Unlock()
+}
+
+// admit applies the admission decision to a voice webhook. When the call may
+// not connect yet it writes the overflow TwiML and returns false. Queued
+// callers hear the hold audio, then the webhook is requested again.
+func (t *Transport) admit(w http.ResponseWriter, r *http.Request) bool {
+	t.mu.Lock()
+	fn := t.admission
+	t.mu.Unlock()
+	if fn == nil {
+		return true
+	}
+	_ = r.ParseForm()
+	callSID := r.PostForm.Get("CallSid")
+	decision := fn(transports.AdmissionRequest{
+		CallSID:  callSID,
+		Outbound: callDirection(r.PostForm.Get("Direction")) == "outbound",
+		CanHold:  true,
+	})
+	var b strings.Builder
+	b.WriteString(`<Response>`)
+	switch decision.Action {
+	case transports.AdmissionReject:
+		b.WriteString(`<Reject reason="busy"/>`)
+	case transports.AdmissionMessage:
+		writeSay(&b, decision.Message)
+		b.WriteString(`<Hangup/>`)
+	case transports.AdmissionQueue:
+		writeSay(&b, decision.Message)
+		if decision.HoldAudioURL != "" {
+			b.WriteString(`<Play>` + xmlEscape(decision.HoldAudioURL) + `</Play>`)
+		} else {
+			fmt.Fprintf(&b, `<Pause length="%d"/>`, max(1, int(decision.RetryAfter.Round(time.Second)/time.Second)))
+		}
+		redirect := t.cfg.VoicePath
+		if r.URL.RawQuery != "" {
+			redirect += "?" + r.URL.RawQuery
+		}
+		b.WriteString(`<Redirect method="POST">` + xmlEscape(redirect) + `</Redirect>`)
+	default:
+		return true
 	}
+	b.WriteString(`</Response>`)
+	slog.Info("twilio_call_overflow", "call_sid", callSID, "action", string(decision.Action))
 	w.Header().Set("Content-Type", "text/xml")
-	_, _ = w.Write([]byte(twiml))
+	_, _ = w.Write([]byte(b.String()))
+	return false
+}
+
+// streamParameters collects the <Parameter> entries for a voice webhook:
+// the call's From, To and Direction, the configured templates, and any
+// parameters the dialer forwarded in the query string.
+func (t *Transport) streamParameters(r *http.Request) map[string]string {
+	if err := r.ParseForm(); err != nil {
+		return nil
+	}
+	params := make(map[string]string)
+	for _, field := range []string{"From", "To", "Direction"} {
+		if v := r.PostForm.Get(field); v != "" {
+			params[field] = v
+		}
+	}
+	for name, tmpl := range t.cfg.StreamParameters {
+		params[name] = os.Expand(tmpl, r.Form.Get)
+	}
+	for key, values := range r.URL.Query() {
+		if name := strings.TrimPrefix(key, dialParamPrefix); name != key && name != "" && len(values) > 0 {
+			params[name] = values[0]
+		}
+	}
+	return params
 }
 
 func (t *Transport) handleTTSWebhook(w http.ResponseWriter, r *http.Request) {
@@ -401,16 +686,84 @@ func (t *Transport) handleStatusCallback(w http.ResponseWriter, r *http.Request)
 	}
 	streamID := t.streamForCall(callSID)
 	if streamID == "" {
+		// The call never reached (or already left) the media stream. Report
+		// the outcome without a stream so outbound dialers still see busy and
+		// no-answer results.
+		meta := map[string]string{frames.MetaCallSID: callSID, frames.MetaCallEndReason: reason}
+		t.sendCallEnd(frames.NewSystemFrame("", time.Now().UnixNano(), "call_end", meta))
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 	meta := t.metaForStream(streamID)
 	meta[frames.MetaCallEndReason] = reason
-	nonBlockingSend(t.recvCh, frames.NewSystemFrame(streamID, time.Now().UnixNano(), "call_end", meta))
+	t.sendCallEnd(frames.NewSystemFrame(streamID, time.Now().UnixNano(), "call_end", meta))
 	t.detach(streamID)
 	w.WriteHeader(http.StatusOK)
 }
 
+// handleTransferStatus takes back a caller parked in a warm transfer
+// conference when the target leg ends without answering.
+func (t *Transport) handleTransferStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if t.cfg.AuthToken != "" && !t.validateTwilioRequest(r) {
+		slog.Warn("twilio_transfer_status_invalid_signature", "reason_code", string(errorsx.ReasonTransportInvalidSignature))
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := r.ParseForm(); err != nil {
+		return
+	}
+	callerSID := r.URL.Query().Get("caller")
+	status := r.PostForm.Get("CallStatus")
+	switch status {
+	case "busy", "no-answer", "failed", "canceled":
+	default:
+		return
+	}
+	if callerSID == "" {
+		return
+	}
+	params := &api.UpdateCallParams{}
+	params.SetTwiml(buildTransferReturnTwiml(t.voiceWebhookURL(), status))
+	if _, err := t.updater().UpdateCall(callerSID, params); err != nil {
+		slog.Warn("twilio_transfer_return_failed", "call_sid", callerSID, "status", status, "error", err)
+		return
+	}
+	slog.Info("twilio_transfer_returned", "call_sid", callerSID, "status", status)
+}
+
+// handleAMDCallback forwards async AMD results (AnsweredBy) to the call's
+// session as an amd_result frame.
+func (t *Transport) handleAMDCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if t.cfg.AuthToken != "" && !t.validateTwilioRequest(r) {
+		slog.Warn("twilio_amd_invalid_signature", "reason_code", string(errorsx.ReasonTransportInvalidSignature))
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := r.ParseForm(); err != nil {
+		return
+	}
+	callSID := r.FormValue("CallSid")
+	answeredBy := r.FormValue("AnsweredBy")
+	streamID := t.streamForCall(callSID)
+	if streamID == "" || answeredBy == "" {
+		slog.Debug("twilio_amd_result_dropped", "call_sid", callSID, "answered_by", answeredBy)
+		return
+	}
+	meta := t.metaForStream(streamID)
+	meta[frames.MetaAnsweredBy] = answeredBy
+	nonBlockingSend(t.recvCh, frames.NewSystemFrame(streamID, time.Now().UnixNano(), "amd_result", meta))
+}
+
 func (t *Transport) websocketURL(r *http.Request) string {
 	if t.cfg.PublicURL != "" {
 		return "wss://" + normalizePublicURL(t.cfg.PublicURL) + t.cfg.WebsocketPath
@@ -450,7 +803,11 @@ func (t *Transport) statusCallbackURL() string {
 	return "http://" + addr + t.cfg.StatusCallbackPath
 }
 
-func (t *Transport) attach(streamID, callSID, traceID, from string, conn *websocket.Conn) (string, *session) {
+func (t *Transport) transferStatusURL(callerSID string) string {
+	return "https://" + normalizePublicURL(t.cfg.PublicURL) + t.cfg.TransferStatusPath + "?caller=" + neturl.QueryEscape(callerSID)
+}
+
+func (t *Transport) attach(streamID, callSID, traceID string, info map[string]string, conn *websocket.Conn) (string, *session) {
 	sess := &session{
 		conn:   conn,
 		sendCh: make(chan []byte, 256),
@@ -465,15 +822,15 @@ func (t *Transport) attach(streamID, callSID, traceID, from string, conn *websoc
 			delete(t.sessions, existing)
 			delete(t.callSIDs, existing)
 			delete(t.traceIDs, existing)
-			delete(t.fromNumbers, existing)
+			delete(t.callMeta, existing)
 		}
 		t.callStreams[callSID] = streamID
 	}
 	t.sessions[streamID] = sess
 	t.callSIDs[streamID] = callSID
 	t.traceIDs[streamID] = traceID
-	if from != "" {
-		t.fromNumbers[streamID] = from
+	if len(info) > 0 {
+		t.callMeta[streamID] = info
 	}
 	t.mu.Unlock()
 	go sess.loop()
@@ -487,7 +844,7 @@ func (t *Transport) detach(streamID string) {
 	delete(t.sessions, streamID)
 	delete(t.callSIDs, streamID)
 	delete(t.traceIDs, streamID)
-	delete(t.fromNumbers, streamID)
+	delete(t.callMeta, streamID)
 	if callSID != "" && t.callStreams[callSID] == streamID {
 		delete(t.callStreams, callSID)
 	}
@@ -519,8 +876,8 @@ func (t *Transport) metaForStream(streamID string) map[string]string {
 	if v := t.traceIDs[streamID]; v != "" {
 		meta[frames.MetaTraceID] = v
 	}
-	if v := t.fromNumbers[streamID]; v != "" {
-		meta[frames.MetaFromNumber] = v
+	for k, v := range t.callMeta[streamID] {
+		meta[k] = v
 	}
 	return meta
 }
@@ -630,6 +987,55 @@ func buildDTMFTwiml(digits string) string {
 	return fmt.Sprintf(`<Response><Play digits="%s"/></Response>`, escaped)
 }
 
+func buildColdTransferTwiml(opts transports.TransferOptions) string {
+	var b strings.Builder
+	b.WriteString("<Response>")
+	writeSay(&b, opts.Announcement)
+	if opts.CallerID != "" {
+		fmt.Fprintf(&b, `<Dial callerId="%s">`, xmlEscape(opts.CallerID))
+	} else {
+		b.WriteString("<Dial>")
+	}
+	if strings.HasPrefix(strings.ToLower(opts.Target), "sip:") {
+		fmt.Fprintf(&b, "<Sip>%s</Sip>", xmlEscape(opts.Target))
+	} else {
+		fmt.Fprintf(&b, "<Number>%s</Number>", xmlEscape(opts.Target))
+	}
+	b.WriteString("</Dial></Response>")
+	return b.String()
+}
+
+// The caller waits on hold music until the target enters; whoever leaves
+// first ends the conference.
+func buildWarmCallerTwiml(room, announcement string) string {
+	var b strings.Builder
+	b.WriteString("<Response>")
+	writeSay(&b, announcement)
+	fmt.Fprintf(&b, `<Dial><Conference startConferenceOnEnter="false" endConferenceOnExit="true">%s</Conference></Dial></Response>`, xmlEscape(room))
+	return b.String()
+}
+
+// buildTransferReturnTwiml sends a caller back to the voice webhook, which
+// opens a new media stream carrying transfer_status.
+func buildTransferReturnTwiml(voiceURL, status string) string {
+	u := voiceURL + "?" + neturl.Values{dialParamPrefix + "transfer_status": {status}}.Encode()
+	return `<Response><Redirect method="POST">` + xmlEscape(u) + `</Redirect></Response>`
+}
+
+func buildWarmTargetTwiml(room, briefing string) string {
+	var b strings.Builder
+	b.WriteString("<Response>")
+	writeSay(&b, briefing)
+	fmt.Fprintf(&b, `<Dial><Conference startConferenceOnEnter="true" endConferenceOnExit="true">%s</Conference></Dial></Response>`, xmlEscape(room))
+	return b.String()
+}
+
+func writeSay(b *strings.Builder, text string) {
+	if text = strings.TrimSpace(text); text != "" {
+		fmt.Fprintf(b, "<Say>%s</Say>", xmlEscape(text))
+	}
+}
+
 func xmlEscape(in string) string {
 	replacer := strings.NewReplacer(
 		"&", "&amp;",
@@ -681,6 +1087,23 @@ func (s *session) enqueue(msg map[string]any) error {
 	return nil
 }
 
+// enqueueWait queues msg like enqueue, but waits for room until ctx is done.
+func (s *session) enqueueWait(ctx context.Context, msg map[string]any) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if s.closed.Load() {
+		return errors.New("media stream closed")
+	}
+	select {
+	case s.sendCh <- b:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("media send queue full: %w", ctx.Err())
+	}
+}
+
 func (s *session) loop() {
 	for msg := range s.sendCh {
 		_ = s.conn.WriteMessage(websocket.TextMessage, msg)
@@ -695,9 +1118,48 @@ func (s *session) close() error {
 }
 
 type TwilioStart struct {
-	CallSID  string `json:"callSid"`
-	StreamID string `json:"streamSid"`
-	From     string `json:"from"`
+	CallSID          string            `json:"callSid"`
+	StreamID         string            `json:"streamSid"`
+	From             string            `json:"from"`
+	CustomParameters map[string]string `json:"customParameters"`
+}
+
+// startMeta maps a start event's custom parameters to frame metadata. From,
+// To and Direction become the call's numbers and direction; names with the
+// global_ prefix are kept as-is so ContextProcessor shares them with the
+// model; everything else is exposed as param_<name>.
+func startMeta(start *TwilioStart) map[string]string {
+	meta := make(map[string]string)
+	if start.From != "" {
+		meta[frames.MetaFromNumber] = start.From
+	}
+	for name, v := range start.CustomParameters {
+		if v == "" {
+			continue
+		}
+		switch {
+		case name == "From":
+			meta[frames.MetaFromNumber] = v
+		case name == "To":
+			meta[frames.MetaToNumber] = v
+		case name == "Direction":
+			meta[frames.MetaCallDirection] = callDirection(v)
+		case strings.HasPrefix(name, frames.MetaGlobalPrefix):
+			meta[name] = v
+		default:
+			meta[frames.MetaParamPrefix+name] = v
+		}
+	}
+	return meta
+}
+
+// callDirection folds Twilio's inbound, outbound-api and outbound-dial into
+// inbound or outbound.
+func callDirection(raw string) string {
+	if strings.HasPrefix(strings.ToLower(raw), "outbound") {
+		return "outbound"
+	}
+	return "inbound"
 }
 
 type TwilioMedia struct {
@@ -749,9 +1211,19 @@ func fallbackMuLawFrames() [][]byte {
 	return fallbackMuLaw
 }
 
-func nonBlockingSend(ch chan frames.Frame, f frames.Frame) {
+// sendCallEnd delivers call_end, waiting briefly rather than drop it.
+func (t *Transport) sendCallEnd(f frames.SystemFrame) {
+	if !transports.SendCallEnd(t.recvCh, f) {
+		md := f.Metadata()
+		slog.Warn("twilio_call_end_dropped", "call_sid", md.CallSID(), "stream_id", md.StreamID())
+	}
+}
+
+func nonBlockingSend(ch chan frames.Frame, f frames.Frame) bool {
 	select {
 	case ch <- f:
+		return true
 	default:
+		return false
 	}
 }
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/transports"
 	api "github.com/twilio/twilio-go/rest/api/v2010"
 )
 
@@ -101,9 +102,10 @@ func TestHandleTTSWebhookSignatureValidation(t *testing.T) {
 }
 
 type stubCallUpdater struct {
-	lastSID   string
-	lastTwiml string
-	err       error
+	lastSID    string
+	lastTwiml  string
+	lastStatus string
+	err        error
 }
 
 func (s *stubCallUpdater) UpdateCall(sid string, params *api.UpdateCallParams) (*api.ApiV2010Call, error) {
@@ -111,14 +113,71 @@ func (s *stubCallUpdater) UpdateCall(sid string, params *api.UpdateCallParams) (
 	if params != nil && params.Twiml != nil {
 		s.lastTwiml = *params.Twiml
 	}
+	if params != nil && params.Status != nil {
+		s.lastStatus = *params.Status
+	}
 	if s.err != nil {
 		return nil, s.err
 	}
 	return &api.ApiV2010Call{}, nil
 }
 
-func TestSendDTMF(t *testing.T) {
+func TestSendDTMFInband(t *testing.T) {
+	tr := New(Config{AccountSID: "AC123", AuthToken: "token"})
+	stub := &stubCallUpdater{}
+	tr.updateClient = stub
+	sess := &session{sendCh: make(chan []byte, 256)}
+	tr.sessions["stream-1"] = sess
+	tr.callStreams["CA123"] = "stream-1"
+
+	if err := tr.SendDTMF(context.Background(), "CA123", "1#"); err != nil {
+		t.Fatalf("SendDTMF error: %v", err)
+	}
+	if stub.lastSID != "" {
+		t.Fatalf("in-band DTMF must not update the call")
+	}
+	// Two digits of 100ms tone + 100ms gap at 8kHz are 20 media chunks.
+	if got := len(sess.sendCh); got != 20 {
+		t.Fatalf("expected 20 media messages, got %d", got)
+	}
+	var msg struct {
+		Event     string `json:"event"`
+		StreamSID string `json:"streamSid"`
+		Media     struct {
+			Payload string `json:"payload"`
+		} `json:"media"`
+	}
+	if err := json.Unmarshal(<-sess.sendCh, &msg); err != nil {
+		t.Fatalf("decode media: %v", err)
+	}
+	payload, _ := base64.StdEncoding.DecodeString(msg.Media.Payload)
+	if msg.Event != "media" || msg.StreamSID != "stream-1" || len(payload) != 160 {
+		t.Fatalf("unexpected media message %+v (%d bytes)", msg, len(payload))
+	}
+
+	if err := tr.SendDTMF(context.Background(), "CA999", "1"); err == nil {
+		t.Fatalf("expected error without a media stream")
+	}
+	if err := tr.SendDTMF(context.Background(), "CA123", "1x"); err == nil {
+		t.Fatalf("expected error on invalid digit")
+	}
+}
+
+func TestSendDTMFInbandFullQueue(t *testing.T) {
 	tr := New(Config{AccountSID: "AC123", AuthToken: "token"})
+	sess := &session{sendCh: make(chan []byte, 4)}
+	tr.sessions["stream-1"] = sess
+	tr.callStreams["CA123"] = "stream-1"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := tr.SendDTMF(ctx, "CA123", "1"); err == nil {
+		t.Fatalf("expected an error when the tones do not fit the send queue")
+	}
+}
+
+func TestSendDTMFREST(t *testing.T) {
+	tr := New(Config{AccountSID: "AC123", AuthToken: "token", DTMFMode: DTMFModeREST})
 	stub := &stubCallUpdater{}
 	tr.updateClient = stub
 
@@ -190,6 +249,51 @@ func TestHandleStatusCallbackMapping(t *testing.T) {
 	}
 }
 
+func TestHandleStatusCallbackWithoutStream(t *testing.T) {
+	tr := New(Config{StatusCallbackPath: "/status"})
+	form := url.Values{}
+	form.Set("CallSid", "CA404")
+	form.Set("CallStatus", "no-answer")
+	req := httptest.NewRequest(http.MethodPost, "/status", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	tr.handleStatusCallback(w, req)
+
+	select {
+	case frame := <-tr.Recv():
+		meta := frame.(frames.SystemFrame).Meta()
+		if meta[frames.MetaStreamID] != "" || meta[frames.MetaCallSID] != "CA404" || meta[frames.MetaCallEndReason] != "no_answer" {
+			t.Fatalf("unexpected call_end meta %v", meta)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected call_end frame")
+	}
+}
+
+func TestHandleAMDCallback(t *testing.T) {
+	tr := New(Config{})
+	tr.mu.Lock()
+	tr.callStreams["CA1"] = "stream-1"
+	tr.callSIDs["stream-1"] = "CA1"
+	tr.mu.Unlock()
+	form := url.Values{}
+	form.Set("CallSid", "CA1")
+	form.Set("AnsweredBy", "machine_end_beep")
+	req := httptest.NewRequest(http.MethodPost, "/amd", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	tr.handleAMDCallback(httptest.NewRecorder(), req)
+
+	select {
+	case frame := <-tr.Recv():
+		sf := frame.(frames.SystemFrame)
+		if sf.Name() != "amd_result" || sf.Meta()[frames.MetaAnsweredBy] != "machine_end_beep" || sf.Meta()[frames.MetaStreamID] != "stream-1" {
+			t.Fatalf("unexpected amd frame %s %v", sf.Name(), sf.Meta())
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected amd_result frame")
+	}
+}
+
 func computeSignature(authToken, url string, params map[string]string) string {
 	keys := make([]string, 0, len(params))
 	for k := range params {
@@ -204,3 +308,176 @@ func computeSignature(authToken, url string, params map[string]string) string {
 	_, _ = mac.Write([]byte(base))
 	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
 }
+
+func TestTransferCall(t *testing.T) {
+	tr := New(Config{AccountSID: "AC123", AuthToken: "token", PublicURL: "voice.example.com"})
+	updater := &stubCallUpdater{}
+	creator := &stubCreator{sid: "CA-agent"}
+	tr.updateClient = updater
+	tr.createClient = creator
+
+	cold := transports.TransferOptions{Target: "+15550001", Announcement: "Connecting you now", CallerID: "+15559999"}
+	if err := tr.TransferCall(context.Background(), "CA123", cold); err != nil {
+		t.Fatalf("cold transfer error: %v", err)
+	}
+	want := `<Response><Say>Connecting you now</Say><Dial callerId="+15559999"><Number>+15550001</Number></Dial></Response>`
+	if updater.lastSID != "CA123" || updater.lastTwiml != want {
+		t.Fatalf("unexpected cold twiml %q", updater.lastTwiml)
+	}
+	if creator.last != nil {
+		t.Fatalf("cold transfer should not dial out")
+	}
+
+	warm := transports.TransferOptions{Target: "sip:agent@pbx.example.com", Mode: transports.TransferWarm, CallerID: "+15559999", Briefing: "Caller needs billing help"}
+	if err := tr.TransferCall(context.Background(), "CA123", warm); err != nil {
+		t.Fatalf("warm transfer error: %v", err)
+	}
+	if creator.last == nil || *creator.last.To != warm.Target || !strings.Contains(*creator.last.Twiml, "<Say>Caller needs billing help</Say>") || !strings.Contains(*creator.last.Twiml, ">transfer-CA123</Conference>") {
+		t.Fatalf("unexpected agent leg %+v", creator.last)
+	}
+	if !strings.Contains(updater.lastTwiml, `startConferenceOnEnter="false"`) || !strings.Contains(updater.lastTwiml, ">transfer-CA123</Conference>") {
+		t.Fatalf("unexpected caller twiml %q", updater.lastTwiml)
+	}
+	if creator.last.StatusCallback == nil || *creator.last.StatusCallback != "https://voice.example.com/transfer-status?caller=CA123" {
+		t.Fatalf("expected target leg status callback, got %v", creator.last.StatusCallback)
+	}
+	if creator.last.Timeout == nil || *creator.last.Timeout != 30 {
+		t.Fatalf("expected target leg ring timeout, got %v", creator.last.Timeout)
+	}
+
+	warm.CallerID = ""
+	if err := tr.TransferCall(context.Background(), "CA123", warm); err == nil {
+		t.Fatalf("expected caller id error for warm transfer")
+	}
+
+	local := New(Config{AccountSID: "AC123", AuthToken: "token"})
+	local.updateClient = updater
+	local.createClient = creator
+	warm.CallerID = "+15559999"
+	if err := local.TransferCall(context.Background(), "CA123", warm); err == nil {
+		t.Fatalf("expected warm transfer without public_url to be refused")
+	}
+}
+
+func TestTransferStatusReturnsCaller(t *testing.T) {
+	tr := New(Config{AccountSID: "AC123", PublicURL: "voice.example.com"})
+	updater := &stubCallUpdater{}
+	tr.updateClient = updater
+
+	post := func(status string) {
+		form := url.Values{"CallSid": {"CA-agent"}, "CallStatus": {status}}
+		req := httptest.NewRequest(http.MethodPost, "/transfer-status?caller=CA123", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		tr.handleTransferStatus(httptest.NewRecorder(), req)
+	}
+	post("completed")
+	if updater.lastSID != "" {
+		t.Fatalf("answered transfer should leave the caller alone, updated %q", updater.lastSID)
+	}
+	post("no-answer")
+	want := `<Response><Redirect method="POST">https://voice.example.com/voice?param_transfer_status=no-answer</Redirect></Response>`
+	if updater.lastSID != "CA123" || updater.lastTwiml != want {
+		t.Fatalf("expected caller back on the voice webhook, got %q %q", updater.lastSID, updater.lastTwiml)
+	}
+}
+
+func TestHangupCall(t *testing.T) {
+	tr := New(Config{AccountSID: "AC123", AuthToken: "token"})
+	stub := &stubCallUpdater{}
+	tr.updateClient = stub
+
+	if err := tr.HangupCall(context.Background(), "CA123"); err != nil {
+		t.Fatalf("HangupCall error: %v", err)
+	}
+	if stub.lastSID != "CA123" || stub.lastStatus != "completed" {
+		t.Fatalf("expected CA123 completed, got %q %q", stub.lastSID, stub.lastStatus)
+	}
+}
+
+func TestHandleVoiceStreamParameters(t *testing.T) {
+	tr := New(Config{PublicURL: "https://example.com", StreamParameters: map[string]string{"zip": "${CallerZip}"}})
+	form := url.Values{"From": {"+15550100"}, "To": {"+15550199"}, "Direction": {"outbound-api"}, "CallerZip": {"94105"}}
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/voice?param_campaign_id=spring&param_global_plan=gold", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	tr.handleVoice(w, req)
+
+	want := `<Response><Connect><Stream url="wss://example.com/ws">` +
+		`<Parameter name="Direction" value="outbound-api"/>` +
+		`<Parameter name="From" value="+15550100"/>` +
+		`<Parameter name="To" value="+15550199"/>` +
+		`<Parameter name="campaign_id" value="spring"/>` +
+		`<Parameter name="global_plan" value="gold"/>` +
+		`<Parameter name="zip" value="94105"/>` +
+		`</Stream></Connect></Response>`
+	if got := w.Body.String(); got != want {
+		t.Fatalf("unexpected twiml\n got: %s\nwant: %s", got, want)
+	}
+
+	meta := startMeta(&TwilioStart{CustomParameters: map[string]string{"From": "+15550100", "To": "+15550199", "Direction": "outbound-api", "campaign_id": "spring", "global_plan": "gold"}})
+	if meta[frames.MetaFromNumber] != "+15550100" || meta[frames.MetaToNumber] != "+15550199" || meta[frames.MetaCallDirection] != "outbound" {
+		t.Fatalf("unexpected call meta %v", meta)
+	}
+	if meta[frames.MetaParamPrefix+"campaign_id"] != "spring" || meta["global_plan"] != "gold" {
+		t.Fatalf("unexpected custom parameters %v", meta)
+	}
+}
+
+func TestDrainCallsRejectsNewCalls(t *testing.T) {
+	tr := New(Config{PublicURL: "https://example.com"})
+	voice := func() int {
+		w := httptest.NewRecorder()
+		tr.handleVoice(w, httptest.NewRequest(http.MethodPost, "https://example.com/voice", nil))
+		return w.Code
+	}
+	ready := func() int {
+		w := httptest.NewRecorder()
+		tr.handleReady(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		return w.Code
+	}
+	if voice() != http.StatusOK || ready() != http.StatusOK {
+		t.Fatalf("expected calls to be accepted before draining")
+	}
+	tr.DrainCalls()
+	if code := voice(); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 on /voice while draining, got %d", code)
+	}
+	if code := ready(); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected readiness to fail while draining, got %d", code)
+	}
+}
+
+func TestHandleVoiceAdmission(t *testing.T) {
+	tr := New(Config{PublicURL: "https://example.com"})
+	var decision transports.AdmissionDecision
+	var got transports.AdmissionRequest
+	tr.SetAdmission(func(req transports.AdmissionRequest) transports.AdmissionDecision {
+		got = req
+		return decision
+	})
+	voice := func() string {
+		form := url.Values{"CallSid": {"CA1"}, "Direction": {"inbound"}}
+		req := httptest.NewRequest(http.MethodPost, "https://example.com/voice?param_campaign_id=spring", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		tr.handleVoice(w, req)
+		return w.Body.String()
+	}
+
+	decision = transports.AdmissionDecision{Action: transports.AdmissionQueue, Message: "You are number 2 in line.", RetryAfter: 5 * time.Second}
+	want := `<Response><Say>You are number 2 in line.</Say><Pause length="5"/><Redirect method="POST">/voice?param_campaign_id=spring</Redirect></Response>`
+	if body := voice(); body != want {
+		t.Fatalf("unexpected queue twiml\n got: %s\nwant: %s", body, want)
+	}
+	if got.CallSID != "CA1" || got.Outbound || !got.CanHold {
+		t.Fatalf("unexpected admission request %+v", got)
+	}
+	decision = transports.AdmissionDecision{Action: transports.AdmissionReject}
+	if body := voice(); body != `<Response><Reject reason="busy"/></Response>` {
+		t.Fatalf("unexpected reject twiml %s", body)
+	}
+	decision = transports.AdmissionDecision{Action: transports.AdmissionAccept}
+	if body := voice(); !strings.Contains(body, "<Connect>") {
+		t.Fatalf("expected admitted call to connect, got %s", body)
+	}
+}
@@ -0,0 +1,166 @@
+package vonage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/transports"
+)
+
+// apiClient calls the Vonage Voice API with an application JWT.
+type apiClient struct {
+	baseURL string
+	appID   string
+	keyPEM  string
+	keyPath string
+	http    *http.Client
+
+	keyOnce sync.Once
+	key     *rsa.PrivateKey
+	keyErr  error
+}
+
+func newAPIClient(cfg Config) *apiClient {
+	return &apiClient{
+		baseURL: cfg.APIBaseURL,
+		appID:   cfg.ApplicationID,
+		keyPEM:  cfg.PrivateKey,
+		keyPath: cfg.PrivateKeyPath,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *apiClient) privateKey() (*rsa.PrivateKey, error) {
+	c.keyOnce.Do(func() {
+		data := []byte(c.keyPEM)
+		if len(data) == 0 && c.keyPath != "" {
+			data, c.keyErr = os.ReadFile(c.keyPath)
+			if c.keyErr != nil {
+				return
+			}
+		}
+		if len(data) == 0 {
+			c.keyErr = errors.New("missing vonage private key")
+			return
+		}
+		c.key, c.keyErr = parsePrivateKey(data)
+	})
+	return c.key, c.keyErr
+}
+
+// updateCall modifies an active call, e.g. {"action":"hangup"}.
+func (c *apiClient) updateCall(ctx context.Context, callUUID string, body any) error {
+	return c.do(ctx, http.MethodPut, "/v1/calls/"+url.PathEscape(callUUID), body, nil)
+}
+
+func (c *apiClient) do(ctx context.Context, method, path string, body any, out any) error {
+	if c.appID == "" {
+		return errors.New("missing vonage application id")
+	}
+	key, err := c.privateKey()
+	if err != nil {
+		return err
+	}
+	token, err := appToken(c.appID, key, time.Now())
+	if err != nil {
+		return err
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("vonage api %s %s: status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+// Dialer provides outbound call creation via the Vonage Voice API.
+type Dialer struct {
+	cfg Config
+	api *apiClient
+}
+
+// NewDialer creates a new Vonage dialer.
+func NewDialer(cfg Config) *Dialer {
+	cfg = cfg.withDefaults()
+	return &Dialer{cfg: cfg, api: newAPIClient(cfg)}
+}
+
+// Dial places an outbound call using Vonage.
+func (d *Dialer) Dial(ctx context.Context, to, from, url string) (string, error) {
+	return d.DialWithOptions(ctx, to, from, url, transports.DialOptions{})
+}
+
+// DialWithOptions places an outbound call. url overrides the answer URL;
+// SendDigits become dtmfAnswer, played when the callee picks up.
+func (d *Dialer) DialWithOptions(ctx context.Context, to, from, url string, opts transports.DialOptions) (string, error) {
+	if to == "" || from == "" {
+		return "", errors.New("to/from required")
+	}
+	if url == "" {
+		url = d.publicURL(d.cfg.AnswerPath)
+	}
+	to = strings.TrimPrefix(to, "+")
+	endpoint := map[string]any{"type": "phone", "number": to}
+	if digits := strings.TrimSpace(opts.SendDigits); digits != "" {
+		endpoint["dtmfAnswer"] = digits
+	}
+	body := map[string]any{
+		"to":            []any{endpoint},
+		"from":          map[string]any{"type": "phone", "number": strings.TrimPrefix(from, "+")},
+		"answer_url":    []string{url},
+		"event_url":     []string{d.publicURL(d.cfg.EventPath)},
+		"answer_method": http.MethodGet,
+	}
+	var resp struct {
+		UUID string `json:"uuid"`
+	}
+	if err := d.api.do(ctx, http.MethodPost, "/v1/calls", body, &resp); err != nil {
+		return "", err
+	}
+	if resp.UUID == "" {
+		return "", errors.New("missing call uuid")
+	}
+	return resp.UUID, nil
+}
+
+func (d *Dialer) publicURL(path string) string {
+	if d.cfg.PublicURL != "" {
+		return "https://" + normalizePublicURL(d.cfg.PublicURL) + path
+	}
+	addr := d.cfg.ServerAddr
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+	return "http://" + addr + path
+}
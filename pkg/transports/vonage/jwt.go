@@ -0,0 +1,117 @@
+package vonage
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Vonage uses two kinds of JWT: webhooks are signed HS256 with the account
+// signature secret, and REST calls carry an RS256 token minted from the
+// application's private key. Both are small enough to handle with stdlib.
+
+var b64 = base64.RawURLEncoding
+
+// jwtTolerance bounds clock skew and replay for signed webhooks.
+const jwtTolerance = 5 * time.Minute
+
+var errBadToken = errors.New("vonage: invalid webhook signature")
+
+// verifyWebhookToken checks an HS256 webhook JWT and, when present, that its
+// payload_hash matches body.
+func verifyWebhookToken(token, secret string, body []byte, now time.Time) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || secret == "" {
+		return errBadToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return errBadToken
+	}
+	sig, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return errBadToken
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return errBadToken
+	}
+	var claims struct {
+		IAT         int64  `json:"iat"`
+		PayloadHash string `json:"payload_hash"`
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return errBadToken
+	}
+	if age := now.Sub(time.Unix(claims.IAT, 0)); age > jwtTolerance || age < -jwtTolerance {
+		return errBadToken
+	}
+	if claims.PayloadHash != "" {
+		sum := sha256.Sum256(body)
+		if subtle.ConstantTimeCompare([]byte(strings.ToLower(claims.PayloadHash)), []byte(hex.EncodeToString(sum[:]))) != 1 {
+			return errBadToken
+		}
+	}
+	return nil
+}
+
+// appToken mints the RS256 application JWT used for Voice API requests.
+func appToken(appID string, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"application_id": appID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(15 * time.Minute).Unix(),
+		"jti":            uuid.NewString(),
+	})
+	signing := b64.EncodeToString(header) + "." + b64.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signing))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return signing + "." + b64.EncodeToString(sig), nil
+}
+
+func parsePrivateKey(pemData []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("vonage: private key is not PEM")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("vonage: private key is not RSA")
+	}
+	return key, nil
+}
+
+func decodeSegment(seg string, out any) error {
+	b, err := b64.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
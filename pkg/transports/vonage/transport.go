@@ -0,0 +1,844 @@
+package vonage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/harunnryd/ranya/pkg/audio"
+	"github.com/harunnryd/ranya/pkg/errorsx"
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/transports"
+)
+
+type Config struct {
+	ServerAddr string `mapstructure:"server_addr"`
+	PublicURL  string `mapstructure:"public_url"`
+	// SignatureSecret verifies signed webhooks (HS256 JWT in the
+	// Authorization header). Without it every webhook is rejected unless
+	// InsecureSkipVerify is set.
+	SignatureSecret string `mapstructure:"signature_secret"`
+	// InsecureSkipVerify accepts unsigned webhooks when SignatureSecret is
+	// empty. Use it for local testing only.
+	InsecureSkipVerify bool `mapstructure:"insecure_skip_verify"`
+	// ApplicationID and PrivateKey (PEM) or PrivateKeyPath authenticate Voice
+	// API requests for outbound calls and DTMF.
+	ApplicationID  string `mapstructure:"application_id"`
+	PrivateKey     string `mapstructure:"private_key"`
+	PrivateKeyPath string `mapstructure:"private_key_path"`
+	AnswerPath     string `mapstructure:"answer_path"`
+	EventPath      string `mapstructure:"event_path"`
+	WebsocketPath  string `mapstructure:"ws_path"`
+	APIBaseURL     string `mapstructure:"api_base_url"`
+	// SampleRate of the linear PCM stream: 16000 (default) or 8000.
+	SampleRate     int      `mapstructure:"sample_rate"`
+	AllowAnyOrigin bool     `mapstructure:"allow_any_origin"`
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+}
+
+func (c Config) withDefaults() Config {
+	if c.ServerAddr == "" {
+		c.ServerAddr = ":8080"
+	}
+	if c.AnswerPath == "" {
+		c.AnswerPath = "/answer"
+	}
+	if c.EventPath == "" {
+		c.EventPath = "/event"
+	}
+	if c.WebsocketPath == "" {
+		c.WebsocketPath = "/ws"
+	}
+	if c.APIBaseURL == "" {
+		c.APIBaseURL = "https://api.nexmo.com"
+	}
+	c.APIBaseURL = strings.TrimRight(c.APIBaseURL, "/")
+	if c.SampleRate != 8000 {
+		c.SampleRate = 16000
+	}
+	if !c.AllowAnyOrigin && len(c.AllowedOrigins) == 0 {
+		c.AllowAnyOrigin = true
+	}
+	return c
+}
+
+// Headers passed through the NCCO websocket endpoint; Vonage echoes them in
+// the websocket:connected message.
+const (
+	headerCallUUID = "call_uuid"
+	headerFrom     = "from"
+	headerTo       = "to"
+)
+
+// carryFlushDelay is how long a partial 20ms frame waits for more audio
+// before it is padded with silence and sent.
+const carryFlushDelay = 100 * time.Millisecond
+
+type Transport struct {
+	cfg      Config
+	server   *http.Server
+	upgrader websocket.Upgrader
+	recvCh   chan frames.Frame
+	api      *apiClient
+
+	mu          sync.Mutex
+	sessions    map[string]*session
+	callStreams map[string]string
+	// dialed holds UUIDs of calls placed by DialWithOptions.
+	dialed    map[string]bool
+	admission transports.AdmissionFunc
+	handlers  sync.WaitGroup
+
+	draining    atomic.Bool
+	rejectCalls atomic.Bool
+	stopOnce    sync.Once
+}
+
+func New(cfg Config) *Transport {
+	cfg = cfg.withDefaults()
+	t := &Transport{
+		cfg: cfg,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  4096,
+			WriteBufferSize: 4096,
+		},
+		recvCh:      make(chan frames.Frame, 512),
+		api:         newAPIClient(cfg),
+		sessions:    make(map[string]*session),
+		callStreams: make(map[string]string),
+		dialed:      make(map[string]bool),
+	}
+	t.upgrader.CheckOrigin = t.checkOrigin
+	return t
+}
+
+func (t *Transport) Name() string { return "vonage" }
+
+func (t *Transport) Recv() <-chan frames.Frame { return t.recvCh }
+
+func (t *Transport) ReadyFields() map[string]any {
+	return map[string]any{
+		"answer_url": t.publicHTTPURL(t.cfg.AnswerPath),
+		"event_url":  t.publicHTTPURL(t.cfg.EventPath),
+	}
+}
+
+func (t *Transport) Start(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if t.cfg.SignatureSecret == "" {
+		if t.cfg.InsecureSkipVerify {
+			slog.Warn("vonage_webhook_verification_disabled", "detail", "unsigned webhooks are accepted; set signature_secret in production")
+		} else {
+			slog.Warn("vonage_signature_secret_missing", "detail", "all webhooks are rejected until signature_secret is set")
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(t.cfg.AnswerPath, t.handleAnswer)
+	mux.HandleFunc(t.cfg.EventPath, t.handleEvent)
+	mux.Handle(t.cfg.WebsocketPath, t)
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/ready", t.handleReady)
+	t.server = &http.Server{
+		Addr:              t.cfg.ServerAddr,
+		ReadHeaderTimeout: 5 * time.Second,
+		Handler:           mux,
+	}
+	go func() {
+		<-ctx.Done()
+		_ = t.server.Close()
+	}()
+	go func() {
+		if err := t.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("vonage_transport_server_error", "error", err.Error())
+		}
+	}()
+	return nil
+}
+
+func (t *Transport) Stop() error {
+	t.stopOnce.Do(func() {
+		t.mu.Lock()
+		t.draining.Store(true)
+		sessions := t.sessions
+		t.sessions = make(map[string]*session)
+		t.mu.Unlock()
+		if t.server != nil {
+			_ = t.server.Close()
+		}
+		for _, sess := range sessions {
+			_ = sess.close()
+		}
+		done := make(chan struct{})
+		go func() {
+			t.handlers.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			close(t.recvCh)
+		case <-time.After(2 * time.Second):
+			slog.Warn("vonage_transport_stop_timeout")
+		}
+	})
+	return nil
+}
+
+// DrainCalls makes the answer webhook fail with 503 so new calls go to
+// another instance. Calls in progress carry on.
+func (t *Transport) DrainCalls() {
+	t.rejectCalls.Store(true)
+}
+
+func (t *Transport) handleReady(w http.ResponseWriter, r *http.Request) {
+	if t.rejectCalls.Load() || t.draining.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// ServeHTTP runs one Vonage websocket leg.
+func (t *Transport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t.mu.Lock()
+	if t.draining.Load() {
+		t.mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	t.handlers.Add(1)
+	t.mu.Unlock()
+	defer t.handlers.Done()
+
+	conn, err := t.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	sess := &session{conn: conn, sendCh: make(chan outbound, 256), rate: t.cfg.SampleRate}
+	go sess.loop()
+	defer func() { _ = sess.close() }()
+
+	reason := "failed"
+	for {
+		typ, data, err := conn.ReadMessage()
+		if err != nil {
+			// Vonage closes the socket normally when the call ends; the
+			// event webhook may refine the reason if it arrives first.
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				reason = "completed"
+			}
+			break
+		}
+		if typ == websocket.BinaryMessage {
+			if sess.streamID == "" {
+				continue
+			}
+			meta := sess.frameMeta()
+			meta[frames.MetaEncoding] = "pcm16"
+			meta[frames.MetaCodec] = "linear16"
+			meta[frames.MetaFormat] = "pcm16_" + strconv.Itoa(sess.rate) + "_1ch"
+			af := frames.NewAudioFrameFromPool(sess.streamID, time.Now().UnixNano(), data, sess.rate, 1, meta)
+			if !nonBlockingSend(t.recvCh, af) {
+				frames.ReleaseAudioFrame(af)
+			}
+			continue
+		}
+		var evt Event
+		if err := json.Unmarshal(data, &evt); err != nil {
+			continue
+		}
+		t.handleStreamEvent(sess, evt, data)
+	}
+	if sess.streamID != "" {
+		t.endStream(sess.streamID, reason)
+	}
+}
+
+func (t *Transport) handleStreamEvent(sess *session, evt Event, raw []byte) {
+	switch evt.Event {
+	case "websocket:connected":
+		if sess.streamID != "" {
+			return
+		}
+		var headers map[string]any
+		_ = json.Unmarshal(raw, &headers)
+		sess.callSID = stringField(headers, headerCallUUID)
+		sess.from = stringField(headers, headerFrom)
+		if rate := contentTypeRate(evt.ContentType); rate > 0 && rate%8000 == 0 {
+			sess.rate = rate
+		}
+		sess.streamID = uuid.NewString()
+		sess.traceID = uuid.NewString()
+		t.mu.Lock()
+		oldStream := ""
+		sess.direction = "inbound"
+		if sess.callSID != "" {
+			oldStream = t.callStreams[sess.callSID]
+			t.callStreams[sess.callSID] = sess.streamID
+			if t.dialed[sess.callSID] {
+				sess.direction = "outbound"
+			}
+		}
+		t.sessions[sess.streamID] = sess
+		old := t.sessions[oldStream]
+		delete(t.sessions, oldStream)
+		t.mu.Unlock()
+		if old != nil {
+			_ = old.close()
+		}
+		nonBlockingSend(t.recvCh, frames.NewSystemFrame(sess.streamID, time.Now().UnixNano(), "call_start", sess.frameMeta()))
+		if oldStream != "" {
+			meta := sess.frameMeta()
+			meta[frames.MetaOldStreamID] = oldStream
+			nonBlockingSend(t.recvCh, frames.NewSystemFrame(sess.streamID, time.Now().UnixNano(), "call_reconnect", meta))
+		}
+	case "websocket:dtmf":
+		if sess.streamID == "" || evt.Digit == "" {
+			return
+		}
+		meta := sess.frameMeta()
+		meta[frames.MetaDTMFDigit] = evt.Digit
+		nonBlockingSend(t.recvCh, frames.NewControlFrame(sess.streamID, time.Now().UnixNano(), frames.ControlDTMF, meta))
+	case "websocket:notify":
+		// Notify payloads come back once queued audio ahead of them has
+		// played, which is how marks are implemented on Vonage.
+		if sess.streamID == "" || evt.Payload == nil {
+			return
+		}
+		meta := sess.frameMeta()
+		meta[frames.MetaMarkName] = evt.Payload.Name
+		nonBlockingSend(t.recvCh, frames.NewControlFrame(sess.streamID, time.Now().UnixNano(), frames.ControlAudioReady, meta))
+	}
+}
+
+// endStream emits call_end once; the event webhook and the socket closing
+// race, and whichever arrives first wins.
+func (t *Transport) endStream(streamID, reason string) {
+	t.mu.Lock()
+	sess := t.sessions[streamID]
+	delete(t.sessions, streamID)
+	if sess != nil && sess.callSID != "" && t.callStreams[sess.callSID] == streamID {
+		delete(t.callStreams, sess.callSID)
+		delete(t.dialed, sess.callSID)
+	}
+	t.mu.Unlock()
+	if sess == nil {
+		return
+	}
+	meta := sess.frameMeta()
+	meta[frames.MetaCallEndReason] = reason
+	if !transports.SendCallEnd(t.recvCh, frames.NewSystemFrame(streamID, time.Now().UnixNano(), "call_end", meta)) {
+		slog.Warn("vonage_call_end_dropped", "call_sid", sess.callSID, "stream_id", streamID)
+	}
+	_ = sess.close()
+}
+
+// Send writes pipeline audio as fixed-size linear PCM frames. μ-law 8kHz
+// (the default TTS output) is decoded and resampled; other audio should
+// already be PCM16 at the configured rate.
+func (t *Transport) Send(f frames.Frame) error {
+	sess := t.session(frames.StreamIDOf(f))
+	if sess == nil {
+		return nil
+	}
+	switch f.Kind() {
+	case frames.KindAudio:
+		af := f.(frames.AudioFrame)
+		pcm, ok := toPCM16(af, sess.rate)
+		if !ok {
+			slog.Debug("vonage_unsupported_audio", "stream_id", sess.streamID, "encoding", af.Metadata().Get(frames.MetaEncoding), "rate", af.Rate())
+			return nil
+		}
+		sess.writeAudio(pcm)
+	case frames.KindControl:
+		cf := f.(frames.ControlFrame)
+		switch cf.Code() {
+		case frames.ControlFlush, frames.ControlCancel, frames.ControlStartInterruption:
+			sess.dropCarry()
+			return sess.enqueueJSON(map[string]any{"action": "clear"})
+		case frames.ControlAudioReady:
+			if cf.Metadata().Get(frames.MetaSource) == "transport" || cf.Metadata().Get(frames.MetaMarkName) != "" {
+				return nil
+			}
+			// The mark must follow all audio sent before it.
+			sess.flushCarry()
+			name := "m-" + strconv.FormatUint(sess.marks.Add(1), 10)
+			return sess.enqueueJSON(map[string]any{"action": "notify", "payload": map[string]string{"name": name}})
+		}
+	}
+	return nil
+}
+
+// Dial places an outbound call through the Voice API.
+func (t *Transport) Dial(ctx context.Context, to, from, url string) (string, error) {
+	return t.DialWithOptions(ctx, to, from, url, transports.DialOptions{})
+}
+
+// DialWithOptions places an outbound call with optional settings.
+func (t *Transport) DialWithOptions(ctx context.Context, to, from, url string, opts transports.DialOptions) (string, error) {
+	dialer := NewDialer(t.cfg)
+	dialer.api = t.api
+	callSID, err := dialer.DialWithOptions(ctx, to, from, url, opts)
+	if err != nil {
+		return "", err
+	}
+	t.mu.Lock()
+	t.dialed[callSID] = true
+	t.mu.Unlock()
+	return callSID, nil
+}
+
+// SendDTMF plays DTMF digits into an active call through the Voice API.
+func (t *Transport) SendDTMF(ctx context.Context, callSID, digits string) error {
+	if strings.TrimSpace(callSID) == "" {
+		return errors.New("call sid required")
+	}
+	if strings.TrimSpace(digits) == "" {
+		return errors.New("digits required")
+	}
+	return t.api.do(ctx, http.MethodPut, "/v1/calls/"+url.PathEscape(callSID)+"/dtmf", map[string]string{"digits": digits}, nil)
+}
+
+// HangupCall ends an active call through the Voice API.
+func (t *Transport) HangupCall(ctx context.Context, callSID string) error {
+	if strings.TrimSpace(callSID) == "" {
+		return errors.New("call sid required")
+	}
+	return t.api.updateCall(ctx, callSID, map[string]string{"action": "hangup"})
+}
+
+// handleAnswer returns an NCCO connecting the call to our websocket.
+func (t *Transport) handleAnswer(w http.ResponseWriter, r *http.Request) {
+	if t.rejectCalls.Load() {
+		slog.Info("vonage_call_rejected", "reason", "draining")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, ok := t.verifiedBody(r)
+	if !ok {
+		slog.Warn("vonage_invalid_signature", "reason_code", string(errorsx.ReasonTransportInvalidSignature))
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	var params WebhookEvent
+	if r.Method == http.MethodPost && len(body) > 0 {
+		_ = json.Unmarshal(body, &params)
+	} else {
+		q := r.URL.Query()
+		params.UUID = q.Get("uuid")
+		params.From = q.Get("from")
+		params.To = q.Get("to")
+		params.ConversationUUID = q.Get("conversation_uuid")
+	}
+	if !t.admit(w, params) {
+		return
+	}
+	ncco := []map[string]any{{
+		"action":   "connect",
+		"eventUrl": []string{t.publicHTTPURL(t.cfg.EventPath)},
+		"endpoint": []map[string]any{{
+			"type":         "websocket",
+			"uri":          t.websocketURL(r),
+			"content-type": "audio/l16;rate=" + strconv.Itoa(t.cfg.SampleRate),
+			"headers": map[string]string{
+				headerCallUUID: params.UUID,
+				headerFrom:     params.From,
+				headerTo:       params.To,
+			},
+		}},
+	}}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(ncco)
+}
+
+// SetAdmission makes the answer webhook consult fn before connecting a call.
+// Callers over capacity hear the overflow message, if any, and are hung up;
+// Vonage calls are never queued.
+func (t *Transport) SetAdmission(fn transports.AdmissionFunc) {
+	t.mu.Lock()
+	t.admission = fn
+	t.mu.Unlock()
+}
+
+// admit applies the admission decision to an answer webhook. When the call
+// may not connect it writes the overflow response and returns false.
+func (t *Transport) admit(w http.ResponseWriter, params WebhookEvent) bool {
+	t.mu.Lock()
+	fn := t.admission
+	t.mu.Unlock()
+	if fn == nil {
+		return true
+	}
+	decision := fn(transports.AdmissionRequest{
+		CallSID:  params.UUID,
+		Outbound: strings.HasPrefix(params.Direction, "outbound"),
+	})
+	if decision.Action == transports.AdmissionAccept {
+		return true
+	}
+	slog.Info("vonage_call_overflow", "call_sid", params.UUID, "action", string(decision.Action))
+	if msg := strings.TrimSpace(decision.Message); msg != "" && decision.Action == transports.AdmissionMessage {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode([]map[string]any{{"action": "talk", "text": msg}})
+		return false
+	}
+	w.WriteHeader(http.StatusServiceUnavailable)
+	return false
+}
+
+// handleEvent maps terminal call statuses to call_end.
+func (t *Transport) handleEvent(w http.ResponseWriter, r *http.Request) {
+	body, ok := t.verifiedBody(r)
+	if !ok {
+		slog.Warn("vonage_event_invalid_signature", "reason_code", string(errorsx.ReasonTransportInvalidSignature))
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	var evt WebhookEvent
+	if err := json.Unmarshal(body, &evt); err != nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	reason := normalizeCallEndReason(evt.Status)
+	if reason != "" && evt.UUID != "" {
+		if streamID := t.streamForCall(evt.UUID); streamID != "" {
+			t.endStream(streamID, reason)
+		}
+		// Unanswered dials never open a stream.
+		t.mu.Lock()
+		delete(t.dialed, evt.UUID)
+		t.mu.Unlock()
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// verifiedBody reads the body and checks the signed-webhook JWT. Without a
+// signature secret it accepts the body only when InsecureSkipVerify is set.
+func (t *Transport) verifiedBody(r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return nil, false
+	}
+	if t.cfg.SignatureSecret == "" {
+		return body, t.cfg.InsecureSkipVerify
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	if err := verifyWebhookToken(token, t.cfg.SignatureSecret, body, time.Now()); err != nil {
+		return nil, false
+	}
+	return body, true
+}
+
+func (t *Transport) websocketURL(r *http.Request) string {
+	if t.cfg.PublicURL != "" {
+		return "wss://" + normalizePublicURL(t.cfg.PublicURL) + t.cfg.WebsocketPath
+	}
+	host := r.Host
+	if host == "" {
+		host = strings.TrimPrefix(t.cfg.ServerAddr, ":")
+	}
+	return "wss://" + host + t.cfg.WebsocketPath
+}
+
+func (t *Transport) publicHTTPURL(path string) string {
+	if t.cfg.PublicURL != "" {
+		return "https://" + normalizePublicURL(t.cfg.PublicURL) + path
+	}
+	addr := t.cfg.ServerAddr
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+	return "http://" + addr + path
+}
+
+func (t *Transport) session(streamID string) *session {
+	if streamID == "" {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessions[streamID]
+}
+
+func (t *Transport) streamForCall(callSID string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.callStreams[callSID]
+}
+
+func (t *Transport) checkOrigin(r *http.Request) bool {
+	if t.cfg.AllowAnyOrigin {
+		return true
+	}
+	origin := strings.TrimRight(strings.TrimSpace(r.Header.Get("Origin")), "/")
+	if origin == "" {
+		return true
+	}
+	originHost := strings.TrimPrefix(strings.TrimPrefix(origin, "https://"), "http://")
+	for _, allowed := range t.cfg.AllowedOrigins {
+		a := strings.TrimRight(strings.TrimSpace(allowed), "/")
+		if a == "" {
+			continue
+		}
+		if strings.HasPrefix(a, "http://") || strings.HasPrefix(a, "https://") {
+			if strings.EqualFold(a, origin) {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(a, originHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeCallEndReason maps Vonage call statuses onto the reasons used by
+// every transport. Non-terminal statuses return "".
+func normalizeCallEndReason(raw string) string {
+	r := strings.ToLower(strings.TrimSpace(raw))
+	switch r {
+	case "", "started", "ringing", "answered", "human", "machine", "input", "transfer", "record", "disconnected":
+		return ""
+	case "completed":
+		return "completed"
+	case "busy":
+		return "busy"
+	case "unanswered", "timeout":
+		return "no_answer"
+	case "cancelled", "rejected", "failed", "transport_closed":
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+func normalizePublicURL(v string) string {
+	v = strings.TrimPrefix(strings.TrimPrefix(v, "https://"), "http://")
+	return strings.TrimRight(v, "/")
+}
+
+// contentTypeRate parses the rate from "audio/l16;rate=16000".
+func contentTypeRate(ct string) int {
+	for _, p := range strings.Split(ct, ";") {
+		k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if ok && strings.EqualFold(k, "rate") {
+			if n, err := strconv.Atoi(v); err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+func stringField(m map[string]any, key string) string {
+	if v, ok := m[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// toPCM16 returns little-endian PCM16 at rate for an outbound frame. μ-law
+// is only upsampled to whole multiples of 8kHz; other rates are refused.
+func toPCM16(af frames.AudioFrame, rate int) ([]byte, bool) {
+	switch audio.EncodingOf(af) {
+	case audio.EncodingPCM16:
+		if af.Rate() != rate {
+			return nil, false
+		}
+		return af.RawPayload(), true
+	case audio.EncodingMuLaw:
+		if af.Rate() != 8000 || rate < 8000 || rate%8000 != 0 {
+			return nil, false
+		}
+		pcm, _ := audio.DecodeFrame(af)
+		return audio.EncodePCM16(audio.Resample(pcm, 8000, rate)), true
+	}
+	return nil, false
+}
+
+type outbound struct {
+	typ  int
+	data []byte
+}
+
+type session struct {
+	conn   *websocket.Conn
+	sendCh chan outbound
+	mu     sync.Mutex
+	closed atomic.Bool
+	marks  atomic.Uint64
+
+	// Set on websocket:connected by the read goroutine before the session
+	// is registered, then read-only.
+	streamID  string
+	callSID   string
+	traceID   string
+	from      string
+	direction string
+	rate      int
+
+	// carry holds the tail of the last frame, shorter than one 20ms
+	// frame, until more audio completes it or carryTimer flushes it.
+	audioMu    sync.Mutex
+	carry      []byte
+	carryTimer *time.Timer
+}
+
+func (s *session) frameMeta() map[string]string {
+	meta := map[string]string{
+		frames.MetaStreamID: s.streamID,
+		frames.MetaTraceID:  s.traceID,
+		frames.MetaSource:   "transport",
+	}
+	if s.callSID != "" {
+		meta[frames.MetaCallSID] = s.callSID
+	}
+	if s.from != "" {
+		meta[frames.MetaFromNumber] = s.from
+	}
+	if s.direction != "" {
+		meta[frames.MetaCallDirection] = s.direction
+	}
+	return meta
+}
+
+// writeAudio sends pcm as whole 20ms frames, carrying any remainder over to
+// the next frame so that TTS chunk boundaries do not add silence.
+func (s *session) writeAudio(pcm []byte) {
+	s.audioMu.Lock()
+	defer s.audioMu.Unlock()
+	frameSize := s.rate / 50 * 2
+	if len(s.carry) > 0 {
+		pcm = append(s.carry, pcm...)
+		s.carry = nil
+	}
+	for len(pcm) >= frameSize {
+		s.push(outbound{typ: websocket.BinaryMessage, data: append([]byte(nil), pcm[:frameSize]...)})
+		pcm = pcm[frameSize:]
+	}
+	if len(pcm) == 0 {
+		return
+	}
+	s.carry = append([]byte(nil), pcm...)
+	if s.carryTimer == nil {
+		s.carryTimer = time.AfterFunc(carryFlushDelay, s.flushCarry)
+	} else {
+		s.carryTimer.Reset(carryFlushDelay)
+	}
+}
+
+// flushCarry pads the carried partial frame with silence and sends it.
+func (s *session) flushCarry() {
+	s.audioMu.Lock()
+	defer s.audioMu.Unlock()
+	if len(s.carry) == 0 {
+		return
+	}
+	chunk := make([]byte, s.rate/50*2)
+	copy(chunk, s.carry)
+	s.carry = nil
+	s.push(outbound{typ: websocket.BinaryMessage, data: chunk})
+}
+
+// dropCarry discards the carried partial frame, used on barge-in.
+func (s *session) dropCarry() {
+	s.audioMu.Lock()
+	s.carry = nil
+	if s.carryTimer != nil {
+		s.carryTimer.Stop()
+	}
+	s.audioMu.Unlock()
+}
+
+func (s *session) enqueueJSON(msg map[string]any) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.push(outbound{typ: websocket.TextMessage, data: b})
+	return nil
+}
+
+func (s *session) push(msg outbound) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed.Load() {
+		return
+	}
+	select {
+	case s.sendCh <- msg:
+	default:
+	}
+}
+
+func (s *session) loop() {
+	for msg := range s.sendCh {
+		_ = s.conn.WriteMessage(msg.typ, msg.data)
+	}
+	_ = s.conn.Close()
+}
+
+func (s *session) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed.CompareAndSwap(false, true) {
+		close(s.sendCh)
+	}
+	return nil
+}
+
+// Event is a JSON message on the Vonage websocket.
+type Event struct {
+	Event       string         `json:"event"`
+	ContentType string         `json:"content-type,omitempty"`
+	Digit       string         `json:"digit,omitempty"`
+	Duration    int            `json:"duration,omitempty"`
+	Payload     *NotifyPayload `json:"payload,omitempty"`
+}
+
+type NotifyPayload struct {
+	Name string `json:"name"`
+}
+
+// WebhookEvent is an answer or event webhook body.
+type WebhookEvent struct {
+	UUID             string `json:"uuid"`
+	ConversationUUID string `json:"conversation_uuid"`
+	Status           string `json:"status"`
+	Direction        string `json:"direction"`
+	From             string `json:"from"`
+	To               string `json:"to"`
+	Timestamp        string `json:"timestamp"`
+}
+
+func nonBlockingSend(ch chan frames.Frame, f frames.Frame) bool {
+	select {
+	case ch <- f:
+		return true
+	default:
+		return false
+	}
+}
+
+var _ transports.Transport = (*Transport)(nil)
+var _ transports.DTMFSender = (*Transport)(nil)
+var _ transports.CallHanger = (*Transport)(nil)
+var _ transports.OutboundDialerWithOptions = (*Transport)(nil)
+var _ transports.ReadyReporter = (*Transport)(nil)
+var _ transports.CallDrainer = (*Transport)(nil)
+var _ transports.CallAdmitter = (*Transport)(nil)
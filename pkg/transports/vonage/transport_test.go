@@ -0,0 +1,304 @@
+package vonage
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/transports"
+)
+
+func recvFrame(t *testing.T, tr *Transport) frames.Frame {
+	t.Helper()
+	select {
+	case f := <-tr.Recv():
+		return f
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for frame")
+		return nil
+	}
+}
+
+func signToken(t *testing.T, secret string, body []byte, iat time.Time) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	sum := sha256.Sum256(body)
+	claims, _ := json.Marshal(map[string]any{"iat": iat.Unix(), "payload_hash": hex.EncodeToString(sum[:])})
+	signing := b64.EncodeToString(header) + "." + b64.EncodeToString(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signing))
+	return signing + "." + b64.EncodeToString(mac.Sum(nil))
+}
+
+func TestSignedAnswerReturnsNCCO(t *testing.T) {
+	tr := New(Config{SignatureSecret: "shh", PublicURL: "https://example.com"})
+	body := []byte(`{"uuid":"call-1","from":"14155550100","to":"14155550101"}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/answer", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+signToken(t, "shh", body, time.Now()))
+	w := httptest.NewRecorder()
+	tr.handleAnswer(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var ncco []struct {
+		Action   string   `json:"action"`
+		EventURL []string `json:"eventUrl"`
+		Endpoint []struct {
+			Type        string            `json:"type"`
+			URI         string            `json:"uri"`
+			ContentType string            `json:"content-type"`
+			Headers     map[string]string `json:"headers"`
+		} `json:"endpoint"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &ncco); err != nil || len(ncco) != 1 || len(ncco[0].Endpoint) != 1 {
+		t.Fatalf("unexpected ncco %s err=%v", w.Body.String(), err)
+	}
+	ep := ncco[0].Endpoint[0]
+	if ncco[0].Action != "connect" || ep.URI != "wss://example.com/ws" || ep.ContentType != "audio/l16;rate=16000" || ep.Headers[headerCallUUID] != "call-1" {
+		t.Fatalf("unexpected ncco %s", w.Body.String())
+	}
+	if len(ncco[0].EventURL) != 1 || ncco[0].EventURL[0] != "https://example.com/event" {
+		t.Fatalf("unexpected event url %v", ncco[0].EventURL)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/answer", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+signToken(t, "shh", body, time.Now().Add(-time.Hour)))
+	w = httptest.NewRecorder()
+	tr.handleAnswer(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for stale token, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/answer", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+signToken(t, "shh", []byte(`{}`), time.Now()))
+	w = httptest.NewRecorder()
+	tr.handleAnswer(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for payload hash mismatch, got %d", w.Code)
+	}
+}
+
+func TestWebsocketRoundTrip(t *testing.T) {
+	tr := New(Config{})
+	srv := httptest.NewServer(tr)
+	t.Cleanup(func() {
+		srv.Close()
+		_ = tr.Stop()
+	})
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"event":"websocket:connected","content-type":"audio/l16;rate=16000","call_uuid":"call-1","from":"14155550100"}`))
+	start := recvFrame(t, tr).(frames.SystemFrame)
+	md := start.Metadata()
+	if start.Name() != "call_start" || md.CallSID() != "call-1" || md.Get(frames.MetaFromNumber) != "14155550100" || md.Get(frames.MetaCallDirection) != "inbound" {
+		t.Fatalf("unexpected call_start %v", start.Meta())
+	}
+	streamID := md.StreamID()
+
+	_ = conn.WriteMessage(websocket.BinaryMessage, make([]byte, 640))
+	af := recvFrame(t, tr).(frames.AudioFrame)
+	if len(af.RawPayload()) != 640 || af.Rate() != 16000 || af.Metadata().Get(frames.MetaEncoding) != "pcm16" {
+		t.Fatalf("unexpected audio frame len=%d meta=%v", len(af.RawPayload()), af.Meta())
+	}
+	frames.ReleaseAudioFrame(af)
+
+	_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"event":"websocket:dtmf","digit":"5","duration":250}`))
+	if cf := recvFrame(t, tr).(frames.ControlFrame); cf.Code() != frames.ControlDTMF || cf.Metadata().Get(frames.MetaDTMFDigit) != "5" {
+		t.Fatalf("unexpected dtmf frame %v", cf.Meta())
+	}
+
+	// 80 μ-law samples at 8kHz become 160 PCM16 samples at 16kHz, padded
+	// to one 20ms frame.
+	out := map[string]string{frames.MetaStreamID: streamID, frames.MetaEncoding: "mulaw"}
+	_ = tr.Send(frames.NewAudioFrame(streamID, 1, bytes.Repeat([]byte{0xFF}, 80), 8000, 1, out))
+	_ = tr.Send(frames.NewControlFrame(streamID, 2, frames.ControlAudioReady, map[string]string{frames.MetaStreamID: streamID}))
+	_ = tr.Send(frames.NewControlFrame(streamID, 3, frames.ControlStartInterruption, map[string]string{frames.MetaStreamID: streamID}))
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	typ, data, err := conn.ReadMessage()
+	if err != nil || typ != websocket.BinaryMessage || len(data) != 640 {
+		t.Fatalf("expected 640-byte binary frame, got type=%d len=%d err=%v", typ, len(data), err)
+	}
+	if s := int16(binary.LittleEndian.Uint16(data[2:])); s != 0 {
+		t.Fatalf("expected silence, got %d", s)
+	}
+	var notify, clear map[string]any
+	if err := conn.ReadJSON(&notify); err != nil || notify["action"] != "notify" {
+		t.Fatalf("expected notify, got %v err=%v", notify, err)
+	}
+	if err := conn.ReadJSON(&clear); err != nil || clear["action"] != "clear" {
+		t.Fatalf("expected clear, got %v err=%v", clear, err)
+	}
+
+	name := notify["payload"].(map[string]any)["name"].(string)
+	_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"event":"websocket:notify","payload":{"name":"`+name+`"}}`))
+	if cf := recvFrame(t, tr).(frames.ControlFrame); cf.Code() != frames.ControlAudioReady || cf.Metadata().Get(frames.MetaMarkName) != name {
+		t.Fatalf("unexpected notify frame %v", cf.Meta())
+	}
+
+	_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	end := recvFrame(t, tr).(frames.SystemFrame)
+	if end.Name() != "call_end" || end.Metadata().Get(frames.MetaCallEndReason) != "completed" {
+		t.Fatalf("unexpected call_end %v", end.Meta())
+	}
+}
+
+func TestEventWebhookMapsReason(t *testing.T) {
+	tr := New(Config{InsecureSkipVerify: true})
+	sess := &session{sendCh: make(chan outbound, 1), streamID: "stream-1", callSID: "call-1"}
+	sess.closed.Store(true)
+	tr.mu.Lock()
+	tr.sessions["stream-1"] = sess
+	tr.callStreams["call-1"] = "stream-1"
+	tr.mu.Unlock()
+
+	for _, status := range []string{"ringing", "busy"} {
+		req := httptest.NewRequest(http.MethodPost, "/event", strings.NewReader(`{"uuid":"call-1","status":"`+status+`"}`))
+		tr.handleEvent(httptest.NewRecorder(), req)
+	}
+	end := recvFrame(t, tr).(frames.SystemFrame)
+	if end.Name() != "call_end" || end.Metadata().Get(frames.MetaCallEndReason) != "busy" || end.Metadata().CallSID() != "call-1" {
+		t.Fatalf("unexpected call_end %v", end.Meta())
+	}
+	if tr.streamForCall("call-1") != "" {
+		t.Fatalf("expected stream to be detached")
+	}
+}
+
+func TestWebhooksRejectedWithoutSignatureSecret(t *testing.T) {
+	tr := New(Config{})
+	for path, handle := range map[string]http.HandlerFunc{"/answer": tr.handleAnswer, "/event": tr.handleEvent} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"uuid":"call-1","status":"completed"}`))
+		w := httptest.NewRecorder()
+		handle(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("expected unsigned %s webhook to be rejected, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestDialUsesApplicationToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("keygen: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: mustPKCS8(t, key)})
+
+	var mu sync.Mutex
+	var gotPath string
+	var gotBody map[string]any
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), ".")
+		sig, _ := b64.DecodeString(parts[len(parts)-1])
+		sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if len(parts) != 3 || rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], sig) != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		gotPath = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"uuid":"call-2","status":"started"}`))
+	}))
+	t.Cleanup(api.Close)
+
+	tr := New(Config{ApplicationID: "app-1", PrivateKey: string(keyPEM), PublicURL: "https://example.com", APIBaseURL: api.URL})
+	uuid, err := tr.DialWithOptions(context.Background(), "+14155550101", "+14155550100", "", transports.DialOptions{SendDigits: "1#"})
+	if err != nil || uuid != "call-2" {
+		t.Fatalf("dial uuid=%q err=%v", uuid, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	to := gotBody["to"].([]any)[0].(map[string]any)
+	if gotPath != "/v1/calls" || to["number"] != "14155550101" || to["dtmfAnswer"] != "1#" {
+		t.Fatalf("unexpected dial request %s %v", gotPath, gotBody)
+	}
+	if answer := gotBody["answer_url"].([]any); answer[0] != "https://example.com/answer" {
+		t.Fatalf("unexpected answer_url %v", answer)
+	}
+
+	if _, err := NewDialer(Config{ApplicationID: "app-1"}).Dial(context.Background(), "1", "2", ""); err == nil {
+		t.Fatalf("expected missing private key error")
+	}
+}
+
+func mustPKCS8(t *testing.T, key *rsa.PrivateKey) []byte {
+	t.Helper()
+	b, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return b
+}
+
+func TestNormalizeCallEndReason(t *testing.T) {
+	cases := map[string]string{
+		"completed":  "completed",
+		"busy":       "busy",
+		"unanswered": "no_answer",
+		"rejected":   "failed",
+		"answered":   "",
+		"weird":      "unknown",
+	}
+	for in, want := range cases {
+		if got := normalizeCallEndReason(in); got != want {
+			t.Fatalf("normalizeCallEndReason(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSendCarriesPartialFrames(t *testing.T) {
+	sess := &session{sendCh: make(chan outbound, 8), rate: 16000}
+	// Two 10ms frames make one 20ms frame with no silence between them.
+	sess.writeAudio(bytes.Repeat([]byte{1}, 320))
+	if len(sess.sendCh) != 0 {
+		t.Fatalf("expected the partial frame to be carried")
+	}
+	sess.writeAudio(bytes.Repeat([]byte{2}, 480))
+	msg := <-sess.sendCh
+	if len(msg.data) != 640 || msg.data[319] != 1 || msg.data[320] != 2 {
+		t.Fatalf("unexpected frame len=%d", len(msg.data))
+	}
+	// The 5ms tail is padded and sent once no more audio follows.
+	select {
+	case msg = <-sess.sendCh:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the carried tail to be flushed")
+	}
+	if len(msg.data) != 640 || msg.data[159] != 2 || msg.data[160] != 0 {
+		t.Fatalf("unexpected tail frame len=%d", len(msg.data))
+	}
+}
+
+func TestToPCM16Resamples(t *testing.T) {
+	af := frames.NewAudioFrame("s1", 0, bytes.Repeat([]byte{0xFF}, 80), 8000, 1, map[string]string{frames.MetaEncoding: "mulaw"})
+	if out, ok := toPCM16(af, 24000); !ok || len(out) != 80*3*2 {
+		t.Fatalf("expected 24kHz output, got len=%d ok=%v", len(out), ok)
+	}
+	if _, ok := toPCM16(af, 12000); ok {
+		t.Fatalf("expected rates that are not multiples of 8kHz to be refused")
+	}
+}
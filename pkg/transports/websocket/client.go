@@ -0,0 +1,113 @@
+package websocket
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	gws "github.com/gorilla/websocket"
+)
+
+// ClientOptions configures Dial.
+type ClientOptions struct {
+	// Token is sent as a bearer token.
+	Token  string
+	Origin string
+	Header http.Header
+}
+
+// Client is a minimal protocol client for tests, load tools and Go apps.
+// Recv must be called from a single goroutine; sends are safe for concurrent
+// use.
+type Client struct {
+	conn *gws.Conn
+	mu   sync.Mutex
+}
+
+// Dial connects to a websocket transport endpoint such as ws://host/ws.
+func Dial(ctx context.Context, url string, opts ClientOptions) (*Client, error) {
+	header := http.Header{}
+	for k, v := range opts.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	if opts.Token != "" {
+		header.Set("Authorization", "Bearer "+opts.Token)
+	}
+	if opts.Origin != "" {
+		header.Set("Origin", opts.Origin)
+	}
+	conn, resp, err := gws.DefaultDialer.DialContext(ctx, url, header)
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn}, nil
+}
+
+// Start sends the start message and waits for the server's "started" reply.
+func (c *Client) Start(callID string, meta map[string]string, audio *AudioFormat) (Message, error) {
+	if err := c.Send(Message{Type: TypeStart, CallID: callID, Metadata: meta, Audio: audio}); err != nil {
+		return Message{}, err
+	}
+	msg, err := c.Recv()
+	if err != nil {
+		return Message{}, err
+	}
+	if msg.Type != TypeStarted {
+		return msg, errors.New("websocket: expected started, got " + msg.Type + " " + msg.Message)
+	}
+	return msg, nil
+}
+
+// Send writes a JSON protocol message.
+func (c *Client) Send(msg Message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return c.write(gws.TextMessage, b)
+}
+
+// SendAudio writes a binary audio chunk.
+func (c *Client) SendAudio(chunk []byte) error { return c.write(gws.BinaryMessage, chunk) }
+
+// SendAudioJSON writes an audio chunk as a base64 "audio" message.
+func (c *Client) SendAudioJSON(chunk []byte) error {
+	return c.Send(Message{Type: TypeAudio, Data: base64.StdEncoding.EncodeToString(chunk)})
+}
+
+func (c *Client) SendDTMF(digit string) error { return c.Send(Message{Type: TypeDTMF, Digit: digit}) }
+func (c *Client) SendText(text string) error  { return c.Send(Message{Type: TypeText, Text: text}) }
+func (c *Client) Interrupt() error            { return c.Send(Message{Type: TypeInterrupt}) }
+func (c *Client) Mark(name string) error      { return c.Send(Message{Type: TypeMark, Name: name}) }
+func (c *Client) Stop(reason string) error    { return c.Send(Message{Type: TypeStop, Reason: reason}) }
+
+// Recv returns the next server message. Binary audio is returned as a
+// message of type "audio" with Payload set.
+func (c *Client) Recv() (Message, error) {
+	typ, data, err := c.conn.ReadMessage()
+	if err != nil {
+		return Message{}, err
+	}
+	if typ == gws.BinaryMessage {
+		return Message{Type: TypeAudio, Payload: data}, nil
+	}
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return Message{}, err
+	}
+	return msg, nil
+}
+
+func (c *Client) Close() error { return c.conn.Close() }
+
+func (c *Client) write(typ int, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteMessage(typ, data)
+}
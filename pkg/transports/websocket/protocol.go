@@ -0,0 +1,104 @@
+// Package websocket implements a generic WebSocket transport for browser and
+// app clients.
+//
+// # Protocol
+//
+// A session is one WebSocket connection. Control messages are JSON text
+// messages with a "type" field; audio travels as binary messages holding raw
+// audio in the encoding negotiated at start (or as base64 in an "audio" JSON
+// message when binary frames are inconvenient).
+//
+// Client to server:
+//
+//	{"type":"start","call_id":"optional","metadata":{"k":"v"},
+//	 "audio":{"encoding":"pcm16","sample_rate":16000,"channels":1}}
+//	<binary audio chunk>
+//	{"type":"audio","data":"<base64 audio>"}
+//	{"type":"dtmf","digit":"5"}
+//	{"type":"text","text":"I'd like to book a visit"}
+//	{"type":"interrupt"}
+//	{"type":"mark","name":"m-3"}          (playback reached a server mark)
+//	{"type":"stop","reason":"completed"}
+//
+// Server to client:
+//
+//	{"type":"started","call_id":"...","stream_id":"...","audio":{...}}
+//	<binary audio chunk>
+//	{"type":"clear"}                      (drop queued playback)
+//	{"type":"mark","name":"m-3"}          (echo back once played)
+//	{"type":"text","role":"assistant","text":"..."}   (when EmitText is set)
+//	{"type":"end","reason":"..."}       (server is closing, e.g. "agent_hangup")
+//	{"type":"error","message":"..."}
+//
+// The server assigns every session its own call_id, returned in "started";
+// a call_id sent in "start" is kept as client_call_id metadata only.
+//
+// The first message must be "start"; anything before it is rejected. Audio
+// encodings are passed through as frame metadata ("pcm16" by default, "opus"
+// and others are accepted as-is), so codecs can be added without changing
+// the protocol.
+package websocket
+
+// Message types.
+const (
+	TypeStart     = "start"
+	TypeStarted   = "started"
+	TypeAudio     = "audio"
+	TypeDTMF      = "dtmf"
+	TypeText      = "text"
+	TypeInterrupt = "interrupt"
+	TypeClear     = "clear"
+	TypeMark      = "mark"
+	TypeStop      = "stop"
+	TypeEnd       = "end"
+	TypeError     = "error"
+)
+
+// Audio encodings understood by the transport. Other values are passed
+// through untouched.
+const (
+	EncodingPCM16 = "pcm16"
+	EncodingOpus  = "opus"
+	EncodingMuLaw = "mulaw"
+)
+
+// AudioFormat describes audio chunks in one direction of a session.
+type AudioFormat struct {
+	Encoding   string `json:"encoding" mapstructure:"encoding"`
+	SampleRate int    `json:"sample_rate" mapstructure:"sample_rate"`
+	Channels   int    `json:"channels" mapstructure:"channels"`
+}
+
+func (a AudioFormat) withDefaults(def AudioFormat) AudioFormat {
+	if a.Encoding == "" {
+		a.Encoding = def.Encoding
+	}
+	if a.SampleRate <= 0 {
+		a.SampleRate = def.SampleRate
+	}
+	if a.Channels <= 0 {
+		a.Channels = def.Channels
+	}
+	return a
+}
+
+// Message is a JSON protocol message in either direction. Only the fields
+// relevant to Type are set.
+type Message struct {
+	Type     string            `json:"type"`
+	CallID   string            `json:"call_id,omitempty"`
+	StreamID string            `json:"stream_id,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Audio    *AudioFormat      `json:"audio,omitempty"`
+	Data     string            `json:"data,omitempty"`
+	Digit    string            `json:"digit,omitempty"`
+	Text     string            `json:"text,omitempty"`
+	Role     string            `json:"role,omitempty"`
+	Name     string            `json:"name,omitempty"`
+	Reason   string            `json:"reason,omitempty"`
+	Message  string            `json:"message,omitempty"`
+
+	// Payload holds binary audio received by Client.Recv; it is never
+	// serialized.
+	Payload []byte `json:"-"`
+}
@@ -0,0 +1,643 @@
+package websocket
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	gws "github.com/gorilla/websocket"
+	"github.com/harunnryd/ranya/pkg/frames"
+	"github.com/harunnryd/ranya/pkg/transports"
+)
+
+type Config struct {
+	ServerAddr string `mapstructure:"server_addr"`
+	PublicURL  string `mapstructure:"public_url"`
+	Path       string `mapstructure:"path"`
+	// AuthTokens are the accepted bearer tokens. Without any, every
+	// connection is refused unless InsecureNoAuth is set.
+	AuthTokens []string `mapstructure:"auth_tokens"`
+	// InsecureNoAuth accepts connections without a token when AuthTokens is
+	// empty. Use it for local testing only.
+	InsecureNoAuth bool     `mapstructure:"insecure_no_auth"`
+	AllowAnyOrigin bool     `mapstructure:"allow_any_origin"`
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+	// InputAudio is the default inbound format when start omits "audio".
+	InputAudio AudioFormat `mapstructure:"input_audio"`
+	// OutputAudio is announced in "started" and describes binary audio sent
+	// to the client.
+	OutputAudio AudioFormat `mapstructure:"output_audio"`
+	// EmitText forwards final user transcripts and assistant text to the
+	// client as "text" messages.
+	EmitText       bool `mapstructure:"emit_text"`
+	MaxMessageSize int  `mapstructure:"max_message_size"`
+	MaxMetadata    int  `mapstructure:"max_metadata"`
+}
+
+var defaultAudio = AudioFormat{Encoding: EncodingPCM16, SampleRate: 16000, Channels: 1}
+
+func (c Config) withDefaults() Config {
+	if c.ServerAddr == "" {
+		c.ServerAddr = ":8081"
+	}
+	if c.Path == "" {
+		c.Path = "/ws"
+	}
+	c.InputAudio = c.InputAudio.withDefaults(defaultAudio)
+	c.OutputAudio = c.OutputAudio.withDefaults(c.InputAudio)
+	if c.MaxMessageSize <= 0 {
+		c.MaxMessageSize = 1 << 20
+	}
+	if c.MaxMetadata <= 0 {
+		c.MaxMetadata = 32
+	}
+	return c
+}
+
+// reservedMeta are keys the transport owns; clients cannot override them
+// through start metadata.
+var reservedMeta = map[string]bool{
+	frames.MetaStreamID:     true,
+	frames.MetaCallSID:      true,
+	frames.MetaClientCallID: true,
+	frames.MetaTraceID:      true,
+	frames.MetaSource:       true,
+}
+
+type Transport struct {
+	cfg      Config
+	server   *http.Server
+	upgrader gws.Upgrader
+	recvCh   chan frames.Frame
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	stopped  bool
+	handlers sync.WaitGroup
+
+	markSeq     atomic.Uint64
+	rejectCalls atomic.Bool
+	stopOnce    sync.Once
+}
+
+func New(cfg Config) *Transport {
+	cfg = cfg.withDefaults()
+	t := &Transport{
+		cfg: cfg,
+		upgrader: gws.Upgrader{
+			ReadBufferSize:  4096,
+			WriteBufferSize: 4096,
+		},
+		recvCh:   make(chan frames.Frame, 512),
+		sessions: make(map[string]*session),
+	}
+	if cfg.AllowAnyOrigin || len(cfg.AllowedOrigins) > 0 {
+		t.upgrader.CheckOrigin = t.checkOrigin
+	}
+	return t
+}
+
+func (t *Transport) Name() string { return "websocket" }
+
+func (t *Transport) Recv() <-chan frames.Frame { return t.recvCh }
+
+func (t *Transport) ReadyFields() map[string]any {
+	return map[string]any{"websocket_url": t.websocketURL()}
+}
+
+func (t *Transport) Start(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if len(t.cfg.AuthTokens) == 0 {
+		if t.cfg.InsecureNoAuth {
+			slog.Warn("websocket_auth_disabled", "detail", "connections without a token are accepted; set auth_tokens in production")
+		} else {
+			slog.Warn("websocket_auth_tokens_missing", "detail", "all connections are refused until auth_tokens is set")
+		}
+	}
+	mux := http.NewServeMux()
+	mux.Handle(t.cfg.Path, t)
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/ready", t.handleReady)
+	t.server = &http.Server{
+		Addr:              t.cfg.ServerAddr,
+		ReadHeaderTimeout: 5 * time.Second,
+		Handler:           mux,
+	}
+	go func() {
+		<-ctx.Done()
+		_ = t.server.Close()
+	}()
+	go func() {
+		if err := t.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("websocket_transport_server_error", "error", err.Error())
+		}
+	}()
+	return nil
+}
+
+func (t *Transport) Stop() error {
+	t.stopOnce.Do(func() {
+		t.mu.Lock()
+		t.stopped = true
+		sessions := t.sessions
+		t.sessions = make(map[string]*session)
+		t.mu.Unlock()
+		if t.server != nil {
+			_ = t.server.Close()
+		}
+		for _, sess := range sessions {
+			_ = sess.enqueueJSON(Message{Type: TypeEnd, Reason: "shutdown"})
+			_ = sess.close()
+		}
+		// Handlers may still be emitting call_end; recvCh closes after them.
+		done := make(chan struct{})
+		go func() {
+			t.handlers.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			close(t.recvCh)
+		case <-time.After(2 * time.Second):
+			slog.Warn("websocket_transport_stop_timeout")
+		}
+	})
+	return nil
+}
+
+// DrainCalls refuses new connections with 503 while open sessions carry on.
+func (t *Transport) DrainCalls() {
+	t.rejectCalls.Store(true)
+}
+
+func (t *Transport) handleReady(w http.ResponseWriter, r *http.Request) {
+	t.mu.Lock()
+	stopped := t.stopped
+	t.mu.Unlock()
+	if stopped || t.rejectCalls.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// ServeHTTP upgrades an authenticated request and runs one session. It can
+// be mounted on an existing mux instead of calling Start.
+func (t *Transport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t.mu.Lock()
+	if t.stopped || t.rejectCalls.Load() {
+		t.mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	t.handlers.Add(1)
+	t.mu.Unlock()
+	defer t.handlers.Done()
+
+	if !t.authorized(r) {
+		slog.Warn("websocket_unauthorized", "remote", r.RemoteAddr)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	conn, err := t.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	conn.SetReadLimit(int64(t.cfg.MaxMessageSize))
+	sess := newSession(conn)
+	go sess.loop()
+	defer func() { _ = sess.close() }()
+
+	reason := "transport_closed"
+	for {
+		typ, data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		if typ == gws.BinaryMessage {
+			t.handleAudio(sess, data)
+			continue
+		}
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			sess.enqueueJSON(Message{Type: TypeError, Message: "invalid json"})
+			continue
+		}
+		if msg.Type != TypeStart && sess.streamID == "" {
+			sess.enqueueJSON(Message{Type: TypeError, Message: "session not started"})
+			continue
+		}
+		if msg.Type == TypeStop {
+			reason = msg.Reason
+			if reason == "" {
+				reason = "completed"
+			}
+			break
+		}
+		t.handleMessage(sess, msg)
+	}
+	if r := sess.endReason(); r != "" {
+		reason = r
+	}
+	if sess.streamID != "" {
+		meta := sess.frameMeta()
+		meta[frames.MetaCallEndReason] = reason
+		if !transports.SendCallEnd(t.recvCh, frames.NewSystemFrame(sess.streamID, time.Now().UnixNano(), "call_end", meta)) {
+			slog.Warn("websocket_call_end_dropped", "call_sid", sess.callID, "stream_id", sess.streamID)
+		}
+		t.detach(sess.streamID)
+	}
+}
+
+func (t *Transport) handleMessage(sess *session, msg Message) {
+	switch msg.Type {
+	case TypeStart:
+		if sess.streamID != "" {
+			sess.enqueueJSON(Message{Type: TypeError, Message: "session already started"})
+			return
+		}
+		t.start(sess, msg)
+	case TypeAudio:
+		payload, err := frames.DecodeAudioBase64(msg.Data)
+		if err != nil {
+			sess.enqueueJSON(Message{Type: TypeError, Message: "invalid audio data"})
+			return
+		}
+		t.emitAudio(sess, frames.NewAudioFrameFromBuf(sess.streamID, time.Now().UnixNano(), payload, sess.input.SampleRate, sess.input.Channels, sess.audioMeta()))
+	case TypeDTMF:
+		if msg.Digit == "" {
+			return
+		}
+		meta := sess.frameMeta()
+		meta[frames.MetaDTMFDigit] = msg.Digit
+		nonBlockingSend(t.recvCh, frames.NewControlFrame(sess.streamID, time.Now().UnixNano(), frames.ControlDTMF, meta))
+	case TypeText:
+		text := strings.TrimSpace(msg.Text)
+		if text == "" {
+			return
+		}
+		// Typed input enters the pipeline like a final transcript.
+		meta := sess.frameMeta()
+		meta[frames.MetaSource] = "stt"
+		meta[frames.MetaIsFinal] = "true"
+		meta[frames.MetaInputMode] = "text"
+		nonBlockingSend(t.recvCh, frames.NewTextFrame(sess.streamID, time.Now().UnixNano(), text, meta))
+	case TypeInterrupt:
+		nonBlockingSend(t.recvCh, frames.NewControlFrame(sess.streamID, time.Now().UnixNano(), frames.ControlStartInterruption, sess.frameMeta()))
+	case TypeMark:
+		meta := sess.frameMeta()
+		meta[frames.MetaMarkName] = msg.Name
+		nonBlockingSend(t.recvCh, frames.NewControlFrame(sess.streamID, time.Now().UnixNano(), frames.ControlAudioReady, meta))
+	default:
+		sess.enqueueJSON(Message{Type: TypeError, Message: "unknown message type"})
+	}
+}
+
+func (t *Transport) start(sess *session, msg Message) {
+	input := t.cfg.InputAudio
+	if msg.Audio != nil {
+		input = msg.Audio.withDefaults(t.cfg.InputAudio)
+	}
+	// The call SID is always minted here: sessions from every transport
+	// share one registry, so a client-chosen SID could attach to another
+	// live call. The client's own ID travels as client_call_id.
+	callID := "ws-" + uuid.NewString()
+	sess.callID = callID
+	sess.clientCallID = strings.TrimSpace(msg.CallID)
+	sess.streamID = uuid.NewString()
+	sess.traceID = uuid.NewString()
+	sess.input = input
+	sess.extra = make(map[string]string, len(msg.Metadata))
+	for k, v := range msg.Metadata {
+		if len(sess.extra) >= t.cfg.MaxMetadata {
+			break
+		}
+		if k == "" || reservedMeta[k] {
+			continue
+		}
+		sess.extra[k] = v
+	}
+	t.mu.Lock()
+	t.sessions[sess.streamID] = sess
+	t.mu.Unlock()
+
+	out := t.cfg.OutputAudio
+	sess.enqueueJSON(Message{Type: TypeStarted, CallID: callID, StreamID: sess.streamID, Audio: &out})
+	nonBlockingSend(t.recvCh, frames.NewSystemFrame(sess.streamID, time.Now().UnixNano(), "call_start", sess.frameMeta()))
+}
+
+func (t *Transport) handleAudio(sess *session, data []byte) {
+	if sess.streamID == "" {
+		sess.enqueueJSON(Message{Type: TypeError, Message: "session not started"})
+		return
+	}
+	t.emitAudio(sess, frames.NewAudioFrameFromPool(sess.streamID, time.Now().UnixNano(), data, sess.input.SampleRate, sess.input.Channels, sess.audioMeta()))
+}
+
+func (t *Transport) emitAudio(sess *session, af frames.AudioFrame) {
+	if !nonBlockingSend(t.recvCh, af) {
+		frames.ReleaseAudioFrame(af)
+	}
+}
+
+func (t *Transport) Send(f frames.Frame) error {
+	md := frames.MetadataOf(f)
+	sess := t.session(md.StreamID())
+	if sess == nil {
+		return nil
+	}
+	switch f.Kind() {
+	case frames.KindAudio:
+		return sess.enqueueAudio(f.(frames.AudioFrame))
+	case frames.KindControl:
+		cf := f.(frames.ControlFrame)
+		switch cf.Code() {
+		case frames.ControlFlush, frames.ControlCancel, frames.ControlStartInterruption:
+			return sess.enqueueJSON(Message{Type: TypeClear})
+		case frames.ControlAudioReady:
+			if md.Get(frames.MetaSource) == "transport" || md.Get(frames.MetaMarkName) != "" {
+				return nil
+			}
+			return sess.enqueueJSON(Message{Type: TypeMark, Name: "m-" + strconv.FormatUint(t.markSeq.Add(1), 10)})
+		}
+	case frames.KindText:
+		if !t.cfg.EmitText {
+			return nil
+		}
+		tf := f.(frames.TextFrame)
+		switch md.Get(frames.MetaSource) {
+		case "llm":
+			return sess.enqueueJSON(Message{Type: TypeText, Role: "assistant", Text: tf.Text()})
+		case "stt":
+			if md.Get(frames.MetaIsFinal) == "true" && md.Get(frames.MetaInputMode) != "text" {
+				return sess.enqueueJSON(Message{Type: TypeText, Role: "user", Text: tf.Text()})
+			}
+		}
+	}
+	return nil
+}
+
+// HangupCall sends "end" to the client owning callSID and closes the
+// connection once queued audio is written.
+func (t *Transport) HangupCall(ctx context.Context, callSID string) error {
+	_ = ctx
+	if strings.TrimSpace(callSID) == "" {
+		return errors.New("call sid required")
+	}
+	var sess *session
+	t.mu.Lock()
+	for _, s := range t.sessions {
+		if s.callID == callSID {
+			sess = s
+			break
+		}
+	}
+	t.mu.Unlock()
+	if sess == nil {
+		return fmt.Errorf("websocket: unknown call %q", callSID)
+	}
+	sess.end("agent_hangup")
+	return nil
+}
+
+func (t *Transport) session(streamID string) *session {
+	if streamID == "" {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessions[streamID]
+}
+
+func (t *Transport) detach(streamID string) {
+	t.mu.Lock()
+	delete(t.sessions, streamID)
+	t.mu.Unlock()
+}
+
+// authorized checks the bearer token from the Authorization header or the
+// "token" query parameter (browsers cannot set headers on WebSocket requests).
+func (t *Transport) authorized(r *http.Request) bool {
+	if len(t.cfg.AuthTokens) == 0 {
+		return t.cfg.InsecureNoAuth
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	if token == "" {
+		return false
+	}
+	for _, allowed := range t.cfg.AuthTokens {
+		if allowed != "" && subtle.ConstantTimeCompare([]byte(allowed), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Transport) checkOrigin(r *http.Request) bool {
+	if t.cfg.AllowAnyOrigin {
+		return true
+	}
+	origin := strings.TrimRight(strings.TrimSpace(r.Header.Get("Origin")), "/")
+	if origin == "" {
+		return true
+	}
+	originHost := strings.TrimPrefix(strings.TrimPrefix(origin, "https://"), "http://")
+	for _, allowed := range t.cfg.AllowedOrigins {
+		a := strings.TrimRight(strings.TrimSpace(allowed), "/")
+		if a == "" {
+			continue
+		}
+		if strings.HasPrefix(a, "http://") || strings.HasPrefix(a, "https://") {
+			if strings.EqualFold(a, origin) {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(a, originHost) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Transport) websocketURL() string {
+	if t.cfg.PublicURL != "" {
+		host := strings.TrimRight(t.cfg.PublicURL, "/")
+		host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+		return "wss://" + host + t.cfg.Path
+	}
+	addr := t.cfg.ServerAddr
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+	return "ws://" + addr + t.cfg.Path
+}
+
+// writeTimeout bounds a single write so a stalled client cannot pin the
+// session writer.
+const writeTimeout = 5 * time.Second
+
+type outbound struct {
+	typ  int
+	data []byte
+	// audio is retained while queued and released after the write.
+	audio *frames.AudioFrame
+}
+
+type session struct {
+	conn   *gws.Conn
+	sendCh chan outbound
+	closed atomic.Bool
+	mu     sync.Mutex
+
+	// Set once by start on the read goroutine before the session is
+	// registered, then read-only.
+	callID       string
+	clientCallID string
+	streamID     string
+	traceID      string
+	input        AudioFormat
+	extra        map[string]string
+
+	// reason is set when the server ends the session; guarded by mu.
+	reason string
+}
+
+func newSession(conn *gws.Conn) *session {
+	return &session{conn: conn, sendCh: make(chan outbound, 256)}
+}
+
+func (s *session) frameMeta() map[string]string {
+	meta := make(map[string]string, len(s.extra)+5)
+	for k, v := range s.extra {
+		meta[k] = v
+	}
+	meta[frames.MetaStreamID] = s.streamID
+	meta[frames.MetaCallSID] = s.callID
+	meta[frames.MetaTraceID] = s.traceID
+	meta[frames.MetaSource] = "transport"
+	if s.clientCallID != "" {
+		meta[frames.MetaClientCallID] = s.clientCallID
+	}
+	return meta
+}
+
+func (s *session) audioMeta() map[string]string {
+	meta := s.frameMeta()
+	meta[frames.MetaEncoding] = s.input.Encoding
+	meta[frames.MetaCodec] = s.input.Encoding
+	meta[frames.MetaFormat] = s.input.Encoding + "_" + strconv.Itoa(s.input.SampleRate) + "_" + strconv.Itoa(s.input.Channels) + "ch"
+	return meta
+}
+
+func (s *session) enqueueJSON(msg Message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return s.enqueue(gws.TextMessage, b)
+}
+
+func (s *session) enqueue(typ int, data []byte) error {
+	s.push(outbound{typ: typ, data: data})
+	return nil
+}
+
+// enqueueAudio queues af without copying; the pipeline releases its own
+// reference once Send returns, so the queue holds another one.
+func (s *session) enqueueAudio(af frames.AudioFrame) error {
+	msg := outbound{typ: gws.BinaryMessage, data: af.RawPayload()}
+	if frames.RetainAudioFrame(af) {
+		msg.audio = &af
+	} else if af.Pooled() {
+		return nil
+	}
+	s.push(msg)
+	return nil
+}
+
+func (s *session) push(msg outbound) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed.Load() {
+		msg.release()
+		return
+	}
+	select {
+	case s.sendCh <- msg:
+	default:
+		msg.release()
+	}
+}
+
+// loop writes queued messages and closes the connection once the queue is
+// closed and drained.
+func (s *session) loop() {
+	for msg := range s.sendCh {
+		_ = s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		_ = s.conn.WriteMessage(msg.typ, msg.data)
+		msg.release()
+	}
+	_ = s.conn.Close()
+}
+
+// end tells the client why the session is ending and closes it.
+func (s *session) end(reason string) {
+	_ = s.enqueueJSON(Message{Type: TypeEnd, Reason: reason})
+	s.mu.Lock()
+	s.reason = reason
+	s.mu.Unlock()
+	_ = s.close()
+}
+
+func (s *session) endReason() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reason
+}
+
+func (s *session) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed.CompareAndSwap(false, true) {
+		close(s.sendCh)
+	}
+	return nil
+}
+
+func (m outbound) release() {
+	if m.audio != nil {
+		frames.ReleaseAudioFrame(*m.audio)
+	}
+}
+
+func nonBlockingSend(ch chan frames.Frame, f frames.Frame) bool {
+	select {
+	case ch <- f:
+		return true
+	default:
+		return false
+	}
+}
+
+var _ transports.Transport = (*Transport)(nil)
+var _ transports.CallHanger = (*Transport)(nil)
+var _ transports.ReadyReporter = (*Transport)(nil)
+var _ transports.CallDrainer = (*Transport)(nil)
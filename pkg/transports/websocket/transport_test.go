@@ -0,0 +1,180 @@
+package websocket
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harunnryd/ranya/pkg/frames"
+)
+
+func startServer(t *testing.T, cfg Config) (*Transport, string) {
+	t.Helper()
+	tr := New(cfg)
+	srv := httptest.NewServer(tr)
+	t.Cleanup(func() {
+		srv.Close()
+		_ = tr.Stop()
+	})
+	return tr, "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func recvFrame(t *testing.T, tr *Transport) frames.Frame {
+	t.Helper()
+	select {
+	case f := <-tr.Recv():
+		return f
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for frame")
+		return nil
+	}
+}
+
+func TestSessionRoundTrip(t *testing.T) {
+	tr, url := startServer(t, Config{AuthTokens: []string{"secret"}})
+	c, err := Dial(context.Background(), url, ClientOptions{Token: "secret"})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	started, err := c.Start("call-1", map[string]string{"customer_id": "42", frames.MetaCallSID: "spoof"}, nil)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if !strings.HasPrefix(started.CallID, "ws-") || started.StreamID == "" || started.Audio.Encoding != EncodingPCM16 {
+		t.Fatalf("unexpected started message %+v", started)
+	}
+	start := recvFrame(t, tr).(frames.SystemFrame)
+	md := start.Metadata()
+	if start.Name() != "call_start" || md.CallSID() != started.CallID || md.Get(frames.MetaClientCallID) != "call-1" || md.Get("customer_id") != "42" {
+		t.Fatalf("unexpected call_start %v", start.Meta())
+	}
+
+	_ = c.SendAudio([]byte{1, 2, 3, 4})
+	af := recvFrame(t, tr).(frames.AudioFrame)
+	if af.Rate() != 16000 || len(af.RawPayload()) != 4 || af.Metadata().Get(frames.MetaEncoding) != EncodingPCM16 {
+		t.Fatalf("unexpected audio frame rate=%d meta=%v", af.Rate(), af.Meta())
+	}
+	frames.ReleaseAudioFrame(af)
+
+	_ = c.SendDTMF("7")
+	if cf := recvFrame(t, tr).(frames.ControlFrame); cf.Code() != frames.ControlDTMF || cf.Metadata().Get(frames.MetaDTMFDigit) != "7" {
+		t.Fatalf("unexpected dtmf frame %v", cf.Meta())
+	}
+	_ = c.SendText("book a visit")
+	if tf := recvFrame(t, tr).(frames.TextFrame); tf.Text() != "book a visit" || tf.Metadata().Get(frames.MetaIsFinal) != "true" {
+		t.Fatalf("unexpected text frame %v", tf.Meta())
+	}
+
+	out := map[string]string{frames.MetaStreamID: started.StreamID}
+	_ = tr.Send(frames.NewAudioFrameFromPool(started.StreamID, 1, []byte{9, 9}, 16000, 1, out))
+	_ = tr.Send(frames.NewControlFrame(started.StreamID, 2, frames.ControlStartInterruption, out))
+	if msg, err := c.Recv(); err != nil || msg.Type != TypeAudio || len(msg.Payload) != 2 {
+		t.Fatalf("expected audio, got %+v err=%v", msg, err)
+	}
+	if msg, err := c.Recv(); err != nil || msg.Type != TypeClear {
+		t.Fatalf("expected clear, got %+v err=%v", msg, err)
+	}
+
+	_ = c.Stop("completed")
+	end := recvFrame(t, tr).(frames.SystemFrame)
+	if end.Name() != "call_end" || end.Metadata().Get(frames.MetaCallEndReason) != "completed" {
+		t.Fatalf("unexpected call_end %v", end.Meta())
+	}
+}
+
+func TestRejectsBadTokenAndOrigin(t *testing.T) {
+	_, url := startServer(t, Config{AuthTokens: []string{"secret"}, AllowedOrigins: []string{"https://app.example.com"}})
+	if _, err := Dial(context.Background(), url, ClientOptions{Token: "wrong"}); err == nil {
+		t.Fatalf("expected bad token to be rejected")
+	}
+	if _, err := Dial(context.Background(), url, ClientOptions{Token: "secret", Origin: "https://evil.example.com"}); err == nil {
+		t.Fatalf("expected foreign origin to be rejected")
+	}
+	c, err := Dial(context.Background(), url+"?token=secret", ClientOptions{Origin: "https://app.example.com"})
+	if err != nil {
+		t.Fatalf("expected query token and allowed origin to pass: %v", err)
+	}
+	_ = c.Close()
+}
+
+func TestRejectsConnectionsWithoutAuthTokens(t *testing.T) {
+	_, url := startServer(t, Config{})
+	if _, err := Dial(context.Background(), url, ClientOptions{}); err == nil {
+		t.Fatalf("expected connections to be refused without auth_tokens")
+	}
+	if _, err := Dial(context.Background(), url, ClientOptions{Token: "anything"}); err == nil {
+		t.Fatalf("expected any token to be refused without auth_tokens")
+	}
+}
+
+func TestMessagesBeforeStartAreRejected(t *testing.T) {
+	_, url := startServer(t, Config{InsecureNoAuth: true})
+	c, err := Dial(context.Background(), url, ClientOptions{})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+	_ = c.SendDTMF("1")
+	if msg, err := c.Recv(); err != nil || msg.Type != TypeError {
+		t.Fatalf("expected error message, got %+v err=%v", msg, err)
+	}
+}
+
+func TestHangupCallEndsSession(t *testing.T) {
+	tr, url := startServer(t, Config{InsecureNoAuth: true})
+	c, err := Dial(context.Background(), url, ClientOptions{})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+	started, err := c.Start("call-9", nil, nil)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	_ = recvFrame(t, tr)
+
+	_ = tr.Send(frames.NewAudioFrameFromPool(started.StreamID, 1, []byte{1, 2}, 16000, 1, map[string]string{frames.MetaStreamID: started.StreamID}))
+	if err := tr.HangupCall(context.Background(), started.CallID); err != nil {
+		t.Fatalf("hangup: %v", err)
+	}
+	if msg, err := c.Recv(); err != nil || msg.Type != TypeAudio {
+		t.Fatalf("expected queued audio before end, got %+v err=%v", msg, err)
+	}
+	if msg, err := c.Recv(); err != nil || msg.Type != TypeEnd || msg.Reason != "agent_hangup" {
+		t.Fatalf("expected end message, got %+v err=%v", msg, err)
+	}
+	end := recvFrame(t, tr).(frames.SystemFrame)
+	if end.Name() != "call_end" || end.Metadata().Get(frames.MetaCallEndReason) != "agent_hangup" {
+		t.Fatalf("unexpected call_end %v", end.Meta())
+	}
+}
+
+func TestClientCannotChooseCallSID(t *testing.T) {
+	tr, url := startServer(t, Config{InsecureNoAuth: true})
+	var sids []string
+	for i := 0; i < 2; i++ {
+		c, err := Dial(context.Background(), url, ClientOptions{})
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer c.Close()
+		if _, err := c.Start("CA-live-call", nil, nil); err != nil {
+			t.Fatalf("start: %v", err)
+		}
+		md := recvFrame(t, tr).(frames.SystemFrame).Metadata()
+		if md.CallSID() == "CA-live-call" || md.Get(frames.MetaClientCallID) != "CA-live-call" {
+			t.Fatalf("client call id must not become the call sid: %v", md.Map())
+		}
+		sids = append(sids, md.CallSID())
+	}
+	if sids[0] == sids[1] {
+		t.Fatalf("expected distinct call sids, got %v", sids)
+	}
+	if err := tr.HangupCall(context.Background(), "CA-live-call"); err == nil {
+		t.Fatalf("expected hangup by client call id to fail")
+	}
+}